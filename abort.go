@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExecuteAbort is a CommandFunc that fails the tape, with the message of the
+// command if any. The evaluation stops right after it, without rendering the
+// outputs, so that a tape can give up on a machine it can't be recorded on,
+// e.g. in a Skip block.
+func ExecuteAbort(c Command, v *VHS) {
+	if c.Args == "" {
+		v.Errors = append(v.Errors, errors.New("the tape was aborted"))
+		return
+	}
+	v.Errors = append(v.Errors, fmt.Errorf("the tape was aborted: %s", c.Args))
+}
+
+// allErrors returns the errors of the recording, along with the ones of the
+// other panes of a split recording.
+func (v *VHS) allErrors() []error {
+	errs := v.Errors
+	if v.panes != nil {
+		for _, pane := range v.panes.list[1:] {
+			errs = append(errs, pane.Errors...)
+		}
+	}
+	return errs
+}
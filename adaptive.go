@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const textConcatFile = "frames-text.ffconcat"
+const cursorConcatFile = "frames-cursor.ffconcat"
+
+// MakeAdaptiveFrames writes the ffconcat scripts used as the inputs of ffmpeg
+// when the adaptive framerate is enabled.
+//
+// Frames are still captured at the full framerate, but consecutive frames
+// which are identical are merged into a single frame which is held for
+// longer. Idle periods of the recording therefore become a handful of frames
+// with long delays, while typing and animations keep every frame.
+func MakeAdaptiveFrames(opts VideoOptions, frames int) error {
+	interval := time.Second / time.Duration(opts.Framerate)
+
+	type frame struct {
+		number   int
+		duration time.Duration
+	}
+
+	var kept []frame
+	var prevText, prevCursor []byte
+	for n := opts.StartingFrame; n < opts.StartingFrame+frames; n++ {
+		text, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(textFrameFormat, n)))
+		if err != nil {
+			return err
+		}
+		cursor, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(cursorFrameFormat, n)))
+		if err != nil {
+			return err
+		}
+
+		if len(kept) > 0 && bytes.Equal(text, prevText) && bytes.Equal(cursor, prevCursor) {
+			kept[len(kept)-1].duration += interval
+			continue
+		}
+		kept = append(kept, frame{number: n, duration: interval})
+		prevText, prevCursor = text, cursor
+	}
+
+	if len(kept) == 0 {
+		return fmt.Errorf("no frames to render")
+	}
+
+	for file, format := range map[string]string{
+		textConcatFile:   textFrameFormat,
+		cursorConcatFile: cursorFrameFormat,
+	} {
+		var s strings.Builder
+		s.WriteString("ffconcat version 1.0\n")
+		for _, f := range kept {
+			fmt.Fprintf(&s, "file '%s'\nduration %f\n", fmt.Sprintf(format, f.number), f.duration.Seconds())
+		}
+		// The duration of the last entry is only honored if the file is
+		// listed once more.
+		fmt.Fprintf(&s, "file '%s'\n", fmt.Sprintf(format, kept[len(kept)-1].number))
+
+		if err := os.WriteFile(filepath.Join(opts.Input, file), []byte(s.String()), 0o644); err != nil { //nolint:gosec,gomnd
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMakeAdaptiveFrames(t *testing.T) {
+	dir := t.TempDir()
+	frames := []string{"a", "a", "a", "b", "c", "c"}
+	for i, f := range frames {
+		for _, format := range []string{textFrameFormat, cursorFrameFormat} {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(format, i+1)), []byte(f), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	opts := VideoOptions{Framerate: 10, Input: dir, StartingFrame: 1}
+	if err := MakeAdaptiveFrames(opts, len(frames)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, textConcatFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `ffconcat version 1.0
+file 'frame-text-00001.png'
+duration 0.300000
+file 'frame-text-00004.png'
+duration 0.100000
+file 'frame-text-00005.png'
+duration 0.200000
+file 'frame-text-00005.png'
+`
+	if string(got) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	cursor, err := os.ReadFile(filepath.Join(dir, cursorConcatFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cursor) != strings.ReplaceAll(expected, "text", "cursor") {
+		t.Errorf("expected cursor frames to match text frames, got:\n%s", cursor)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// AudioClip is an audio file played in the MP4 and WebM outputs from Start of
+// the recording.
+type AudioClip struct {
+	File  string
+	Start time.Duration
+}
+
+// ExecuteAudio plays the audio file from the current position of the
+// recording, or later by the duration of the command.
+func ExecuteAudio(c Command, v *VHS) {
+	if _, err := os.Stat(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Audio %s`: %w", c.Args, err))
+		return
+	}
+	var delay time.Duration
+	if c.Options != "" {
+		delay, _ = time.ParseDuration(c.Options)
+	}
+	video := &v.Options.Video
+	video.Audio = append(video.Audio, AudioClip{File: c.Args, Start: v.overlayStart() + delay})
+}
+
+// audioInputs returns the ffmpeg arguments to read the audio clips, after the
+// other inputs, and the filter which mixes them into a track as long as the
+// video.
+func audioInputs(opts VideoOptions) ([]string, string) {
+	if len(opts.Audio) == 0 {
+		return nil, ""
+	}
+	input := inputCount(opts)
+	if len(opts.Chapters) > 0 {
+		input++
+	}
+
+	var args []string
+	var filter, labels strings.Builder
+	for i, clip := range opts.Audio {
+		args = append(args, "-i", clip.File)
+		fmt.Fprintf(&filter, ";[%d:a]adelay=delays=%d:all=1[audio%d]", input+i, outputTime(opts, clip.Start).Milliseconds(), i)
+		fmt.Fprintf(&labels, "[audio%d]", i)
+	}
+	fmt.Fprintf(&filter, ";%samix=inputs=%d:duration=longest:normalize=0,apad[sound]", labels.String(), len(opts.Audio))
+	return args, filter.String()
+}
+
+// audioOutput returns the ffmpeg output arguments of the audio track, encoded
+// with the codec, which is cut at the end of the video. Without audio clips,
+// the output has no audio.
+func audioOutput(opts VideoOptions, codec string) []string {
+	if len(opts.Audio) == 0 {
+		return []string{"-an"}
+	}
+	return []string{"-map", "[sound]", "-c:a", codec, "-shortest"}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteAudio(t *testing.T) {
+	dir := t.TempDir()
+	narration := filepath.Join(dir, "narration.mp3")
+	if err := os.WriteFile(narration, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := New()
+	_ = os.RemoveAll(v.Options.Video.Input)
+	ExecuteSetAudio(Command{Type: SET, Options: "Audio", Args: narration}, &v)
+	v.frames = 2 * v.Options.Video.Framerate
+	ExecuteAudio(Command{Type: AUDIO, Options: "500ms", Args: narration}, &v)
+	ExecuteAudio(Command{Type: AUDIO, Args: filepath.Join(dir, "missing.wav")}, &v)
+
+	want := []AudioClip{{File: narration}, {File: narration, Start: 2500 * time.Millisecond}}
+	if len(v.Options.Video.Audio) != len(want) {
+		t.Fatalf("expected the clips %v, got %v", want, v.Options.Video.Audio)
+	}
+	for i, clip := range v.Options.Video.Audio {
+		if clip != want[i] {
+			t.Errorf("expected the clip %v, got %v", want[i], clip)
+		}
+	}
+	if len(v.Errors) != 1 || !strings.Contains(v.Errors[0].Error(), "missing.wav") {
+		t.Errorf("expected an error for the missing file, got %v", v.Errors)
+	}
+}
+
+func TestAudioInputs(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = "frames"
+	opts.Output.MP4 = "out.mp4"
+	opts.Output.WebM = "out.webm"
+
+	if mp4 := strings.Join(MakeMP4(opts).Args, " "); !strings.Contains(mp4, " -an ") || strings.Contains(mp4, "amix") {
+		t.Errorf("expected no audio by default: %s", mp4)
+	}
+
+	opts.PlaybackSpeed = 2
+	opts.Chapters = []Chapter{{Title: "Intro"}}
+	opts.Audio = []AudioClip{{File: "narration.mp3"}, {File: "ding.wav", Start: 3 * time.Second}}
+	mp4 := strings.Join(MakeMP4(opts).Args, " ")
+	for _, want := range []string{
+		"-i frames/chapters.txt -i narration.mp3 -i ding.wav -filter_complex",
+		";[3:a]adelay=delays=0:all=1[audio0];[4:a]adelay=delays=1500:all=1[audio1];[audio0][audio1]amix=inputs=2:duration=longest:normalize=0,apad[sound]",
+		"-map [sound] -c:a aac -shortest",
+	} {
+		if !strings.Contains(mp4, want) {
+			t.Errorf("expected %q in the command: %s", want, mp4)
+		}
+	}
+	if strings.Contains(mp4, " -an ") {
+		t.Errorf("expected the audio in the MP4: %s", mp4)
+	}
+	if webm := strings.Join(MakeWebM(opts).Args, " "); !strings.Contains(webm, "-c:a libopus") {
+		t.Errorf("expected the audio in the WebM: %s", webm)
+	}
+}
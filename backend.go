@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultBackend is the terminal backend used when none is given.
+const defaultBackend = "ttyd"
+
+// Terminal is a terminal served as a web page, on which VHS types the commands
+// and captures the frames. The page must expose the xterm.js terminal as
+// window.term.
+type Terminal interface {
+	// Start starts the terminal and returns the URL of its page.
+	Start(opts TerminalOptions) (string, error)
+	// Stop stops the terminal and the shell running in it.
+	Stop() error
+}
+
+// TerminalOptions are the options of the shell running in a terminal.
+type TerminalOptions struct {
+	// Dir is the directory of the shell, the current one if empty.
+	Dir string
+	// Env are the environment variables of the shell, in addition to the ones
+	// of the current process.
+	Env []string
+	// CursorBlink is whether the cursor blinks.
+	CursorBlink bool
+	// Shell is the command of the shell along with its arguments, the
+	// default shell if empty.
+	Shell []string
+}
+
+// shellWithArgs returns the command of the shell of the options.
+func (opts TerminalOptions) shellWithArgs() []string {
+	if len(opts.Shell) > 0 {
+		return opts.Shell
+	}
+	return defaultShellWithArgs()
+}
+
+// Backend creates the terminals of a backend.
+type Backend struct {
+	// New returns a terminal which isn't started yet.
+	New func() Terminal
+	// Check returns an error if the programs needed by the backend are
+	// missing or out of date.
+	Check func() error
+}
+
+// Backends are the terminal backends which can be selected with --backend.
+var Backends = map[string]Backend{
+	"ttyd":    {New: func() Terminal { return &ttydTerminal{} }, Check: checkTTYD},
+	"builtin": {New: func() Terminal { return &builtinTerminal{} }, Check: checkBuiltin},
+}
+
+// findBackend returns the backend of the name, or the default one if empty.
+func findBackend(name string) (Backend, error) {
+	if name == "" {
+		name = defaultBackend
+	}
+	b, ok := Backends[name]
+	if !ok {
+		names := make([]string, 0, len(Backends))
+		for n := range Backends {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Backend{}, fmt.Errorf("unknown backend %q: expected %s", name, strings.Join(names, ", "))
+	}
+	return b, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindBackend(t *testing.T) {
+	for _, name := range []string{"", defaultBackend} {
+		b, err := findBackend(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := b.New().(*ttydTerminal); !ok {
+			t.Errorf("expected the ttyd terminal for %q", name)
+		}
+	}
+
+	_, err := findBackend("tmux")
+	if err == nil || !strings.Contains(err.Error(), "ttyd") {
+		t.Errorf("expected an error listing the backends, got %v", err)
+	}
+}
+
+func TestTerminalStopBeforeStart(t *testing.T) {
+	if err := (&ttydTerminal{}).Stop(); err != nil {
+		t.Errorf("expected no error stopping a terminal which isn't started, got %v", err)
+	}
+}
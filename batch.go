@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// batchTape is one of the tapes of a batch, with its own options and cache.
+type batchTape struct {
+	file  string
+	opts  []EvaluatorOption
+	cache *RenderCache
+}
+
+// batchResult is the outcome of rendering one of the tapes of a batch.
+type batchResult struct {
+	file     string
+	tape     string
+	errs     []error
+	outputs  []string
+	duration time.Duration
+}
+
+// RunBatch renders the tape files concurrently, with at most jobs tapes at a
+// time. Each tape is evaluated on its own VHS instance, and its log is printed
+// at once when it is done so that the logs of the tapes don't interleave. The
+// tapes whose outputs are in the cache, if any, are restored instead.
+//
+// Failures don't stop the other tapes. The errors of every failed tape are
+// printed at the end, followed by a summary.
+func RunBatch(ctx context.Context, files []string, jobs int, cache *RenderCache, out io.Writer, opts ...EvaluatorOption) error {
+	tapes := make([]batchTape, len(files))
+	for i, file := range files {
+		tapes[i] = batchTape{file: file, opts: opts, cache: cache}
+	}
+	results := runTapes(ctx, tapes, jobs, out)
+	failed := printBatchErrors(results)
+
+	fmt.Fprintf(out, "%d succeeded, %d failed\n", len(files)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d tape(s) failed", failed)
+	}
+	return nil
+}
+
+// runTapes renders the tapes concurrently, with at most jobs tapes at a time,
+// and returns their results in the same order.
+func runTapes(ctx context.Context, tapes []batchTape, jobs int, out io.Writer) []batchResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, jobs)
+		results = make([]batchResult, len(tapes))
+	)
+
+	for i, tape := range tapes {
+		wg.Add(1)
+		go func(i int, tape batchTape) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := batchResult{file: tape.file}
+			var log bytes.Buffer
+			start := time.Now()
+			b, err := os.ReadFile(tape.file)
+			if err != nil {
+				result.errs = []error{err}
+			} else {
+				// The options are shared by the tapes, so they are copied
+				// before adding the one which keeps the VHS instance.
+				var v *VHS
+				opts := append(append([]EvaluatorOption{}, tape.opts...), func(vhs *VHS) { v = vhs })
+				result.tape = string(b)
+				result.outputs, result.errs = tape.cache.Evaluate(ctx, result.tape, &log, opts...)
+				if result.outputs == nil && len(result.errs) == 0 && v != nil {
+					result.outputs = v.outputFiles()
+				}
+			}
+			result.duration = time.Since(start)
+			results[i] = result
+
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintln(out, FileStyle.Render("File: "+tape.file))
+			_, _ = log.WriteTo(out)
+		}(i, tape)
+	}
+	wg.Wait()
+	return results
+}
+
+// printBatchErrors prints the errors of every failed tape, and returns the
+// number of failed tapes.
+func printBatchErrors(results []batchResult) int {
+	var failed int
+	for _, result := range results {
+		if len(result.errs) == 0 {
+			continue
+		}
+		failed++
+		fmt.Fprintln(os.Stderr, ErrorFileStyle.Render(result.file))
+		printErrors(os.Stderr, result.tape, result.errs)
+	}
+	return failed
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBatchAggregatesErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.tape")
+	empty := filepath.Join(dir, "empty.tape")
+	if err := os.WriteFile(invalid, []byte("Type\nSleep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(empty, []byte("# nothing to record"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{invalid, empty, filepath.Join(dir, "missing.tape")}
+
+	var out bytes.Buffer
+	err := RunBatch(context.Background(), files, 2, nil, &out)
+	if err == nil || err.Error() != "3 tape(s) failed" {
+		t.Fatalf("expected 3 tapes to fail, got %v", err)
+	}
+
+	for _, file := range files {
+		if !strings.Contains(out.String(), file) {
+			t.Errorf("expected the log of %s, got:\n%s", file, out.String())
+		}
+	}
+	if !strings.HasSuffix(out.String(), "0 succeeded, 3 failed\n") {
+		t.Errorf("expected a summary, got:\n%s", out.String())
+	}
+}
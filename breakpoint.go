@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ExecuteBreakpoint pauses the tape until Enter is pressed, printing the
+// current screen of the terminal so that its state can be inspected. The
+// recording is paused in the meantime so that the output is not affected.
+//
+// Breakpoints are ignored unless VHS runs in an interactive terminal, so
+// tapes with breakpoints can still be rendered in CI.
+func ExecuteBreakpoint(c Command, v *VHS) {
+	if !isInteractive(os.Stdin) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return
+	}
+
+	if v.recording {
+		v.PauseRecording()
+		defer v.ResumeRecording()
+	}
+
+	screen, err := v.currentScreen()
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("failed to read the screen at breakpoint: %w", err))
+		return
+	}
+	printBreakpoint(os.Stdout, screen)
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+// printBreakpoint prints the screen of the terminal at a breakpoint, along
+// with how to continue.
+func printBreakpoint(out io.Writer, screen []string) {
+	for len(screen) > 0 && strings.TrimSpace(screen[len(screen)-1]) == "" {
+		screen = screen[:len(screen)-1]
+	}
+
+	fmt.Fprintln(out, WarningStyle.Render("Breakpoint"))
+	for _, line := range screen {
+		fmt.Fprintln(out, FaintStyle.Render("│ ")+line)
+	}
+	fmt.Fprint(out, FaintStyle.Render("Press Enter to continue..."))
+}
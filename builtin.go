@@ -0,0 +1,284 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+//go:generate ./scripts/download_xterm.sh xterm
+
+// xtermFiles are the xterm.js files served by the builtin backend, downloaded
+// into the xterm directory by go generate and embedded in the binary.
+//
+//go:embed xterm
+var xtermFiles embed.FS
+
+// xtermDirEnv is the environment variable of a directory with xterm.js, for
+// the builtin backend to serve it instead of the embedded files. It must
+// contain xterm.js, xterm.css, xterm-addon-fit.js and xterm-addon-canvas.js.
+const xtermDirEnv = "VHS_XTERM_DIR"
+
+// xtermCDNEnv is the environment variable which lets the builtin backend load
+// the xterm.js files missing from the binary and VHS_XTERM_DIR from the CDN.
+const xtermCDNEnv = "VHS_XTERM_CDN"
+
+// xtermAssets are the URLs of the xterm.js files on the CDN, by file name,
+// for the builds without the embedded files if VHS_XTERM_CDN is set. The
+// versions match the ones served by ttyd and downloaded by
+// scripts/download_xterm.sh.
+var xtermAssets = map[string]string{
+	"xterm.js":              "https://cdn.jsdelivr.net/npm/xterm@5.1.0/lib/xterm.js",
+	"xterm.css":             "https://cdn.jsdelivr.net/npm/xterm@5.1.0/css/xterm.css",
+	"xterm-addon-fit.js":    "https://cdn.jsdelivr.net/npm/xterm-addon-fit@0.7.0/lib/xterm-addon-fit.js",
+	"xterm-addon-canvas.js": "https://cdn.jsdelivr.net/npm/xterm-addon-canvas@0.3.0/lib/xterm-addon-canvas.js",
+}
+
+// xtermFS returns the files of xterm.js served under /assets/: the directory
+// of VHS_XTERM_DIR if set, or the embedded files.
+func xtermFS() fs.FS {
+	if dir := os.Getenv(xtermDirEnv); dir != "" {
+		return os.DirFS(dir)
+	}
+	sub, _ := fs.Sub(xtermFiles, "xterm")
+	return sub
+}
+
+// checkXterm checks that the xterm.js files can be served, or loaded from the
+// CDN if VHS_XTERM_CDN is set.
+func checkXterm() error {
+	if os.Getenv(xtermCDNEnv) != "" {
+		return nil
+	}
+	assets := xtermFS()
+	var missing []string
+	for name := range xtermAssets {
+		if _, err := fs.Stat(assets, name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("the builtin backend is missing %s: build VHS after `make xterm`, set %s to a directory with the files, or set %s=1 to load them from the CDN",
+		strings.Join(missing, ", "), xtermDirEnv, xtermCDNEnv)
+}
+
+// builtinPage is the page of the builtin backend. The terminal is only exposed
+// as window.term once the shell is connected, so that nothing is typed before.
+const builtinPage = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<link rel="stylesheet" href="%s">
+<style>html, body, #terminal { margin: 0; padding: 0; width: 100%%; height: 100%%; overflow: hidden; }</style>
+<script src="%s"></script>
+<script src="%s"></script>
+<script src="%s"></script>
+</head>
+<body>
+<div id="terminal"></div>
+<script>
+const term = new Terminal({ cursorBlink: %t, customGlyphs: true, allowProposedApi: true });
+const fit = new FitAddon.FitAddon();
+term.loadAddon(fit);
+term.open(document.getElementById('terminal'));
+term.loadAddon(new CanvasAddon.CanvasAddon());
+fit.fit();
+window.addEventListener('resize', () => fit.fit());
+
+const ws = new WebSocket('ws://' + location.host + '/ws');
+ws.binaryType = 'arraybuffer';
+const send = (msg) => ws.readyState === WebSocket.OPEN && ws.send(JSON.stringify(msg));
+ws.onmessage = (e) => term.write(new Uint8Array(e.data));
+ws.onopen = () => {
+	send({ type: 'resize', cols: term.cols, rows: term.rows });
+	window.term = term;
+};
+term.onData((data) => send({ type: 'input', data }));
+term.onResize(({ cols, rows }) => send({ type: 'resize', cols, rows }));
+</script>
+</body>
+</html>
+`
+
+// builtinMessage is a message of the page to the shell: what is typed, or the
+// new size of the terminal.
+type builtinMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+	Cols uint16 `json:"cols"`
+	Rows uint16 `json:"rows"`
+}
+
+// builtinShell is a shell running in a PTY, a ConPTY on Windows.
+type builtinShell interface {
+	io.ReadWriteCloser
+	// Resize sets the size of the PTY.
+	Resize(cols, rows uint16) error
+	// Kill kills the shell, and Wait waits for it to exit.
+	Kill() error
+	Wait() error
+}
+
+// builtinTerminal is the terminal of the builtin backend, which serves
+// xterm.js and runs the shell in a PTY itself, instead of ttyd.
+type builtinTerminal struct {
+	opts   TerminalOptions
+	server *http.Server
+
+	mu     sync.Mutex
+	shells []builtinShell
+}
+
+// checkBuiltin checks that PTYs are supported, that the xterm.js files are
+// there and that the shell of the builtin backend is installed.
+func checkBuiltin() error {
+	if err := checkPTY(); err != nil {
+		return err
+	}
+	if err := checkXterm(); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath(defaultShellWithArgs()[0]); err != nil {
+		return fmt.Errorf("%s is not installed", defaultShellWithArgs()[0])
+	}
+	return nil
+}
+
+// Start serves the page and the shell on a random port of localhost.
+func (t *builtinTerminal) Start(opts TerminalOptions) (string, error) {
+	t.opts = opts
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", t.servePage)
+	mux.Handle("/ws", websocket.Server{Handler: t.serveShell, Handshake: sameOrigin})
+	mux.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.FS(xtermFS()))))
+	t.server = &http.Server{Handler: mux} //nolint:gosec
+	go t.server.Serve(ln)                 //nolint:errcheck
+
+	return "http://" + ln.Addr().String(), nil
+}
+
+// Stop stops the server and kills the shells.
+func (t *builtinTerminal) Stop() error {
+	if t.server == nil {
+		return nil
+	}
+	err := t.server.Close()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, shell := range t.shells {
+		_ = shell.Kill()
+	}
+	t.shells = nil
+	return err
+}
+
+// servePage serves the page of the terminal, with xterm.js from /assets/, or
+// from the CDN for the files missing there if VHS_XTERM_CDN is set.
+func (t *builtinTerminal) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	assets, cdn := xtermFS(), os.Getenv(xtermCDNEnv) != ""
+	asset := func(name string) string {
+		if _, err := fs.Stat(assets, name); err != nil && cdn {
+			return xtermAssets[name]
+		}
+		return "/assets/" + name
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, builtinPage, asset("xterm.css"), asset("xterm.js"), asset("xterm-addon-fit.js"),
+		asset("xterm-addon-canvas.js"), t.opts.CursorBlink)
+}
+
+// serveShell runs a shell in a PTY for the connection of the page. What the
+// shell prints is sent as binary messages, and the page sends what is typed
+// and the size of the terminal as JSON messages.
+func (t *builtinTerminal) serveShell(ws *websocket.Conn) {
+	defer ws.Close() //nolint:errcheck
+
+	env := append(append(os.Environ(), "TERM=xterm-256color"), t.opts.Env...)
+	f, err := startShell(t.opts.shellWithArgs(), t.opts.Dir, env)
+	if err != nil {
+		_ = websocket.Message.Send(ws, []byte(err.Error()+"\r\n"))
+		return
+	}
+	defer f.Close() //nolint:errcheck
+
+	t.mu.Lock()
+	t.shells = append(t.shells, f)
+	t.mu.Unlock()
+	defer func() {
+		_ = f.Kill()
+		_ = f.Wait()
+	}()
+
+	go func() {
+		buf := make([]byte, 32*1024) //nolint:gomnd
+		for {
+			n, err := f.Read(buf)
+			if n > 0 {
+				if err := websocket.Message.Send(ws, buf[:n]); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				_ = ws.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		var b []byte
+		if err := websocket.Message.Receive(ws, &b); err != nil {
+			return
+		}
+		var msg builtinMessage
+		if err := json.Unmarshal(b, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "input":
+			if _, err := io.WriteString(f, msg.Data); err != nil {
+				return
+			}
+		case "resize":
+			_ = f.Resize(msg.Cols, msg.Rows)
+		}
+	}
+}
+
+// sameOrigin only accepts the connections of the page of the terminal, so
+// that no other page can type in the shell.
+func sameOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil || origin.Host != r.Host {
+		return errors.New("the shell only accepts the page of the terminal")
+	}
+	config.Origin = origin
+	return nil
+}
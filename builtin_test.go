@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// writeXterm writes placeholders of the xterm.js files to a directory of
+// VHS_XTERM_DIR, for the checks of the builtin backend.
+func writeXterm(t *testing.T, names ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("// "+name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(xtermDirEnv, dir)
+}
+
+func TestBuiltinTerminal(t *testing.T) {
+	writeXterm(t, "xterm.js", "xterm.css", "xterm-addon-fit.js", "xterm-addon-canvas.js")
+	if err := checkBuiltin(); err != nil {
+		t.Skip(err)
+	}
+
+	term := &builtinTerminal{}
+	url, err := term.Start(TerminalOptions{Dir: t.TempDir(), Env: []string{"GREETING=hello"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Stop() //nolint:errcheck
+
+	resp, err := http.Get(url) //nolint:noctx
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(page), "window.term = term") {
+		t.Errorf("expected the page of the terminal, got:\n%s", page)
+	}
+
+	if _, err := websocket.Dial(strings.Replace(url, "http", "ws", 1)+"/ws", "", "http://example.com"); err == nil {
+		t.Error("expected the shell to refuse another origin")
+	}
+
+	ws, err := websocket.Dial(strings.Replace(url, "http", "ws", 1)+"/ws", "", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close() //nolint:errcheck
+
+	input, _ := json.Marshal(builtinMessage{Type: "input", Data: "echo \"$GREETING, $TERM\"\r"})
+	if err := websocket.Message.Send(ws, input); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for !strings.Contains(out.String(), "hello, xterm-256color") {
+		var b []byte
+		if err := websocket.Message.Receive(ws, &b); err != nil {
+			t.Fatalf("expected the output of the shell, got %q: %v", out.String(), err)
+		}
+		out.Write(b)
+	}
+}
+
+func TestBuiltinAssets(t *testing.T) {
+	writeXterm(t, "xterm.js")
+	t.Setenv(xtermCDNEnv, "")
+
+	err := checkXterm()
+	if err == nil || !strings.Contains(err.Error(), "missing xterm-addon-canvas.js, xterm-addon-fit.js, xterm.css:") {
+		t.Errorf("expected an error for the missing files, got %v", err)
+	}
+
+	term := &builtinTerminal{}
+	url, err := term.Start(TerminalOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Stop() //nolint:errcheck
+
+	page := func() string {
+		t.Helper()
+		resp, err := http.Get(url) //nolint:noctx
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	if p := page(); !strings.Contains(p, `src="/assets/xterm.js"`) || strings.Contains(p, "cdn.jsdelivr.net") {
+		t.Errorf("expected xterm.js from /assets/ and nothing from the CDN, got:\n%s", p)
+	}
+
+	t.Setenv(xtermCDNEnv, "1")
+	if err := checkXterm(); err != nil {
+		t.Errorf("expected the CDN to be allowed, got %v", err)
+	}
+	if p := page(); !strings.Contains(p, `src="/assets/xterm.js"`) || !strings.Contains(p, xtermAssets["xterm.css"]) {
+		t.Errorf("expected the missing xterm.css from the CDN, got:\n%s", p)
+	}
+
+	resp, err := http.Get(url + "/assets/xterm.js") //nolint:noctx
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(js) != "// xterm.js" {
+		t.Errorf("expected xterm.js to be served, got %q", js)
+	}
+}
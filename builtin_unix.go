@@ -0,0 +1,46 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// ptyShell is a shell running in a PTY.
+type ptyShell struct {
+	*os.File
+	cmd *exec.Cmd
+}
+
+// checkPTY checks that the shell can run in a PTY, which it always can.
+func checkPTY() error {
+	return nil
+}
+
+// startShell starts the command of args in a PTY.
+func startShell(args []string, dir string, env []string) (builtinShell, error) {
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Dir = dir
+	cmd.Env = env
+	f, err := pty.Start(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return &ptyShell{File: f, cmd: cmd}, nil
+}
+
+func (s *ptyShell) Resize(cols, rows uint16) error {
+	return pty.Setsize(s.File, &pty.Winsize{Cols: cols, Rows: rows})
+}
+
+func (s *ptyShell) Kill() error {
+	return s.cmd.Process.Kill()
+}
+
+func (s *ptyShell) Wait() error {
+	return s.cmd.Wait()
+}
@@ -0,0 +1,200 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// procThreadAttributePseudoconsole is the attribute of a process which
+// attaches it to a ConPTY.
+const procThreadAttributePseudoconsole = 0x00020016
+
+// conptyEnv is the environment variable which enables the builtin backend on
+// Windows. The ConPTY backend is experimental until it runs in the CI.
+const conptyEnv = "VHS_EXPERIMENTAL_CONPTY"
+
+var (
+	kernel32                = windows.NewLazySystemDLL("kernel32.dll")
+	procCreatePseudoConsole = kernel32.NewProc("CreatePseudoConsole")
+	procResizePseudoConsole = kernel32.NewProc("ResizePseudoConsole")
+	procClosePseudoConsole  = kernel32.NewProc("ClosePseudoConsole")
+)
+
+// conptyShell is a shell running in a ConPTY, the pseudo console of Windows.
+type conptyShell struct {
+	console windows.Handle
+	process windows.Handle
+	in      *os.File
+	out     *os.File
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// checkPTY checks that the experimental ConPTY backend is enabled, and that
+// Windows has ConPTY, since Windows 10 1809.
+func checkPTY() error {
+	if os.Getenv(conptyEnv) == "" {
+		return fmt.Errorf("the builtin backend is experimental on Windows, set %s=1 to use it, or use --backend ttyd", conptyEnv)
+	}
+	if err := procCreatePseudoConsole.Find(); err != nil {
+		return errors.New("the builtin backend needs ConPTY, since Windows 10 1809, use --backend ttyd")
+	}
+	return nil
+}
+
+// coord packs the size of a console as a COORD, which is passed by value.
+func coord(cols, rows uint16) uintptr {
+	return uintptr(rows)<<16 | uintptr(cols) //nolint:gomnd
+}
+
+// startShell starts the command of args in a ConPTY.
+func startShell(args []string, dir string, env []string) (builtinShell, error) {
+	if err := checkPTY(); err != nil {
+		return nil, err
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// The ConPTY reads what is typed from ptyIn and writes the output of the
+	// shell to ptyOut; the other ends are ours.
+	var ptyIn, inWrite, outRead, ptyOut windows.Handle
+	if err := windows.CreatePipe(&ptyIn, &inWrite, nil, 0); err != nil {
+		return nil, err
+	}
+	if err := windows.CreatePipe(&outRead, &ptyOut, nil, 0); err != nil {
+		_ = windows.CloseHandle(ptyIn)
+		_ = windows.CloseHandle(inWrite)
+		return nil, err
+	}
+	s := &conptyShell{
+		in:  os.NewFile(uintptr(inWrite), "conpty-in"),
+		out: os.NewFile(uintptr(outRead), "conpty-out"),
+	}
+
+	hr, _, _ := procCreatePseudoConsole.Call(coord(80, 24), uintptr(ptyIn), uintptr(ptyOut), 0, uintptr(unsafe.Pointer(&s.console))) //nolint:gomnd
+	// The ConPTY has its own handles of the pipes.
+	_ = windows.CloseHandle(ptyIn)
+	_ = windows.CloseHandle(ptyOut)
+	if hr != uintptr(windows.S_OK) {
+		_ = s.in.Close()
+		_ = s.out.Close()
+		return nil, fmt.Errorf("could not create the ConPTY: %w", windows.Errno(hr))
+	}
+
+	if err := s.start(path, args, dir, env); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// start starts the process of the shell, attached to the ConPTY.
+func (s *conptyShell) start(path string, args []string, dir string, env []string) error {
+	attrs, err := windows.NewProcThreadAttributeList(1)
+	if err != nil {
+		return err
+	}
+	defer attrs.Delete()
+	// The value of the attribute is the handle of the ConPTY itself.
+	if err := attrs.Update(procThreadAttributePseudoconsole, *(*unsafe.Pointer)(unsafe.Pointer(&s.console)), unsafe.Sizeof(s.console)); err != nil {
+		return err
+	}
+
+	si := windows.StartupInfoEx{ProcThreadAttributeList: attrs.List()}
+	si.Cb = uint32(unsafe.Sizeof(si))
+	// Without standard handles, the shell doesn't inherit the console of VHS.
+	si.Flags = windows.STARTF_USESTDHANDLES
+
+	app, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	cmdline, err := windows.UTF16PtrFromString(windows.ComposeCommandLine(args))
+	if err != nil {
+		return err
+	}
+	var cwd *uint16
+	if dir != "" {
+		if cwd, err = windows.UTF16PtrFromString(dir); err != nil {
+			return err
+		}
+	}
+	block, err := windows.UTF16FromString(strings.Join(env, "\x00") + "\x00")
+	if err != nil {
+		return err
+	}
+
+	var pi windows.ProcessInformation
+	flags := uint32(windows.EXTENDED_STARTUPINFO_PRESENT | windows.CREATE_UNICODE_ENVIRONMENT)
+	if err := windows.CreateProcess(app, cmdline, nil, nil, false, flags, &block[0], cwd, &si.StartupInfo, &pi); err != nil {
+		return err
+	}
+	_ = windows.CloseHandle(pi.Thread)
+	s.process = pi.Process
+	return nil
+}
+
+func (s *conptyShell) Read(p []byte) (int, error) {
+	return s.out.Read(p)
+}
+
+func (s *conptyShell) Write(p []byte) (int, error) {
+	return s.in.Write(p)
+}
+
+func (s *conptyShell) Resize(cols, rows uint16) error {
+	hr, _, _ := procResizePseudoConsole.Call(uintptr(s.console), coord(cols, rows))
+	if hr != uintptr(windows.S_OK) {
+		return windows.Errno(hr)
+	}
+	return nil
+}
+
+func (s *conptyShell) Kill() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.process == 0 {
+		return nil
+	}
+	return windows.TerminateProcess(s.process, 1)
+}
+
+func (s *conptyShell) Wait() error {
+	s.mu.Lock()
+	process := s.process
+	s.mu.Unlock()
+	if process == 0 {
+		return nil
+	}
+	_, err := windows.WaitForSingleObject(process, windows.INFINITE)
+	return err
+}
+
+// Close closes the ConPTY, which ends the output of the shell, and the pipes.
+func (s *conptyShell) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	_, _, _ = procClosePseudoConsole.Call(uintptr(s.console))
+	if s.process != 0 {
+		_ = windows.CloseHandle(s.process)
+	}
+	_ = s.in.Close()
+	return s.out.Close()
+}
@@ -0,0 +1,68 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConPTYShell(t *testing.T) {
+	t.Setenv(conptyEnv, "1")
+	if err := checkPTY(); err != nil {
+		t.Skip(err)
+	}
+
+	s, err := startShell([]string{"cmd.exe", "/c", "echo hello from %GREETING%"}, t.TempDir(), append(os.Environ(), "GREETING=conpty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close() //nolint:errcheck
+
+	var mu sync.Mutex
+	var out strings.Builder
+	go func() {
+		b := make([]byte, 1024)
+		for {
+			n, err := s.Read(b)
+			mu.Lock()
+			out.Write(b[:n])
+			mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		got := out.String()
+		mu.Unlock()
+		if strings.Contains(got, "hello from conpty") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the output of cmd.exe, got %q", got)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := s.Resize(100, 30); err != nil {
+		t.Errorf("expected the ConPTY to be resized: %v", err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("expected cmd.exe to exit: %v", err)
+	}
+}
+
+func TestConPTYExperimental(t *testing.T) {
+	t.Setenv(conptyEnv, "")
+	if err := checkPTY(); err == nil || !strings.Contains(err.Error(), conptyEnv) {
+		t.Errorf("expected the builtin backend to be disabled, got %v", err)
+	}
+}
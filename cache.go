@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cacheEntryFile is the file name of the list of outputs of a cache entry.
+const cacheEntryFile = "outputs.json"
+
+// RenderCache reuses the outputs of the tapes which were already rendered.
+// The entries are keyed on the hash of the commands of the tape, once parsed,
+// so that the sourced tapes and the variables are part of the key, of the
+// files it reads, such as its fonts and themes, and of a salt for what else
+// affects the outputs, such as the versions of VHS, ttyd and ffmpeg.
+//
+// The tapes which check something, with Expect, Require, Wait or a golden
+// file, are never cached, since restoring their outputs would skip the checks.
+type RenderCache struct {
+	Dir  string
+	Salt string
+}
+
+// cacheEntry lists the outputs of a tape, in the order in which they are
+// stored in the entry.
+type cacheEntry struct {
+	Outputs []string `json:"outputs"`
+}
+
+// defaultCacheDir returns the directory of the render cache, in the cache
+// directory of the user.
+func defaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "vhs"), nil
+}
+
+// cacheSalt returns the salt of the cache keys: the versions of VHS, ttyd and
+// ffmpeg, and the flags which change the outputs.
+func cacheSalt(flags ...string) string {
+	salt := []string{"vhs " + Version, commandVersion("ttyd", "--version"), commandVersion("ffmpeg", "-version")}
+	return strings.Join(append(salt, flags...), "\n")
+}
+
+// commandVersion returns the first line of the version of a program, or an
+// empty string if it can't be run.
+func commandVersion(program string, flag string) string {
+	out, err := exec.Command(program, flag).Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(line)
+}
+
+// Key returns the key of the tape in the cache, or false if the tape can't be
+// parsed or has checks, in which case it isn't cached.
+func (c *RenderCache) Key(tape string, vars map[string]string) (string, bool) {
+	p := NewParser(NewLexer(tape), WithParserVars(vars))
+	cmds := p.Parse()
+	if len(p.Errors()) > 0 || len(cmds) == 0 || hasChecks(cmds) {
+		return "", false
+	}
+	b, err := json.Marshal(cmds)
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	_, _ = h.Write(b)
+	for _, file := range append(p.Sourced(), inputFiles(cmds)...) {
+		_, _ = io.WriteString(h, "\n"+file+"\n")
+		if f, err := os.Open(file); err == nil {
+			_, _ = io.Copy(h, f)
+			_ = f.Close()
+		}
+	}
+	_, _ = io.WriteString(h, "\n"+c.Salt)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// hasChecks returns whether the tape checks the terminal or its environment,
+// which has to happen on every render.
+func hasChecks(cmds []Command) bool {
+	for _, cmd := range cmds {
+		switch cmd.Type {
+		case EXPECT, EXPECT_NOT, REQUIRE, WAIT:
+			return true
+		case OUTPUT:
+			if cmd.Options == ".test" || cmd.Options == ".ascii" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inputFiles returns the files the commands read: the themes, the fonts, the
+// audio and the images of the margin.
+func inputFiles(cmds []Command) []string {
+	var files []string
+	for _, cmd := range cmds {
+		switch {
+		case cmd.Type == AUDIO:
+			files = append(files, cmd.Args)
+		case cmd.Type != SET:
+		case cmd.Options == "Theme" && strings.HasSuffix(cmd.Args, ".json") && !strings.HasPrefix(cmd.Args, "{"),
+			cmd.Options == "FontFile", cmd.Options == "Audio",
+			cmd.Options == "MarginFill" && isImageFill(cmd.Args):
+			files = append(files, cmd.Args)
+		}
+	}
+	return files
+}
+
+// Restore copies the outputs of the cache entry back to their paths. It
+// returns the outputs, or false if the entry is missing or incomplete.
+func (c *RenderCache) Restore(key string) ([]string, bool) {
+	dir := filepath.Join(c.Dir, key)
+	b, err := os.ReadFile(filepath.Join(dir, cacheEntryFile))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil || len(entry.Outputs) == 0 {
+		return nil, false
+	}
+	for i, output := range entry.Outputs {
+		if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+			return nil, false
+		}
+		if err := copyFile(filepath.Join(dir, strconv.Itoa(i)), output); err != nil {
+			return nil, false
+		}
+	}
+	return entry.Outputs, true
+}
+
+// Store saves the outputs in a new cache entry. Outputs which aren't regular
+// files, such as the directories of frames, can't be cached, in which case
+// nothing is stored.
+func (c *RenderCache) Store(key string, outputs []string) error {
+	if len(outputs) == 0 {
+		return nil
+	}
+	for _, output := range outputs {
+		info, err := os.Stat(output)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	// Write the entry to a temporary directory first, so that a concurrent
+	// render never restores an incomplete entry.
+	tmp, err := os.MkdirTemp(c.Dir, "."+key)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp) //nolint:errcheck
+	for i, output := range outputs {
+		if err := copyFile(output, filepath.Join(tmp, strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(cacheEntry{Outputs: outputs})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, cacheEntryFile), b, 0o600); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(c.Dir, key)
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	return os.Rename(tmp, dir)
+}
+
+// Evaluate restores the outputs of the tape from the cache or, if they aren't
+// cached, evaluates it and stores its outputs. It returns the outputs which
+// were restored, if any. A nil cache always evaluates the tape.
+func (c *RenderCache) Evaluate(ctx context.Context, tape string, out io.Writer, opts ...EvaluatorOption) ([]string, []error) {
+	if c == nil {
+		return nil, Evaluate(ctx, tape, out, opts...)
+	}
+	key, ok := c.Key(tape, evaluatorVars(opts))
+	if !ok {
+		return nil, Evaluate(ctx, tape, out, opts...)
+	}
+	if outputs, ok := c.Restore(key); ok {
+		for _, output := range outputs {
+			fmt.Fprintln(out, FaintStyle.Render("Cached: "+output))
+		}
+		return outputs, nil
+	}
+
+	var v *VHS
+	errs := Evaluate(ctx, tape, out, append(opts, func(vhs *VHS) { v = vhs })...)
+	if len(errs) == 0 && v != nil {
+		if err := c.Store(key, v.outputFiles()); err != nil {
+			fmt.Fprintln(out, WarningStyle.Render("could not cache the outputs: "+err.Error()))
+		}
+	}
+	return nil, errs
+}
+
+// outputFiles returns the files written by the tape: its outputs, in the
+// order in which they are opened, and its screenshots.
+func (v *VHS) outputFiles() []string {
+	video := v.Options.Video.Output
+	var files []string
+	for _, output := range []string{
+		video.GIF, video.MP4, video.WebM, video.WebP, video.APNG, video.PNG,
+		video.SVG, video.Cast, video.SRT, video.VTT, video.Frames,
+		v.Options.Test.Output, v.Options.Test.Screen, v.Options.HTML.Output,
+	} {
+		if output != "" {
+			files = append(files, output)
+		}
+	}
+	return append(files, v.shots...)
+}
+
+// copyFile copies the file at src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
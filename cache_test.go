@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRenderCacheKey(t *testing.T) {
+	c := &RenderCache{Salt: "vhs 1.0.0"}
+	tape := "Output ${NAME}.gif\nType \"hello\""
+
+	key, ok := c.Key(tape, map[string]string{"NAME": "demo"})
+	if !ok {
+		t.Fatal("expected a key")
+	}
+	if again, _ := c.Key(tape+"\n# a comment", map[string]string{"NAME": "demo"}); again != key {
+		t.Errorf("expected the comments not to change the key")
+	}
+	if other, _ := c.Key(tape, map[string]string{"NAME": "other"}); other == key {
+		t.Errorf("expected the variables to change the key")
+	}
+	salted := &RenderCache{Salt: "vhs 1.1.0"}
+	if other, _ := salted.Key(tape, map[string]string{"NAME": "demo"}); other == key {
+		t.Errorf("expected the salt to change the key")
+	}
+	if _, ok := c.Key("Type", nil); ok {
+		t.Errorf("expected no key for an invalid tape")
+	}
+}
+
+func TestRenderCacheKeyChecks(t *testing.T) {
+	c := &RenderCache{}
+	for _, tape := range []string{
+		"Type \"ls\"\nExpect \"README\"",
+		"Type \"ls\"\nExpectNot /error/",
+		"Require git\nType \"git log\"",
+		"Type \"make\"\nEnter\nWait",
+		"Output golden.test\nType \"ls\"",
+		"Output golden.ascii\nType \"ls\"",
+	} {
+		if _, ok := c.Key(tape, nil); ok {
+			t.Errorf("expected no key for a tape with checks:\n%s", tape)
+		}
+	}
+}
+
+func TestRenderCacheKeyFiles(t *testing.T) {
+	c := &RenderCache{}
+	dir := t.TempDir()
+	theme := filepath.Join(dir, "theme.json")
+	source := filepath.Join(dir, "setup.tape")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(theme, `{"background": "#000000"}`)
+	write(source, `Type "ls"`)
+
+	tape := "Set Theme \"" + theme + "\"\nSource \"" + source + "\"\nEnter"
+	key, ok := c.Key(tape, nil)
+	if !ok {
+		t.Fatal("expected a key")
+	}
+
+	write(theme, `{"background": "#ffffff"}`)
+	themed, _ := c.Key(tape, nil)
+	if themed == key {
+		t.Errorf("expected the theme file to change the key")
+	}
+	write(source, `Type "ls -l"`)
+	if sourced, _ := c.Key(tape, nil); sourced == themed {
+		t.Errorf("expected the sourced tape to change the key")
+	}
+}
+
+func TestRenderCacheStoreAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	c := &RenderCache{Dir: filepath.Join(dir, "cache")}
+	gif := filepath.Join(dir, "out", "demo.gif")
+	txt := filepath.Join(dir, "out", "demo.txt")
+	if err := os.MkdirAll(filepath.Dir(gif), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{gif, txt} {
+		if err := os.WriteFile(f, []byte(filepath.Base(f)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := c.Restore("key"); ok {
+		t.Fatal("expected a missing entry")
+	}
+	if err := c.Store("key", []string{gif, txt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Dir(gif)); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, ok := c.Restore("key")
+	if !ok {
+		t.Fatal("expected the entry to be restored")
+	}
+	if !reflect.DeepEqual(outputs, []string{gif, txt}) {
+		t.Errorf("expected the outputs to be restored, got %v", outputs)
+	}
+	for _, f := range outputs {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != filepath.Base(f) {
+			t.Errorf("expected %s to be restored, got %q", f, b)
+		}
+	}
+
+	// The directories of frames can't be cached.
+	if err := c.Store("frames", []string{gif, dir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Restore("frames"); ok {
+		t.Errorf("expected no entry for a directory output")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// castVersion is the version of the asciicast format of the .cast outputs.
+// See https://docs.asciinema.org/manual/asciicast/v2/
+const castVersion = 2
+
+// castRecorder wraps term.write so that everything written to the terminal
+// is kept until it is read by castDrain. The bytes are decoded as a stream,
+// since a character can be split over two writes.
+const castRecorder = `() => {
+	const decoder = new TextDecoder();
+	window.vhsCast = [];
+	const write = term.write.bind(term);
+	term.write = (data, callback) => {
+		window.vhsCast.push(typeof data === 'string' ? data : decoder.decode(data, { stream: true }));
+		return write(data, callback);
+	};
+}`
+
+// castDrain returns what was written to the terminal since the last call.
+const castDrain = "() => window.vhsCast.splice(0).join('')"
+
+// castEvent is an output event of an asciicast, at a time in seconds since
+// the start of the recording.
+type castEvent struct {
+	Time float64
+	Data string
+}
+
+// MarshalJSON encodes the event as the [time, "o", data] array of asciicast.
+func (e castEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.Time, "o", e.Data})
+}
+
+// castHeader is the first line of an asciicast.
+type castHeader struct {
+	Version   int        `json:"version"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	Timestamp int64      `json:"timestamp"`
+	Title     string     `json:"title,omitempty"`
+	Theme     *castTheme `json:"theme,omitempty"`
+}
+
+// castTheme is the theme of an asciicast, with the 8 normal colors and the 8
+// bright colors of the palette separated by colons.
+type castTheme struct {
+	Foreground string `json:"fg"`
+	Background string `json:"bg"`
+	Palette    string `json:"palette"`
+}
+
+// newCastTheme returns the asciicast theme of a theme.
+func newCastTheme(t Theme) *castTheme {
+	return &castTheme{
+		Foreground: t.Foreground,
+		Background: t.Background,
+		Palette: strings.Join([]string{
+			t.Black, t.Red, t.Green, t.Yellow, t.Blue, t.Magenta, t.Cyan, t.White,
+			t.BrightBlack, t.BrightRed, t.BrightGreen, t.BrightYellow, t.BrightBlue, t.BrightMagenta, t.BrightCyan, t.BrightWhite,
+		}, ":"),
+	}
+}
+
+// captureCastEvent reads what was written to the terminal since the last
+// frame and adds it to the asciicast at the time of the frame. What is
+// written while the recording is paused, e.g. by Hide, is added at the next
+// frame, so that the asciicast skips the same time as the videos.
+func (vhs *VHS) captureCastEvent(at time.Duration) error {
+	res, err := vhs.Page.Eval(castDrain)
+	if err != nil {
+		return err
+	}
+	if data := res.Value.Str(); data != "" {
+		vhs.castEvents = append(vhs.castEvents, castEvent{Time: at.Seconds(), Data: data})
+	}
+	return nil
+}
+
+// MakeCast writes the asciicast v2 output of the recording, if any.
+func MakeCast(vhs *VHS) error {
+	path := vhs.Options.Video.Output.Cast
+	if path == "" {
+		return nil
+	}
+
+	fmt.Println("Creating Cast...")
+
+	// The events are played at the speed of the videos.
+	speed := vhs.Options.Video.PlaybackSpeed
+	if speed <= 0 {
+		speed = defaultPlaybackSpeed
+	}
+	events := make([]castEvent, len(vhs.castEvents))
+	for i, e := range vhs.castEvents {
+		events[i] = castEvent{Time: e.Time / speed, Data: e.Data}
+	}
+
+	header := castHeader{
+		Version:   castVersion,
+		Width:     vhs.castWidth,
+		Height:    vhs.castHeight,
+		Timestamp: vhs.castTimestamp(),
+		Title:     vhs.Options.Title,
+		Theme:     newCastTheme(vhs.Options.Theme),
+	}
+	b, err := encodeCast(header, events)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644) //nolint:gosec,gomnd
+}
+
+// castTimestamp returns the time at which the recording started, as a Unix
+// timestamp, or 0 in the deterministic mode so that the cast is the same on
+// every recording.
+func (vhs *VHS) castTimestamp() int64 {
+	if vhs.Options.Deterministic {
+		return 0
+	}
+	return vhs.recordStart.Unix()
+}
+
+// encodeCast encodes the header and the events of an asciicast, one JSON
+// value per line.
+func encodeCast(header castHeader, events []castEvent) ([]byte, error) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeCast(t *testing.T) {
+	header := castHeader{Version: castVersion, Width: 80, Height: 24, Timestamp: 1700000000, Theme: newCastTheme(DefaultTheme)}
+	events := []castEvent{
+		{Time: 0, Data: "$ "},
+		{Time: 0.5, Data: "echo \"hi\"\r\n"},
+	}
+
+	b, err := encodeCast(header, events)
+	requireNoErr(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 events, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], `{"version":2,"width":80,"height":24,"timestamp":1700000000,"theme":{"fg":"`+DefaultTheme.Foreground) {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+	if lines[1] != `[0,"o","$ "]` || lines[2] != `[0.5,"o","echo \"hi\"\r\n"]` {
+		t.Errorf("unexpected events: %s, %s", lines[1], lines[2])
+	}
+}
+
+func TestCastTheme(t *testing.T) {
+	palette := strings.Split(newCastTheme(DefaultTheme).Palette, ":")
+	if len(palette) != 16 || palette[0] != DefaultTheme.Black || palette[15] != DefaultTheme.BrightWhite {
+		t.Errorf("expected the 16 colors of the theme, got %v", palette)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// chaptersFile is the file name of the chapters in the frames directory, in
+// the metadata format of ffmpeg.
+const chaptersFile = "chapters.txt"
+
+// progressBarHeight is the height of the progress bar, in pixels.
+const progressBarHeight = 4
+
+// Chapter is a named part of the recording, from Start to the next chapter.
+type Chapter struct {
+	Title string
+	Start time.Duration
+}
+
+// ExecuteChapter starts a new chapter of the recording.
+func ExecuteChapter(c Command, v *VHS) {
+	video := &v.Options.Video
+	video.Chapters = append(video.Chapters, Chapter{Title: c.Args, Start: v.overlayStart()})
+}
+
+// outputTime returns the time in the outputs of a position of the recording,
+// once the playback speed is applied.
+func outputTime(opts VideoOptions, d time.Duration) time.Duration {
+	if opts.PlaybackSpeed <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) / opts.PlaybackSpeed)
+}
+
+// MakeChapters writes the chapters of the recording in the metadata format of
+// ffmpeg, so that they are added to the MP4 and WebM outputs.
+//
+//	;FFMETADATA1
+//	[CHAPTER]
+//	TIMEBASE=1/1000
+//	START=0
+//	END=2500
+//	title=Install
+func MakeChapters(opts VideoOptions) error {
+	if len(opts.Chapters) == 0 || (opts.Output.MP4 == "" && opts.Output.WebM == "") {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for i, c := range opts.Chapters {
+		end := opts.Duration
+		if i+1 < len(opts.Chapters) {
+			end = opts.Chapters[i+1].Start
+		}
+		if end <= c.Start {
+			continue
+		}
+		fmt.Fprintf(&b, "[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			outputTime(opts, c.Start).Milliseconds(), outputTime(opts, end).Milliseconds(), escapeMetadata(c.Title))
+	}
+	return os.WriteFile(filepath.Join(opts.Input, chaptersFile), []byte(b.String()), 0o600)
+}
+
+// escapeMetadata escapes the special characters of a value of the metadata
+// format of ffmpeg.
+func escapeMetadata(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n").Replace(s)
+}
+
+// chapterInputs returns the ffmpeg arguments to read the chapters, after the
+// other inputs, and to add them to the output.
+func chapterInputs(opts VideoOptions) ([]string, []string) {
+	if len(opts.Chapters) == 0 {
+		return nil, nil
+	}
+	n := fmt.Sprint(inputCount(opts))
+	return []string{"-i", filepath.Join(opts.Input, chaptersFile)},
+		[]string{"-map_metadata", n, "-map_chapters", n}
+}
+
+// progressFilter returns the filters which draw the progress bar along the
+// bottom of the frames, with a mark at the start of every chapter.
+func progressFilter(opts VideoOptions) string {
+	if !opts.ProgressBar || opts.Duration <= 0 {
+		return ""
+	}
+	duration := outputTime(opts, opts.Duration).Seconds()
+	var b strings.Builder
+	fmt.Fprintf(&b, "[progress];color=c=%s:s=%dx%d[bar];[progress][bar]overlay=x='-W+W*t/%.3f':y=H-h:shortest=1",
+		opts.ProgressColor, opts.Width, progressBarHeight, duration)
+	for _, c := range opts.Chapters {
+		if c.Start <= 0 || c.Start >= opts.Duration {
+			continue
+		}
+		x := int(float64(opts.Width) * float64(c.Start) / float64(opts.Duration))
+		fmt.Fprintf(&b, ",drawbox=x=%d:y=ih-%d:w=2:h=%d:color=%s:t=fill",
+			x, progressBarHeight, progressBarHeight, opts.BackgroundColor)
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMakeChapters(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = t.TempDir()
+	opts.Output.MP4 = "out.mp4"
+	opts.PlaybackSpeed = 2
+	opts.Duration = 10 * time.Second
+	opts.Chapters = []Chapter{
+		{Title: "Install", Start: 0},
+		{Title: "Run = go", Start: 4 * time.Second},
+	}
+
+	if err := MakeChapters(opts); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(opts.Input, chaptersFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `;FFMETADATA1
+[CHAPTER]
+TIMEBASE=1/1000
+START=0
+END=2000
+title=Install
+[CHAPTER]
+TIMEBASE=1/1000
+START=2000
+END=5000
+title=Run \= go
+`
+	if string(b) != want {
+		t.Errorf("expected the chapters:\n%s\ngot:\n%s", want, b)
+	}
+
+	opts.WindowBar = "Colorful"
+	mp4 := strings.Join(MakeMP4(opts).Args, " ")
+	for _, s := range []string{
+		"-i " + filepath.Join(opts.Input, chaptersFile) + " -filter_complex",
+		"-map_metadata 3 -map_chapters 3",
+	} {
+		if !strings.Contains(mp4, s) {
+			t.Errorf("expected %q in the command: %s", s, mp4)
+		}
+	}
+	if gif := strings.Join(MakeGIF(opts).Args, " "); strings.Contains(gif, chaptersFile) {
+		t.Errorf("expected no chapters in the GIF: %s", gif)
+	}
+}
+
+func TestProgressFilter(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Duration = 10 * time.Second
+	opts.Chapters = []Chapter{{Title: "Install"}, {Title: "Run", Start: 5 * time.Second}}
+	opts.ProgressColor = "#0000ff"
+
+	if filter := progressFilter(opts); filter != "" {
+		t.Errorf("expected no progress bar by default, got %s", filter)
+	}
+
+	opts.ProgressBar = true
+	want := "[progress];color=c=#0000ff:s=1200x4[bar];[progress][bar]overlay=x='-W+W*t/10.000':y=H-h:shortest=1" +
+		",drawbox=x=600:y=ih-4:w=2:h=4:color=" + opts.BackgroundColor + ":t=fill"
+	if filter := progressFilter(opts); filter != want {
+		t.Errorf("expected the progress bar %s, got %s", want, filter)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Clipboard is the clipboard which Copy writes to and Paste reads from.
+type Clipboard interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// errNoClipboard is returned when there is no clipboard on the host, e.g. on
+// a headless CI runner.
+var errNoClipboard = errors.New("no clipboard available")
+
+// systemClipboard is the clipboard of the host, accessed with the commands of
+// the operating system.
+type systemClipboard struct{}
+
+// ReadAll returns the text of the clipboard.
+func (systemClipboard) ReadAll() (string, error) {
+	read, _ := clipboardCommands()
+	if len(read) == 0 {
+		return "", errNoClipboard
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(read[0], read[1:]...) //nolint:gosec
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %s", read[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// WriteAll replaces the text of the clipboard.
+func (systemClipboard) WriteAll(text string) error {
+	_, write := clipboardCommands()
+	if len(write) == 0 {
+		return errNoClipboard
+	}
+	cmd := exec.Command(write[0], write[1:]...) //nolint:gosec
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", write[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ExecuteCopy is a CommandFunc that copies the text to the clipboard, for a
+// later Paste. The text is also kept by the vhs, so that it can be pasted when
+// there is no clipboard on the host.
+func ExecuteCopy(c Command, v *VHS) {
+	v.copied = c.Args
+	v.hasCopied = true
+	if v.Options.Deterministic {
+		return
+	}
+	_ = v.clipboard.WriteAll(c.Args)
+}
+
+// ExecutePaste is a CommandFunc that pastes the text of the clipboard in the
+// terminal, as a bracketed paste if the program running in it enabled
+// bracketed paste mode, just like pasting in a terminal emulator.
+func ExecutePaste(c Command, v *VHS) {
+	text, err := v.pasteText()
+	if err != nil {
+		v.Errors = append(v.Errors, err)
+		return
+	}
+	if _, err := v.Page.Eval("(text) => term.paste(text)", text); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("failed to paste: %w", err))
+		return
+	}
+	v.sleep(v.typingSpeed(c))
+}
+
+// pasteText returns the text pasted by Paste: the one of the clipboard of the
+// host, or the one of the last Copy when there is no clipboard. With
+// --deterministic, only the text of Copy is pasted, since the clipboard of
+// the host changes from one run to the next.
+func (v *VHS) pasteText() (string, error) {
+	if !v.Options.Deterministic && v.clipboard != nil {
+		text, err := v.clipboard.ReadAll()
+		if err == nil {
+			return text, nil
+		}
+		if !v.hasCopied {
+			return "", fmt.Errorf("failed to paste: nothing was copied and the clipboard can't be read: %w", err)
+		}
+	}
+	if !v.hasCopied {
+		return "", errors.New("failed to paste: nothing was copied")
+	}
+	return v.copied, nil
+}
@@ -0,0 +1,8 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+func clipboardCommands() (read, write []string) {
+	return []string{"pbpaste"}, []string{"pbcopy"}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+type testClipboard struct {
+	text string
+	err  error
+}
+
+func (c *testClipboard) ReadAll() (string, error) { return c.text, c.err }
+
+func (c *testClipboard) WriteAll(text string) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.text = text
+	return nil
+}
+
+func TestPasteText(t *testing.T) {
+	t.Run("clipboard", func(t *testing.T) {
+		v := New()
+		v.clipboard = &testClipboard{text: "from the host"}
+		text, err := v.pasteText()
+		requireNoErr(t, err)
+		if text != "from the host" {
+			t.Errorf("expected the text of the clipboard, got %q", text)
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		v := New()
+		clipboard := &testClipboard{}
+		v.clipboard = clipboard
+		ExecuteCopy(Command{Type: COPY, Args: "copied"}, &v)
+		if clipboard.text != "copied" {
+			t.Errorf("expected Copy to write to the clipboard, got %q", clipboard.text)
+		}
+		text, err := v.pasteText()
+		requireNoErr(t, err)
+		if text != "copied" {
+			t.Errorf("expected the copied text, got %q", text)
+		}
+	})
+
+	t.Run("headless", func(t *testing.T) {
+		v := New()
+		v.clipboard = &testClipboard{err: errNoClipboard}
+		ExecuteCopy(Command{Type: COPY, Args: "copied"}, &v)
+		text, err := v.pasteText()
+		requireNoErr(t, err)
+		if text != "copied" {
+			t.Errorf("expected the copied text, got %q", text)
+		}
+	})
+
+	t.Run("nothing to paste", func(t *testing.T) {
+		v := New()
+		v.clipboard = &testClipboard{err: errNoClipboard}
+		_, err := v.pasteText()
+		if !errors.Is(err, errNoClipboard) {
+			t.Errorf("expected an error for the missing clipboard, got %v", err)
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		v := New()
+		clipboard := &testClipboard{text: "from the host"}
+		v.clipboard = clipboard
+		v.Options.Deterministic = true
+		if _, err := v.pasteText(); err == nil {
+			t.Error("expected an error since nothing was copied")
+		}
+		ExecuteCopy(Command{Type: COPY, Args: "copied"}, &v)
+		text, err := v.pasteText()
+		requireNoErr(t, err)
+		if text != "copied" || clipboard.text != "from the host" {
+			t.Errorf("expected the clipboard to be left alone, got %q and %q", text, clipboard.text)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+//go:build dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// clipboardCommands returns the commands which read and write the clipboard,
+// or nothing if there is no display or no clipboard utility is installed.
+func clipboardCommands() (read, write []string) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wl-paste"); err == nil {
+			return []string{"wl-paste", "--no-newline"}, []string{"wl-copy"}
+		}
+	}
+	if os.Getenv("DISPLAY") == "" {
+		return nil, nil
+	}
+	if _, err := exec.LookPath("xclip"); err == nil {
+		return []string{"xclip", "-selection", "clipboard", "-o"}, []string{"xclip", "-selection", "clipboard", "-i"}
+	}
+	if _, err := exec.LookPath("xsel"); err == nil {
+		return []string{"xsel", "--clipboard", "--output"}, []string{"xsel", "--clipboard", "--input"}
+	}
+	return nil, nil
+}
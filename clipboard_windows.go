@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package main
+
+func clipboardCommands() (read, write []string) {
+	return []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+		[]string{"powershell.exe", "-NoProfile", "-Command", "$input | Set-Clipboard"}
+}
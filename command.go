@@ -1,8 +1,10 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
+	"math"
+	"math/rand"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -11,38 +13,50 @@ import (
 	"github.com/go-rod/rod/lib/input"
 )
 
-// CommandType is a type that represents a command.
-type CommandType TokenType
-
 // CommandTypes is a list of the available commands that can be executed.
 var CommandTypes = []CommandType{ //nolint: deadcode
+	ABORT,
 	BACKSPACE,
+	BREAKPOINT,
+	CAPTION,
+	CHAPTER,
+	COPY,
 	CTRL,
+	ALT,
+	SHIFT,
+	AUDIO,
 	DOWN,
 	ENTER,
+	ENV,
 	ESCAPE,
+	EXPECT,
+	EXPECT_NOT,
+	FLASH,
+	FOCUS,
+	HIGHLIGHT,
 	ILLEGAL,
 	LEFT,
 	RIGHT,
 	SET,
+	SETUP,
 	OUTPUT,
+	PASTE,
 	SLEEP,
 	SPACE,
+	SPLIT,
 	HIDE,
+	QUIET,
+	SKIP,
+	SPEED,
 	REQUIRE,
+	SCREENSHOT,
 	SHOW,
 	TAB,
+	TEARDOWN,
 	TYPE,
 	UP,
-}
-
-// String returns the string representation of the command.
-func (c CommandType) String() string {
-	if len(c) < 1 {
-		return ""
-	}
-	s := string(c)
-	return string(s[0]) + strings.ToLower(s[1:])
+	VAR,
+	WAIT,
 }
 
 // CommandFunc is a function that executes a command on a running
@@ -51,46 +65,70 @@ type CommandFunc func(c Command, v *VHS)
 
 // CommandFuncs maps command types to their executable functions.
 var CommandFuncs = map[CommandType]CommandFunc{
-	BACKSPACE: ExecuteKey(input.Backspace),
-	DOWN:      ExecuteKey(input.ArrowDown),
-	ENTER:     ExecuteKey(input.Enter),
-	LEFT:      ExecuteKey(input.ArrowLeft),
-	RIGHT:     ExecuteKey(input.ArrowRight),
-	SPACE:     ExecuteKey(input.Space),
-	UP:        ExecuteKey(input.ArrowUp),
-	TAB:       ExecuteKey(input.Tab),
-	ESCAPE:    ExecuteKey(input.Escape),
-	HIDE:      ExecuteHide,
-	REQUIRE:   ExecuteRequire,
-	SHOW:      ExecuteShow,
-	SET:       ExecuteSet,
-	OUTPUT:    ExecuteOutput,
-	SLEEP:     ExecuteSleep,
-	TYPE:      ExecuteType,
-	CTRL:      ExecuteCtrl,
-	ILLEGAL:   ExecuteNoop,
-}
-
-// Command represents a command with options and arguments.
-type Command struct {
-	Type    CommandType
-	Options string
-	Args    string
-}
-
-// String returns the string representation of the command.
-// This includes the options and arguments of the command.
-func (c Command) String() string {
-	if c.Options != "" {
-		return fmt.Sprintf("%s %s %s", c.Type, c.Options, c.Args)
-	}
-	return fmt.Sprintf("%s %s", c.Type, c.Options)
-}
-
-// Execute executes a command on a running instance of vhs.
-func (c Command) Execute(v *VHS) {
+	BACKSPACE:  ExecuteKey(input.Backspace),
+	DOWN:       ExecuteKey(input.ArrowDown),
+	ENTER:      ExecuteEnter,
+	LEFT:       ExecuteKey(input.ArrowLeft),
+	RIGHT:      ExecuteKey(input.ArrowRight),
+	SPACE:      ExecuteKey(input.Space),
+	UP:         ExecuteKey(input.ArrowUp),
+	TAB:        ExecuteKey(input.Tab),
+	ESCAPE:     ExecuteKey(input.Escape),
+	HIDE:       ExecuteHide,
+	FLASH:      ExecuteFlash,
+	CAPTION:    ExecuteCaption,
+	CHAPTER:    ExecuteChapter,
+	SPLIT:      ExecuteSplit,
+	FOCUS:      ExecuteFocus,
+	AUDIO:      ExecuteAudio,
+	HIGHLIGHT:  ExecuteHighlight,
+	BREAKPOINT: ExecuteBreakpoint,
+	ABORT:      ExecuteAbort,
+	QUIET:      ExecuteQuiet,
+	SKIP:       ExecuteSkip,
+	SPEED:      ExecuteSpeed,
+	SCREENSHOT: ExecuteScreenshot,
+	REQUIRE:    ExecuteRequire,
+	ENV:        ExecuteEnv,
+	SETUP:      ExecuteSetup,
+	TEARDOWN:   ExecuteTeardown,
+	SHOW:       ExecuteShow,
+	SET:        ExecuteSet,
+	OUTPUT:     ExecuteOutput,
+	SLEEP:      ExecuteSleep,
+	TYPE:       ExecuteType,
+	CTRL:       ExecuteChord,
+	ALT:        ExecuteChord,
+	SHIFT:      ExecuteChord,
+	VAR:        ExecuteNoop,
+	WAIT:       ExecuteWait,
+	COPY:       ExecuteCopy,
+	PASTE:      ExecutePaste,
+	EXPECT:     ExecuteExpect,
+	EXPECT_NOT: ExecuteExpect,
+	ILLEGAL:    ExecuteNoop,
+}
+
+// executeCommand executes a command on a running instance of vhs.
+func executeCommand(c Command, v *VHS) {
+	if v.recording && v.Options.KeyLog != "" && isKeyCommand(c.Type) {
+		v.LogKey(c)
+	}
+	var start time.Duration
+	if v.recording && isKeyCommand(c.Type) {
+		start = v.overlayStart()
+	}
 	CommandFuncs[c.Type](c, v)
-	if v.recording && v.Options.Test.Output != "" {
+	if v.recording && v.Options.ShowKeys && isKeyCommand(c.Type) {
+		v.ShowKey(c, start)
+	}
+	if v.recording && c.Type == TYPE {
+		v.addSubtitle(c.Args, start, v.overlayStart()+subtitleHold, true)
+	}
+	if v.Options.KeyDelay > 0 && isKeyCommand(c.Type) {
+		v.sleep(v.Options.KeyDelay)
+	}
+	if v.recording && v.Page != nil && v.Options.Test.Output != "" {
 		v.SaveOutput()
 	}
 }
@@ -108,31 +146,70 @@ func ExecuteNoop(c Command, v *VHS) {}
 // the ArrowDown key press.
 func ExecuteKey(k input.Key) CommandFunc {
 	return func(c Command, v *VHS) {
-		typingSpeed, err := time.ParseDuration(c.Options)
-		if err != nil {
-			typingSpeed = v.Options.TypingSpeed
-		}
+		typingSpeed := v.typingSpeed(c)
 		repeat, err := strconv.Atoi(c.Args)
 		if err != nil {
 			repeat = 1
 		}
 		for i := 0; i < repeat; i++ {
 			_ = v.Page.Keyboard.Type(k)
-			time.Sleep(typingSpeed)
+			v.sleep(typingSpeed)
 		}
 	}
 }
 
-// ExecuteCtrl is a CommandFunc that presses the argument key with the ctrl key
-// held down on the running instance of vhs.
-func ExecuteCtrl(c Command, v *VHS) {
-	_ = v.Page.Keyboard.Press(input.ControlLeft)
-	for _, r := range c.Args {
-		if k, ok := keymap[r]; ok {
-			_ = v.Page.Keyboard.Type(k)
-		}
+// ExecuteEnter is a CommandFunc that presses the enter key. Inside a Quiet
+// block, the recording is paused before the key press so that the output of
+// the command is not captured.
+func ExecuteEnter(c Command, v *VHS) {
+	if v.quiet {
+		v.hideOutput()
 	}
-	_ = v.Page.Keyboard.Release(input.ControlLeft)
+	ExecuteKey(input.Enter)(c, v)
+}
+
+// ExecuteChord is a CommandFunc that presses the key of a chord with its
+// modifiers held down on the running instance of vhs. The command type is the
+// first modifier, and the arguments are the other modifiers and the key, e.g.
+// Shift+T for Ctrl+Shift+T.
+func ExecuteChord(c Command, v *VHS) {
+	parts := strings.Split(c.Args, "+")
+	key, ok := chordKey(parts[len(parts)-1])
+	if !ok {
+		return
+	}
+
+	modifiers := []input.Key{modifierKeys[c.Type.String()]}
+	for _, m := range parts[:len(parts)-1] {
+		modifiers = append(modifiers, modifierKeys[m])
+	}
+
+	for _, m := range modifiers {
+		_ = v.Page.Keyboard.Press(m)
+	}
+	_ = v.Page.Keyboard.Type(key)
+	for i := len(modifiers) - 1; i >= 0; i-- {
+		_ = v.Page.Keyboard.Release(modifiers[i])
+	}
+}
+
+// defaultFlashFrames is the number of frames a Flash lasts when no duration is
+// given.
+const defaultFlashFrames = 2
+
+// ExecuteFlash briefly tints the terminal with the flash color to draw
+// attention to the current point of the recording.
+func ExecuteFlash(c Command, v *VHS) {
+	duration, err := time.ParseDuration(c.Options)
+	if err != nil {
+		duration = defaultFlashFrames * time.Second / time.Duration(v.Options.Video.Framerate)
+	}
+
+	flash := v.Options.Theme
+	flash.Background = v.Options.FlashColor
+	_, _ = v.Page.Eval(fmt.Sprintf("() => term.options.theme = %s", flash.String()))
+	v.sleep(duration)
+	_, _ = v.Page.Eval(fmt.Sprintf("() => term.options.theme = %s", v.Options.Theme.String()))
 }
 
 // ExecuteHide is a CommandFunc that starts or stops the recording of the vhs.
@@ -149,6 +226,21 @@ func ExecuteRequire(c Command, v *VHS) {
 	}
 }
 
+// ExecuteEnv sets an environment variable of the shell on the vhs.
+func ExecuteEnv(c Command, v *VHS) {
+	v.Options.Env = append(v.Options.Env, c.Options+"="+c.Args)
+}
+
+// ExecuteSetup adds a script to run before the shell of the vhs starts.
+func ExecuteSetup(c Command, v *VHS) {
+	v.Options.Setup = append(v.Options.Setup, c.Args)
+}
+
+// ExecuteTeardown adds a script to run once the recording is done.
+func ExecuteTeardown(c Command, v *VHS) {
+	v.Options.Teardown = append(v.Options.Teardown, c.Args)
+}
+
 // ExecuteShow is a CommandFunc that resumes the recording of the vhs.
 func ExecuteShow(c Command, v *VHS) {
 	v.ResumeRecording()
@@ -161,15 +253,53 @@ func ExecuteSleep(c Command, v *VHS) {
 	if err != nil {
 		return
 	}
-	time.Sleep(dur)
+	v.sleep(time.Duration(float64(dur) * v.Options.SleepScale))
+}
+
+// defaultSleep waits for the default sleep after a top-level command. There
+// is no pause after the settings, Hide and Show, nor between the commands of
+// a Quiet block.
+func (v *VHS) defaultSleep(c Command) {
+	if v.Options.DefaultSleep <= 0 || v.quiet {
+		return
+	}
+	switch c.Type {
+	case SET, OUTPUT, REQUIRE, ENV, VAR, SETUP, TEARDOWN, HIDE, SHOW, SPLIT, FOCUS, SKIP, SPEED:
+		return
+	case QUIET:
+		if c.Args == "on" {
+			return
+		}
+	}
+	v.sleep(time.Duration(float64(v.Options.DefaultSleep) * v.Options.SleepScale))
+}
+
+// typingSpeed returns the pause after every key press of a Type or key
+// command: its own @<time> if given, otherwise the TypingSpeed setting. A
+// typing speed of 0 types instantly.
+func (v *VHS) typingSpeed(c Command) time.Duration {
+	if typingSpeed, err := time.ParseDuration(c.Options); err == nil && typingSpeed >= 0 {
+		return typingSpeed
+	}
+	return v.Options.TypingSpeed
+}
+
+// typingDelay returns the pause after a typed character, which varies around
+// the typing speed by up to the TypingVariance setting. The variations follow
+// the TypingSeed setting, so that the same tape is typed the same way.
+func (v *VHS) typingDelay(typingSpeed time.Duration) time.Duration {
+	if v.Options.TypingVariance <= 0 {
+		return typingSpeed
+	}
+	if v.rand == nil {
+		v.rand = rand.New(rand.NewSource(v.Options.TypingSeed)) //nolint:gosec
+	}
+	return time.Duration(float64(typingSpeed) * (1 + v.Options.TypingVariance*(2*v.rand.Float64()-1)))
 }
 
 // ExecuteType types the argument string on the running instance of vhs.
 func ExecuteType(c Command, v *VHS) {
-	typingSpeed, err := time.ParseDuration(c.Options)
-	if err != nil {
-		typingSpeed = v.Options.TypingSpeed
-	}
+	typingSpeed := v.typingSpeed(c)
 	for _, r := range c.Args {
 		k, ok := keymap[r]
 		if ok {
@@ -178,22 +308,52 @@ func ExecuteType(c Command, v *VHS) {
 			_ = v.Page.MustElement("textarea").Input(string(r))
 			v.Page.MustWaitIdle()
 		}
-		time.Sleep(typingSpeed)
+		v.sleep(v.typingDelay(typingSpeed))
 	}
 }
 
-// ExecuteOutput applies the output on the vhs videos.
+// ExecuteOutput applies the output on the vhs videos. The outputs of a tape
+// rendered by the server are ignored, see sandboxOutputs.
 func ExecuteOutput(c Command, v *VHS) {
+	if v.sandboxed {
+		return
+	}
+	setOutput(c, v)
+}
+
+// setOutput sets the output of the file type of the command.
+func setOutput(c Command, v *VHS) {
 	switch c.Options {
 	case ".mp4":
 		v.Options.Video.Output.MP4 = c.Args
-	case ".test", ".ascii", ".txt":
+	case ".test", ".ascii":
 		v.Options.Test.Output = c.Args
+	case ".txt":
+		v.Options.Test.Screen = c.Args
+	case ".html":
+		v.Options.HTML.Output = c.Args
+	case ".svg":
+		v.Options.Video.Output.SVG = c.Args
+	case ".cast":
+		v.Options.Video.Output.Cast = c.Args
+	case ".srt":
+		v.Options.Video.Output.SRT = c.Args
+	case ".vtt":
+		v.Options.Video.Output.VTT = c.Args
 	case ".png":
+		if !strings.HasSuffix(c.Args, "/") {
+			v.Options.Video.Output.PNG = c.Args
+			return
+		}
 		v.Options.Video.Input = c.Args
+		v.Options.Video.Output.Frames = c.Args
 		v.Options.Video.CleanupFrames = false
 	case ".webm":
 		v.Options.Video.Output.WebM = c.Args
+	case ".webp":
+		v.Options.Video.Output.WebP = c.Args
+	case ".apng":
+		v.Options.Video.Output.APNG = c.Args
 	default:
 		v.Options.Video.Output.GIF = c.Args
 	}
@@ -201,19 +361,59 @@ func ExecuteOutput(c Command, v *VHS) {
 
 // Settings maps the Set commands to their respective functions.
 var Settings = map[string]CommandFunc{
-	"FontFamily":    ExecuteSetFontFamily,
-	"FontSize":      ExecuteSetFontSize,
-	"Framerate":     ExecuteSetFramerate,
-	"Height":        ExecuteSetHeight,
-	"LetterSpacing": ExecuteSetLetterSpacing,
-	"LineHeight":    ExecuteSetLineHeight,
-	"PlaybackSpeed": ExecuteSetPlaybackSpeed,
-	"Padding":       ExecuteSetPadding,
-	"Theme":         ExecuteSetTheme,
-	"TypingSpeed":   ExecuteSetTypingSpeed,
-	"Width":         ExecuteSetWidth,
-	"Shell":         ExecuteSetShell,
-	"LoopOffset":    ExecuteLoopOffset,
+	"FontFamily":     ExecuteSetFontFamily,
+	"FontFile":       ExecuteSetFontFile,
+	"FontSize":       ExecuteSetFontSize,
+	"Framerate":      ExecuteSetFramerate,
+	"Height":         ExecuteSetHeight,
+	"LetterSpacing":  ExecuteSetLetterSpacing,
+	"LineHeight":     ExecuteSetLineHeight,
+	"PlaybackSpeed":  ExecuteSetPlaybackSpeed,
+	"Loops":          ExecuteSetLoops,
+	"Padding":        ExecuteSetPadding,
+	"Theme":          ExecuteSetTheme,
+	"TypingSpeed":    ExecuteSetTypingSpeed,
+	"TypingVariance": ExecuteSetTypingVariance,
+	"TypingSeed":     ExecuteSetTypingSeed,
+	"Width":          ExecuteSetWidth,
+	"Shell":          ExecuteSetShell,
+	"LoopOffset":     ExecuteLoopOffset,
+	"SleepScale":     ExecuteSetSleepScale,
+	"KeyLog":         ExecuteSetKeyLog,
+	"FlashColor":     ExecuteSetFlashColor,
+	"ShowGrid":       ExecuteSetShowGrid,
+	"ShowKeys":       ExecuteSetShowKeys,
+	"ProgressBar":    ExecuteSetProgressBar,
+	"Audio":          ExecuteSetAudio,
+	"Timezone":       ExecuteSetTimezone,
+	"HtmlFull":       ExecuteSetHTMLFull,
+	"Crt":            ExecuteSetCRT,
+	"CrtIntensity":   ExecuteSetCRTIntensity,
+	"WebPQuality":    ExecuteSetWebPQuality,
+	"WebPLossless":   ExecuteSetWebPLossless,
+	"Secret":         ExecuteSetSecret,
+	"SSH":            ExecuteSetSSH,
+	"Container":      ExecuteSetContainer,
+
+	"FrameRateFromTyping": ExecuteSetFrameRateFromTyping,
+	"Title":               ExecuteSetTitle,
+	"ScreenshotDir":       ExecuteSetScreenshotDir,
+	"ScreenshotDigits":    ExecuteSetScreenshotDigits,
+	"KeyDelay":            ExecuteSetKeyDelay,
+	"CursorColor":         ExecuteSetCursorColor,
+	"CursorBlink":         ExecuteSetCursorBlink,
+	"CursorStyle":         ExecuteSetCursorStyle,
+	"DefaultSleep":        ExecuteSetDefaultSleep,
+	"WindowBar":           ExecuteSetWindowBar,
+	"WindowBarSize":       ExecuteSetWindowBarSize,
+	"WindowTitle":         ExecuteSetWindowTitle,
+	"WindowBarTitle":      ExecuteSetWindowTitle,
+	"BorderRadius":        ExecuteSetBorderRadius,
+	"Margin":              ExecuteSetMargin,
+	"MarginFill":          ExecuteSetMarginFill,
+	"Shadow":              ExecuteSetShadow,
+	"MaxFileSize":         ExecuteSetMaxFileSize,
+	"WorkingDirectory":    ExecuteSetWorkingDirectory,
 }
 
 // ExecuteSet applies the settings on the running vhs specified by the
@@ -226,19 +426,26 @@ func ExecuteSet(c Command, v *VHS) {
 func ExecuteSetFontSize(c Command, v *VHS) {
 	fontSize, _ := strconv.Atoi(c.Args)
 	v.Options.FontSize = fontSize
-	_, _ = v.Page.Eval(fmt.Sprintf("() => term.options.fontSize = %d", fontSize))
-
-	// When changing the font size only the canvas dimensions change which are
-	// scaled back during the render to fit the aspect ration and dimensions.
-	//
-	// We need to call term.fit to ensure that everything is resized properly.
-	_, _ = v.Page.Eval("term.fit")
 }
 
 // ExecuteSetFontFamily applies the font family on the vhs.
 func ExecuteSetFontFamily(c Command, v *VHS) {
 	v.Options.FontFamily = c.Args
-	_, _ = v.Page.Eval(fmt.Sprintf("() => term.options.fontFamily = '%s'", c.Args))
+}
+
+// ExecuteSetFontFile adds a font file to the font family of the terminal on the
+// vhs. The font files are used in the order they are set, before the fonts of
+// FontFamily.
+func ExecuteSetFontFile(c Command, v *VHS) {
+	if fontFileType(c.Args) == "" {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set FontFile %s`: expected a .ttf, .otf, .woff or .woff2 file", c.Args))
+		return
+	}
+	if _, err := os.Stat(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set FontFile %s`: %w", c.Args, err))
+		return
+	}
+	v.Options.FontFiles = append(v.Options.FontFiles, c.Args)
 }
 
 // ExecuteSetHeight applies the height on the vhs.
@@ -269,14 +476,12 @@ const base = 10
 func ExecuteSetLetterSpacing(c Command, v *VHS) {
 	letterSpacing, _ := strconv.ParseFloat(c.Args, bitSize)
 	v.Options.LetterSpacing = letterSpacing
-	_, _ = v.Page.Eval(fmt.Sprintf("() => term.options.letterSpacing = %f", letterSpacing))
 }
 
 // ExecuteSetLineHeight applies the line height on the vhs.
 func ExecuteSetLineHeight(c Command, v *VHS) {
 	lineHeight, _ := strconv.ParseFloat(c.Args, bitSize)
 	v.Options.LineHeight = lineHeight
-	_, _ = v.Page.Eval(fmt.Sprintf("() => term.options.lineHeight = %f", lineHeight))
 }
 
 // ExecuteSetTheme applies the theme on the vhs.
@@ -288,20 +493,68 @@ func ExecuteSetTheme(c Command, v *VHS) {
 		return
 	}
 
-	bts, _ := json.Marshal(v.Options.Theme)
-	_, _ = v.Page.Eval(fmt.Sprintf("() => term.options.theme = %s", string(bts)))
 	v.Options.Video.BackgroundColor = v.Options.Theme.Background
 }
 
+// ExecuteSetKeyDelay applies the pause after every key and type command on
+// the vhs. A delay of 0 disables it.
+func ExecuteSetKeyDelay(c Command, v *VHS) {
+	keyDelay, err := time.ParseDuration(c.Args)
+	if err != nil || keyDelay < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set KeyDelay %s`: expected a duration", c.Args))
+		return
+	}
+	v.Options.KeyDelay = keyDelay
+}
+
+// ExecuteSetDefaultSleep applies the pause after every top-level command on
+// the vhs. A duration of 0 disables it.
+func ExecuteSetDefaultSleep(c Command, v *VHS) {
+	defaultSleep, err := time.ParseDuration(c.Args)
+	if err != nil || defaultSleep < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set DefaultSleep %s`: expected a duration", c.Args))
+		return
+	}
+	v.Options.DefaultSleep = defaultSleep
+}
+
 // ExecuteSetTypingSpeed applies the default typing speed on the vhs.
 func ExecuteSetTypingSpeed(c Command, v *VHS) {
 	typingSpeed, err := time.ParseDuration(c.Args)
-	if err != nil {
+	if err != nil || typingSpeed < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set TypingSpeed %s`: expected a duration", c.Args))
 		return
 	}
 	v.Options.TypingSpeed = typingSpeed
 }
 
+// ExecuteSetTypingVariance applies the variation of the typing speed on the
+// vhs, as a fraction of it or a percentage, between 0 and 1 or 100%.
+func ExecuteSetTypingVariance(c Command, v *VHS) {
+	percent := strings.HasSuffix(c.Args, "%")
+	variance, err := strconv.ParseFloat(strings.TrimSuffix(c.Args, "%"), bitSize)
+	if percent {
+		variance /= 100
+	}
+	if err != nil || variance < 0 || variance > 1 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set TypingVariance %s`: expected a fraction between 0 and 1, or a percentage", c.Args))
+		return
+	}
+	v.Options.TypingVariance = variance
+}
+
+// ExecuteSetTypingSeed sets the seed of the variations of the typing speed on
+// the vhs.
+func ExecuteSetTypingSeed(c Command, v *VHS) {
+	seed, err := strconv.ParseInt(c.Args, 10, 64)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set TypingSeed %s`: expected a number", c.Args))
+		return
+	}
+	v.Options.TypingSeed = seed
+	v.rand = nil
+}
+
 // ExecuteSetPadding applies the padding on the vhs.
 func ExecuteSetPadding(c Command, v *VHS) {
 	v.Options.Video.Padding, _ = strconv.Atoi(c.Args)
@@ -319,12 +572,363 @@ func ExecuteSetFramerate(c Command, v *VHS) {
 // ExecuteSetPlaybackSpeed applies the playback speed option on the vhs.
 func ExecuteSetPlaybackSpeed(c Command, v *VHS) {
 	playbackSpeed, err := strconv.ParseFloat(c.Args, bitSize)
-	if err != nil {
+	if err != nil || playbackSpeed <= 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set PlaybackSpeed %s`: expected a positive number", c.Args))
 		return
 	}
 	v.Options.Video.PlaybackSpeed = playbackSpeed
 }
 
+// ExecuteSetLoops sets the number of times the animated outputs of the vhs
+// are played, 0 to loop forever.
+func ExecuteSetLoops(c Command, v *VHS) {
+	loops, err := strconv.Atoi(c.Args)
+	if err != nil || loops < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Loops %s`: expected a number of times to play, or 0 to loop forever", c.Args))
+		return
+	}
+	v.Options.Video.Loops = loops
+}
+
+// ExecuteSetSleepScale applies the sleep scale option on the vhs.
+// Every Sleep duration is multiplied by the scale, typing speed is unaffected.
+func ExecuteSetSleepScale(c Command, v *VHS) {
+	sleepScale, err := strconv.ParseFloat(c.Args, bitSize)
+	if err != nil || sleepScale < 0 || math.IsNaN(sleepScale) || math.IsInf(sleepScale, 0) {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set SleepScale %s`: expected a number, 0 or more", c.Args))
+		return
+	}
+	v.Options.SleepScale = sleepScale
+}
+
+// ExecuteSetKeyLog sets the file to which key commands are logged.
+func ExecuteSetKeyLog(c Command, v *VHS) {
+	if v.sandboxed {
+		return
+	}
+	v.Options.KeyLog = c.Args
+}
+
+// ExecuteSetFlashColor sets the color used by the Flash command.
+func ExecuteSetFlashColor(c Command, v *VHS) {
+	v.Options.FlashColor = c.Args
+}
+
+// ExecuteSetCursorColor sets the color of the cursor on the vhs, instead of
+// the one of the theme.
+func ExecuteSetCursorColor(c Command, v *VHS) {
+	if _, err := parseHexColor(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set CursorColor %s`: expected a hex color", c.Args))
+		return
+	}
+	v.Options.CursorColor = c.Args
+}
+
+// cursorStyles are the styles of the cursor of xterm.js.
+var cursorStyles = map[string]bool{
+	"block":     true,
+	"bar":       true,
+	"underline": true,
+}
+
+// ExecuteSetCursorStyle sets the shape of the cursor on the vhs.
+func ExecuteSetCursorStyle(c Command, v *VHS) {
+	style := strings.ToLower(c.Args)
+	if !cursorStyles[style] {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set CursorStyle %s`: expected block, bar or underline", c.Args))
+		return
+	}
+	v.Options.CursorStyle = style
+}
+
+// ExecuteSetCursorBlink toggles the blinking of the cursor on the vhs, with
+// true or on, false or off. A steady cursor is drawn in every frame.
+func ExecuteSetCursorBlink(c Command, v *VHS) {
+	switch c.Args {
+	case "on":
+		v.Options.CursorBlink = true
+		return
+	case "off":
+		v.Options.CursorBlink = false
+		return
+	}
+	cursorBlink, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set CursorBlink %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.CursorBlink = cursorBlink
+}
+
+// ExecuteSetShowGrid toggles the cell grid overlay on the vhs.
+func ExecuteSetShowGrid(c Command, v *VHS) {
+	showGrid, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set ShowGrid %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.Video.ShowGrid = showGrid
+}
+
+// ExecuteSetShowKeys toggles the display of the keys pressed on the vhs.
+func ExecuteSetShowKeys(c Command, v *VHS) {
+	showKeys, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set ShowKeys %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.ShowKeys = showKeys
+}
+
+// ExecuteSetAudio sets the audio track of the MP4 and WebM outputs, which
+// plays from the start of the recording.
+func ExecuteSetAudio(c Command, v *VHS) {
+	if _, err := os.Stat(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Audio %s`: %w", c.Args, err))
+		return
+	}
+	v.Options.Video.Audio = append(v.Options.Video.Audio, AudioClip{File: c.Args})
+}
+
+// ExecuteSetProgressBar toggles the progress bar along the bottom of the
+// frames on the vhs.
+func ExecuteSetProgressBar(c Command, v *VHS) {
+	progressBar, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set ProgressBar %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.Video.ProgressBar = progressBar
+}
+
+// ExecuteSetWindowBar sets the style of the window bar drawn above the
+// terminal on the vhs.
+func ExecuteSetWindowBar(c Command, v *VHS) {
+	if c.Args == "None" {
+		v.Options.Video.WindowBar = ""
+		return
+	}
+	if _, ok := windowBarStyles[c.Args]; !ok {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set WindowBar %s`: expected Colorful, ColorfulRight, Rings, RingsRight or None", c.Args))
+		return
+	}
+	v.Options.Video.WindowBar = c.Args
+}
+
+// ExecuteSetWindowBarSize sets the height of the window bar, in pixels, on
+// the vhs.
+func ExecuteSetWindowBarSize(c Command, v *VHS) {
+	size, err := strconv.Atoi(c.Args)
+	if err != nil || size <= 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set WindowBarSize %s`: expected a number of pixels", c.Args))
+		return
+	}
+	v.Options.Video.WindowBarSize = size
+}
+
+// ExecuteSetBorderRadius sets the radius of the rounded corners of the window
+// on the vhs, in pixels. A radius of 0 keeps the corners square.
+func ExecuteSetBorderRadius(c Command, v *VHS) {
+	radius, err := strconv.Atoi(c.Args)
+	if err != nil || radius < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set BorderRadius %s`: expected a number of pixels", c.Args))
+		return
+	}
+	v.Options.Video.BorderRadius = radius
+}
+
+// ExecuteSetMargin sets the margin around the frames on the vhs, in pixels,
+// which is filled with the fill of the margin.
+func ExecuteSetMargin(c Command, v *VHS) {
+	margin, err := strconv.Atoi(c.Args)
+	if err != nil || margin < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Margin %s`: expected a number of pixels", c.Args))
+		return
+	}
+	v.Options.Video.Margin = margin
+}
+
+// ExecuteSetMarginFill sets the fill of the margin on the vhs, which is either
+// a hex color or a PNG or JPEG image.
+func ExecuteSetMarginFill(c Command, v *VHS) {
+	if isImageFill(c.Args) {
+		if _, err := os.Stat(c.Args); err != nil {
+			v.Errors = append(v.Errors, fmt.Errorf("invalid `Set MarginFill %s`: %w", c.Args, err))
+			return
+		}
+	} else if _, err := parseHexColor(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set MarginFill %s`: expected a hex color or an image", c.Args))
+		return
+	}
+	v.Options.Video.MarginFill = c.Args
+}
+
+// ExecuteSetShadow toggles the drop shadow below the frames, in the margin,
+// on the vhs.
+func ExecuteSetShadow(c Command, v *VHS) {
+	shadow, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Shadow %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.Video.Shadow = shadow
+}
+
+// ExecuteSetMaxFileSize sets the maximum size of the GIF on the vhs, which is
+// encoded again with fewer colors, frames or pixels until it fits.
+func ExecuteSetMaxFileSize(c Command, v *VHS) {
+	size, err := parseFileSize(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set MaxFileSize %s`: %w", c.Args, err))
+		return
+	}
+	v.Options.Video.MaxFileSize = size
+}
+
+// ExecuteSetWindowTitle sets the title written in the window bar on the vhs.
+func ExecuteSetWindowTitle(c Command, v *VHS) {
+	v.Options.Video.WindowTitle = c.Args
+}
+
+// ExecuteSetWorkingDirectory sets the directory in which the shell of the vhs
+// starts, along with the Setup and Teardown scripts. It is created if needed.
+func ExecuteSetWorkingDirectory(c Command, v *VHS) {
+	if c.Args == "" {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set WorkingDirectory %s`: expected a directory", c.Args))
+		return
+	}
+	v.Options.WorkingDirectory = c.Args
+}
+
+// ExecuteSetTitle sets the title of the terminal on the vhs.
+func ExecuteSetTitle(c Command, v *VHS) {
+	v.Options.Title = c.Args
+}
+
+// ExecuteSetScreenshotDir sets the directory in which the screenshots are
+// saved on the vhs.
+func ExecuteSetScreenshotDir(c Command, v *VHS) {
+	if v.sandboxed {
+		return
+	}
+	v.Options.ScreenshotDir = c.Args
+}
+
+// ExecuteSetScreenshotDigits sets the number of digits, padded with zeros, of
+// the {n} counter of the screenshot names on the vhs.
+func ExecuteSetScreenshotDigits(c Command, v *VHS) {
+	digits, err := strconv.Atoi(c.Args)
+	if err != nil || digits < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set ScreenshotDigits %s`: expected a number of digits", c.Args))
+		return
+	}
+	v.Options.ScreenshotDigits = digits
+}
+
+// ExecuteSetTimezone sets the timezone (TZ) of the shell on the vhs.
+// The timezone must exist in the tz database of the system.
+func ExecuteSetTimezone(c Command, v *VHS) {
+	if _, err := time.LoadLocation(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Timezone %s`: %w", c.Args, err))
+		return
+	}
+	v.Options.Timezone = c.Args
+}
+
+// ExecuteSetHTMLFull sets whether the HTML output is a full document or a
+// fragment.
+func ExecuteSetHTMLFull(c Command, v *VHS) {
+	full, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set HtmlFull %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.HTML.Full = full
+}
+
+// ExecuteSetCRT toggles the retro CRT filter on the vhs.
+func ExecuteSetCRT(c Command, v *VHS) {
+	crt, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Crt %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.Video.CRT = crt
+}
+
+// ExecuteSetCRTIntensity applies the intensity of the CRT filter on the vhs.
+func ExecuteSetCRTIntensity(c Command, v *VHS) {
+	intensity, err := strconv.ParseFloat(c.Args, bitSize)
+	if err != nil || intensity < 0 || intensity > 1 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set CrtIntensity %s`: expected a number between 0 and 1", c.Args))
+		return
+	}
+	v.Options.Video.CRTIntensity = intensity
+}
+
+// ExecuteSetWebPQuality sets the quality, from 0 to 100, of the WebP output
+// of the vhs.
+func ExecuteSetWebPQuality(c Command, v *VHS) {
+	quality, err := strconv.Atoi(c.Args)
+	if err != nil || quality < 0 || quality > 100 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set WebPQuality %s`: expected a number between 0 and 100", c.Args))
+		return
+	}
+	v.Options.Video.WebPQuality = quality
+}
+
+// ExecuteSetWebPLossless toggles the lossless compression of the WebP output
+// of the vhs.
+func ExecuteSetWebPLossless(c Command, v *VHS) {
+	lossless, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set WebPLossless %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.Video.WebPLossless = lossless
+}
+
+// ExecuteSetSecret marks a string to be masked in the recording. It is still
+// typed in the terminal, but shown as dots in the frames, the text outputs and
+// the logs. It can be set several times, for several secrets.
+func ExecuteSetSecret(c Command, v *VHS) {
+	if c.Args == "" {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Secret %s`: expected a non-empty string", c.Args))
+		return
+	}
+	v.Options.Secrets = append(v.Options.Secrets, c.Args)
+}
+
+// ExecuteSetSSH sets the host, as [user@]host, on which the shell of the vhs
+// runs over SSH.
+func ExecuteSetSSH(c Command, v *VHS) {
+	if err := validSSHTarget(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set SSH %s`: %w", c.Args, err))
+		return
+	}
+	v.Options.SSH = c.Args
+}
+
+// ExecuteSetContainer sets the image of the container in which the shell of
+// the vhs runs, with Docker or Podman.
+func ExecuteSetContainer(c Command, v *VHS) {
+	if err := validContainerImage(c.Args); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set Container %s`: %w", c.Args, err))
+		return
+	}
+	v.Options.Container = c.Args
+}
+
+// ExecuteSetFrameRateFromTyping toggles the adaptive framerate on the vhs.
+func ExecuteSetFrameRateFromTyping(c Command, v *VHS) {
+	adaptive, err := strconv.ParseBool(c.Args)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Set FrameRateFromTyping %s`: expected true or false", c.Args))
+		return
+	}
+	v.Options.Video.Adaptive = adaptive
+}
+
 // ExecuteLoopOffset applies the loop offset option on the vhs.
 func ExecuteLoopOffset(c Command, v *VHS) {
 	loopOffset, err := strconv.ParseFloat(strings.TrimRight(c.Args, "%"), bitSize)
@@ -338,18 +942,24 @@ func getTheme(s string) (Theme, error) {
 	if strings.TrimSpace(s) == "" {
 		return DefaultTheme, nil
 	}
-	switch s[0] {
-	case '{':
+	switch {
+	case s[0] == '{':
 		return getJSONTheme(s)
+	case strings.HasSuffix(s, ".json"):
+		t, err := readThemeFile(s)
+		if err != nil {
+			return DefaultTheme, fmt.Errorf("invalid `Set Theme %q`: %w", s, err)
+		}
+		return t, nil
 	default:
 		return findTheme(s)
 	}
 }
 
 func getJSONTheme(s string) (Theme, error) {
-	var t Theme
-	if err := json.Unmarshal([]byte(s), &t); err != nil {
-		return DefaultTheme, fmt.Errorf("invalid `Set Theme %q: %w`", s, err)
+	t, err := parseJSONTheme(s)
+	if err != nil {
+		return DefaultTheme, fmt.Errorf("invalid `Set Theme %q`: %w", s, err)
 	}
 	return t, nil
 }
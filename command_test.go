@@ -3,10 +3,11 @@ package main
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestCommand(t *testing.T) {
-	const numberOfCommands = 18
+	const numberOfCommands = 42
 	if len(CommandTypes) != numberOfCommands {
 		t.Errorf("Expected %d commands, got %d", numberOfCommands, len(CommandTypes))
 	}
@@ -16,6 +17,98 @@ func TestCommand(t *testing.T) {
 	}
 }
 
+func TestTypingSpeed(t *testing.T) {
+	v := VHS{Options: &Options{TypingSpeed: defaultTypingSpeed}}
+
+	tests := []struct {
+		name     string
+		cmds     []Command
+		expected time.Duration
+	}{
+		{"default", []Command{{Type: TYPE, Args: "hello"}}, defaultTypingSpeed},
+		{"setting", []Command{{Type: SET, Options: "TypingSpeed", Args: "100ms"}, {Type: TYPE, Args: "hello"}}, 100 * time.Millisecond},
+		{"inline overrides the setting", []Command{{Type: SET, Options: "TypingSpeed", Args: "100ms"}, {Type: TYPE, Options: "10ms", Args: "hello"}}, 10 * time.Millisecond},
+		{"inline on keys", []Command{{Type: ENTER, Options: "1s", Args: "1"}}, time.Second},
+		{"instant", []Command{{Type: SET, Options: "TypingSpeed", Args: "0s"}, {Type: TYPE, Args: "hello"}}, 0},
+		{"instant inline", []Command{{Type: TYPE, Options: "0ms", Args: "hello"}}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v.Options.TypingSpeed = defaultTypingSpeed
+			for _, cmd := range tc.cmds[:len(tc.cmds)-1] {
+				ExecuteSet(cmd, &v)
+			}
+			if got := v.typingSpeed(tc.cmds[len(tc.cmds)-1]); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+
+	ExecuteSetTypingSpeed(Command{Type: SET, Options: "TypingSpeed", Args: "-1s"}, &v)
+	if len(v.Errors) != 1 {
+		t.Errorf("expected an error for a negative typing speed, got %v", v.Errors)
+	}
+}
+
+func TestExecuteSetCursorBlink(t *testing.T) {
+	v := New()
+	if !v.Options.CursorBlink {
+		t.Fatal("expected the cursor to blink by default")
+	}
+
+	ExecuteSetCursorBlink(Command{Type: SET, Options: "CursorBlink", Args: "false"}, &v)
+	if v.Options.CursorBlink {
+		t.Error("expected a steady cursor")
+	}
+
+	ExecuteSetCursorBlink(Command{Type: SET, Options: "CursorBlink", Args: "on"}, &v)
+	if !v.Options.CursorBlink {
+		t.Error("expected the cursor to blink with on")
+	}
+
+	ExecuteSetCursorBlink(Command{Type: SET, Options: "CursorBlink", Args: "sometimes"}, &v)
+	if len(v.Errors) != 1 {
+		t.Errorf("expected an error for an invalid value, got %v", v.Errors)
+	}
+}
+
+func TestExecuteSetCursorStyle(t *testing.T) {
+	v := New()
+	if v.Options.CursorStyle != "block" {
+		t.Fatalf("expected a block cursor by default, got %s", v.Options.CursorStyle)
+	}
+
+	ExecuteSetCursorStyle(Command{Type: SET, Options: "CursorStyle", Args: "Bar"}, &v)
+	if v.Options.CursorStyle != "bar" {
+		t.Errorf("expected a bar cursor, got %s", v.Options.CursorStyle)
+	}
+
+	ExecuteSetCursorStyle(Command{Type: SET, Options: "CursorStyle", Args: "beam"}, &v)
+	if len(v.Errors) != 1 || v.Options.CursorStyle != "bar" {
+		t.Errorf("expected an error for an invalid style, got %v", v.Errors)
+	}
+}
+
+func TestExecuteSetSleepScale(t *testing.T) {
+	v := New()
+	ExecuteSetSleepScale(Command{Type: SET, Options: "SleepScale", Args: "0.5"}, &v)
+	if v.Options.SleepScale != 0.5 || len(v.Errors) != 0 {
+		t.Errorf("expected a sleep scale of 0.5, got %v (%v)", v.Options.SleepScale, v.Errors)
+	}
+
+	for _, arg := range []string{"-1", "fast", "NaN"} {
+		v := New()
+		ExecuteSetSleepScale(Command{Type: SET, Options: "SleepScale", Args: arg}, &v)
+		if len(v.Errors) != 1 || v.Errors[0].Error() != "invalid `Set SleepScale "+arg+"`: expected a number, 0 or more" {
+			t.Errorf("expected an error for %s, got %v", arg, v.Errors)
+		}
+		if v.Options.SleepScale != New().Options.SleepScale {
+			t.Errorf("expected the default sleep scale for %s, got %v", arg, v.Options.SleepScale)
+		}
+	}
+}
+
 func TestExecuteSetTheme(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		theme, err := getTheme("  ")
@@ -89,3 +182,40 @@ func requireNotDefaultTheme(tb testing.TB, theme Theme) {
 		tb.Fatalf("expected theme to be different from the default theme, got the default instead")
 	}
 }
+
+func TestTypingVariance(t *testing.T) {
+	v := New()
+	speed := 100 * time.Millisecond
+	if d := v.typingDelay(speed); d != speed {
+		t.Errorf("expected no variation by default, got %s", d)
+	}
+
+	ExecuteSetTypingVariance(Command{Type: SET, Options: "TypingVariance", Args: "30%"}, &v)
+	ExecuteSetTypingSeed(Command{Type: SET, Options: "TypingSeed", Args: "42"}, &v)
+	var delays []time.Duration
+	varied := false
+	for i := 0; i < 20; i++ {
+		d := v.typingDelay(speed)
+		if d < 70*time.Millisecond || d > 130*time.Millisecond {
+			t.Errorf("expected a delay within 30%% of %s, got %s", speed, d)
+		}
+		varied = varied || d != speed
+		delays = append(delays, d)
+	}
+	if !varied {
+		t.Error("expected the delays to vary")
+	}
+
+	// The same seed types the same way.
+	ExecuteSetTypingSeed(Command{Type: SET, Options: "TypingSeed", Args: "42"}, &v)
+	for i, want := range delays {
+		if d := v.typingDelay(speed); d != want {
+			t.Fatalf("expected the delay %d to be %s with the same seed, got %s", i, want, d)
+		}
+	}
+
+	ExecuteSetTypingVariance(Command{Type: SET, Options: "TypingVariance", Args: "1.5"}, &v)
+	if len(v.Errors) != 1 || v.Options.TypingVariance != 0.3 {
+		t.Errorf("expected an error for a variance above 1, got %v", v.Errors)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ComposePane is a tape rendered as one side of a composition.
+type ComposePane struct {
+	Tape     string
+	Position string
+
+	// Set once the tape is rendered.
+	video      string
+	width      int
+	height     int
+	background string
+	duration   time.Duration
+}
+
+// composeLayouts are the pairs of positions which can be composed together,
+// along with the ffmpeg filter stacking them.
+var composeLayouts = map[string]string{
+	"left,right": "hstack",
+	"top,bottom": "vstack",
+}
+
+// ParseComposePanes parses the panes of the --compose flag, in the form
+// <tape>:<position>. The panes are sorted so that the left or top one comes
+// first.
+func ParseComposePanes(values []string) ([]ComposePane, error) {
+	if len(values) != 2 { //nolint:gomnd
+		return nil, errors.New("--compose expects two tapes, e.g. --compose a.tape:left --compose b.tape:right")
+	}
+
+	panes := make([]ComposePane, 0, len(values))
+	for _, value := range values {
+		i := strings.LastIndex(value, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid pane %q: expected <tape>:<position>", value)
+		}
+		tape, position := value[:i], value[i+1:]
+		switch position {
+		case "left", "top":
+			panes = append([]ComposePane{{Tape: tape, Position: position}}, panes...)
+		case "right", "bottom":
+			panes = append(panes, ComposePane{Tape: tape, Position: position})
+		default:
+			return nil, fmt.Errorf("invalid position %q: expected left, right, top or bottom", position)
+		}
+	}
+
+	if _, ok := composeLayouts[panes[0].Position+","+panes[1].Position]; !ok {
+		return nil, fmt.Errorf("can't compose %s with %s: use left and right, or top and bottom", panes[0].Position, panes[1].Position)
+	}
+	return panes, nil
+}
+
+// Compose renders every pane and tiles their videos into a single output.
+// Timelines are aligned on the start of the recordings, and the shorter
+// recording holds its last frame until the longer one ends.
+func Compose(ctx context.Context, panes []ComposePane, output string, out io.Writer) error {
+	dir, err := os.MkdirTemp(os.TempDir(), "vhs-compose")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	for i := range panes {
+		pane := &panes[i]
+		tape, err := os.ReadFile(pane.Tape)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, FileStyle.Render("File: "+pane.Tape))
+		pane.video = filepath.Join(dir, fmt.Sprintf("pane-%d.mp4", i))
+
+		var vhs *VHS
+		errs := Evaluate(ctx, string(tape), out, func(v *VHS) {
+			v.Options.Video.Output = VideoOutputs{MP4: pane.video}
+			vhs = v
+		})
+		if len(errs) > 0 {
+			printErrors(os.Stderr, string(tape), errs)
+			return fmt.Errorf("recording %s failed", pane.Tape)
+		}
+
+		video := vhs.Options.Video
+		pane.width, pane.height = marginSize(video)
+		pane.background = video.BackgroundColor
+		pane.duration = time.Duration(float64(vhs.totalFrames) / float64(video.Framerate) / video.PlaybackSpeed * float64(time.Second))
+	}
+
+	fmt.Fprintln(out, "Composing "+output+"...")
+	if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+		return err
+	}
+	cmd := MakeCompose(panes, output)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		fmt.Fprintln(out, string(b))
+		return fmt.Errorf("composing %s failed: %w", output, err)
+	}
+	return nil
+}
+
+// MakeCompose returns the ffmpeg command which tiles the rendered panes into
+// the output. Panes are padded to the same height (or width) and the shorter
+// one is extended with its last frame.
+func MakeCompose(panes []ComposePane, output string) *exec.Cmd {
+	stack := composeLayouts[panes[0].Position+","+panes[1].Position]
+
+	var longest time.Duration
+	var width, height int
+	for _, pane := range panes {
+		if pane.duration > longest {
+			longest = pane.duration
+		}
+		if pane.width > width {
+			width = pane.width
+		}
+		if pane.height > height {
+			height = pane.height
+		}
+	}
+
+	args := []string{"-y"}
+	var filters []string
+	for i, pane := range panes {
+		args = append(args, "-i", pane.video)
+
+		w, h := pane.width, height
+		if stack == "vstack" {
+			w, h = width, pane.height
+		}
+		filters = append(filters, fmt.Sprintf("[%d]tpad=stop_mode=clone:stop_duration=%f,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:%s[p%d]",
+			i, (longest-pane.duration).Seconds(), w, h, pane.background, i))
+	}
+	filters = append(filters, fmt.Sprintf("[p0][p1]%s=inputs=2", stack))
+
+	filter := strings.Join(filters, ";")
+	if filepath.Ext(output) == ".gif" {
+		filter += "[stacked];[stacked]split[a][b];[a]palettegen=max_colors=256[p];[b][p]paletteuse"
+	}
+
+	args = append(args, "-filter_complex", filter)
+	switch filepath.Ext(output) {
+	case ".mp4":
+		args = append(args, "-vcodec", "libx264", "-pix_fmt", "yuv420p", "-an", "-crf", "20")
+	case ".webm":
+		args = append(args, "-pix_fmt", "yuv420p", "-an", "-crf", "30", "-b:v", "0")
+	}
+	args = append(args, output)
+
+	//nolint:gosec
+	return exec.Command("ffmpeg", args...)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseComposePanes(t *testing.T) {
+	panes, err := ParseComposePanes([]string{"b.tape:right", "a.tape:left"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if panes[0].Tape != "a.tape" || panes[1].Tape != "b.tape" {
+		t.Errorf("expected the left pane first, got %v", panes)
+	}
+
+	for _, values := range [][]string{
+		{"a.tape:left"},
+		{"a.tape:left", "b.tape:bottom"},
+		{"a.tape:left", "b.tape:middle"},
+		{"a.tape", "b.tape:right"},
+	} {
+		if _, err := ParseComposePanes(values); err == nil {
+			t.Errorf("expected an error for %v", values)
+		}
+	}
+}
+
+func TestMakeCompose(t *testing.T) {
+	panes := []ComposePane{
+		{Position: "left", video: "a.mp4", width: 600, height: 400, background: "#000000", duration: 2 * time.Second},
+		{Position: "right", video: "b.mp4", width: 800, height: 600, background: "#ffffff", duration: 5 * time.Second},
+	}
+
+	cmd := MakeCompose(panes, "out.gif")
+	args := strings.Join(cmd.Args, " ")
+
+	expected := []string{
+		"-i a.mp4 -i b.mp4",
+		"[0]tpad=stop_mode=clone:stop_duration=3.000000,pad=600:600:(ow-iw)/2:(oh-ih)/2:#000000[p0]",
+		"[1]tpad=stop_mode=clone:stop_duration=0.000000,pad=800:600:(ow-iw)/2:(oh-ih)/2:#ffffff[p1]",
+		"[p0][p1]hstack=inputs=2[stacked]",
+		"paletteuse out.gif",
+	}
+	for _, e := range expected {
+		if !strings.Contains(args, e) {
+			t.Errorf("expected %q in %s", e, args)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// containerRuntimes are the programs which can run the container of Set
+// Container, in order of preference.
+var containerRuntimes = []string{"docker", "podman"}
+
+// container is the container running the shell of a recording.
+type container struct {
+	runtime string
+	name    string
+}
+
+// validContainerImage returns an error if the image is empty, has spaces or
+// starts with a dash, which the runtime would read as an option.
+func validContainerImage(image string) error {
+	if image == "" || strings.ContainsAny(image, " \t\r\n") || strings.HasPrefix(image, "-") {
+		return errors.New("expected an image, e.g. ubuntu:22.04")
+	}
+	return nil
+}
+
+// containerRuntime returns the first container runtime which is installed.
+func containerRuntime() (string, error) {
+	for _, runtime := range containerRuntimes {
+		if _, err := exec.LookPath(runtime); err == nil {
+			return runtime, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not installed", strings.Join(containerRuntimes, " or "))
+}
+
+// containerShellWithArgs returns the command which runs bash in a new
+// container of the image, with a terminal and the environment variables. The
+// container is named so that it can be removed once the recording is done.
+func containerShellWithArgs(image string, env []string) ([]string, *container, error) {
+	if err := validContainerImage(image); err != nil {
+		return nil, nil, fmt.Errorf("invalid container image %s: %w", image, err)
+	}
+	runtime, err := containerRuntime()
+	if err != nil {
+		return nil, nil, err
+	}
+	id := make([]byte, 6) //nolint:gomnd
+	if _, err := rand.Read(id); err != nil {
+		return nil, nil, err
+	}
+	c := &container{runtime: runtime, name: "vhs-" + hex.EncodeToString(id)}
+
+	args := []string{runtime, "run", "--rm", "-it", "--name", c.name}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, image, "bash", "--login")
+	return args, c, nil
+}
+
+// remove removes the container, which keeps running when the terminal is
+// stopped, since only the client of the runtime is.
+func (c *container) remove() error {
+	if c == nil {
+		return nil
+	}
+	return exec.Command(c.runtime, "rm", "-f", c.name).Run() //nolint:gosec
+}
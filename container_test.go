@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestValidContainerImage(t *testing.T) {
+	for _, image := range []string{"ubuntu:22.04", "ghcr.io/charmbracelet/vhs:latest", "alpine@sha256:abc"} {
+		if err := validContainerImage(image); err != nil {
+			t.Errorf("expected %q to be valid, got %v", image, err)
+		}
+	}
+	for _, image := range []string{"", "--privileged", "ubuntu bash"} {
+		if err := validContainerImage(image); err == nil {
+			t.Errorf("expected %q to be invalid", image)
+		}
+	}
+}
+
+func TestContainerShellWithArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake runtime is a shell script")
+	}
+
+	// A fake docker which logs its arguments.
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0o755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	args, c, err := containerShellWithArgs("ubuntu:22.04", []string{"TZ=UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.runtime != "docker" || !strings.HasPrefix(c.name, "vhs-") {
+		t.Errorf("expected a named docker container, got %+v", c)
+	}
+	want := []string{"docker", "run", "--rm", "-it", "--name", c.name, "-e", "TZ=UTC", "ubuntu:22.04", "bash", "--login"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("want %q, got %q", want, args)
+	}
+
+	if err := c.remove(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(b)); got != "rm -f "+c.name {
+		t.Errorf("expected the container to be removed, got %q", got)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, _, err := containerShellWithArgs("ubuntu:22.04", nil); err == nil {
+		t.Error("expected an error without docker nor podman")
+	}
+}
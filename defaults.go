@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// projectDefaultsFile is the file of the default settings of a project. It is
+// looked up from the current directory up to the root.
+const projectDefaultsFile = "vhs.toml"
+
+var (
+	tomlKeyRegex   = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	tomlTableRegex = regexp.MustCompile(`^\[\s*([A-Za-z0-9_-]+)\s*\]\s*(#.*)?$`)
+	tomlBareRegex  = regexp.MustCompile(`^(true|false|[+-]?[0-9][0-9_]*(\.[0-9_]+)?)$`)
+	bareValueRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(ms|s|%)?$`)
+)
+
+// publishTable is the table of the configuration files with the settings of
+// --publish, see newPublisher.
+const publishTable = "publish"
+
+// configTables are the tables of the configuration files, besides the
+// settings at the top level, with their keys.
+var configTables = map[string][]string{
+	publishTable: {"to", "region", "endpoint", "url"},
+}
+
+// WithDefaults returns an EvaluatorOption which executes the Set commands
+// before the ones of the tape, so that the tape can override them.
+func WithDefaults(cmds []Command) EvaluatorOption {
+	return func(v *VHS) {
+		v.defaults = cmds
+	}
+}
+
+// evaluatorDefaults returns the commands given to the evaluator with
+// WithDefaults. They are executed before the configuration of the tape, so
+// the options are applied to a blank instance to find them.
+func evaluatorDefaults(opts []EvaluatorOption) []Command {
+	v := VHS{Options: &Options{}}
+	for _, opt := range opts {
+		opt(&v)
+	}
+	return v.defaults
+}
+
+// userDefaultsFile returns the path of the default settings of the user, in
+// the configuration directory of the user.
+func userDefaultsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "vhs", "config.toml"), nil
+}
+
+// defaultsFiles returns the files of the default settings which exist: the
+// one of the user, then the nearest vhs.toml from dir up to the root.
+func defaultsFiles(dir string) []string {
+	var files []string
+	if path, err := userDefaultsFile(); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return files
+	}
+	for {
+		path := filepath.Join(dir, projectDefaultsFile)
+		if _, err := os.Stat(path); err == nil {
+			return append(files, path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return files
+		}
+		dir = parent
+	}
+}
+
+// loadDefaults returns the default settings of the files of dir, as the Set
+// commands of a tape, along with the commands. The settings of the project
+// override the ones of the user.
+func loadDefaults(dir string) (string, []Command, error) {
+	var lines []string
+	var cmds []Command
+	for _, file := range defaultsFiles(dir) {
+		settings, err := readDefaults(file)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, s := range settings {
+			lines = append(lines, s.line)
+			cmds = append(cmds, s.cmd)
+		}
+	}
+	return strings.Join(lines, "\n"), cmds, nil
+}
+
+// defaultSetting is a setting of a defaults file, with its Set command.
+type defaultSetting struct {
+	line string
+	cmd  Command
+}
+
+// tomlValue is a `key = value` line of a configuration file, at the top level
+// if its table is empty.
+type tomlValue struct {
+	line  int
+	table string
+	key   string
+	value string
+}
+
+// readTOML reads the `key = value` lines of a configuration file, at the top
+// level or in the tables of configTables. It is the subset of TOML with basic
+// and literal strings, numbers and booleans as values, and the rest of TOML,
+// such as arrays, inline tables or multi-line strings, is an error on the line
+// where it is used.
+func readTOML(path string) ([]tomlValue, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	var values []tomlValue
+	var table string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if table, err = parseTOMLTable(line); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			continue
+		}
+		key, value, err := parseTOMLLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		name := key
+		if table != "" {
+			name = table + "." + key
+			if !containsString(configTables[table], key) {
+				return nil, fmt.Errorf("%s:%d: unknown key %s, expected one of %s", path, n, name, strings.Join(configTables[table], ", "))
+			}
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s:%d: %s is set twice", path, n, name)
+		}
+		seen[name] = true
+		values = append(values, tomlValue{line: n, table: table, key: key, value: value})
+	}
+	return values, scanner.Err()
+}
+
+// parseTOMLTable parses the `[table]` line of a table of configTables.
+func parseTOMLTable(line string) (string, error) {
+	if strings.HasPrefix(line, "[[") {
+		return "", errors.New("arrays of tables are not supported")
+	}
+	m := tomlTableRegex.FindStringSubmatch(line)
+	if m == nil {
+		return "", errors.New("expected [table]")
+	}
+	if _, ok := configTables[m[1]]; !ok {
+		return "", fmt.Errorf("unknown table [%s], the settings are top-level keys", m[1])
+	}
+	return m[1], nil
+}
+
+// containsString returns whether the strings contain s.
+func containsString(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readDefaults reads the settings of a defaults file. It is a TOML file whose
+// top-level keys are the names of the settings of Set, with strings, numbers
+// and booleans as values:
+//
+//	FontFamily = "JetBrains Mono"
+//	FontSize = 22
+//	TypingSpeed = "75ms"
+//
+// Each setting is parsed as the Set command of a tape, so that its value is
+// checked the same way. The tables are left to the other settings, such as
+// [publish].
+func readDefaults(path string) ([]defaultSetting, error) {
+	values, err := readTOML(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []defaultSetting
+	for _, v := range values {
+		if v.table != "" {
+			continue
+		}
+		arg := v.value
+		if !bareValueRegex.MatchString(v.value) && v.value != "true" && v.value != "false" {
+			arg = quote(v.value)
+		}
+		set := "Set " + v.key + " " + arg
+		p := NewParser(NewLexer(set))
+		cmds := p.Parse()
+		if errs := p.Errors(); len(errs) > 0 {
+			return nil, fmt.Errorf("%s:%d: %s", path, v.line, errs[0].Msg)
+		}
+		if len(cmds) != 1 || cmds[0].Type != SET {
+			return nil, fmt.Errorf("%s:%d: %s is not a setting", path, v.line, v.key)
+		}
+		settings = append(settings, defaultSetting{line: set, cmd: cmds[0]})
+	}
+	return settings, nil
+}
+
+// loadPublishConfig returns the settings of the [publish] tables of the
+// files of dir, see defaultsFiles. The settings of the project override the
+// ones of the user.
+func loadPublishConfig(dir string) (map[string]string, error) {
+	config := map[string]string{}
+	for _, file := range defaultsFiles(dir) {
+		values, err := readTOML(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			if v.table == publishTable {
+				config[v.key] = v.value
+			}
+		}
+	}
+	return config, nil
+}
+
+// parseTOMLLine parses a `key = value` line of TOML, with a basic or literal
+// string, a number or a boolean as value, and an optional comment.
+func parseTOMLLine(line string) (string, string, error) {
+	key, rest, ok := strings.Cut(line, "=")
+	key, rest = strings.TrimSpace(key), strings.TrimSpace(rest)
+	switch {
+	case ok && strings.Contains(key, "."):
+		return "", "", errors.New("dotted keys are not supported, use a [table]")
+	case !ok || !tomlKeyRegex.MatchString(key):
+		return "", "", errors.New("expected key = value")
+	case strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''"):
+		return "", "", errors.New("multi-line strings are not supported")
+	case strings.HasPrefix(rest, "["):
+		return "", "", errors.New("arrays are not supported")
+	case strings.HasPrefix(rest, "{"):
+		return "", "", errors.New("inline tables are not supported")
+	}
+
+	var value string
+	switch {
+	case strings.HasPrefix(rest, `"`):
+		end := 1
+		for ; end < len(rest); end++ {
+			if rest[end] == '\\' {
+				end++
+			} else if rest[end] == '"' {
+				break
+			}
+		}
+		if end >= len(rest) {
+			return "", "", errors.New("unterminated string")
+		}
+		s, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return "", "", fmt.Errorf("invalid string: %w", err)
+		}
+		value, rest = s, rest[end+1:]
+	case strings.HasPrefix(rest, "'"):
+		end := strings.IndexByte(rest[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("unterminated string")
+		}
+		value, rest = rest[1:end+1], rest[end+2:]
+	default:
+		value, rest, _ = strings.Cut(rest, "#")
+		value, rest = strings.TrimSpace(value), ""
+		if !tomlBareRegex.MatchString(value) {
+			return "", "", fmt.Errorf("invalid value %s, expected a string, a number or a boolean", value)
+		}
+		value = strings.ReplaceAll(value, "_", "")
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", "", fmt.Errorf("unexpected %s after the value", rest)
+	}
+	return key, value, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOMLLine(t *testing.T) {
+	tests := []struct {
+		line, key, value string
+		err              bool
+	}{
+		{line: `FontFamily = "JetBrains Mono"`, key: "FontFamily", value: "JetBrains Mono"},
+		{line: `Theme = 'Catppuccin "Mocha"' # comment`, key: "Theme", value: `Catppuccin "Mocha"`},
+		{line: `FontFamily = "Fira \"Code\""`, key: "FontFamily", value: `Fira "Code"`},
+		{line: `FontSize = 22 # px`, key: "FontSize", value: "22"},
+		{line: `Width = 1_200`, key: "Width", value: "1200"},
+		{line: `LineHeight = 1.2`, key: "LineHeight", value: "1.2"},
+		{line: `CursorBlink = false`, key: "CursorBlink", value: "false"},
+		{line: `[set]`, err: true},
+		{line: `FontSize`, err: true},
+		{line: `FontSize = big`, err: true},
+		{line: `FontFamily = "Fira`, err: true},
+		{line: `FontFamily = "Fira" Code`, err: true},
+		{line: `publish.to = "s3://demos"`, err: true},
+		{line: `FontFamily = ["Fira", "Hack"]`, err: true},
+		{line: `Margin = { size = 10 }`, err: true},
+		{line: `FontFamily = """Fira`, err: true},
+	}
+	for _, tt := range tests {
+		key, value, err := parseTOMLLine(tt.line)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.line)
+			}
+			continue
+		}
+		if err != nil || key != tt.key || value != tt.value {
+			t.Errorf("%s: got %q = %q (%v)", tt.line, key, value, err)
+		}
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("AppData", home)
+	user, err := userDefaultsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(user), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(user, []byte("FontSize = 32\nTheme = \"Dracula\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, projectDefaultsFile), []byte("# Defaults of the project.\nFontSize = 22\nTypingSpeed = \"75ms\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(project, "docs", "tapes")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tape, cmds, err := loadDefaults(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Set FontSize 32\nSet Theme \"Dracula\"\nSet FontSize 22\nSet TypingSpeed 75ms"
+	if tape != want {
+		t.Errorf("want %q, got %q", want, tape)
+	}
+
+	v := New()
+	for _, cmd := range evaluatorDefaults([]EvaluatorOption{WithDefaults(cmds)}) {
+		executeCommand(cmd, &v)
+	}
+	if v.Options.FontSize != 22 || v.Options.TypingSpeed.String() != "75ms" || v.Options.Theme.Name != "Dracula" {
+		t.Errorf("expected the project to override the user, got %d %s %q", v.Options.FontSize, v.Options.TypingSpeed, v.Options.Theme.Name)
+	}
+}
+
+func TestReadDefaultsErrors(t *testing.T) {
+	for name, tt := range map[string]struct{ content, err string }{
+		"unknown setting":    {"FontColor = \"red\"\n", ":1: "},
+		"twice":              {"FontSize = 22\nFontSize = 32\n", ":2: FontSize is set twice"},
+		"not a setting":      {"Var = \"x\"\n", ":1: "},
+		"array":              {"FontSize = 22\nFontFamily = [\"Fira\"]\n", ":2: arrays are not supported"},
+		"inline table":       {"publish = { to = \"s3://demos\" }\n", ":1: inline tables are not supported"},
+		"multi-line string":  {"\nFontFamily = \"\"\"\nFira\n\"\"\"\n", ":2: multi-line strings are not supported"},
+		"unknown table":      {"[settings]\nFontSize = 22\n", ":1: unknown table [settings]"},
+		"array of tables":    {"[[publish]]\n", ":1: arrays of tables are not supported"},
+		"unknown publish":    {"[publish]\nbucket = \"demos\"\n", ":2: unknown key publish.bucket"},
+		"publish set twice":  {"[publish]\nto = \"a\"\n[publish]\nto = \"b\"\n", ":4: publish.to is set twice"},
+		"dotted publish key": {"publish.to = \"s3://demos\"\n", ":1: dotted keys are not supported"},
+	} {
+		path := filepath.Join(t.TempDir(), projectDefaultsFile)
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readDefaults(path); err == nil || !strings.Contains(err.Error(), path+tt.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, tt.err, err)
+		}
+	}
+}
+
+func TestLoadPublishConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("AppData", home)
+	user, err := userDefaultsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(user), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "FontSize = 32\n\n# Publish to our bucket.\n[publish]\nto = \"s3://demos/vhs\"\nregion = \"eu-west-1\"\n"
+	if err := os.WriteFile(user, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, projectDefaultsFile), []byte("[publish]\nregion = 'us-west-2'\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadPublishConfig(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"to": "s3://demos/vhs", "region": "us-west-2"}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("want %v, got %v", want, config)
+	}
+
+	// The [publish] table isn't made of settings of the tapes.
+	tape, _, err := loadDefaults(project)
+	if err != nil || tape != "Set FontSize 32" {
+		t.Errorf("expected the settings only, got %q %v", tape, err)
+	}
+}
+
+func TestEvaluatorDefaults(t *testing.T) {
+	cmds := []Command{{Type: SET, Options: "FontSize", Args: "22"}}
+	if got := evaluatorDefaults([]EvaluatorOption{WithVars(nil), WithDefaults(cmds)}); !reflect.DeepEqual(got, cmds) {
+		t.Errorf("expected the defaults, got %v", got)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/spf13/cobra"
+)
+
+// ttydInstallVersion is the version of ttyd installed by vhs doctor --install.
+const ttydInstallVersion = "1.7.7"
+
+// ttydReleaseURL is the URL of the release of ttyd installed by vhs doctor
+// --install, with its binaries and their SHA256SUMS.
+var ttydReleaseURL = "https://github.com/tsl0922/ttyd/releases/download/" + ttydInstallVersion
+
+// browserProbeTimeout is how long vhs doctor waits for the browser to start.
+const browserProbeTimeout = 30 * time.Second
+
+// The statuses of the checks of vhs doctor.
+const (
+	doctorOK = iota
+	doctorWarning
+	doctorFailure
+)
+
+// doctorResult is the result of a check of vhs doctor, with how to fix it if
+// it isn't ok.
+type doctorResult struct {
+	Status int
+	Detail string
+	Fix    string
+}
+
+// doctorCheck is a check of vhs doctor.
+type doctorCheck struct {
+	Name string
+	Run  func() doctorResult
+}
+
+var (
+	doctorInstall bool
+	doctorCmd     = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the dependencies of VHS are installed and work",
+		Long: `Check that ffmpeg, ttyd, bash and a browser are installed and recent enough,
+that the browser starts, and that one of the default fonts is installed.
+
+With --install, a static build of ttyd is installed in the data directory of
+VHS when it is missing, on the platforms which have one.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if doctorInstall {
+				if _, err := exec.LookPath("ttyd"); err != nil {
+					fmt.Fprintln(cmd.OutOrStdout(), "Installing ttyd "+ttydInstallVersion+"...")
+					path, err := installTTYD(cmd.Context(), runtime.GOOS, runtime.GOARCH)
+					if err != nil {
+						return fmt.Errorf("could not install ttyd: %w", err)
+					}
+					fmt.Fprintln(cmd.OutOrStdout(), StringStyle.Render("Installed ttyd in "+path))
+				}
+			}
+			if failures := runDoctor(cmd.OutOrStdout(), doctorChecks()); failures > 0 {
+				return fmt.Errorf("%d of the checks failed", failures)
+			}
+			return nil
+		},
+	}
+)
+
+// doctorChecks returns the checks of vhs doctor, in the order they are shown.
+func doctorChecks() []doctorCheck {
+	return []doctorCheck{
+		{"ffmpeg", checkFFmpegVersion},
+		{"ttyd", checkTTYDVersion},
+		{"bash", checkBash},
+		{"browser", checkBrowser},
+		{"fonts", checkFonts},
+	}
+}
+
+// runDoctor runs the checks, prints their results and returns the number of
+// checks which failed.
+func runDoctor(out io.Writer, checks []doctorCheck) int {
+	var failures int
+	for _, check := range checks {
+		r := check.Run()
+		switch r.Status {
+		case doctorOK:
+			fmt.Fprintln(out, StringStyle.Render("✓ "+check.Name)+" "+r.Detail)
+		case doctorWarning:
+			fmt.Fprintln(out, WarningStyle.Render("! "+check.Name)+" "+r.Detail)
+		default:
+			failures++
+			fmt.Fprintln(out, ErrorStyle.Render("✗ "+check.Name)+" "+r.Detail)
+		}
+		if r.Fix != "" && r.Status != doctorOK {
+			fmt.Fprintln(out, FaintStyle.Render("  "+r.Fix))
+		}
+	}
+	return failures
+}
+
+// checkFFmpegVersion checks that ffmpeg is installed, with its version.
+func checkFFmpegVersion() doctorResult {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return doctorResult{Status: doctorFailure, Detail: "is not installed", Fix: "Install it from: http://ffmpeg.org"}
+	}
+	v := parseVersion(commandVersion("ffmpeg", "-version"))
+	if v == nil {
+		return doctorResult{Status: doctorOK, Detail: "unknown version (" + path + ")"}
+	}
+	return doctorResult{Status: doctorOK, Detail: v.String() + " (" + path + ")"}
+}
+
+// checkTTYDVersion checks that ttyd is installed and recent enough.
+func checkTTYDVersion() doctorResult {
+	fix := "Install it with vhs doctor --install, or from: https://github.com/tsl0922/ttyd"
+	if _, err := ttydAsset(runtime.GOOS, runtime.GOARCH); err != nil {
+		fix = "Install it from: https://github.com/tsl0922/ttyd"
+	}
+	path, err := exec.LookPath("ttyd")
+	if err != nil {
+		return doctorResult{Status: doctorFailure, Detail: "is not installed", Fix: fix}
+	}
+	v := getVersion("ttyd")
+	if v == nil || v.LessThan(ttydMinVersion) {
+		return doctorResult{
+			Status: doctorFailure,
+			Detail: fmt.Sprintf("%s is out of date, VHS requires %s (%s)", v, ttydMinVersion, path),
+			Fix:    fix,
+		}
+	}
+	return doctorResult{Status: doctorOK, Detail: v.String() + " (" + path + ")"}
+}
+
+// checkBash checks that bash, the default shell, is installed.
+func checkBash() doctorResult {
+	path, err := exec.LookPath("bash")
+	if err != nil {
+		return doctorResult{Status: doctorWarning, Detail: "is not installed", Fix: "Set Shell to another shell in the tapes"}
+	}
+	return doctorResult{Status: doctorOK, Detail: path}
+}
+
+// checkBrowser checks that a browser is installed and that it starts
+// headless, as when recording.
+func checkBrowser() doctorResult {
+	path, ok := launcher.LookPath()
+	if !ok {
+		return doctorResult{
+			Status: doctorWarning,
+			Detail: "Chrome or Chromium is not installed",
+			Fix:    "Chromium is downloaded on the first recording, or install Chrome",
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), browserProbeTimeout)
+	defer cancel()
+	l := launcher.New().Leakless(false).Bin(path).Context(ctx)
+	if _, err := l.Launch(); err != nil {
+		return doctorResult{
+			Status: doctorFailure,
+			Detail: fmt.Sprintf("%s doesn't start: %s", path, err),
+			Fix:    "Check that it starts with --headless, e.g. that its libraries are installed",
+		}
+	}
+	l.Kill()
+	return doctorResult{Status: doctorOK, Detail: path}
+}
+
+// checkFonts checks that one of the default fonts is installed, with the
+// fontconfig of Linux and the BSDs.
+func checkFonts() doctorResult {
+	out, err := exec.Command("fc-list", ":", "family").Output()
+	if err != nil {
+		return doctorResult{Status: doctorOK, Detail: "fc-list is not installed, the fonts weren't checked"}
+	}
+	if font := installedFont(string(out), strings.Split(defaultFontFamily, fontsSeparator)); font != "" {
+		return doctorResult{Status: doctorOK, Detail: font}
+	}
+	return doctorResult{
+		Status: doctorWarning,
+		Detail: "none of the default fonts is installed",
+		Fix:    "Install JetBrains Mono, or Set FontFamily to an installed font in the tapes",
+	}
+}
+
+// installedFont returns the first of the fonts which is in the families
+// listed by fc-list, one or more per line separated by commas. The generic
+// families, such as monospace, are never installed.
+func installedFont(families string, fonts []string) string {
+	installed := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(families))
+	for scanner.Scan() {
+		for _, family := range strings.Split(scanner.Text(), ",") {
+			installed[strings.ToLower(strings.TrimSpace(family))] = true
+		}
+	}
+	for _, font := range fonts {
+		if installed[strings.ToLower(strings.TrimSpace(font))] {
+			return font
+		}
+	}
+	return ""
+}
+
+// ttydAsset returns the name of the static build of ttyd of the platform, in
+// its releases.
+func ttydAsset(goos, goarch string) (string, error) {
+	switch goos {
+	case "linux":
+		arch, ok := map[string]string{
+			"amd64":  "x86_64",
+			"arm64":  "aarch64",
+			"386":    "i686",
+			"arm":    "armhf",
+			"mips":   "mips",
+			"mipsle": "mipsel",
+			"s390x":  "s390x",
+		}[goarch]
+		if ok {
+			return "ttyd." + arch, nil
+		}
+	case "windows":
+		if goarch == "amd64" {
+			return "ttyd.win32.exe", nil
+		}
+	}
+	return "", fmt.Errorf("there is no build of ttyd for %s/%s", goos, goarch)
+}
+
+// dataBinDir returns the directory of the programs installed by VHS, in the
+// data directory of the user: $XDG_DATA_HOME/vhs/bin, ~/.local/share/vhs/bin
+// by default, on Linux, and in the configuration directory elsewhere.
+func dataBinDir() (string, error) {
+	if runtime.GOOS == "linux" {
+		if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+			return filepath.Join(dir, "vhs", "bin"), nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "share", "vhs", "bin"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "vhs", "bin"), nil
+}
+
+// addDataBinDirToPath adds the directory of the programs installed by VHS to
+// the end of PATH, so that they are used unless they are installed elsewhere.
+func addDataBinDirToPath() {
+	dir, err := dataBinDir()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	_ = os.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+dir)
+}
+
+// installTTYD downloads the static build of ttyd of the platform into the
+// directory of the programs installed by VHS, once its checksum is verified,
+// and returns its path.
+func installTTYD(ctx context.Context, goos, goarch string) (string, error) {
+	asset, err := ttydAsset(goos, goarch)
+	if err != nil {
+		return "", err
+	}
+	dir, err := dataBinDir()
+	if err != nil {
+		return "", err
+	}
+
+	sums, err := download(ctx, ttydReleaseURL+"/SHA256SUMS")
+	if err != nil {
+		return "", err
+	}
+	sum, err := releaseChecksum(string(sums), asset)
+	if err != nil {
+		return "", err
+	}
+	b, err := download(ctx, ttydReleaseURL+"/"+asset)
+	if err != nil {
+		return "", err
+	}
+	if got := sha256.Sum256(b); hex.EncodeToString(got[:]) != sum {
+		return "", fmt.Errorf("the checksum of %s doesn't match", asset)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	name := "ttyd"
+	if goos == "windows" {
+		name += ".exe"
+	}
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o755); err != nil { //nolint:gosec,gomnd
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	addDataBinDirToPath()
+	return path, nil
+}
+
+// releaseChecksum returns the SHA-256 checksum of the asset in the SHA256SUMS
+// of a release, whose lines are a checksum followed by a file name.
+func releaseChecksum(sums, asset string) (string, error) {
+	for _, line := range strings.Split(sums, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset { //nolint:gomnd
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s", asset)
+}
+
+// download returns the body of the URL.
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(url + ": " + resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRunDoctor(t *testing.T) {
+	var out bytes.Buffer
+	failures := runDoctor(&out, []doctorCheck{
+		{"ffmpeg", func() doctorResult { return doctorResult{Status: doctorOK, Detail: "6.1.1", Fix: "unused"} }},
+		{"fonts", func() doctorResult { return doctorResult{Status: doctorWarning, Detail: "missing", Fix: "Install one"} }},
+		{"ttyd", func() doctorResult { return doctorResult{Status: doctorFailure, Detail: "is not installed"} }},
+	})
+
+	expected := "✓ ffmpeg 6.1.1\n! fonts missing\n  Install one\n✗ ttyd is not installed\n"
+	if failures != 1 || out.String() != expected {
+		t.Errorf("expected 1 failure and:\n%s\ngot %d and:\n%s", expected, failures, out.String())
+	}
+}
+
+func TestInstalledFont(t *testing.T) {
+	families := "DejaVu Sans,DejaVu Sans Condensed\nHack\nJetBrains Mono,JetBrains Mono NL\n"
+	if got := installedFont(families, []string{"Fira Code", "jetbrains mono", "Hack"}); got != "jetbrains mono" {
+		t.Errorf("expected the first installed font, got %q", got)
+	}
+	if got := installedFont(families, []string{"Fira Code", "monospace"}); got != "" {
+		t.Errorf("expected no installed font, got %q", got)
+	}
+}
+
+func TestTTYDAsset(t *testing.T) {
+	for _, tc := range []struct {
+		goos, goarch, expected string
+	}{
+		{"linux", "amd64", "ttyd.x86_64"},
+		{"linux", "arm64", "ttyd.aarch64"},
+		{"windows", "amd64", "ttyd.win32.exe"},
+	} {
+		if got, err := ttydAsset(tc.goos, tc.goarch); err != nil || got != tc.expected {
+			t.Errorf("ttydAsset(%s, %s): expected %s, got %s (%v)", tc.goos, tc.goarch, tc.expected, got, err)
+		}
+	}
+	if _, err := ttydAsset("darwin", "arm64"); err == nil {
+		t.Error("expected no build of ttyd for macOS")
+	}
+}
+
+func TestInstallTTYD(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the data directory is only set with XDG_DATA_HOME on Linux")
+	}
+	binary := []byte("#!/bin/sh\necho ttyd version 1.7.7\n")
+	sum := sha256.Sum256(binary)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHA256SUMS":
+			_, _ = w.Write([]byte(hex.EncodeToString(sum[:]) + "  ttyd.x86_64\n0000  ttyd.aarch64\n"))
+		case "/ttyd.x86_64", "/ttyd.aarch64":
+			_, _ = w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { ttydReleaseURL = url }(ttydReleaseURL)
+	ttydReleaseURL = server.URL
+
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	t.Setenv("PATH", os.Getenv("PATH"))
+
+	path, err := installTTYD(context.Background(), "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "vhs", "bin", "ttyd"); path != expected {
+		t.Errorf("expected ttyd in %s, got %s", expected, path)
+	}
+	if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, binary) {
+		t.Errorf("unexpected binary %q (%v)", b, err)
+	}
+
+	if _, err := installTTYD(context.Background(), "linux", "arm64"); err == nil {
+		t.Error("expected an error when the checksum doesn't match")
+	}
+}
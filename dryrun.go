@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DryRun parses the tape and prints the commands which would be executed, one
+// per line, along with the time at which they start and how long they take,
+// without starting ttyd or ffmpeg. It then prints the total duration of the
+// tape, the duration of the videos, which leave out what is hidden and are
+// played at the playback speed, and the outputs which would be written. The
+// variables of the tape are replaced with the given values.
+//
+// 0s 0s Set FontSize 32
+// 0s 250ms Type hello
+// 250ms 1s Sleep 1s
+// Total: 1.25s
+// Video: 1.25s
+// Output: out.gif
+//
+// The durations only account for the pauses of the tape (typing speed,
+// sleeps, key delay and default sleep), so a recording takes a bit longer.
+func DryRun(tape string, vars map[string]string, out io.Writer) []error {
+	l := NewLexer(tape)
+	p := NewParser(l, WithParserVars(vars))
+
+	cmds := p.Parse()
+	errs := p.Errors()
+	if len(errs) != 0 || len(cmds) == 0 {
+		return []error{InvalidSyntaxError{errs}}
+	}
+
+	v := New()
+	input := v.Options.Video.Input
+	defer func() { _ = os.RemoveAll(input) }()
+
+	var elapsed, recorded time.Duration
+	started, hidden := false, false
+	for _, cmd := range cmds {
+		if !isConfiguration(cmd) {
+			started = true
+		}
+		if v.skip(cmd, out) {
+			continue
+		}
+
+		// As during the recording, only the runtime settings can be changed
+		// once the tape has started.
+		if cmd.Type == SET && (!started || isRuntimeSetting(cmd.Options)) {
+			Settings[cmd.Options](cmd, &v)
+		}
+		if cmd.Type == OUTPUT {
+			ExecuteOutput(cmd, &v)
+		}
+
+		duration := v.commandDuration(cmd)
+		line := cmd.String()
+		if cmd.Type == SET && cmd.Options == "Secret" {
+			line = maskSecrets(line, []string{cmd.Args})
+		}
+		fmt.Fprintf(out, "%s %s %s\n", elapsed, duration, strings.TrimSpace(maskSecrets(line, v.Options.Secrets)))
+		elapsed += duration
+
+		switch cmd.Type {
+		case ABORT:
+			ExecuteAbort(cmd, &v)
+			return v.Errors
+		case QUIET:
+			v.quiet = cmd.Args == "on"
+		case SPEED:
+			ExecuteSpeed(cmd, &v)
+		case HIDE:
+			hidden = true
+		case SHOW:
+			hidden = false
+		}
+		if !hidden && !isConfiguration(cmd) {
+			recorded += spedUp(duration, v.speed())
+		}
+	}
+
+	fmt.Fprintf(out, "Total: %s\n", elapsed)
+	fmt.Fprintf(out, "Video: %s\n", outputTime(v.Options.Video, recorded))
+	for _, file := range v.outputFiles() {
+		fmt.Fprintf(out, "Output: %s\n", file)
+	}
+	return v.Errors
+}
+
+// commandDuration returns how long the command pauses the tape for, including
+// the key delay and the default sleep which follow it.
+func (v *VHS) commandDuration(c Command) time.Duration {
+	var d time.Duration
+	var err error
+
+	typingSpeed := v.typingSpeed(c)
+
+	switch c.Type {
+	case TYPE:
+		d = time.Duration(utf8.RuneCountInString(c.Args)) * typingSpeed
+	case BACKSPACE, DOWN, ENTER, ESCAPE, LEFT, RIGHT, SPACE, TAB, UP:
+		repeat, err := strconv.Atoi(c.Args)
+		if err != nil {
+			repeat = 1
+		}
+		d = time.Duration(repeat) * typingSpeed
+	case SLEEP:
+		if dur, err := time.ParseDuration(c.Args); err == nil {
+			d = time.Duration(float64(dur) * v.Options.SleepScale)
+		}
+	case FLASH:
+		d, err = time.ParseDuration(c.Options)
+		if err != nil {
+			d = defaultFlashFrames * time.Second / time.Duration(v.Options.Video.Framerate)
+		}
+	}
+
+	if isKeyCommand(c.Type) {
+		d += v.Options.KeyDelay
+	}
+
+	if v.Options.DefaultSleep > 0 && !v.quiet {
+		switch {
+		case isConfiguration(c), c.Type == HIDE, c.Type == SHOW, c.Type == SKIP:
+		case c.Type == QUIET && c.Args == "on":
+		default:
+			d += time.Duration(float64(v.Options.DefaultSleep) * v.Options.SleepScale)
+		}
+	}
+
+	return d
+}
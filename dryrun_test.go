@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	tape := `Output demo.gif
+Set TypingSpeed 100ms
+Type "hello"
+Enter 2
+Sleep 1s
+Set DefaultSleep 500ms
+Type@10ms "ls"
+Hide`
+
+	var out bytes.Buffer
+	if errs := DryRun(tape, nil, &out); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	expected := `0s 0s Output .gif demo.gif
+0s 0s Set TypingSpeed 100ms
+0s 500ms Type hello
+500ms 200ms Enter 2
+700ms 1s Sleep 1s
+1.7s 0s Set DefaultSleep 500ms
+1.7s 520ms Type 10ms ls
+2.22s 0s Hide
+Total: 2.22s
+Video: 2.22s
+Output: demo.gif
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestDryRunAbort(t *testing.T) {
+	tape := `Output demo.gif
+Type "hello"
+Skip unless vhs-missing-program { Abort "not installed" }
+Abort "too old"
+Type "never"`
+
+	var out bytes.Buffer
+	errs := DryRun(tape, nil, &out)
+	if len(errs) != 1 || errs[0].Error() != "the tape was aborted: too old" {
+		t.Fatalf("expected the tape to be aborted, got %v", errs)
+	}
+	if strings.Contains(out.String(), "never") || strings.Contains(out.String(), "Total:") {
+		t.Errorf("expected the dry run to stop at Abort, got:\n%s", out.String())
+	}
+}
+
+func TestDryRunInvalidTape(t *testing.T) {
+	var out bytes.Buffer
+	if errs := DryRun("Foo", nil, &out); len(errs) == 0 {
+		t.Error("expected an error for an invalid tape")
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+}
+
+func TestDryRunVideoDuration(t *testing.T) {
+	tape := `Output demo.mp4
+Output frames/
+Set PlaybackSpeed 2
+Hide
+Sleep 1s
+Show
+Sleep 3s`
+
+	var out bytes.Buffer
+	if errs := DryRun(tape, nil, &out); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for _, want := range []string{
+		"Total: 4s\nVideo: 1.5s\n",
+		"Output: out.gif\nOutput: demo.mp4\nOutput: frames/\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+	if _, err := os.Stat("frames"); err == nil {
+		t.Error("expected no frames directory to be created")
+	}
+}
+
+func TestDryRunSpeed(t *testing.T) {
+	tape := `Output demo.mp4
+Speed 4 {
+  Sleep 4s
+}
+Cut { Sleep 10s }
+Sleep 1s`
+
+	var out bytes.Buffer
+	if errs := DryRun(tape, nil, &out); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if want := "Total: 15s\nVideo: 2s\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, out.String())
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
 // InvalidSyntaxError is returned when the parser encounters one or more errors.
@@ -15,35 +17,10 @@ func (e InvalidSyntaxError) Error() string {
 	return fmt.Sprintf("parser: %d error(s)", len(e.Errors))
 }
 
-// ParserError represents an error with parsing a tape file.
-// It tracks the token causing the error and a human readable error message.
-type ParserError struct {
-	Token Token
-	Msg   string
-}
-
-// NewError returns a new ParserError with the given token and message.
-func NewError(token Token, msg string) ParserError {
-	return ParserError{
-		Token: token,
-		Msg:   msg,
-	}
-}
-
 // ErrorColumnOffset is the number of columns that an error should be printed
 // to the left to account for the line number.
 const ErrorColumnOffset = 5
 
-// String returns a human readable error message printing the token line number
-// and message.
-func (e ParserError) String() string {
-	return fmt.Sprintf("%2d:%-2d │ %s", e.Token.Line, e.Token.Column, e.Msg)
-}
-
-func (e ParserError) Error() string {
-	return e.String()
-}
-
 // Underline returns a string of ^ characters which helps underline the problematic token
 // in a ParserError.
 func Underline(n int) string {
@@ -56,12 +33,20 @@ func LineNumber(line int) string {
 }
 
 func printParserError(out io.Writer, tape string, err ParserError) {
+	printParserMessage(out, tape, err, ErrorStyle)
+}
+
+func printParserWarning(out io.Writer, tape string, err ParserError) {
+	printParserMessage(out, tape, err, WarningStyle)
+}
+
+func printParserMessage(out io.Writer, tape string, err ParserError, style lipgloss.Style) {
 	lines := strings.Split(tape, "\n")
 
 	fmt.Fprint(out, LineNumber(err.Token.Line))
 	fmt.Fprintln(out, lines[err.Token.Line-1])
 	fmt.Fprint(out, strings.Repeat(" ", err.Token.Column+ErrorColumnOffset))
-	fmt.Fprintln(out, Underline(len(err.Token.Literal)), err.Msg)
+	fmt.Fprintln(out, style.Render(strings.Repeat("^", len(err.Token.Literal))), err.Msg)
 	fmt.Fprintln(out)
 }
 
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestPrintParserErrorInvalidUTF8(t *testing.T) {
+	input := "Type \"caf\xe9 \xff\xfe\"\nEnter\n\xc3\x28"
+
+	p := NewParser(NewLexer(input))
+	_ = p.Parse()
+	if len(p.Errors()) != 2 {
+		t.Fatalf("Expected 2 errors, got %d", len(p.Errors()))
+	}
+
+	// Printing the errors must not choke on the invalid bytes.
+	for _, err := range p.Errors() {
+		printParserError(io.Discard, input, err)
+	}
+}
+
+func FuzzParser(f *testing.F) {
+	f.Add("Type \"echo 'Hello, World!'\"\nEnter")
+	f.Add("Set Theme { \"background\": \"#171717\" }")
+	f.Add("Ctrl+\nSleep @ 100ms\n\x1b[31m\x1b]0;title\x07")
+	f.Add("Output \nSet LoopOffset\nBackspace@")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := NewLexer(input)
+		p := NewParser(l)
+
+		_ = p.Parse()
+		for _, err := range p.Errors() {
+			printParserError(io.Discard, input, err)
+		}
+	})
+}
@@ -5,16 +5,150 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // EvaluatorOption is a function that can be used to modify the VHS instance.
 type EvaluatorOption func(*VHS)
 
+// WithOutputs returns an EvaluatorOption which replaces the outputs declared
+// by the tape with the given paths. The file type of each output is chosen
+// from its extension, as with the Output command.
+func WithOutputs(paths []string) (EvaluatorOption, error) {
+	cmds := make([]Command, 0, len(paths))
+	for _, path := range paths {
+		ext := filepath.Ext(path)
+		if ext == "" {
+			if !strings.HasSuffix(path, "/") {
+				return nil, fmt.Errorf("invalid output %s: expected a file extension or a folder with a trailing slash", path)
+			}
+			ext = ".png"
+		}
+		cmds = append(cmds, Command{Type: OUTPUT, Options: ext, Args: path})
+	}
+
+	return func(v *VHS) {
+		v.Options.Video.Output = VideoOutputs{}
+		v.Options.Test.Output = ""
+		v.Options.Test.Screen = ""
+		v.Options.HTML.Output = ""
+		for _, cmd := range cmds {
+			ExecuteOutput(cmd, v)
+		}
+	}, nil
+}
+
+// WithOutputDir returns an EvaluatorOption which moves the outputs declared by
+// the tape into the directory, keeping their file names.
+func WithOutputDir(dir string) EvaluatorOption {
+	return func(v *VHS) {
+		video := &v.Options.Video.Output
+		for _, path := range []*string{
+			&video.GIF, &video.WebM, &video.MP4, &video.WebP, &video.APNG, &video.SVG,
+			&video.PNG, &video.Cast, &video.SRT, &video.VTT, &video.Frames,
+			&v.Options.Test.Output, &v.Options.Test.Screen, &v.Options.HTML.Output,
+		} {
+			if *path == "" {
+				continue
+			}
+			moved := filepath.Join(dir, filepath.Base(*path))
+			// Keep the trailing slash of the directories of frames.
+			if strings.HasSuffix(*path, "/") {
+				moved += "/"
+			}
+			*path = moved
+		}
+	}
+}
+
+// WithTheme returns an EvaluatorOption which sets the theme, taking
+// precedence over the Set Theme command of the tape.
+func WithTheme(theme string) EvaluatorOption {
+	return func(v *VHS) {
+		ExecuteSetTheme(Command{Type: SET, Options: "Theme", Args: theme}, v)
+	}
+}
+
+// WithVars returns an EvaluatorOption which sets the values of the variables
+// of the tape, taking precedence over the defaults of the Var commands.
+func WithVars(vars map[string]string) EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.Vars = vars
+	}
+}
+
+// WithEnv returns an EvaluatorOption which sets environment variables of the
+// shell, in the NAME=VALUE form. They take precedence over the Env commands of
+// the tape.
+func WithEnv(env []string) EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.Env = append(v.Options.Env, env...)
+	}
+}
+
+// WithSSH returns an EvaluatorOption which runs the shell on the host, as
+// [user@]host, over SSH, taking precedence over Set SSH.
+func WithSSH(target string) EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.SSH = target
+	}
+}
+
+// WithContainer returns an EvaluatorOption which runs the shell in a container
+// of the image, taking precedence over Set Container.
+func WithContainer(image string) EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.Container = image
+	}
+}
+
+// WithBackend returns an EvaluatorOption which records the tape with the
+// terminal of the backend.
+func WithBackend(name string) EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.Backend = name
+	}
+}
+
+// WithMaxFileSize returns an EvaluatorOption which sets the maximum size of
+// the GIF, in bytes, overriding the one of the tape.
+func WithMaxFileSize(size int64) EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.Video.MaxFileSize = size
+	}
+}
+
+// WithDeterministic returns an EvaluatorOption which captures the frames on a
+// virtual clock driven by the sleeps and the typing of the tape, so that the
+// number of frames and their timing are the same on every recording, and
+// encodes them so that the outputs are byte-identical.
+func WithDeterministic() EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.Deterministic = true
+		v.Options.Video.Deterministic = true
+	}
+}
+
+// evaluatorVars returns the variables given to the evaluator with WithVars.
+// They are needed to parse the tape, before the VHS instance is created, so
+// the options are applied to a blank instance to find them.
+func evaluatorVars(opts []EvaluatorOption) map[string]string {
+	v := VHS{Options: &Options{}}
+	for _, opt := range opts {
+		opt(&v)
+	}
+	return v.Options.Vars
+}
+
 // Evaluate takes as input a tape string, an output writer, and an output file
 // and evaluates all the commands within the tape string and produces a GIF.
 func Evaluate(ctx context.Context, tape string, out io.Writer, opts ...EvaluatorOption) []error {
+	start := time.Now()
 	l := NewLexer(tape)
-	p := NewParser(l)
+	p := NewParser(l, WithParserVars(evaluatorVars(opts)))
 
 	cmds := p.Parse()
 	errs := p.Errors()
@@ -23,33 +157,78 @@ func Evaluate(ctx context.Context, tape string, out io.Writer, opts ...Evaluator
 	}
 
 	v := New()
+	v.started = start
 	defer func() { _ = v.close() }()
+	defer func() { _ = v.closeKeyLog() }()
+
+	// Run the default settings of the configuration files first, so that the
+	// settings of the tape override them.
+	for _, cmd := range evaluatorDefaults(opts) {
+		executeCommand(cmd, &v)
+	}
 
 	// Run Output and Set commands as they only modify options on the VHS instance.
 	var offset int
 	for i, cmd := range cmds {
-		if cmd.Type == SET || cmd.Type == OUTPUT || cmd.Type == REQUIRE {
-			fmt.Fprintln(out, cmd.Highlight(false))
-			cmd.Execute(&v)
+		if isConfiguration(cmd) {
+			fmt.Fprintln(out, v.highlight(cmd, false))
+			executeCommand(cmd, &v)
 		} else {
 			offset = i
 			break
 		}
 	}
 
+	// Apply the evaluator options once the tape's settings and outputs are
+	// known, so that they can override them. If running as an SSH server, the
+	// output file is a temporary file to use for the output, see `serve.go`.
+	for _, opt := range opts {
+		opt(&v)
+	}
+
 	video := v.Options.Video
 	minDimension := video.Padding + video.Padding
 	if video.Height < minDimension || video.Width < minDimension {
 		v.Errors = append(v.Errors, fmt.Errorf("height and width must be greater than %d", minDimension))
 	}
 
+	if v.Options.Deterministic {
+		v.Errors = append(v.Errors, deterministicErrors(cmds, p.Tokens())...)
+	}
+	v.Errors = append(v.Errors, splitErrors(v.Options)...)
+
 	if len(v.Errors) > 0 {
 		return v.Errors
 	}
 
-	// Setup the terminal session so we can start executing commands.
+	// Prepare the working directory with the Setup scripts before the shell
+	// starts in it, and clean it up with the Teardown scripts at the end.
+	if err := v.prepareWorkingDirectory(); err != nil {
+		return []error{err}
+	}
+	defer v.runTeardown()
+	if err := v.runSetup(ctx); err != nil {
+		return []error{err}
+	}
+
+	// Start the terminal and the browser now that the options are known, and setup the
+	// terminal session so we can start executing commands.
+	splitSize(v.Options)
+	if err := v.Start(); err != nil {
+		return []error{err}
+	}
 	v.Setup()
 
+	// Start the shell of the second pane of a split recording.
+	if v.Options.Split != "" {
+		pane, err := v.startPanes()
+		if err != nil {
+			return []error{err}
+		}
+		defer func() { _ = pane.close() }()
+		defer func() { _ = pane.Cleanup() }()
+	}
+
 	// If the first command (after Settings and Outputs) is a Hide command, we can
 	// begin executing the commands before we start recording to avoid capturing
 	// any unwanted frames.
@@ -59,14 +238,23 @@ func Evaluate(ctx context.Context, tape string, out io.Writer, opts ...Evaluator
 				offset += i
 				break
 			}
-			fmt.Fprintln(out, cmd.Highlight(true))
-			cmd.Execute(&v)
+			if v.skip(cmd, out) {
+				continue
+			}
+			fmt.Fprintln(out, v.highlight(cmd, true))
+			for _, pane := range v.paneTargets(cmd) {
+				executeCommand(cmd, pane)
+			}
+			if cmd.Type == ABORT {
+				return v.allErrors()
+			}
 		}
 	}
 
 	// Begin recording frames as we are now in a recording state.
 	ctx, cancel := context.WithCancel(ctx)
 	ch := v.Record(ctx)
+	stopPanes := v.recordPanes(ctx)
 
 	// Clean up temporary files at the end.
 	defer func() { _ = v.Cleanup() }()
@@ -76,6 +264,7 @@ func Evaluate(ctx context.Context, tape string, out io.Writer, opts ...Evaluator
 		cancel()
 		// Read from channel to ensure recorder is done.
 		<-ch
+		stopPanes()
 	}
 
 	// Log errors from the recording process.
@@ -85,11 +274,15 @@ func Evaluate(ctx context.Context, tape string, out io.Writer, opts ...Evaluator
 		}
 	}()
 
-	for _, cmd := range cmds[offset:] {
+	for i, cmd := range cmds[offset:] {
 		if ctx.Err() != nil {
 			teardown()
 			return []error{ctx.Err()}
 		}
+		v.reportProgress(Progress{Command: offset + i, Total: len(cmds), Name: maskSecrets(strings.TrimSpace(cmd.String()), v.Options.Secrets)})
+		if v.skip(cmd, out) {
+			continue
+		}
 
 		// When changing the FontFamily, FontSize, LineHeight, Padding
 		// The xterm.js canvas changes dimensions and causes FFMPEG to not work
@@ -99,30 +292,88 @@ func Evaluate(ctx context.Context, tape string, out io.Writer, opts ...Evaluator
 		// GIF as the frame sequence will change dimensions. This is fixable.
 		//
 		// We should remove if isSetting statement.
-		isSetting := cmd.Type == SET && cmd.Options != "TypingSpeed"
-		if isSetting || cmd.Type == REQUIRE {
-			fmt.Fprintln(out, cmd.Highlight(true))
+		isSetting := cmd.Type == SET && !isRuntimeSetting(cmd.Options)
+		if isSetting || cmd.Type == REQUIRE || cmd.Type == ENV || cmd.Type == SETUP || cmd.Type == TEARDOWN || cmd.Type == SPLIT {
+			fmt.Fprintln(out, v.highlight(cmd, true))
 			continue
 		}
-		fmt.Fprintln(out, cmd.Highlight(!v.recording || cmd.Type == SHOW || cmd.Type == HIDE || isSetting))
-		cmd.Execute(&v)
+		fmt.Fprintln(out, v.highlight(cmd, !v.recording || cmd.Type == SHOW || cmd.Type == HIDE || isSetting))
+		for _, pane := range v.paneTargets(cmd) {
+			executeCommand(cmd, pane)
+		}
+		if cmd.Type == ABORT {
+			teardown()
+			return v.allErrors()
+		}
+		v.focused().defaultSleep(cmd)
 	}
+	v.reportProgress(Progress{Command: len(cmds), Total: len(cmds)})
 
-	// If running as an SSH server, the output file is a temporary file
-	// to use for the output.
-	//
-	// We need to set the GIF file path before it is created but after all of
-	// the settings and commands are executed. This is done in `serve.go`.
-	//
-	// Since the GIF creation is deferred, setting the output file here will
-	// achieve what we want.
-	for _, opt := range opts {
-		opt(&v)
+	// Save the final screen, while the terminal is still running.
+	if v.Options.Test.Screen != "" {
+		if err := v.SaveScreen(); err != nil {
+			v.Errors = append(v.Errors, err)
+		}
+	}
+	if v.Options.HTML.Output != "" {
+		if err := v.SaveHTML(); err != nil {
+			v.Errors = append(v.Errors, err)
+		}
 	}
 
 	teardown()
-	if err := v.Render(); err != nil {
+	v.Errors = v.allErrors()
+	if v.panes != nil {
+		if err := v.renderPanes(); err != nil {
+			return []error{err}
+		}
+	} else if err := v.Render(); err != nil {
 		return []error{err}
 	}
+	if len(v.Errors) > 0 {
+		return v.Errors
+	}
 	return nil
 }
+
+// deterministicErrors returns an error for every type of command of the tape
+// which can't be recorded on a virtual clock, since it waits for an event
+// which doesn't happen at a time known in advance, with the lines of the
+// commands. The tokens are those of the commands, as returned by the parser.
+func deterministicErrors(cmds []Command, tokens []Token) []error {
+	var types []CommandType
+	lines := map[CommandType][]string{}
+	for i, cmd := range cmds {
+		if cmd.Type != BREAKPOINT && cmd.Type != WAIT && cmd.Type != SPLIT && cmd.Type != SPEED {
+			continue
+		}
+		if _, ok := lines[cmd.Type]; !ok {
+			types = append(types, cmd.Type)
+		}
+		line := "?"
+		if i < len(tokens) {
+			line = strconv.Itoa(tokens[i].Line)
+		}
+		lines[cmd.Type] = append(lines[cmd.Type], line)
+	}
+
+	var errs []error
+	for _, t := range types {
+		on := "line"
+		if len(lines[t]) > 1 {
+			on = "lines"
+		}
+		errs = append(errs, fmt.Errorf("%s can't be used with --deterministic, on %s %s", t, on, strings.Join(lines[t], ", ")))
+	}
+	return errs
+}
+
+// reportProgress reports the progress, along with the frames captured and the
+// time elapsed so far, if a progress function was given with WithProgress.
+func (v *VHS) reportProgress(p Progress) {
+	if v.progress != nil {
+		p.Frames = v.frames
+		p.Elapsed = time.Since(v.started)
+		v.progress(p)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithOutputs(t *testing.T) {
+	if _, err := WithOutputs([]string{"frames"}); err == nil {
+		t.Error("expected an error for an output without extension nor trailing slash")
+	}
+
+	opt, err := WithOutputs([]string{"out/demo.webm", "out/demo.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := VHS{Options: &Options{}}
+	v.Options.Video.Output = VideoOutputs{GIF: "demo.gif", MP4: "demo.mp4"}
+	v.Options.HTML.Output = "demo.html"
+	opt(&v)
+
+	if (v.Options.Video.Output != VideoOutputs{WebM: "out/demo.webm"}) {
+		t.Errorf("expected only the WebM output, got %+v", v.Options.Video.Output)
+	}
+	if v.Options.Test.Screen != "out/demo.txt" {
+		t.Errorf("expected the screen output to be out/demo.txt, got %q", v.Options.Test.Screen)
+	}
+	if v.Options.HTML.Output != "" {
+		t.Errorf("expected the HTML output of the tape to be removed, got %q", v.Options.HTML.Output)
+	}
+}
+
+func TestWithEnv(t *testing.T) {
+	v := New()
+	ExecuteEnv(Command{Type: ENV, Options: "GREETING", Args: "hello"}, &v)
+	WithEnv([]string{"GREETING=bye", "NO_COLOR=1"})(&v)
+
+	expected := []string{"GREETING=hello", "GREETING=bye", "NO_COLOR=1"}
+	if !reflect.DeepEqual(v.Options.Env, expected) {
+		t.Errorf("expected %v, got %v", expected, v.Options.Env)
+	}
+}
+
+func TestDeterministicErrors(t *testing.T) {
+	l := NewLexer("Type hello\nBreakpoint\nSleep 1s\nWait\nBreakpoint")
+	p := NewParser(l)
+	cmds := p.Parse()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	errs := deterministicErrors(cmds, p.Tokens())
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Error() != "Breakpoint can't be used with --deterministic, on lines 2, 5" {
+		t.Errorf("unexpected error %q", errs[0])
+	}
+	if errs[1].Error() != "Wait can't be used with --deterministic, on line 4" {
+		t.Errorf("unexpected error %q", errs[1])
+	}
+
+	v := VHS{Options: &Options{}}
+	WithDeterministic()(&v)
+	if !v.Options.Deterministic {
+		t.Error("expected the deterministic mode to be enabled")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ExecuteExpect is a CommandFunc that checks that the screen of the terminal
+// contains the text, or matches the regular expression, of the command. With
+// ExpectNot, it checks that it doesn't. A failed check is an error of the
+// recording, so that vhs exits with a non-zero status.
+func ExecuteExpect(c Command, v *VHS) {
+	screen, err := v.currentScreen()
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("failed to read the screen for %s %s: %w", expectName(c.Type), c.Args, err))
+		return
+	}
+
+	matched, err := expectMatches(c.Args, strings.Join(screen, "\n"))
+	if err != nil {
+		v.Errors = append(v.Errors, err)
+		return
+	}
+	switch {
+	case c.Type == EXPECT && !matched:
+		v.Errors = append(v.Errors, fmt.Errorf("%s %s failed: not found on the screen", expectName(c.Type), c.Args))
+	case c.Type == EXPECT_NOT && matched:
+		v.Errors = append(v.Errors, fmt.Errorf("%s %s failed: found on the screen", expectName(c.Type), c.Args))
+	}
+}
+
+// expectMatches returns whether the screen matches the argument of Expect,
+// which is either a quoted text or a regular expression delimited by slashes.
+func expectMatches(arg, screen string) (bool, error) {
+	if strings.HasPrefix(arg, "/") {
+		pattern := strings.TrimSuffix(strings.TrimPrefix(arg, "/"), "/")
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %s: %w", arg, err)
+		}
+		return re.MatchString(screen), nil
+	}
+	text, err := strconv.Unquote(arg)
+	if err != nil {
+		return false, fmt.Errorf("invalid text %s: %w", arg, err)
+	}
+	return strings.Contains(screen, text), nil
+}
+
+// expectName returns the name of the command in the tape.
+func expectName(t CommandType) string {
+	if t == EXPECT_NOT {
+		return "ExpectNot"
+	}
+	return "Expect"
+}
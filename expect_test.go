@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestExpectMatches(t *testing.T) {
+	screen := "> make test\nok  \tgithub.com/charmbracelet/vhs\t0.7s\n>"
+
+	tests := []struct {
+		arg      string
+		expected bool
+	}{
+		{`"ok"`, true},
+		{`"FAIL"`, false},
+		{`"charmbracelet/vhs"`, true},
+		{`/\d+\.\d+s/`, true},
+		{`/^FAIL/`, false},
+	}
+	for _, tc := range tests {
+		matched, err := expectMatches(tc.arg, screen)
+		requireNoErr(t, err)
+		if matched != tc.expected {
+			t.Errorf("expected %s to match: %t, got %t", tc.arg, tc.expected, matched)
+		}
+	}
+
+	if _, err := expectMatches("/[/", screen); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fontFileTypes are the MIME types of the font files of Set FontFile, by
+// extension.
+var fontFileTypes = map[string]string{
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+// fontFileType returns the MIME type of the font file, or an empty string if
+// it isn't a font file.
+func fontFileType(file string) string {
+	return fontFileTypes[strings.ToLower(filepath.Ext(file))]
+}
+
+// fontFileFamily returns the family under which the nth font file, counted
+// from 0, is loaded in the page.
+func fontFileFamily(n int) string {
+	return fmt.Sprintf("vhs-font-%d", n+1)
+}
+
+// terminalFontFamily returns the font family of the terminal: the font files,
+// in the order they were set, and then the font family of Set FontFamily, for
+// the glyphs which aren't in the files.
+func terminalFontFamily(opts *Options) string {
+	families := make([]string, 0, len(opts.FontFiles)+1)
+	for i := range opts.FontFiles {
+		families = append(families, fontFileFamily(i))
+	}
+	return strings.Join(append(families, opts.FontFamily), fontsSeparator)
+}
+
+// fontLoader returns the script which loads the font files in the page, as
+// data URLs, and resolves once they are all loaded.
+func fontLoader(files []string) (string, error) {
+	faces := make([]string, 0, len(files))
+	for i, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		url := "data:" + fontFileType(file) + ";base64," + base64.StdEncoding.EncodeToString(b)
+		face, err := json.Marshal([]string{fontFileFamily(i), "url(" + url + ")"})
+		if err != nil {
+			return "", err
+		}
+		faces = append(faces, string(face))
+	}
+	return fmt.Sprintf(`async () => {
+	for (const [family, source] of [%s]) {
+		const face = new FontFace(family, source);
+		document.fonts.add(await face.load());
+	}
+}`, strings.Join(faces, ",")), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetFontFile(t *testing.T) {
+	dir := t.TempDir()
+	regular := filepath.Join(dir, "Regular.ttf")
+	symbols := filepath.Join(dir, "Symbols.WOFF2")
+	for _, file := range []string{regular, symbols, filepath.Join(dir, "font.txt")} {
+		if err := os.WriteFile(file, []byte("font"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := VHS{Options: &Options{FontFamily: "JetBrains Mono,monospace"}}
+	ExecuteSetFontFile(Command{Args: regular}, &v)
+	ExecuteSetFontFile(Command{Args: symbols}, &v)
+	if len(v.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", v.Errors)
+	}
+	if got := terminalFontFamily(v.Options); got != "vhs-font-1,vhs-font-2,JetBrains Mono,monospace" {
+		t.Errorf("unexpected font family %q", got)
+	}
+
+	ExecuteSetFontFile(Command{Args: filepath.Join(dir, "font.txt")}, &v)
+	ExecuteSetFontFile(Command{Args: filepath.Join(dir, "missing.otf")}, &v)
+	if len(v.Errors) != 2 || len(v.Options.FontFiles) != 2 {
+		t.Errorf("expected the invalid font files to be errors, got %v", v.Errors)
+	}
+}
+
+func TestFontLoader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "font.otf")
+	if err := os.WriteFile(file, []byte("font"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := fontLoader([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(loader, `[["vhs-font-1","url(data:font/otf;base64,Zm9udA==)"]]`) {
+		t.Errorf("expected the font to be loaded as a data URL:\n%s", loader)
+	}
+
+	if _, err := fontLoader([]string{file + ".missing"}); err == nil {
+		t.Error("expected an error for a missing font file")
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// formatToken is a token of the tape along with its text in the tape.
+type formatToken struct {
+	Token
+	text string
+	// space is the text between the previous token and this one.
+	space string
+}
+
+// formatLine is a line of the formatted tape: the tokens which start on the
+// same line of the tape, along with the ones which continue it.
+type formatLine []formatToken
+
+// multiline returns whether the line spans several lines of the tape, e.g.
+// with a block comment or a line continuation, in which case it is kept as is.
+func (l formatLine) multiline() bool {
+	for i, tok := range l {
+		if strings.Contains(tok.text, "\n") || (i > 0 && strings.Contains(tok.space, "\n")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSet returns whether the line is a Set command which can be aligned with
+// the Set commands around it.
+func (l formatLine) isSet() bool {
+	return len(l) >= 3 && l[0].Type == SET && IsSetting(l[1].Type) && !l.multiline()
+}
+
+// canonicalKeywords maps the keywords, in lower case, to their canonical
+// casing. The units are left out, since they are never recased.
+var canonicalKeywords = func() map[string]string {
+	m := make(map[string]string, len(keywords))
+	for k, t := range keywords {
+		if t != EM && t != PX && !isDurationUnit(t) {
+			m[strings.ToLower(k)] = k
+		}
+	}
+	return m
+}()
+
+// Format rewrites the tape in the canonical style: commands and settings in
+// their usual casing, a single space between the arguments, durations in
+// their largest whole unit, consecutive Set commands aligned and at most one
+// blank line in a row. Comments are kept, and the lines which span several
+// lines of the tape are kept as they are.
+func Format(tape string) string {
+	var lines []formatLine
+	var blank []bool
+	for _, line := range formatLines(tape) {
+		if len(line) == 0 {
+			if len(lines) > 0 && !blank[len(blank)-1] {
+				lines, blank = append(lines, nil), append(blank, true)
+			}
+			continue
+		}
+		lines, blank = append(lines, line), append(blank, false)
+	}
+	for len(lines) > 0 && blank[len(blank)-1] {
+		lines, blank = lines[:len(lines)-1], blank[:len(blank)-1]
+	}
+
+	var s strings.Builder
+	for i := 0; i < len(lines); i++ {
+		if !lines[i].isSet() {
+			s.WriteString(formatTokens(lines[i], 0))
+			s.WriteByte('\n')
+			continue
+		}
+		// Align the values of the consecutive Set commands.
+		j, width := i, 0
+		for ; j < len(lines) && lines[j].isSet(); j++ {
+			if w := len(lines[j][1].text); w > width {
+				width = w
+			}
+		}
+		for ; i < j; i++ {
+			s.WriteString(formatTokens(lines[i], width))
+			s.WriteByte('\n')
+		}
+		i--
+	}
+	return s.String()
+}
+
+// formatLines splits the tokens of the tape into lines. An empty line is a
+// blank line of the tape.
+func formatLines(tape string) []formatLine {
+	// Offsets of the start of each line, to locate the tokens in the tape.
+	starts := []int{0}
+	for i := 0; i < len(tape); i++ {
+		if tape[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	offset := func(tok Token) int {
+		if tok.Line < 1 || tok.Line > len(starts) {
+			return len(tape)
+		}
+		if o := starts[tok.Line-1] + tok.Column - 1; o < len(tape) {
+			return o
+		}
+		return len(tape)
+	}
+
+	var lines []formatLine
+	var line formatLine
+	l := NewLexer(tape)
+	end, last := 0, 1
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		start := offset(tok)
+		stop := l.Offset()
+		if stop > len(tape) {
+			stop = len(tape)
+		}
+		ft := formatToken{Token: tok, text: strings.TrimRight(tape[start:stop], " \t\r\n"), space: tape[end:start]}
+
+		// A token on a new line of the tape starts a new line, unless the
+		// previous one is continued with a backslash.
+		if tok.Line > last && !strings.Contains(ft.space, "\\") {
+			if line != nil {
+				lines = append(lines, line)
+				line = nil
+			}
+			for i := strings.Count(ft.space, "\n"); i > 1; i-- {
+				lines = append(lines, nil)
+			}
+		}
+		// Recase the command and the setting of a Set command, so that they
+		// are read as keywords.
+		if len(line) == 0 || (len(line) == 1 && line[0].Type == SET) {
+			ft = canonicalKeyword(ft)
+		}
+		line = append(line, ft)
+		end = start + len(ft.text)
+		last = tok.Line + strings.Count(ft.text, "\n")
+	}
+	if line != nil {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatTokens returns the formatted text of a line. The setting of a Set
+// command is padded to width, to align the values.
+func formatTokens(line formatLine, width int) string {
+	if line.multiline() {
+		var s strings.Builder
+		for i, tok := range line {
+			if i > 0 {
+				s.WriteString(tok.space)
+			}
+			s.WriteString(tok.text)
+		}
+		return s.String()
+	}
+
+	var s strings.Builder
+	for i := 0; i < len(line); i++ {
+		tok := line[i]
+		text := tok.text
+		if tok.Type == NUMBER && i+1 < len(line) && line[i+1].space == "" && isDurationUnit(line[i+1].Type) {
+			text = formatDuration(tok.text + line[i+1].text)
+			i++
+		}
+
+		if i > 0 && tok.space != "" {
+			s.WriteByte(' ')
+		}
+		s.WriteString(text)
+		if i == 1 && line[0].Type == SET && width > len(text) {
+			s.WriteString(strings.Repeat(" ", width-len(text)))
+		}
+	}
+	return s.String()
+}
+
+// canonicalKeyword returns the token with its keyword in the canonical
+// casing, or as is if it isn't a keyword. Quoted strings are never keywords.
+func canonicalKeyword(tok formatToken) formatToken {
+	if tok.Type == COMMENT || tok.text != tok.Literal {
+		return tok
+	}
+	if k, ok := canonicalKeywords[strings.ToLower(tok.text)]; ok {
+		tok.text, tok.Literal, tok.Type = k, k, keywords[k]
+	}
+	return tok
+}
+
+// isDurationUnit returns whether the token is the unit of a duration.
+func isDurationUnit(t TokenType) bool {
+	return t == MILLISECONDS || t == SECONDS || t == MINUTES
+}
+
+// formatDuration returns the duration in its largest whole unit of the tapes,
+// e.g. 1s rather than 1000ms, or as is if it can't be parsed. Minutes aren't
+// a unit of the tapes, so 60s stays 60s.
+func formatDuration(s string) string {
+	d, err := time.ParseDuration(s)
+	switch {
+	case err != nil || d <= 0:
+		return s
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	case d%time.Millisecond == 0:
+		return fmt.Sprintf("%dms", d/time.Millisecond)
+	default:
+		return s
+	}
+}
+
+var (
+	formatCheck bool
+	formatCmd   = &cobra.Command{
+		Use:   "fmt <file>...",
+		Short: "Rewrite tape files in the canonical style",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var unformatted int
+			for _, file := range args {
+				b, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				formatted := []byte(Format(string(b)))
+				if bytes.Equal(b, formatted) {
+					continue
+				}
+				if formatCheck {
+					fmt.Fprintln(cmd.OutOrStdout(), file)
+					unformatted++
+					continue
+				}
+				if err := os.WriteFile(file, formatted, 0o644); err != nil { //nolint:gosec
+					return err
+				}
+			}
+			if unformatted > 0 {
+				return errors.New("tape file(s) not formatted, run vhs fmt")
+			}
+			return nil
+		},
+	}
+)
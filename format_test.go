@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	tape := `
+
+# Demo
+output demo.gif
+set fontsize 32
+Set   Width 1200
+set padding 10   # small
+
+
+type   "echo 'hi'"   Enter
+Sleep 1000ms
+Type@0.5s "x"
+Ctrl+C
+Type "multi\
+line"
+/* block
+comment */
+Sleep 60s
+
+`
+	expected := `# Demo
+Output demo.gif
+Set FontSize 32
+Set Width    1200
+Set Padding  10 # small
+
+Type "echo 'hi'" Enter
+Sleep 1s
+Type@500ms "x"
+Ctrl+C
+Type "multi\
+line"
+/* block
+comment */
+Sleep 60s
+`
+	formatted := Format(tape)
+	if formatted != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, formatted)
+	}
+	if again := Format(formatted); again != formatted {
+		t.Errorf("expected the formatting to be stable, got:\n%s", again)
+	}
+
+}
+
+func TestFormatReparse(t *testing.T) {
+	tape := `Output demo.gif
+Set TypingSpeed 0.05s
+Sleep 60s
+Sleep 1000ms
+Sleep 120000ms
+Type@0.5s "x"
+Backspace@1500ms 3
+Wait /done/ 90s
+`
+	original := NewParser(NewLexer(tape))
+	cmds := original.Parse()
+	reparsed := NewParser(NewLexer(Format(tape)))
+	got := reparsed.Parse()
+	if len(original.Errors()) > 0 || len(reparsed.Errors()) > 0 {
+		t.Fatalf("expected both tapes to parse, got %v and %v", original.Errors(), reparsed.Errors())
+	}
+	if !reflect.DeepEqual(normalizeDurations(got), normalizeDurations(cmds)) {
+		t.Errorf("expected the formatting to keep the commands\n%v\ngot\n%v", cmds, got)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for in, want := range map[string]string{"1000ms": "1s", "60s": "60s", "120000ms": "120s", "1500ms": "1500ms", "0.5s": "500ms"} {
+		if got := formatDuration(in); got != want {
+			t.Errorf("formatDuration(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatExamples(t *testing.T) {
+	files, err := filepath.Glob("examples/*/*.tape")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := NewParser(NewLexer(string(b)))
+		cmds := p.Parse()
+		if len(p.Errors()) > 0 {
+			continue
+		}
+		formatted := NewParser(NewLexer(Format(string(b))))
+		if got := formatted.Parse(); !reflect.DeepEqual(normalizeDurations(got), normalizeDurations(cmds)) {
+			t.Errorf("%s: expected the formatting to keep the commands\n%v\ngot\n%v", file, cmds, got)
+		}
+	}
+}
+
+// normalizeDurations writes the durations of the commands in the same way, as
+// the formatting changes their units.
+func normalizeDurations(cmds []Command) []Command {
+	normalize := func(s string) string {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d.String()
+		}
+		return s
+	}
+	for i := range cmds {
+		cmds[i].Options, cmds[i].Args = normalize(cmds[i].Options), normalize(cmds[i].Args)
+	}
+	return cmds
+}
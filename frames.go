@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// frameFormat is the file name of the composed frames of `Output frames/`.
+	frameFormat = "frame-%05d.png"
+	// framesManifest is the file name of the timing manifest of the frames.
+	framesManifest = "frames.json"
+)
+
+// FramesManifest describes the frames exported by `Output frames/`, so that
+// they can be encoded by other tools than ffmpeg.
+type FramesManifest struct {
+	Framerate     int             `json:"framerate"`
+	PlaybackSpeed float64         `json:"playback_speed"`
+	Width         int             `json:"width"`
+	Height        int             `json:"height"`
+	Frames        []ManifestFrame `json:"frames"`
+}
+
+// ManifestFrame is a frame of the manifest, with its time and how long it is
+// shown, in seconds.
+type ManifestFrame struct {
+	File     string  `json:"file"`
+	Time     float64 `json:"time"`
+	Duration float64 `json:"duration"`
+}
+
+// MakeFrames composes the recorded frames, in the order in which they are
+// rendered, into complete PNG images numbered from 1 in the frames directory,
+// along with a JSON manifest of their timing. The layers of the frames are
+// kept as they are.
+func MakeFrames(opts VideoOptions, frames int) error {
+	if opts.Output.Frames == "" {
+		return nil
+	}
+
+	speed := opts.PlaybackSpeed
+	if speed <= 0 {
+		speed = 1
+	}
+	interval := time.Second / time.Duration(opts.Framerate)
+	duration := (time.Duration(float64(interval) / speed)).Seconds()
+
+	manifest := FramesManifest{
+		Framerate:     opts.Framerate,
+		PlaybackSpeed: speed,
+		Width:         opts.Width,
+		Height:        opts.Height,
+		Frames:        make([]ManifestFrame, 0, frames),
+	}
+	for i := 0; i < frames; i++ {
+		n := opts.StartingFrame + i
+		text, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(textFrameFormat, n)))
+		if err != nil {
+			return err
+		}
+		cursor, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(cursorFrameFormat, n)))
+		if err != nil {
+			return err
+		}
+		img, err := composeScreenshot(text, cursor, opts.Padding, opts.BackgroundColor)
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf(frameFormat, i+1)
+		if err := os.WriteFile(filepath.Join(opts.Input, name), img, 0o644); err != nil { //nolint:gosec
+			return fmt.Errorf("error exporting frame %d: %w", i+1, err)
+		}
+		manifest.Frames = append(manifest.Frames, ManifestFrame{
+			File:     name,
+			Time:     float64(i) * duration,
+			Duration: duration,
+		})
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(opts.Input, framesManifest), append(b, '\n'), 0o644) //nolint:gosec
+}
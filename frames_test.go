@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMakeFrames(t *testing.T) {
+	dir := t.TempDir()
+	colors := []color.NRGBA{{R: 0xff, A: 0xff}, {G: 0xff, A: 0xff}, {B: 0xff, A: 0xff}}
+	for n, c := range colors {
+		text := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		text.Set(0, 0, c)
+		cursor := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(textFrameFormat, n+1)), encodePNG(t, text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(cursorFrameFormat, n+1)), encodePNG(t, cursor), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without a frames output, nothing is exported.
+	opts := VideoOptions{Input: dir, StartingFrame: 2, Framerate: 10, PlaybackSpeed: 2, BackgroundColor: "#000000"}
+	if err := MakeFrames(opts, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, framesManifest)); !os.IsNotExist(err) {
+		t.Fatalf("expected no manifest, got %v", err)
+	}
+
+	// Start from the second frame, as after a loop offset.
+	opts.Output.Frames = dir
+	if err := MakeFrames(opts, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range colors[1:] {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf(frameFormat, i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); got != want {
+			t.Errorf("expected frame %d of %v, got %v", i+1, want, got)
+		}
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, framesManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest FramesManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	want := []ManifestFrame{
+		{File: "frame-00001.png", Time: 0, Duration: 0.05},
+		{File: "frame-00002.png", Time: 0.05, Duration: 0.05},
+	}
+	if len(manifest.Frames) != len(want) {
+		t.Fatalf("expected %d frames, got %d", len(want), len(manifest.Frames))
+	}
+	for i, f := range manifest.Frames {
+		if f != want[i] {
+			t.Errorf("expected frame %d to be %+v, got %+v", i+1, want[i], f)
+		}
+	}
+	if manifest.Framerate != 10 || manifest.PlaybackSpeed != 2 {
+		t.Errorf("expected a framerate of 10 at 2x, got %d at %gx", manifest.Framerate, manifest.PlaybackSpeed)
+	}
+
+	if err := MakeFrames(opts, 3); err == nil {
+		t.Errorf("expected an error for a missing frame")
+	}
+}
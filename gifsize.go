@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fileSizeUnits are the units of the sizes of Set MaxFileSize, in bytes.
+var fileSizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+}
+
+// parseFileSize parses a size such as 5MB, 1.5MiB, 500KB or a number of bytes.
+func parseFileSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := fileSizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", s[i:])
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("expected a positive size")
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// formatFileSize formats a number of bytes with the largest decimal unit.
+func formatFileSize(n int64) string {
+	switch {
+	case n >= 1000*1000*1000:
+		return fmt.Sprintf("%.1fGB", float64(n)/1e9)
+	case n >= 1000*1000:
+		return fmt.Sprintf("%.1fMB", float64(n)/1e6)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fKB", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
+
+// gifReductions are the tradeoffs tried in turn, each on top of the previous
+// ones, until the GIF fits in its maximum size. Each one returns false if it
+// doesn't reduce the GIF any further.
+var gifReductions = []func(*VideoOptions) bool{
+	reduceGIFColors(128),   //nolint:gomnd
+	reduceGIFFramerate(25), //nolint:gomnd
+	reduceGIFColors(64),    //nolint:gomnd
+	reduceGIFScale(0.75),   //nolint:gomnd
+	reduceGIFFramerate(15), //nolint:gomnd
+	reduceGIFScale(0.5),    //nolint:gomnd
+	reduceGIFColors(32),    //nolint:gomnd
+}
+
+func reduceGIFColors(colors int) func(*VideoOptions) bool {
+	return func(opts *VideoOptions) bool {
+		if gifColors(*opts) <= colors {
+			return false
+		}
+		opts.MaxColors = colors
+		return true
+	}
+}
+
+func reduceGIFFramerate(framerate int) func(*VideoOptions) bool {
+	return func(opts *VideoOptions) bool {
+		// The frames of the adaptive framerate keep their own durations.
+		if opts.Adaptive || gifFramerate(*opts) <= framerate {
+			return false
+		}
+		opts.GIFFramerate = framerate
+		return true
+	}
+}
+
+func reduceGIFScale(scale float64) func(*VideoOptions) bool {
+	return func(opts *VideoOptions) bool {
+		if gifScale(*opts) <= scale {
+			return false
+		}
+		opts.GIFScale = scale
+		return true
+	}
+}
+
+// gifColors returns the number of colors of the palette of the GIF.
+func gifColors(opts VideoOptions) int {
+	if opts.MaxColors <= 0 || opts.MaxColors > defaultMaxColors {
+		return defaultMaxColors
+	}
+	return opts.MaxColors
+}
+
+// gifFramerate returns the framerate of the GIF.
+func gifFramerate(opts VideoOptions) int {
+	if opts.GIFFramerate > 0 && opts.GIFFramerate < opts.Framerate {
+		return opts.GIFFramerate
+	}
+	return opts.Framerate
+}
+
+// gifScale returns the scale of the GIF, relative to the other outputs.
+func gifScale(opts VideoOptions) float64 {
+	if opts.GIFScale > 0 && opts.GIFScale < 1 {
+		return opts.GIFScale
+	}
+	return 1
+}
+
+// gifReductionFilter returns the filters which lower the framerate and the
+// size of the GIF, once everything else is drawn.
+func gifReductionFilter(opts VideoOptions) string {
+	var filter string
+	if framerate := gifFramerate(opts); framerate < opts.Framerate && !opts.Adaptive {
+		filter += fmt.Sprintf(",fps=%d", framerate)
+	}
+	if scale := gifScale(opts); scale < 1 {
+		filter += fmt.Sprintf(",scale=trunc(iw*%.2f/2)*2:-2:flags=lanczos", scale)
+	}
+	return filter
+}
+
+// gifTradeoffs describes how the GIF is reduced, e.g. "64 colors, 25 fps".
+func gifTradeoffs(opts VideoOptions) string {
+	var tradeoffs []string
+	if colors := gifColors(opts); colors < defaultMaxColors {
+		tradeoffs = append(tradeoffs, fmt.Sprintf("%d colors", colors))
+	}
+	if framerate := gifFramerate(opts); framerate < opts.Framerate && !opts.Adaptive {
+		tradeoffs = append(tradeoffs, fmt.Sprintf("%d fps", framerate))
+	}
+	if scale := gifScale(opts); scale < 1 {
+		tradeoffs = append(tradeoffs, fmt.Sprintf("%d%% scale", int(math.Round(scale*100)))) //nolint:gomnd
+	}
+	return strings.Join(tradeoffs, ", ")
+}
+
+// FitGIF encodes the GIF again with fewer colors, a lower framerate and a
+// smaller size, in turn, until it fits in its maximum size, and reports the
+// tradeoffs it made. If the GIF is still too large once every tradeoff is
+// made, a warning is printed and the smallest GIF is kept.
+func FitGIF(opts VideoOptions) error {
+	if opts.Output.GIF == "" || opts.MaxFileSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(opts.Output.GIF)
+	if err != nil {
+		// The GIF couldn't be encoded, which is already reported.
+		return nil //nolint:nilerr
+	}
+	size := info.Size()
+	if size <= opts.MaxFileSize {
+		return nil
+	}
+	original := size
+
+	for _, reduce := range gifReductions {
+		if !reduce(&opts) {
+			continue
+		}
+		fmt.Printf("Reducing GIF of %s to %s...\n", formatFileSize(size), gifTradeoffs(opts))
+		if out, err := gifCommand(opts).CombinedOutput(); err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("could not reduce the GIF: %w", err)
+		}
+		info, err := os.Stat(opts.Output.GIF)
+		if err != nil {
+			return err
+		}
+		size = info.Size()
+		if size <= opts.MaxFileSize {
+			fmt.Printf("Reduced GIF from %s to %s to fit in %s: %s\n",
+				formatFileSize(original), formatFileSize(size), formatFileSize(opts.MaxFileSize), gifTradeoffs(opts))
+			return nil
+		}
+	}
+
+	fmt.Println(WarningStyle.Render(fmt.Sprintf("%s is %s, over the maximum of %s, even with %s",
+		opts.Output.GIF, formatFileSize(size), formatFileSize(opts.MaxFileSize), gifTradeoffs(opts))))
+	return nil
+}
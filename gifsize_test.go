@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseFileSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5MB", 5000000},
+		{"10 MB", 10000000},
+		{"1.5MiB", 1572864},
+		{"500kb", 500000},
+		{"1000", 1000},
+		{"2GB", 2000000000},
+	}
+	for _, tc := range tests {
+		got, err := parseFileSize(tc.input)
+		if err != nil || got != tc.expected {
+			t.Errorf("parseFileSize(%q): expected %d, got %d (%v)", tc.input, tc.expected, got, err)
+		}
+	}
+
+	for _, input := range []string{"", "MB", "0MB", "-5MB", "5TB", "five"} {
+		if _, err := parseFileSize(input); err == nil {
+			t.Errorf("parseFileSize(%q): expected an error", input)
+		}
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	for n, expected := range map[int64]string{
+		512:        "512B",
+		1500:       "1.5KB",
+		4200000:    "4.2MB",
+		5000000000: "5.0GB",
+	} {
+		if got := formatFileSize(n); got != expected {
+			t.Errorf("formatFileSize(%d): expected %s, got %s", n, expected, got)
+		}
+	}
+}
+
+func TestGIFReductions(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Output.GIF = "out.gif"
+
+	gif := strings.Join(MakeGIF(opts).Args, " ")
+	if !strings.Contains(gif, "[bordered];[bordered]split[a][b];[a]palettegen=max_colors=256[p]") {
+		t.Errorf("expected the GIF to be encoded as is: %s", gif)
+	}
+	if tradeoffs := gifTradeoffs(opts); tradeoffs != "" {
+		t.Errorf("expected no tradeoffs, got %q", tradeoffs)
+	}
+
+	expected := []string{
+		"128 colors",
+		"128 colors, 25 fps",
+		"64 colors, 25 fps",
+		"64 colors, 25 fps, 75% scale",
+		"64 colors, 15 fps, 75% scale",
+		"64 colors, 15 fps, 50% scale",
+		"32 colors, 15 fps, 50% scale",
+	}
+	for i, reduce := range gifReductions {
+		if !reduce(&opts) {
+			t.Fatalf("expected reduction %d to apply", i)
+		}
+		if got := gifTradeoffs(opts); got != expected[i] {
+			t.Errorf("expected the tradeoffs %q, got %q", expected[i], got)
+		}
+	}
+
+	gif = strings.Join(gifCommand(opts).Args, " ")
+	if !strings.Contains(gif, ",fps=15,scale=trunc(iw*0.50/2)*2:-2:flags=lanczos[bordered];[bordered]split[a][b];[a]palettegen=max_colors=32[p]") {
+		t.Errorf("expected the GIF to be reduced: %s", gif)
+	}
+
+	adaptive := DefaultVideoOptions()
+	adaptive.Adaptive = true
+	if reduceGIFFramerate(25)(&adaptive) {
+		t.Error("expected the framerate of an adaptive GIF to be kept")
+	}
+}
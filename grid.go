@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// gridFrame is the file name of the grid overlay in the frames directory.
+const gridFrame = "grid.png"
+
+// gridLabelInterval is the number of cells between two coordinate labels.
+const gridLabelInterval = 5
+
+var (
+	gridLineColor  = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x28}
+	gridLabelColor = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x90}
+)
+
+// GridOptions holds the terminal dimensions needed to draw the cell grid
+// overlay enabled with `Set ShowGrid true`.
+type GridOptions struct {
+	Columns int
+	Rows    int
+	Width   int
+	Height  int
+}
+
+// MakeGrid draws a transparent image, the size of the terminal canvas, with a
+// line at every cell boundary and the column and row number every few cells.
+// It is overlaid on top of every frame to help positioning commands.
+func MakeGrid(opts VideoOptions) error {
+	grid := opts.Grid
+	if grid.Columns <= 0 || grid.Rows <= 0 {
+		return fmt.Errorf("invalid terminal dimensions for grid: %dx%d", grid.Columns, grid.Rows)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, grid.Width, grid.Height))
+	cellWidth := float64(grid.Width) / float64(grid.Columns)
+	cellHeight := float64(grid.Height) / float64(grid.Rows)
+
+	for col := 1; col < grid.Columns; col++ {
+		x := int(float64(col) * cellWidth)
+		draw.Draw(img, image.Rect(x, 0, x+1, grid.Height), image.NewUniform(gridLineColor), image.Point{}, draw.Over)
+	}
+	for row := 1; row < grid.Rows; row++ {
+		y := int(float64(row) * cellHeight)
+		draw.Draw(img, image.Rect(0, y, grid.Width, y+1), image.NewUniform(gridLineColor), image.Point{}, draw.Over)
+	}
+
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(gridLabelColor),
+		Face: basicfont.Face7x13,
+	}
+	ascent := basicfont.Face7x13.Ascent
+	for col := 0; col < grid.Columns; col += gridLabelInterval {
+		d.Dot = fixed.P(int(float64(col)*cellWidth)+1, ascent)
+		d.DrawString(fmt.Sprint(col))
+	}
+	for row := gridLabelInterval; row < grid.Rows; row += gridLabelInterval {
+		d.Dot = fixed.P(1, int(float64(row)*cellHeight)+ascent)
+		d.DrawString(fmt.Sprint(row))
+	}
+
+	f, err := os.Create(filepath.Join(opts.Input, gridFrame))
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	return png.Encode(f, img)
+}
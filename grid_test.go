@@ -0,0 +1,43 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMakeGrid(t *testing.T) {
+	opts := VideoOptions{
+		Input: t.TempDir(),
+		Grid:  GridOptions{Columns: 80, Rows: 24, Width: 800, Height: 480},
+	}
+	if err := MakeGrid(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filepath.Join(opts.Input, gridFrame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 800 || b.Dy() != 480 {
+		t.Errorf("expected grid of 800x480, got %dx%d", b.Dx(), b.Dy())
+	}
+	if _, _, _, a := img.At(10, 105).RGBA(); a == 0 {
+		t.Errorf("expected a grid line at the first column boundary")
+	}
+	if _, _, _, a := img.At(15, 105).RGBA(); a != 0 {
+		t.Errorf("expected the inside of a cell to be transparent")
+	}
+
+	opts.Grid = GridOptions{}
+	if err := MakeGrid(opts); err == nil {
+		t.Errorf("expected an error for a terminal without cells")
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HTMLOptions is the set of options for the HTML output of the final screen.
+type HTMLOptions struct {
+	Output string
+	Full   bool
+}
+
+// Cell color modes, as reported by xterm.js.
+const (
+	colorDefault = iota
+	colorPalette
+	colorRGB
+)
+
+// Cell attributes, as a bit set.
+const (
+	attrBold = 1 << iota
+	attrItalic
+	attrUnderline
+	attrInverse
+	attrDim
+)
+
+// Cell is a single character of the terminal along with its colors and
+// attributes.
+type Cell struct {
+	Char   string
+	FgMode int
+	Fg     int
+	BgMode int
+	Bg     int
+	Attrs  int
+}
+
+// colors returns the foreground and background colors of the cell, given the
+// theme. An empty string is returned for the default colors.
+func (c Cell) colors(theme Theme) (string, string) {
+	fg := cellColor(c.FgMode, c.Fg, theme)
+	bg := cellColor(c.BgMode, c.Bg, theme)
+	if c.Attrs&attrInverse != 0 {
+		fg, bg = bg, fg
+		if fg == "" {
+			fg = theme.Background
+		}
+		if bg == "" {
+			bg = theme.Foreground
+		}
+	}
+	return fg, bg
+}
+
+// style returns the inline CSS of the cell, given the theme.
+func (c Cell) style(theme Theme) string {
+	fg, bg := c.colors(theme)
+
+	var css []string
+	if fg != "" {
+		css = append(css, "color:"+fg)
+	}
+	if bg != "" {
+		css = append(css, "background-color:"+bg)
+	}
+	if c.Attrs&attrBold != 0 {
+		css = append(css, "font-weight:bold")
+	}
+	if c.Attrs&attrItalic != 0 {
+		css = append(css, "font-style:italic")
+	}
+	if c.Attrs&attrUnderline != 0 {
+		css = append(css, "text-decoration:underline")
+	}
+	if c.Attrs&attrDim != 0 {
+		css = append(css, "opacity:0.5")
+	}
+	return strings.Join(css, ";")
+}
+
+// cellColor returns the CSS color for the given xterm.js color mode and value.
+// An empty string is returned for the default color.
+func cellColor(mode, color int, theme Theme) string {
+	switch mode {
+	case colorPalette:
+		return paletteColor(color, theme)
+	case colorRGB:
+		return fmt.Sprintf("#%06x", color)
+	default:
+		return ""
+	}
+}
+
+// paletteColor returns the color of the 256 color palette at index i. The 16
+// first colors come from the theme.
+func paletteColor(i int, theme Theme) string {
+	ansi := []string{
+		theme.Black, theme.Red, theme.Green, theme.Yellow,
+		theme.Blue, theme.Magenta, theme.Cyan, theme.White,
+		theme.BrightBlack, theme.BrightRed, theme.BrightGreen, theme.BrightYellow,
+		theme.BrightBlue, theme.BrightMagenta, theme.BrightCyan, theme.BrightWhite,
+	}
+
+	switch {
+	case i < 0:
+		return ""
+	case i < len(ansi):
+		return ansi[i]
+	case i < 232: //nolint:gomnd
+		// 6x6x6 color cube.
+		levels := []int{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+		i -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[i/36], levels[i/6%6], levels[i%6])
+	case i < 256: //nolint:gomnd
+		// Grayscale ramp.
+		gray := 8 + (i-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+	default:
+		return ""
+	}
+}
+
+// screenCells returns the cells currently visible in the terminal.
+func (v *VHS) screenCells() ([][]Cell, error) {
+	res, err := v.Page.Eval(`() => {
+		const buffer = term.buffer.active;
+		const rows = [];
+		for (let y = 0; y < term.rows; y++) {
+			const line = buffer.getLine(buffer.viewportY + y);
+			const cells = [];
+			for (let x = 0; line && x < term.cols; x++) {
+				const c = line.getCell(x);
+				if (!c || c.getWidth() === 0) continue;
+				const mode = (def, pal) => def ? 0 : (pal ? 1 : 2);
+				cells.push({
+					Char: c.getChars() || " ",
+					FgMode: mode(c.isFgDefault(), c.isFgPalette()),
+					Fg: c.getFgColor(),
+					BgMode: mode(c.isBgDefault(), c.isBgPalette()),
+					Bg: c.getBgColor(),
+					Attrs: (c.isBold() ? 1 : 0) | (c.isItalic() ? 2 : 0) | (c.isUnderline() ? 4 : 0) | (c.isInverse() ? 8 : 0) | (c.isDim() ? 16 : 0),
+				});
+			}
+			rows.push(cells);
+		}
+		return rows;
+	}`)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]Cell
+	if err := json.Unmarshal([]byte(res.Value.JSON("", "")), &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// RenderHTML renders the cells of a terminal screen to HTML with inline
+// styles. Consecutive cells with the same style are grouped into a single
+// span and trailing blank lines are trimmed.
+//
+// If full is set, a complete HTML document is returned, otherwise only a
+// <pre> fragment which can be embedded in another document.
+func RenderHTML(rows [][]Cell, theme Theme, fontFamily string, fontSize int, full bool) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		var line strings.Builder
+		var text strings.Builder
+		style := ""
+
+		flush := func() {
+			if text.Len() == 0 {
+				return
+			}
+			if style == "" {
+				line.WriteString(html.EscapeString(text.String()))
+			} else {
+				fmt.Fprintf(&line, `<span style="%s">%s</span>`, style, html.EscapeString(text.String()))
+			}
+			text.Reset()
+		}
+
+		for _, cell := range row {
+			cellStyle := cell.style(theme)
+			if cellStyle != style {
+				flush()
+				style = cellStyle
+			}
+			text.WriteString(cell.Char)
+		}
+		flush()
+
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	pre := fmt.Sprintf(`<pre style="background-color:%s;color:%s;font-family:%s;font-size:%dpx;padding:1em">%s</pre>`,
+		theme.Background, theme.Foreground, html.EscapeString(fontFamily), fontSize,
+		strings.Join(lines, "\n"))
+
+	if !full {
+		return pre + "\n"
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+</head>
+<body style="margin:0;background-color:%s">
+%s
+</body>
+</html>
+`, theme.Background, pre)
+}
+
+// SaveHTML saves the final screen of the terminal as HTML to the HTML output
+// file.
+func (v *VHS) SaveHTML() error {
+	rows, err := v.screenCells()
+	if err != nil {
+		return err
+	}
+
+	out := RenderHTML(rows, v.Options.Theme, v.Options.FontFamily, v.Options.FontSize, v.Options.HTML.Full)
+
+	if err := os.MkdirAll(filepath.Dir(v.Options.HTML.Output), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(v.Options.HTML.Output, []byte(out), 0o644) //nolint:gosec,gomnd
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	text := func(s string) []Cell {
+		var cells []Cell
+		for _, r := range s {
+			cells = append(cells, Cell{Char: string(r)})
+		}
+		return cells
+	}
+
+	rows := [][]Cell{
+		append(text("> "), Cell{Char: "o", FgMode: colorPalette, Fg: 1, Attrs: attrBold}, Cell{Char: "k", FgMode: colorPalette, Fg: 1, Attrs: attrBold}),
+		append(text("<a&b> "), Cell{Char: "x", FgMode: colorRGB, Fg: 0x5b56e0}),
+		text("      "),
+		text("   "),
+	}
+
+	got := RenderHTML(rows, DefaultTheme, "monospace", 22, false)
+	want := `<pre style="background-color:#171717;color:#dddddd;font-family:monospace;font-size:22px;padding:1em">&gt; <span style="color:#D74E6F;font-weight:bold">ok</span>
+&lt;a&amp;b&gt; <span style="color:#5b56e0">x</span></pre>
+`
+	if got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	full := RenderHTML(rows, DefaultTheme, "monospace", 22, true)
+	if !strings.HasPrefix(full, "<!DOCTYPE html>") || !strings.Contains(full, want) {
+		t.Fatalf("expected a full document wrapping the fragment, got:\n%s", full)
+	}
+}
+
+func TestPaletteColor(t *testing.T) {
+	tests := map[int]string{
+		1:   DefaultTheme.Red,
+		15:  DefaultTheme.BrightWhite,
+		16:  "#000000",
+		196: "#ff0000",
+		231: "#ffffff",
+		232: "#080808",
+		255: "#eeeeee",
+		256: "",
+	}
+	for i, want := range tests {
+		if got := paletteColor(i, DefaultTheme); got != want {
+			t.Errorf("palette color %d: want %q, got %q", i, want, got)
+		}
+	}
+}
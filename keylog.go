@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// isKeyCommand returns whether the command type presses keys on the terminal.
+func isKeyCommand(t CommandType) bool {
+	switch t {
+	case ALT, BACKSPACE, CTRL, DOWN, ENTER, ESCAPE, LEFT, RIGHT, SHIFT, SPACE, TAB, TYPE, UP:
+		return true
+	default:
+		return false
+	}
+}
+
+// LogKey writes the key command along with the time elapsed since the
+// recording started to the key log file specified with `Set KeyLog`.
+//
+// 1.25s Type ls
+// 1.5s Enter 1
+func (vhs *VHS) LogKey(c Command) {
+	if vhs.keyLog == nil {
+		if err := os.MkdirAll(filepath.Dir(vhs.Options.KeyLog), os.ModePerm); err != nil {
+			vhs.Errors = append(vhs.Errors, err)
+			return
+		}
+		f, err := os.Create(vhs.Options.KeyLog)
+		if err != nil {
+			vhs.Errors = append(vhs.Errors, err)
+			return
+		}
+		vhs.keyLog = f
+	}
+
+	elapsed := vhs.elapsed().Truncate(time.Millisecond)
+	_, _ = fmt.Fprintf(vhs.keyLog, "%s %s\n", elapsed, maskSecrets(c.String(), vhs.Options.Secrets))
+}
+
+// closeKeyLog closes the key log file, if one was opened.
+func (vhs *VHS) closeKeyLog() error {
+	if vhs.keyLog == nil {
+		return nil
+	}
+	return vhs.keyLog.Close()
+}
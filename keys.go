@@ -21,6 +21,59 @@ func shift(k input.Key) input.Key {
 	return k
 }
 
+// modifierKeys is the map of the modifiers of key chords to input.Keys.
+var modifierKeys = map[string]input.Key{
+	"Ctrl":  input.ControlLeft,
+	"Alt":   input.AltLeft,
+	"Shift": input.ShiftLeft,
+}
+
+// namedKeys is the map of the keys, other than characters, which can be
+// pressed along with modifiers, e.g. Ctrl+Left.
+var namedKeys = map[string]input.Key{
+	"Left":      input.ArrowLeft,
+	"Right":     input.ArrowRight,
+	"Up":        input.ArrowUp,
+	"Down":      input.ArrowDown,
+	"Enter":     input.Enter,
+	"Tab":       input.Tab,
+	"Backspace": input.Backspace,
+	"Escape":    input.Escape,
+	"Space":     input.Space,
+	"Delete":    input.Delete,
+	"Insert":    input.Insert,
+	"Home":      input.Home,
+	"End":       input.End,
+	"PageUp":    input.PageUp,
+	"PageDown":  input.PageDown,
+	"F1":        input.F1,
+	"F2":        input.F2,
+	"F3":        input.F3,
+	"F4":        input.F4,
+	"F5":        input.F5,
+	"F6":        input.F6,
+	"F7":        input.F7,
+	"F8":        input.F8,
+	"F9":        input.F9,
+	"F10":       input.F10,
+	"F11":       input.F11,
+	"F12":       input.F12,
+}
+
+// chordKey returns the input.Key of the key of a chord, which is either a
+// single character or one of the named keys.
+func chordKey(name string) (input.Key, bool) {
+	if k, ok := namedKeys[name]; ok {
+		return k, true
+	}
+	r := []rune(name)
+	if len(r) != 1 {
+		return 0, false
+	}
+	k, ok := keymap[r[0]]
+	return k, ok
+}
+
 // keymap is the map of runes to input.Keys.
 // It is used to convert a string to the correct set of input.Keys for go-rod.
 var keymap = map[rune]input.Key{
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// LintOptions are the thresholds above which a tape is considered slow or
+// wasteful.
+type LintOptions struct {
+	// MaxSleep is the longest total time of the Sleep commands.
+	MaxSleep time.Duration
+	// MaxPixels is the largest number of pixels of the frames.
+	MaxPixels int
+	// MaxTypingSpeed is the longest pause between two key presses.
+	MaxTypingSpeed time.Duration
+}
+
+// DefaultLintOptions are the thresholds of vhs lint.
+var DefaultLintOptions = LintOptions{
+	MaxSleep:       time.Minute,
+	MaxPixels:      1920 * 1080, //nolint:gomnd
+	MaxTypingSpeed: 500 * time.Millisecond,
+}
+
+// The rules of vhs lint.
+const (
+	lintMaxSleep       = "max-sleep"
+	lintMissingOutput  = "missing-output"
+	lintMaxPixels      = "max-pixels"
+	lintMaxTypingSpeed = "max-typing-speed"
+)
+
+// LintWarning is a smell found in a tape by vhs lint.
+type LintWarning struct {
+	Rule    string `json:"rule"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%d: %s (%s)", w.Line, w.Message, w.Rule)
+}
+
+// Lint returns the warnings for the commands of a tape which make it slow to
+// record or its outputs large. The tokens are those of the commands, as
+// returned by the parser, to report their lines.
+func Lint(cmds []Command, tokens []Token, opts LintOptions) []LintWarning {
+	var warnings []LintWarning
+	warn := func(i int, rule, msg string) {
+		line := 1
+		if i >= 0 && i < len(tokens) {
+			line = tokens[i].Line
+		}
+		warnings = append(warnings, LintWarning{Rule: rule, Line: line, Message: msg})
+	}
+
+	width, height := defaultWidth, defaultHeight
+	var sleep time.Duration
+	var hasOutput bool
+	for i, cmd := range cmds {
+		switch cmd.Type {
+		case OUTPUT:
+			hasOutput = true
+		case SLEEP:
+			d, err := time.ParseDuration(cmd.Args)
+			if err != nil {
+				continue
+			}
+			if sleep <= opts.MaxSleep && sleep+d > opts.MaxSleep {
+				warn(i, lintMaxSleep, fmt.Sprintf("the tape sleeps for more than %s in total", opts.MaxSleep))
+			}
+			sleep += d
+		case TYPE:
+			if d, err := time.ParseDuration(cmd.Options); err == nil && d > opts.MaxTypingSpeed {
+				warn(i, lintMaxTypingSpeed, fmt.Sprintf("typing speed of %s is slower than %s", d, opts.MaxTypingSpeed))
+			}
+		case SET:
+			switch cmd.Options {
+			case "TypingSpeed":
+				if d, err := time.ParseDuration(cmd.Args); err == nil && d > opts.MaxTypingSpeed {
+					warn(i, lintMaxTypingSpeed, fmt.Sprintf("typing speed of %s is slower than %s", d, opts.MaxTypingSpeed))
+				}
+			case "Width", "Height":
+				n, err := strconv.Atoi(cmd.Args)
+				if err != nil {
+					continue
+				}
+				before := width * height
+				if cmd.Options == "Width" {
+					width = n
+				} else {
+					height = n
+				}
+				if before <= opts.MaxPixels && width*height > opts.MaxPixels {
+					warn(i, lintMaxPixels, fmt.Sprintf("frames of %dx%d are larger than %d pixels", width, height, opts.MaxPixels))
+				}
+			}
+		}
+	}
+
+	if !hasOutput {
+		warn(-1, lintMissingOutput, "the tape has no Output, so it is rendered to out.gif")
+	}
+	return warnings
+}
+
+var (
+	lintOptions = DefaultLintOptions
+	lintCmd     = &cobra.Command{
+		Use:   "lint <file>...",
+		Short: "Warn about tapes which are slow to record or render large outputs",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vars, err := parseVars(varFlags)
+			if err != nil {
+				return err
+			}
+
+			valid := true
+			warnings := 0
+			for _, file := range args {
+				b, err := os.ReadFile(file)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, ErrorFileStyle.Render(file))
+					fmt.Fprintln(os.Stderr, ErrorStyle.Render(err.Error()))
+					valid = false
+					continue
+				}
+
+				p := NewParser(NewLexer(string(b)), WithParserVars(vars))
+				cmds := p.Parse()
+				if errs := p.Errors(); len(errs) > 0 {
+					fmt.Fprintln(os.Stderr, ErrorFileStyle.Render(file))
+					for _, err := range errs {
+						printParserError(os.Stderr, string(b), err)
+					}
+					valid = false
+					continue
+				}
+
+				for _, w := range Lint(cmds, p.Tokens(), lintOptions) {
+					fmt.Fprintln(os.Stderr, WarningStyle.Render(file+":"+w.String()))
+					warnings++
+				}
+			}
+
+			if !valid {
+				return errors.New("invalid tape file(s)")
+			}
+			if strict && warnings > 0 {
+				return fmt.Errorf("%d warning(s) in strict mode", warnings)
+			}
+			return nil
+		},
+	}
+)
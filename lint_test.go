@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	tape := `Set Width 3000
+Set Height 1200
+Set TypingSpeed 1s
+Type@2s "hello"
+Quiet { Sleep 50s }
+Sleep 20s
+Sleep 1s`
+
+	p := NewParser(NewLexer(tape))
+	cmds := p.Parse()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	got := Lint(cmds, p.Tokens(), DefaultLintOptions)
+	want := []LintWarning{
+		{Rule: lintMaxPixels, Line: 2, Message: "frames of 3000x1200 are larger than 2073600 pixels"},
+		{Rule: lintMaxTypingSpeed, Line: 3, Message: "typing speed of 1s is slower than 500ms"},
+		{Rule: lintMaxTypingSpeed, Line: 4, Message: "typing speed of 2s is slower than 500ms"},
+		{Rule: lintMaxSleep, Line: 6, Message: "the tape sleeps for more than 1m0s in total"},
+		{Rule: lintMissingOutput, Line: 1, Message: "the tape has no Output, so it is rendered to out.gif"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v", want, got)
+	}
+
+	p = NewParser(NewLexer("Output demo.gif\nSleep 2s"))
+	cmds = p.Parse()
+	opts := DefaultLintOptions
+	opts.MaxSleep = time.Second
+	got = Lint(cmds, p.Tokens(), opts)
+	want = []LintWarning{{Rule: lintMaxSleep, Line: 2, Message: "the tape sleeps for more than 1s in total"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v", want, got)
+	}
+}
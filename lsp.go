@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server for tape files on stdin and stdout",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return NewLanguageServer(os.Stdin, os.Stdout).Run()
+	},
+}
+
+// The kinds of the diagnostics and the completion items of the language
+// server protocol.
+const (
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+
+	lspCompletionProperty = 10
+	lspCompletionValue    = 12
+	lspCompletionKeyword  = 14
+
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+// LanguageServer is a minimal language server for tape files. It publishes
+// the errors of the parser as diagnostics, completes the commands, the
+// settings and the themes, and shows the syntax of the commands and settings
+// on hover.
+type LanguageServer struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]string
+	shutdown bool
+}
+
+// NewLanguageServer returns a language server which reads the messages of the
+// client from in and writes its own to out.
+func NewLanguageServer(in io.Reader, out io.Writer) *LanguageServer {
+	return &LanguageServer{in: bufio.NewReader(in), out: out, docs: map[string]string{}}
+}
+
+type lspRequest struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspCompletionItem struct {
+	Label      string `json:"label"`
+	Kind       int    `json:"kind"`
+	Detail     string `json:"detail,omitempty"`
+	InsertText string `json:"insertText,omitempty"`
+}
+
+type lspTextDocumentParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+	Position lspPosition `json:"position"`
+}
+
+// Run handles the messages of the client until it exits.
+func (s *LanguageServer) Run() error {
+	for {
+		req, err := s.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("the client exited without shutting down the server")
+			}
+			return nil
+		}
+
+		result, code, err := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if err != nil {
+			resp["error"] = map[string]interface{}{"code": code, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle returns the result of a request, or the code of its error. The
+// results of the notifications are ignored.
+func (s *LanguageServer) handle(req lspRequest) (interface{}, int, error) {
+	var params lspTextDocumentParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, lspInvalidParams, err
+		}
+	}
+	uri := params.TextDocument.URI
+
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1,
+				"completionProvider": map[string]interface{}{"triggerCharacters": []string{" "}},
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]string{"name": "vhs", "version": Version},
+		}, 0, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, 0, nil
+	case "textDocument/didOpen":
+		s.docs[uri] = params.TextDocument.Text
+		return nil, 0, s.publishDiagnostics(uri)
+	case "textDocument/didChange":
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[uri] = params.ContentChanges[n-1].Text
+		}
+		return nil, 0, s.publishDiagnostics(uri)
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+		return nil, 0, s.publishDiagnostics(uri)
+	case "textDocument/completion":
+		return lspCompletion(s.docs[uri], params.Position), 0, nil
+	case "textDocument/hover":
+		return lspHover(s.docs[uri], params.Position), 0, nil
+	default:
+		return nil, lspMethodNotFound, fmt.Errorf("method %s not found", req.Method)
+	}
+}
+
+// publishDiagnostics sends the errors and warnings of the parser for the
+// document, or none once it is closed.
+func (s *LanguageServer) publishDiagnostics(uri string) error {
+	diagnostics := []lspDiagnostic{}
+	if tape, ok := s.docs[uri]; ok {
+		diagnostics = lspDiagnostics(tape)
+	}
+	return s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/publishDiagnostics",
+		"params":  map[string]interface{}{"uri": uri, "diagnostics": diagnostics},
+	})
+}
+
+// lspDiagnostics returns the errors and warnings of the parser for the tape.
+func lspDiagnostics(tape string) []lspDiagnostic {
+	p := NewParser(NewLexer(tape))
+	p.Parse()
+
+	diagnostics := []lspDiagnostic{}
+	add := func(errs []ParserError, severity int) {
+		for _, err := range errs {
+			start := lspPosition{Line: err.Token.Line - 1, Character: err.Token.Column - 1}
+			end := start
+			end.Character += len(err.Token.Literal)
+			if end.Character == start.Character {
+				end.Character++
+			}
+			diagnostics = append(diagnostics, lspDiagnostic{
+				Range:    lspRange{Start: start, End: end},
+				Severity: severity,
+				Source:   "vhs",
+				Message:  err.Msg,
+			})
+		}
+	}
+	add(p.Errors(), lspSeverityError)
+	add(p.Warnings(), lspSeverityWarning)
+	return diagnostics
+}
+
+// lspCompletion completes the word before the position: a command at the
+// start of a line, a setting after Set or a theme after Set Theme.
+func lspCompletion(tape string, pos lspPosition) []lspCompletionItem {
+	prefix := lspLine(tape, pos.Line)
+	if pos.Character < len(prefix) {
+		prefix = prefix[:pos.Character]
+	}
+	words := strings.Fields(prefix)
+	// The index of the word being completed.
+	n := len(words)
+	if n > 0 && !strings.HasSuffix(prefix, " ") && !strings.HasSuffix(prefix, "\t") {
+		n--
+	}
+
+	usage := manUsage()
+	items := []lspCompletionItem{}
+	switch {
+	case n == 0:
+		for _, name := range sortedKeys(usage.commands) {
+			items = append(items, lspCompletionItem{Label: name, Kind: lspCompletionKeyword, Detail: usage.commands[name]})
+		}
+	case n == 1 && words[0] == "Set":
+		for _, name := range sortedKeys(usage.settings) {
+			items = append(items, lspCompletionItem{Label: name, Kind: lspCompletionProperty, Detail: usage.settings[name]})
+		}
+	case n == 2 && words[0] == "Set" && words[1] == "Theme":
+		themes, _ := sortedThemeNames()
+		for _, theme := range themes {
+			item := lspCompletionItem{Label: theme, Kind: lspCompletionValue}
+			if strings.ContainsAny(theme, " \t") {
+				item.InsertText = strconv.Quote(theme)
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// lspHover shows the syntax of the command or the setting at the position.
+func lspHover(tape string, pos lspPosition) interface{} {
+	line := lspLine(tape, pos.Line)
+	if pos.Character > len(line) {
+		return nil
+	}
+	start, end := pos.Character, pos.Character
+	for start > 0 && isLetter(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isLetter(line[end]) {
+		end++
+	}
+	word := line[start:end]
+	if word == "" {
+		return nil
+	}
+
+	usage := manUsage()
+	doc, ok := usage.commands[word]
+	if before := strings.Fields(line[:start]); len(before) == 1 && before[0] == "Set" {
+		doc, ok = usage.settings[word]
+	}
+	if !ok {
+		return nil
+	}
+	return map[string]interface{}{
+		"contents": map[string]string{"kind": "markdown", "value": "```\n" + doc + "\n```"},
+	}
+}
+
+// lspLine returns the line of the tape, without its line ending.
+func lspLine(tape string, n int) string {
+	lines := strings.Split(tape, "\n")
+	if n < 0 || n >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[n], "\r")
+}
+
+// usage is the syntax of the commands and the settings, by name.
+type usage struct {
+	commands map[string]string
+	settings map[string]string
+}
+
+// manUsage returns the syntax of the commands and the settings from the
+// manual, so that they are documented in a single place.
+func manUsage() usage {
+	u := usage{commands: map[string]string{}, settings: map[string]string{}}
+	parse := func(manual string, names map[string]string) {
+		for _, line := range strings.Split(manual, "\n") {
+			if !strings.HasPrefix(line, "* ") {
+				continue
+			}
+			line = strings.TrimPrefix(line, "* ")
+			parts := strings.Split(line, specialChar)
+			if len(parts) < 3 { //nolint:gomnd
+				continue
+			}
+			if _, ok := names[parts[1]]; !ok {
+				names[parts[1]] = sanitizeSpecial(line)
+			}
+		}
+	}
+	parse(manDescription, u.commands)
+	parse(manSettings, u.settings)
+	return u
+}
+
+// sortedKeys returns the keys of the map in order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// read reads the next message of the client.
+func (s *LanguageServer) read() (lspRequest, error) {
+	var req lspRequest
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return req, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return req, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return req, err
+	}
+	return req, json.Unmarshal(body, &req)
+}
+
+// write writes a message to the client.
+func (s *LanguageServer) write(msg interface{}) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+// isLetter returns whether a character is a letter, as in the keywords.
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+}
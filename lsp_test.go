@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLanguageServer(t *testing.T) {
+	var in bytes.Buffer
+	send := func(msg string) {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	send(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///demo.tape","text":"Set FontSize 32\nFoo\nSet Fon"}}}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"textDocument/completion","params":{"textDocument":{"uri":"file:///demo.tape"},"position":{"line":2,"character":7}}}`)
+	send(`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///demo.tape"},"position":{"line":0,"character":6}}}`)
+	send(`{"jsonrpc":"2.0","id":4,"method":"textDocument/definition","params":{}}`)
+	send(`{"jsonrpc":"2.0","id":5,"method":"shutdown"}`)
+	send(`{"jsonrpc":"2.0","method":"exit"}`)
+
+	var out bytes.Buffer
+	if err := NewLanguageServer(&in, &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := readLSPMessages(t, out.String())
+	if len(responses) != 6 {
+		t.Fatalf("expected 6 messages, got %d: %s", len(responses), out.String())
+	}
+
+	var diagnostics struct {
+		Params struct {
+			Diagnostics []lspDiagnostic `json:"diagnostics"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(responses[1], &diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics.Params.Diagnostics) == 0 || diagnostics.Params.Diagnostics[0].Range.Start.Line != 1 {
+		t.Errorf("expected a diagnostic on the second line, got %+v", diagnostics.Params.Diagnostics)
+	}
+
+	var completion struct {
+		Result []lspCompletionItem `json:"result"`
+	}
+	if err := json.Unmarshal(responses[2], &completion); err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, item := range completion.Result {
+		labels = append(labels, item.Label)
+	}
+	if !strings.Contains(strings.Join(labels, ","), "FontFamily") {
+		t.Errorf("expected the settings to be completed, got %v", labels)
+	}
+
+	if !strings.Contains(string(responses[3]), `Set FontSize \u003cnumber\u003e`) {
+		t.Errorf("expected the syntax of FontSize on hover, got %s", responses[3])
+	}
+	if !strings.Contains(string(responses[4]), `"code":-32601`) {
+		t.Errorf("expected an unknown method to fail, got %s", responses[4])
+	}
+	if !strings.Contains(string(responses[5]), `"result":null`) {
+		t.Errorf("expected a null result for shutdown, got %s", responses[5])
+	}
+}
+
+// readLSPMessages splits the messages written by the language server.
+func readLSPMessages(t *testing.T, out string) []json.RawMessage {
+	t.Helper()
+	var msgs []json.RawMessage
+	r := bufio.NewReader(strings.NewReader(out))
+	for {
+		var length int
+		if _, err := fmt.Fscanf(r, "Content-Length: %d\r\n\r\n", &length); err != nil {
+			return msgs
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, body)
+	}
+}
@@ -3,22 +3,28 @@ package main
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"syscall"
 
+	"github.com/charmbracelet/vhs/parser"
 	version "github.com/hashicorp/go-version"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
-const extension = ".tape"
+const extension = parser.Extension
 
 var (
 	// Version stores the build version of VHS at the time of packaging through -ldflags
@@ -31,28 +37,99 @@ var (
 
 	ttydMinVersion = version.Must(version.NewVersion("1.7.2"))
 
-	publish bool
-	rootCmd = &cobra.Command{
-		Use:           "vhs <file>",
+	publish          bool
+	open             bool
+	openAll          bool
+	stdin            bool
+	compose          []string
+	outputFlags      []string
+	varFlags         []string
+	envFlags         []string
+	backendFlag      string
+	sshFlag          string
+	containerFlag    string
+	noDepsCheck      bool
+	skipVersionCheck bool
+	verbose          bool
+	jobs             int
+	dryRun           bool
+	deterministic    bool
+	quietFlag        bool
+	progressFlag     string
+	maxSizeFlag      string
+	stdoutFlag       bool
+	noCache          bool
+	watchFlag        bool
+	noColorFlag      bool
+	colorFlag        bool
+	rootCmd          = &cobra.Command{
+		Use:           "vhs <file>...",
 		Short:         "Run a given tape file and generates its outputs.",
-		Args:          cobra.MaximumNArgs(1),
+		Args:          cobra.ArbitraryArgs,
 		SilenceUsage:  true,
 		SilenceErrors: true, // we print our own errors
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			tty := isInteractive(os.Stdout) && isInteractive(os.Stderr)
+			setColor(colorEnabled(noColorFlag, colorFlag, os.Getenv("NO_COLOR"), tty), tty)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := ensureDependencies()
+			if len(compose) > 0 {
+				return runCompose(cmd, args)
+			}
+			vars, err := parseVars(varFlags)
 			if err != nil {
 				return err
 			}
+			if err := checkEnv(envFlags); err != nil {
+				return err
+			}
+			if err := checkRemote(sshFlag, containerFlag); err != nil {
+				return err
+			}
+			if progressFlag != progressFormatBar && progressFlag != progressFormatJSON {
+				return fmt.Errorf("invalid --progress %s: expected bar or json", progressFlag)
+			}
+			if watchFlag {
+				return runWatch(cmd, args, vars)
+			}
+			args, err = expandTapes(args)
+			if err != nil {
+				return err
+			}
+			if len(args) > 1 {
+				return runBatch(cmd, args, vars)
+			}
+
+			fromStdin := stdin || (len(args) > 0 && args[0] == "-")
+			if stdin && len(args) > 0 && args[0] != "-" {
+				return errors.New("--stdin can't be used with a tape file")
+			}
+
+			// Without a tape file, reading from an interactive terminal
+			// would wait for input forever, so show how to use VHS instead.
+			if len(args) == 0 && !stdin && isInteractive(cmd.InOrStdin()) {
+				return cmd.Help()
+			}
+
+			// Nothing is executed in a dry run, so ffmpeg and ttyd are not
+			// needed.
+			if !dryRun && !skipDependencyCheck() {
+				if err = ensureDependencies(); err != nil {
+					return err
+				}
+			}
 
 			in := cmd.InOrStdin()
 			// Set the input to the file contents if a file is given
 			// otherwise, use stdin
-			if len(args) > 0 && args[0] != "-" {
+			if len(args) > 0 && !fromStdin {
 				in, err = os.Open(args[0])
 				if err != nil {
 					return err
 				}
-				fmt.Println(FileStyle.Render("File: " + args[0]))
+				if !dryRun {
+					fmt.Fprintln(logOutput(), FileStyle.Render("File: "+args[0]))
+				}
 			}
 
 			input, err := io.ReadAll(in)
@@ -63,23 +140,110 @@ var (
 				return errors.New("no input provided")
 			}
 
+			if dryRun {
+				if errs := DryRun(string(input), vars, os.Stdout); len(errs) > 0 {
+					printErrors(os.Stderr, string(input), errs)
+					return errors.New("invalid tape file")
+				}
+				return nil
+			}
+
+			opts, err := evaluatorOptions(vars)
+			if err != nil {
+				return err
+			}
+			if stdoutFlag {
+				switch {
+				case publish:
+					return errors.New("--publish can't be used with --stdout, as there is no file to upload")
+				case open, openAll:
+					return errors.New("--open can't be used with --stdout, as there is no file to open")
+				case len(outputFlags) > 0:
+					return errors.New("--output can't be used with --stdout")
+				}
+				opts = append(opts, WithFrameStream(os.Stdout))
+			}
+			if len(outputFlags) > 0 {
+				override, err := WithOutputs(outputFlags)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, override)
+			}
+			// Check the publish destination before rendering, rather than
+			// failing once the GIF is rendered.
+			var publisher Publisher
+			if publish {
+				if publisher, err = configuredPublisher(); err != nil {
+					return err
+				}
+			}
+
+			// The progress is drawn on stderr, so that stdout only has the log
+			// of the commands and the URL of the published GIF.
+			stdout := logOutput()
+			var done func()
+			switch {
+			case quietFlag:
+			case progressFlag == progressFormatJSON:
+				events := newJSONProgress(os.Stderr)
+				done = events.Done
+				opts = append(opts, WithProgress(events.Report))
+			default:
+				bar := newProgressBar(os.Stderr, isInteractive(os.Stdout))
+				done = bar.Done
+				stdout = bar.Writer(stdout)
+				opts = append(opts, WithProgress(bar.Report))
+			}
+
 			var output string
-			errs := Evaluate(cmd.Context(), string(input), os.Stdout, func(v *VHS) {
+			var outputs []string
+			var cache *RenderCache
+			if !stdoutFlag {
+				cache = renderCache()
+			}
+			cached, errs := cache.Evaluate(cmd.Context(), string(input), stdout, append(opts, func(v *VHS) {
 				output = v.Options.Video.Output.GIF
-			})
+				outputs = []string{
+					v.Options.Video.Output.GIF,
+					v.Options.Video.Output.MP4,
+					v.Options.Video.Output.WebM,
+					v.Options.Video.Output.WebP,
+					v.Options.Video.Output.APNG,
+					v.Options.Video.Output.PNG,
+				}
+			})...)
 			if len(errs) > 0 {
 				printErrors(os.Stderr, string(input), errs)
 				return errors.New("recording failed")
 			}
+			if done != nil {
+				done()
+			}
+			if cached != nil {
+				outputs = cached
+				for _, o := range cached {
+					if filepath.Ext(o) == ".gif" {
+						output = o
+						break
+					}
+				}
+			}
 
 			if publish && output != "" {
-				url, err := Publish(cmd.Context(), output)
+				ctx, cancel := publishContext(cmd.Context())
+				defer cancel()
+				url, err := Publish(ctx, publisher, output)
 				if err != nil {
 					return err
 				}
 				fmt.Println(StringStyle.Render("URL: " + url))
 			}
 
+			if open || openAll {
+				openOutputs(outputs, openAll)
+			}
+
 			return nil
 		},
 	}
@@ -106,8 +270,46 @@ var (
 		},
 	}
 
-	shell     string
-	recordCmd = &cobra.Command{
+	themeOutput     string
+	themesExportCmd = &cobra.Command{
+		Use:   "export <name>",
+		Short: "Print the colors of a theme as JSON, to customize it with Set Theme { ... }",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bts, err := ExportTheme(args[0])
+			if err != nil {
+				return err
+			}
+			if themeOutput == "" {
+				_, err = cmd.OutOrStdout().Write(bts)
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(themeOutput), os.ModePerm); err != nil {
+				return err
+			}
+			return os.WriteFile(themeOutput, bts, 0o644) //nolint:gosec,gomnd
+		},
+	}
+
+	themesAddCmd = &cobra.Command{
+		Use:   "add <file>.json",
+		Short: "Add a theme, in the format of Set Theme { ... }, to use it by name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := AddTheme(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added theme %q, use it with Set Theme %q\n", name, name)
+			return nil
+		},
+	}
+
+	shell        string
+	noTiming     bool
+	recordOutput string
+	keepTape     bool
+	recordCmd    = &cobra.Command{
 		Use:   "record",
 		Short: "Create a new tape file by recording your actions",
 		Args:  cobra.NoArgs,
@@ -137,39 +339,117 @@ var (
 		},
 	}
 
+	catCmd = &cobra.Command{
+		Use:   "cat <file>...",
+		Short: "Print tape files with syntax highlighting",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for i, file := range args {
+				b, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				if len(args) > 1 {
+					if i > 0 {
+						fmt.Fprintln(cmd.OutOrStdout())
+					}
+					fmt.Fprintln(cmd.OutOrStdout(), FileStyle.Render(file))
+				}
+				fmt.Fprint(cmd.OutOrStdout(), HighlightTape(string(b)))
+			}
+			return nil
+		},
+	}
+
+	schemaCmd = &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the output of validate --json",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := Schema()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+			return nil
+		},
+	}
+
+	strict      bool
+	maxWarnings int
+	jsonOutput  bool
 	validateCmd = &cobra.Command{
 		Use:   "validate <file>...",
 		Short: "Validate a glob file path and parses all the files to ensure they are valid without running them.",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			vars, err := parseVars(varFlags)
+			if err != nil {
+				return err
+			}
+
 			valid := true
+			warnings := 0
+			docs := make([]TapeDocument, 0, len(args))
 
 			for _, file := range args {
 
 				b, err := os.ReadFile(file)
 				if err != nil {
+					if jsonOutput {
+						valid = false
+						docs = append(docs, TapeDocument{
+							File:     file,
+							Commands: []TapeCommand{},
+							Errors:   []Diagnostic{{Message: err.Error()}},
+							Warnings: []Diagnostic{},
+						})
+					}
 					continue
 				}
 
 				l := NewLexer(string(b))
-				p := NewParser(l)
+				p := NewParser(l, WithParserVars(vars))
 
-				_ = p.Parse()
+				cmds := p.Parse()
 				errs := p.Errors()
+				warns := p.Warnings()
 
-				if len(errs) != 0 {
+				if jsonOutput {
+					docs = append(docs, NewTapeDocument(file, cmds, p.Tokens(), errs, warns))
+				} else if len(errs) != 0 || len(warns) != 0 {
 					fmt.Println(ErrorFileStyle.Render(file))
 
 					for _, err := range errs {
 						printParserError(os.Stderr, string(b), err)
 					}
+					for _, warn := range warns {
+						printParserWarning(os.Stderr, string(b), warn)
+					}
+				}
+				if len(errs) != 0 {
 					valid = false
 				}
+				warnings += len(warns)
+			}
+
+			if jsonOutput {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(docs); err != nil {
+					return err
+				}
 			}
 
 			if !valid {
 				return errors.New("invalid tape file(s)")
 			}
+			if strict && warnings > 0 {
+				return fmt.Errorf("%d warning(s) in strict mode", warnings)
+			}
+			if maxWarnings >= 0 && warnings > maxWarnings {
+				return fmt.Errorf("%d warning(s) exceed the maximum of %d", warnings, maxWarnings)
+			}
 
 			return nil
 		},
@@ -183,6 +463,7 @@ func main() {
 	)
 	defer cancel()
 
+	addDataBinDirToPath()
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -191,17 +472,90 @@ func main() {
 
 func init() {
 	rootCmd.Flags().BoolVarP(&publish, "publish", "p", false, "publish your GIF to vhs.charm.sh and get a shareable URL")
+	rootCmd.Flags().StringVar(&publishTo, "publish-to", "", "publish to s3://bucket/prefix, gs://bucket/prefix or the http(s) URL of a directory instead of vhs.charm.sh")
+	rootCmd.Flags().DurationVar(&publishTimeout, "publish-timeout", defaultPublishTimeout, "give up publishing after this long, including the retries (0 for no limit)")
+	rootCmd.Flags().BoolVar(&open, "open", false, "open the first output with the default viewer after rendering")
+	rootCmd.Flags().BoolVar(&stdin, "stdin", false, "read the tape from stdin, same as passing - as the file")
+	rootCmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a variable of the tape, e.g. --var VERSION=1.0.0, or --var TOKEN from the environment (repeatable)")
+	rootCmd.Flags().StringArrayVar(&envFlags, "env", nil, "set an environment variable of the shell, e.g. --env NO_COLOR=1 (repeatable)")
+	rootCmd.Flags().StringVar(&backendFlag, "backend", defaultBackend, "terminal backend which runs the shell")
+	rootCmd.Flags().StringVar(&sshFlag, "ssh", "", "run the shell on this host over SSH, as [user@]host")
+	rootCmd.Flags().StringVar(&containerFlag, "container", "", "run the shell in a container of this image, with Docker or Podman")
+	rootCmd.Flags().StringArrayVarP(&outputFlags, "output", "o", nil, "render to this output instead of the ones of the tape (repeatable)")
+	rootCmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "number of tapes rendered at the same time when several tapes are given")
+	rootCmd.Flags().IntVar(&jobs, "concurrency", runtime.NumCPU(), "same as --jobs")
+	_ = rootCmd.Flags().MarkHidden("concurrency")
+	rootCmd.Flags().StringSliceVar(&compose, "compose", nil, "render two tapes side by side into the output given as argument, e.g. --compose a.tape:left,b.tape:right out.gif")
+	rootCmd.Flags().BoolVar(&noDepsCheck, "no-deps-check", false, "skip checking that ffmpeg and ttyd are installed (also VHS_NO_DEPS_CHECK)")
+	rootCmd.Flags().BoolVar(&skipVersionCheck, "skip-version-check", false, "skip checking the version of ttyd")
+	rootCmd.Flags().BoolVar(&verbose, "verbose", false, "print the detected versions of the dependencies")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the commands of the tape and their timing without recording")
+	rootCmd.Flags().StringVar(&maxSizeFlag, "max-size", "", "reduce the GIF until it fits in this size, e.g. 5MB, overriding Set MaxFileSize")
+	rootCmd.Flags().BoolVar(&deterministic, "deterministic", false, "capture the frames on a virtual clock so that recordings are reproducible")
+	rootCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "don't show the progress of the recording")
+	rootCmd.Flags().StringVar(&progressFlag, "progress", progressFormatBar, "format of the progress on stderr: bar, or json for one event per line")
+	rootCmd.Flags().BoolVar(&stdoutFlag, "stdout", false, "write the frames to stdout as concatenated PNG images instead of the outputs of the tape")
+	rootCmd.Flags().BoolVarP(&watchFlag, "watch", "w", false, "record the tape again every time it changes, same as vhs watch")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "render the tapes even if their outputs are in the cache")
+	rootCmd.PersistentFlags().BoolVar(&noColorFlag, "no-color", false, "don't color the output (also NO_COLOR)")
+	rootCmd.PersistentFlags().BoolVar(&colorFlag, "color", false, "color the output even when it isn't a terminal")
+	rootCmd.Flags().BoolVar(&openAll, "open-all", false, "open every output with the default viewer after rendering")
+	runCmd.Flags().IntVarP(&runJobs, "jobs", "j", 0, "number of tapes rendered at the same time (defaults to the jobs of the manifest, or 1)")
+	runCmd.Flags().BoolVar(&noCache, "no-cache", false, "render the tapes even if their outputs are in the cache")
+	runCmd.Flags().StringVar(&runReport, "report", "", "write the summary as JSON to this file, instead of the report of the manifest")
+	doctorCmd.Flags().BoolVar(&doctorInstall, "install", false, "install the missing programs which have a static build, such as ttyd")
 	themesCmd.Flags().BoolVar(&markdown, "markdown", false, "output as markdown")
 	_ = themesCmd.Flags().MarkHidden("markdown")
-	recordCmd.Flags().StringVarP(&shell, "shell", "s", "bash", "shell for recording")
+	publishCmd.Flags().StringVar(&publishTo, "to", "", "publish to s3://bucket/prefix, gs://bucket/prefix or the http(s) URL of a directory instead of vhs.charm.sh")
+	publishCmd.Flags().DurationVar(&publishTimeout, "timeout", defaultPublishTimeout, "give up publishing after this long, including the retries (0 for no limit)")
+	themesExportCmd.Flags().StringVarP(&themeOutput, "output", "o", "", "write the theme to this file instead of stdout")
+	themesPreviewCmd.Flags().StringVarP(&previewDir, "output", "o", defaultPreviewDir, "directory of the previews and their gallery")
+	themesPreviewCmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "number of themes rendered at the same time")
+	themesImportCmd.Flags().StringVar(&importName, "name", "", "name of the imported theme, instead of the one of the file")
+	themesCmd.AddCommand(themesExportCmd, themesAddCmd, themesImportCmd, themesPreviewCmd)
+	validateCmd.Flags().BoolVar(&strict, "strict", false, "treat warnings as errors")
+	validateCmd.Flags().IntVar(&maxWarnings, "max-warnings", -1, "fail if there are more than this many warnings (-1 for no limit)")
+	validateCmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a variable of the tape, e.g. --var VERSION=1.0.0, or --var TOKEN from the environment (repeatable)")
+	watchCmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a variable of the tape, e.g. --var VERSION=1.0.0, or --var TOKEN from the environment (repeatable)")
+	watchCmd.Flags().StringVar(&backendFlag, "backend", defaultBackend, "terminal backend which runs the shell")
+	watchCmd.Flags().StringVar(&sshFlag, "ssh", "", "run the shell on this host over SSH, as [user@]host")
+	watchCmd.Flags().StringVar(&containerFlag, "container", "", "run the shell in a container of this image, with Docker or Podman")
+	watchCmd.Flags().StringArrayVar(&envFlags, "env", nil, "set an environment variable of the shell, e.g. --env NO_COLOR=1 (repeatable)")
+	lintCmd.Flags().BoolVar(&strict, "strict", false, "exit with an error if there are warnings")
+	lintCmd.Flags().DurationVar(&lintOptions.MaxSleep, "max-sleep", DefaultLintOptions.MaxSleep, "warn if the tape sleeps for longer than this in total")
+	lintCmd.Flags().IntVar(&lintOptions.MaxPixels, "max-pixels", DefaultLintOptions.MaxPixels, "warn if the frames have more pixels than this (width × height)")
+	lintCmd.Flags().DurationVar(&lintOptions.MaxTypingSpeed, "max-typing-speed", DefaultLintOptions.MaxTypingSpeed, "warn if the typing speed is slower than this")
+	lintCmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a variable of the tape, e.g. --var VERSION=1.0.0, or --var TOKEN from the environment (repeatable)")
+	formatCmd.Flags().BoolVar(&formatCheck, "check", false, "list the tape files which aren't formatted, without rewriting them")
+	parseCmd.Flags().StringVar(&parseFormat, "format", parseFormatJSON, "format of the commands: json or text")
+	parseCmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a variable of the tape, e.g. --var VERSION=1.0.0, or --var TOKEN from the environment (repeatable)")
+	validateCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the parsed tapes and their errors as JSON")
+	recordCmd.Flags().StringVarP(&shell, "shell", "s", "", "shell for recording: bash, zsh, fish or pwsh (defaults to $SHELL)")
+	recordCmd.Flags().BoolVar(&noTiming, "no-timing", false, "don't keep the pauses and the typing speed of the recording")
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "", "render the recording to this output instead of printing its tape, e.g. demo.gif")
+	recordCmd.Flags().BoolVar(&keepTape, "keep-tape", false, "write the tape of the recording next to the output of --output")
+	renderCmd.Flags().StringVar(&repoOptions.URL, "from-repo", "", "URL of the repository to render")
+	renderCmd.Flags().StringVar(&repoOptions.Ref, "ref", "HEAD", "branch, tag or commit of the repository")
+	renderCmd.Flags().StringVar(&repoOptions.Path, "path", ".", "directory of the repository with the tapes")
+	renderCmd.Flags().StringVar(&repoOptions.Artifacts, "artifacts", "vhs-artifacts", "directory where the outputs of the tapes are copied")
 	rootCmd.AddCommand(
 		recordCmd,
 		newCmd,
 		themesCmd,
 		validateCmd,
+		parseCmd,
+		formatCmd,
+		lintCmd,
+		lspCmd,
+		catCmd,
+		schemaCmd,
 		manCmd,
 		serveCmd,
+		renderCmd,
+		watchCmd,
 		publishCmd,
+		doctorCmd,
+		runCmd,
 	)
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
 
@@ -219,7 +573,7 @@ func init() {
 	rootCmd.Version = Version
 }
 
-var versionRegex = regexp.MustCompile(`\d+\.\d+\.\d+`)
+var versionRegex = regexp.MustCompile(`\d+(\.\d+){1,2}`)
 
 // getVersion returns the parsed version of a program
 func getVersion(program string) *version.Version {
@@ -228,8 +582,245 @@ func getVersion(program string) *version.Version {
 	if err != nil {
 		return nil
 	}
-	programVersion, _ := version.NewVersion(versionRegex.FindString(string(out)))
-	return programVersion
+	return parseVersion(string(out))
+}
+
+// parseVersion finds and parses the version in the output of a program.
+// Only the numeric part of the version is kept, so that pre-release and build
+// suffixes of packaged builds (1.7.3-a2312cb) are not considered older than
+// the release.
+func parseVersion(out string) *version.Version {
+	v, err := version.NewVersion(versionRegex.FindString(out))
+	if err != nil {
+		return nil
+	}
+	return v.Core()
+}
+
+// parseVars parses the values of the --var flags, in the NAME=VALUE form, or
+// NAME alone to take the value of the environment variable.
+func parseVars(flags []string) (map[string]string, error) {
+	vars := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !varName.MatchString(name) {
+			return nil, fmt.Errorf("invalid --var %q: expected NAME=VALUE", flag)
+		}
+		if !ok {
+			if value, ok = os.LookupEnv(name); !ok {
+				return nil, fmt.Errorf("invalid --var %q: expected NAME=VALUE, or NAME of an environment variable", flag)
+			}
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// checkEnv checks the values of the --env flags, in the NAME=VALUE form.
+func checkEnv(flags []string) error {
+	for _, flag := range flags {
+		name, _, ok := strings.Cut(flag, "=")
+		if !ok || !varName.MatchString(name) {
+			return fmt.Errorf("invalid --env %q: expected NAME=VALUE", flag)
+		}
+	}
+	return nil
+}
+
+// checkRemote checks the host of the --ssh flag and the image of the
+// --container flag, if any.
+func checkRemote(target, image string) error {
+	if target != "" && image != "" {
+		return errors.New("--ssh can't be used with --container")
+	}
+	if target != "" {
+		if err := validSSHTarget(target); err != nil {
+			return fmt.Errorf("invalid --ssh %q: %w", target, err)
+		}
+	}
+	if image != "" {
+		if err := validContainerImage(image); err != nil {
+			return fmt.Errorf("invalid --container %q: %w", image, err)
+		}
+	}
+	return nil
+}
+
+// expandTapes expands the glob patterns of the tape files, for the shells which
+// don't expand them, such as cmd.exe, or when they are quoted. A pattern which
+// matches no file is an error, rather than being read as a file.
+func expandTapes(args []string) ([]string, error) {
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no tape file matches %s", arg)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// runBatch renders several tape files concurrently.
+func runBatch(cmd *cobra.Command, files []string, vars map[string]string) error {
+	switch {
+	case stdin:
+		return errors.New("--stdin can't be used with tape files")
+	case len(outputFlags) > 0:
+		return errors.New("--output can only be used with a single tape")
+	case publish, open, openAll:
+		return errors.New("--publish and --open can only be used with a single tape")
+	case dryRun:
+		return errors.New("--dry-run can only be used with a single tape")
+	case stdoutFlag:
+		return errors.New("--stdout can only be used with a single tape")
+	}
+	for _, file := range files {
+		if file == "-" {
+			return errors.New("stdin can't be used with several tapes")
+		}
+	}
+
+	if !skipDependencyCheck() {
+		if err := ensureDependencies(); err != nil {
+			return err
+		}
+	}
+
+	opts, err := evaluatorOptions(vars)
+	if err != nil {
+		return err
+	}
+	return RunBatch(cmd.Context(), files, jobs, renderCache(), os.Stdout, opts...)
+}
+
+// renderCache returns the cache of the outputs of the tapes, or nil if it is
+// disabled with --no-cache or there is no cache directory.
+func renderCache() *RenderCache {
+	if noCache {
+		return nil
+	}
+	dir, err := defaultCacheDir()
+	if err != nil {
+		return nil
+	}
+	// The default settings aren't part of the tape, so they are part of the
+	// salt instead.
+	defaults, _, _ := loadDefaults(".")
+	return &RenderCache{
+		Dir:  dir,
+		Salt: cacheSalt(fmt.Sprintf("deterministic=%t", deterministic), "output="+strings.Join(outputFlags, ","), "max-size="+maxSizeFlag, "env="+strings.Join(envFlags, "\x00"), "backend="+backendFlag, "ssh="+sshFlag, "container="+containerFlag, "defaults="+defaults),
+	}
+}
+
+// logOutput returns where the log of the recording is written: stdout, unless
+// the frames are streamed to it with --stdout.
+func logOutput() io.Writer {
+	if stdoutFlag {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// evaluatorOptions returns the options of the evaluator shared by the
+// recordings of the root command.
+func evaluatorOptions(vars map[string]string) ([]EvaluatorOption, error) {
+	opts := []EvaluatorOption{WithVars(vars)}
+	if _, defaults, err := loadDefaults("."); err != nil {
+		return nil, err
+	} else if len(defaults) > 0 {
+		opts = append(opts, WithDefaults(defaults))
+	}
+	if deterministic {
+		opts = append(opts, WithDeterministic())
+	}
+	if maxSizeFlag != "" {
+		size, err := parseFileSize(maxSizeFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-size %s: %w", maxSizeFlag, err)
+		}
+		opts = append(opts, WithMaxFileSize(size))
+	}
+	if len(envFlags) > 0 {
+		opts = append(opts, WithEnv(envFlags))
+	}
+	if sshFlag != "" {
+		opts = append(opts, WithSSH(sshFlag))
+	}
+	if containerFlag != "" {
+		opts = append(opts, WithContainer(containerFlag))
+	}
+	if backendFlag != defaultBackend {
+		opts = append(opts, WithBackend(backendFlag))
+	}
+	return opts, nil
+}
+
+// defaultComposeOutput is the output of --compose when none is given.
+const defaultComposeOutput = "composed.gif"
+
+// runCompose renders the tapes of the --compose flag and tiles them into the
+// output given as argument.
+func runCompose(cmd *cobra.Command, args []string) error {
+	if dryRun {
+		return errors.New("--dry-run can't be used with --compose")
+	}
+	if deterministic {
+		return errors.New("--deterministic can't be used with --compose")
+	}
+	if stdoutFlag {
+		return errors.New("--stdout can't be used with --compose")
+	}
+	panes, err := ParseComposePanes(compose)
+	if err != nil {
+		return err
+	}
+
+	output := defaultComposeOutput
+	if len(args) > 0 {
+		output = args[0]
+	}
+	switch filepath.Ext(output) {
+	case ".gif", ".mp4", ".webm":
+	default:
+		return fmt.Errorf("invalid output %s: --compose supports .gif, .mp4 and .webm", output)
+	}
+
+	if !skipDependencyCheck() {
+		if err := ensureDependencies(); err != nil {
+			return err
+		}
+	}
+
+	if err := Compose(cmd.Context(), panes, output, os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println(StringStyle.Render("Output: " + output))
+	return nil
+}
+
+// isInteractive returns whether the reader is a terminal, rather than a pipe
+// or a file.
+func isInteractive(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+// skipDependencyCheck returns whether the dependency check was disabled with
+// the --no-deps-check flag or the VHS_NO_DEPS_CHECK environment variable.
+func skipDependencyCheck() bool {
+	if noDepsCheck {
+		return true
+	}
+	skip, _ := strconv.ParseBool(os.Getenv("VHS_NO_DEPS_CHECK"))
+	return skip
 }
 
 // ensureDependencies ensures that all dependencies are correctly installed
@@ -239,6 +830,16 @@ func ensureDependencies() error {
 	if ffmpegErr != nil {
 		return fmt.Errorf("ffmpeg is not installed. Install it from: http://ffmpeg.org")
 	}
+	backend, err := findBackend(backendFlag)
+	if err != nil {
+		return err
+	}
+	return backend.Check()
+}
+
+// checkTTYD checks that ttyd and bash are installed and that ttyd is recent
+// enough, for the ttyd backend.
+func checkTTYD() error {
 	_, ttydErr := exec.LookPath("ttyd")
 	if ttydErr != nil {
 		return fmt.Errorf("ttyd is not installed. Install it from: https://github.com/tsl0922/ttyd")
@@ -248,7 +849,14 @@ func ensureDependencies() error {
 		return fmt.Errorf("bash is not installed")
 	}
 
+	if skipVersionCheck {
+		return nil
+	}
+
 	ttydVersion := getVersion("ttyd")
+	if verbose {
+		fmt.Fprintln(os.Stderr, FaintStyle.Render(fmt.Sprintf("ttyd version: %s", ttydVersion)))
+	}
 	if ttydVersion == nil || ttydVersion.LessThan(ttydMinVersion) {
 		return fmt.Errorf("ttyd version (%s) is out of date, VHS requires %s\n%s",
 			ttydVersion,
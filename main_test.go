@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		out      string
+		expected string
+	}{
+		{"ttyd version 1.7.3-a2312cb\n", "1.7.3"},
+		{"ttyd version 1.7.2\n", "1.7.2"},
+		{"ttyd 1.7-rc1", "1.7.0"},
+		{"ffmpeg version n6.0 Copyright (c) 2000-2023", "6.0.0"},
+	}
+
+	for _, tc := range tests {
+		v := parseVersion(tc.out)
+		if v == nil {
+			t.Errorf("expected %q to be parsed as %s, got nil", tc.out, tc.expected)
+			continue
+		}
+		if v.String() != tc.expected {
+			t.Errorf("expected %q to be parsed as %s, got %s", tc.out, tc.expected, v)
+		}
+	}
+
+	if v := parseVersion("ttyd"); v != nil {
+		t.Errorf("expected no version, got %s", v)
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	t.Setenv("VHS_TEST_TOKEN", "secret")
+	vars, err := parseVars([]string{"VERSION=1.0.0", "GREETING=a=b", "EMPTY=", "VHS_TEST_TOKEN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"VERSION": "1.0.0", "GREETING": "a=b", "EMPTY": "", "VHS_TEST_TOKEN": "secret"}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("expected %v, got %v", expected, vars)
+	}
+
+	for _, flag := range []string{"VHS_TEST_UNSET", "=1.0.0", "1VERSION=1"} {
+		if _, err := parseVars([]string{flag}); err == nil {
+			t.Errorf("expected an error for %q", flag)
+		}
+	}
+}
+
+func TestCheckEnv(t *testing.T) {
+	if err := checkEnv([]string{"NO_COLOR=1", "GREETING=a=b", "EMPTY="}); err != nil {
+		t.Fatal(err)
+	}
+	for _, flag := range []string{"NO_COLOR", "=1", "1NO_COLOR=1", "NO-COLOR=1"} {
+		if err := checkEnv([]string{flag}); err == nil {
+			t.Errorf("expected an error for %q", flag)
+		}
+	}
+}
+
+func TestCheckRemote(t *testing.T) {
+	if err := checkRemote("me@example.com", ""); err != nil {
+		t.Error(err)
+	}
+	if err := checkRemote("", "ubuntu:22.04"); err != nil {
+		t.Error(err)
+	}
+	for _, flags := range [][2]string{{"me@example.com", "ubuntu:22.04"}, {"-oProxyCommand=sh", ""}, {"", "--privileged"}} {
+		if err := checkRemote(flags[0], flags[1]); err == nil {
+			t.Errorf("expected an error for %q", flags)
+		}
+	}
+}
+
+func TestExpandTapes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.tape", "b.tape", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := expandTapes([]string{filepath.Join(dir, "*.tape"), "-", "missing.tape"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(dir, "a.tape"), filepath.Join(dir, "b.tape"), "-", "missing.tape"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+
+	if _, err := expandTapes([]string{filepath.Join(dir, "*.gif")}); err == nil {
+		t.Errorf("expected an error for a pattern without a match")
+	}
+}
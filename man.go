@@ -24,12 +24,24 @@ A tape file is a script made up of commands describing what actions to perform i
 
 The following is a list of all possible commands in VHS:
 
-* %Output% <path>.(gif|webm|mp4)
+* %Output% <path>.(gif|webm|mp4|webp|apng|png)
 * %Require% <program>
+* %Env% <key> <value>
+* %Setup% { <script> }
+* %Teardown% { <script> }
+* %Var% <name> <default>
+* %Source% <path>.tape [args...]
+* %Include% <path>.tape [args...]
 * %Set% <setting> <value>
 * %Sleep% <time>
+* %Wait%[+Line|+Screen] [/<regex>/] [<timeout>]
+* %Expect% "<string>" | /<regex>/
+* %ExpectNot% "<string>" | /<regex>/
 * %Type% "<string>"
 * %Ctrl%+<key>
+* %Alt%+<key>
+* %Shift%+<key>
+* %Ctrl%+%Shift%+<key>
 * %Backspace% [repeat]
 * %Down% [repeat]
 * %Enter% [repeat]
@@ -37,12 +49,37 @@ The following is a list of all possible commands in VHS:
 * %Right% [repeat]
 * %Tab% [repeat]
 * %Up% [repeat]
+* %Flash%[@<time>]
 * %Hide%
 * %Show%
+* %Screenshot% <path>.png
+* %Chapter% "<title>"
+* %Split% <horizontal|vertical>
+* %Focus% <number>
+* %Audio% "<path>" [<time>]
+* %Caption% "<text>" [<time>]
+* %Highlight% <row>,<col>,<width>,<height> [<time>]
+* %Copy% "<text>"
+* %Paste%[@<time>]
+* %Breakpoint%
+* %Abort% [message]
+* %Quiet% { <commands> }
+* %Repeat% <count> { <commands> }
+* %Skip% unless <program>|on <os> { <commands> }
+* %Speed% <factor> { <commands> }
+* %Cut% { <commands> }
 `
 
 	manOutput = `The Output command instructs VHS where to save the output of the recording.
-File names with the extension %.gif%, %.webm%, %.mp4% will have the respective file types.
+File names with the extension %.gif%, %.webm%, %.mp4%, %.webp%, %.apng% will have the respective file types.
+File names with the extension %.png% save the last frame of the recording.
+File names with the extension %.ascii% record the terminal after every command, for golden file testing.
+File names with the extension %.txt% contain the final screen of the terminal as plain text.
+File names with the extension %.html% contain the final screen of the terminal as colored HTML.
+File names with the extension %.svg% contain an animation of the terminal as text.
+File names with the extension %.cast% contain the output of the terminal as an asciinema recording.
+File names with the extension %.srt% and %.vtt% contain subtitles of the typed text and the captions.
+Directories, such as %frames/%, contain the frames of the recording as PNG images along with a %frames.json% manifest of their timing.
 `
 
 	manSettings = `The Set command allows VHS to adjust settings in the terminal, such as fonts, dimensions, and themes.
@@ -52,15 +89,53 @@ The following is a list of all possible setting commands in VHS:
 * Set %Shell% <string>
 * Set %FontSize% <number>
 * Set %FontFamily% <string>
+* Set %FontFile% <path>
 * Set %Height% <number>
 * Set %Width% <number>
 * Set %LetterSpacing% <float>
 * Set %LineHeight% <float>
 * Set %TypingSpeed% <time>
+* Set %TypingVariance% <float|percent>
+* Set %TypingSeed% <number>
 * Set %Theme% <json|string>
 * Set %Padding% <number>
 * Set %Framerate% <number>
 * Set %PlaybackSpeed% <float>
+* Set %WebPQuality% <number>
+* Set %WebPLossless% <bool>
+* Set %Loops% <number>
+* Set %SleepScale% <float>
+* Set %KeyLog% <path>
+* Set %Secret% <string>
+* Set %SSH% [user@]host
+* Set %Container% <image>
+* Set %FlashColor% <color>
+* Set %ShowGrid% <bool>
+* Set %ShowKeys% <bool>
+* Set %ProgressBar% <bool>
+* Set %Audio% <path>
+* Set %Timezone% <string>
+* Set %Title% <string>
+* Set %ScreenshotDir% <path>
+* Set %ScreenshotDigits% <number>
+* Set %KeyDelay% <time>
+* Set %CursorColor% <color>
+* Set %CursorBlink% <bool|on|off>
+* Set %CursorStyle% <block|bar|underline>
+* Set %WindowBar% <Colorful|ColorfulRight|Rings|RingsRight|None>
+* Set %WindowBarSize% <number>
+* Set %WindowTitle% <string>
+* Set %BorderRadius% <number>
+* Set %Margin% <number>
+* Set %MarginFill% <color|image>
+* Set %Shadow% <bool>
+* Set %MaxFileSize% <size>
+* Set %WorkingDirectory% <path>
+* Set %DefaultSleep% <time>
+* Set %HtmlFull% <bool>
+* Set %Crt% <bool>
+* Set %CrtIntensity% <float>
+* Set %FrameRateFromTyping% <bool>
 `
 	manBugs = "See GitHub Issues: <https://github.com/charmbracelet/vhs/issues>"
 
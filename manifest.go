@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultManifestFile is the manifest read by vhs run when none is given.
+const defaultManifestFile = "vhs.yaml"
+
+// Manifest lists the tapes rendered by vhs run, with the overrides which
+// apply to all of them. The overrides of a tape take precedence over the ones
+// of the manifest, which take precedence over the tape itself.
+//
+//	jobs: 4
+//	output: docs/demos
+//	theme: Catppuccin Mocha
+//	env:
+//	  NO_COLOR: "1"
+//	report: docs/demos/report.json
+//	tapes:
+//	  - demos/install.tape
+//	  - tape: demos/usage.tape
+//	    output: docs/demos/usage
+//	    vars:
+//	      VERSION: 1.2.0
+type Manifest struct {
+	Jobs   int               `yaml:"jobs"`
+	Output string            `yaml:"output"`
+	Theme  string            `yaml:"theme"`
+	Env    map[string]string `yaml:"env"`
+	Vars   map[string]string `yaml:"vars"`
+	Report string            `yaml:"report"`
+	Tapes  []ManifestTape    `yaml:"tapes"`
+}
+
+// ManifestTape is a tape of the manifest, with its own overrides.
+type ManifestTape struct {
+	Tape   string            `yaml:"tape"`
+	Output string            `yaml:"output"`
+	Theme  string            `yaml:"theme"`
+	Env    map[string]string `yaml:"env"`
+	Vars   map[string]string `yaml:"vars"`
+}
+
+// UnmarshalYAML reads a tape of the manifest, either as the path of the tape
+// alone or with its overrides.
+func (t *ManifestTape) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Tape = node.Value
+		return nil
+	}
+	type plain ManifestTape
+	return node.Decode((*plain)(t))
+}
+
+// LoadManifest reads the manifest at path. The paths of the tapes, of the
+// outputs and of the report are relative to the directory of the manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if len(m.Tapes) == 0 {
+		return nil, fmt.Errorf("invalid manifest %s: no tapes", path)
+	}
+
+	dir := filepath.Dir(path)
+	m.Output = manifestPath(dir, m.Output)
+	m.Report = manifestPath(dir, m.Report)
+	for i, tape := range m.Tapes {
+		if tape.Tape == "" {
+			return nil, fmt.Errorf("invalid manifest %s: tape %d has no path", path, i+1)
+		}
+		m.Tapes[i].Tape = manifestPath(dir, tape.Tape)
+		m.Tapes[i].Output = manifestPath(dir, tape.Output)
+	}
+	return &m, nil
+}
+
+// manifestPath returns the path relative to the directory of the manifest.
+func manifestPath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// overrides returns the overrides of the tape, combined with the ones of the
+// manifest.
+func (m *Manifest) overrides(tape ManifestTape) ManifestTape {
+	t := ManifestTape{Tape: tape.Tape, Output: m.Output, Theme: m.Theme, Env: map[string]string{}, Vars: map[string]string{}}
+	if tape.Output != "" {
+		t.Output = tape.Output
+	}
+	if tape.Theme != "" {
+		t.Theme = tape.Theme
+	}
+	for _, values := range []map[string]string{m.Env, tape.Env} {
+		for name, value := range values {
+			t.Env[name] = value
+		}
+	}
+	for _, values := range []map[string]string{m.Vars, tape.Vars} {
+		for name, value := range values {
+			t.Vars[name] = value
+		}
+	}
+	return t
+}
+
+// options returns the evaluator options of the overrides of a tape.
+func (t ManifestTape) options() []EvaluatorOption {
+	var opts []EvaluatorOption
+	if len(t.Vars) > 0 {
+		opts = append(opts, WithVars(t.Vars))
+	}
+	if len(t.Env) > 0 {
+		opts = append(opts, WithEnv(sortedEnv(t.Env)))
+	}
+	if t.Theme != "" {
+		opts = append(opts, WithTheme(t.Theme))
+	}
+	if t.Output != "" {
+		opts = append(opts, WithOutputDir(t.Output))
+	}
+	return opts
+}
+
+// salt returns what the overrides add to the salt of the cache.
+func (t ManifestTape) salt() string {
+	vars := make([]string, 0, len(t.Vars))
+	for name, value := range t.Vars {
+		vars = append(vars, name+"="+value)
+	}
+	sort.Strings(vars)
+	return strings.Join([]string{
+		"manifest-output=" + t.Output,
+		"manifest-theme=" + t.Theme,
+		"manifest-env=" + strings.Join(sortedEnv(t.Env), "\x00"),
+		"manifest-vars=" + strings.Join(vars, "\x00"),
+	}, "\n")
+}
+
+// sortedEnv returns the variables in the NAME=VALUE form, sorted by name.
+func sortedEnv(env map[string]string) []string {
+	vars := make([]string, 0, len(env))
+	for name, value := range env {
+		vars = append(vars, name+"="+value)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// ManifestReport is the summary of vhs run, written as JSON to the report of
+// the manifest.
+type ManifestReport struct {
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Elapsed   float64              `json:"elapsed"`
+	Tapes     []ManifestTapeReport `json:"tapes"`
+}
+
+// ManifestTapeReport is the outcome of a tape of the manifest, with how long
+// it took in seconds and the sizes of its outputs in bytes.
+type ManifestTapeReport struct {
+	Tape    string           `json:"tape"`
+	Elapsed float64          `json:"elapsed"`
+	Outputs []ManifestOutput `json:"outputs,omitempty"`
+	Errors  []string         `json:"errors,omitempty"`
+}
+
+// ManifestOutput is an output of a tape of the manifest, with its size.
+type ManifestOutput struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// RunManifest renders the tapes of the manifest with their overrides, with at
+// most jobs tapes at a time, and prints a summary of their durations, the
+// sizes of their outputs and their failures. The summary is also written as
+// JSON to the report of the manifest, if any.
+func RunManifest(ctx context.Context, m *Manifest, jobs int, cache *RenderCache, out io.Writer, opts ...EvaluatorOption) (ManifestReport, error) {
+	start := time.Now()
+	tapes := make([]batchTape, len(m.Tapes))
+	for i, tape := range m.Tapes {
+		t := m.overrides(tape)
+		tapes[i] = batchTape{file: t.Tape, opts: append(append([]EvaluatorOption{}, opts...), t.options()...)}
+		if cache != nil {
+			tapes[i].cache = &RenderCache{Dir: cache.Dir, Salt: cache.Salt + "\n" + t.salt()}
+		}
+	}
+	results := runTapes(ctx, tapes, jobs, out)
+	printBatchErrors(results)
+
+	report := ManifestReport{Tapes: make([]ManifestTapeReport, len(results))}
+	for i, result := range results {
+		r := ManifestTapeReport{Tape: result.file, Elapsed: roundSeconds(result.duration)}
+		for _, output := range result.outputs {
+			var size int64
+			if info, err := os.Stat(output); err == nil && info.Mode().IsRegular() {
+				size = info.Size()
+			}
+			r.Outputs = append(r.Outputs, ManifestOutput{Path: output, Size: size})
+		}
+		for _, err := range result.errs {
+			r.Errors = append(r.Errors, err.Error())
+		}
+		if len(r.Errors) > 0 {
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+		report.Tapes[i] = r
+	}
+	report.Elapsed = roundSeconds(time.Since(start))
+	printManifestReport(out, report)
+
+	if m.Report != "" {
+		if err := writeManifestReport(m.Report, report); err != nil {
+			return report, err
+		}
+	}
+	if report.Failed > 0 {
+		return report, fmt.Errorf("%d tape(s) failed", report.Failed)
+	}
+	return report, nil
+}
+
+// roundSeconds returns the duration in seconds, to the millisecond.
+func roundSeconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
+
+// printManifestReport prints a line per tape, with its duration and its
+// outputs, followed by the totals.
+func printManifestReport(out io.Writer, report ManifestReport) {
+	fmt.Fprintln(out)
+	for _, tape := range report.Tapes {
+		elapsed := time.Duration(tape.Elapsed * float64(time.Second)).String()
+		if len(tape.Errors) > 0 {
+			fmt.Fprintln(out, ErrorStyle.Render("✗ "+tape.Tape)+" "+FaintStyle.Render(elapsed))
+			continue
+		}
+		outputs := make([]string, len(tape.Outputs))
+		for i, output := range tape.Outputs {
+			outputs[i] = output.Path + " (" + formatFileSize(output.Size) + ")"
+		}
+		fmt.Fprintln(out, StringStyle.Render("✓ "+tape.Tape)+" "+FaintStyle.Render(elapsed)+" "+strings.Join(outputs, ", "))
+	}
+	fmt.Fprintf(out, "%d succeeded, %d failed in %s\n", report.Succeeded, report.Failed, time.Duration(report.Elapsed*float64(time.Second)))
+}
+
+// writeManifestReport writes the report as JSON.
+func writeManifestReport(path string, report ManifestReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+var (
+	runJobs   int
+	runReport string
+	runCmd    = &cobra.Command{
+		Use:   "run [<manifest>]",
+		Short: "Render the tapes listed in a manifest, vhs.yaml by default",
+		Long: `Render the tapes listed in a manifest, with the output directory, the theme,
+the environment variables and the variables of each tape, and print a summary
+of how long they took, the sizes of their outputs and their failures.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := defaultManifestFile
+			if len(args) > 0 {
+				path = args[0]
+			}
+			m, err := LoadManifest(path)
+			if err != nil {
+				return err
+			}
+			if runReport != "" {
+				m.Report = runReport
+			}
+			jobs := runJobs
+			if jobs <= 0 {
+				jobs = m.Jobs
+			}
+
+			if !skipDependencyCheck() {
+				if err := ensureDependencies(); err != nil {
+					return err
+				}
+			}
+			opts, err := evaluatorOptions(nil)
+			if err != nil {
+				return err
+			}
+			_, err = RunManifest(cmd.Context(), m, jobs, renderCache(), cmd.OutOrStdout(), opts...)
+			return err
+		},
+	}
+)
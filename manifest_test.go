@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vhs.yaml")
+	manifest := `jobs: 2
+output: out
+theme: Dracula
+env:
+  NO_COLOR: "1"
+tapes:
+  - install.tape
+  - tape: usage.tape
+    output: /tmp/usage
+    env:
+      NO_COLOR: "0"
+      GREETING: hi
+`
+	if err := os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Jobs != 2 || len(m.Tapes) != 2 {
+		t.Fatalf("expected 2 jobs and 2 tapes, got %+v", m)
+	}
+	if m.Tapes[0].Tape != filepath.Join(dir, "install.tape") {
+		t.Errorf("expected the tape relative to the manifest, got %s", m.Tapes[0].Tape)
+	}
+
+	install := m.overrides(m.Tapes[0])
+	if install.Output != filepath.Join(dir, "out") || install.Theme != "Dracula" {
+		t.Errorf("expected the overrides of the manifest, got %+v", install)
+	}
+	usage := m.overrides(m.Tapes[1])
+	if usage.Output != "/tmp/usage" {
+		t.Errorf("expected the output of the tape, got %s", usage.Output)
+	}
+	if env := sortedEnv(usage.Env); !reflect.DeepEqual(env, []string{"GREETING=hi", "NO_COLOR=0"}) {
+		t.Errorf("expected the environment of the tape over the one of the manifest, got %v", env)
+	}
+	if install.salt() == usage.salt() {
+		t.Error("expected the overrides to be part of the cache salt")
+	}
+}
+
+func TestLoadManifestInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for _, manifest := range []string{"tapes: []", "tapes:\n  - output: out", "tapes: ["} {
+		path := filepath.Join(dir, "vhs.yaml")
+		if err := os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadManifest(path); err == nil {
+			t.Errorf("expected an error for the manifest %q", manifest)
+		}
+	}
+}
+
+func TestWithOutputDir(t *testing.T) {
+	v := New()
+	v.Options.Video.Output.GIF = "demos/demo.gif"
+	v.Options.Video.Output.Frames = "frames/"
+	WithOutputDir("docs")(&v)
+	if v.Options.Video.Output.GIF != filepath.Join("docs", "demo.gif") {
+		t.Errorf("expected the GIF in docs, got %s", v.Options.Video.Output.GIF)
+	}
+	if v.Options.Video.Output.Frames != filepath.Join("docs", "frames")+"/" {
+		t.Errorf("expected the frames in docs, got %s", v.Options.Video.Output.Frames)
+	}
+	if v.Options.Video.Output.MP4 != "" {
+		t.Errorf("expected no MP4 output, got %s", v.Options.Video.Output.MP4)
+	}
+}
+
+func TestRunManifestReport(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.tape")
+	if err := os.WriteFile(invalid, []byte("Type\nSleep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manifest{
+		Report: filepath.Join(dir, "report", "report.json"),
+		Tapes:  []ManifestTape{{Tape: invalid}, {Tape: filepath.Join(dir, "missing.tape")}},
+	}
+
+	var out bytes.Buffer
+	report, err := RunManifest(context.Background(), m, 2, nil, &out)
+	if err == nil || err.Error() != "2 tape(s) failed" {
+		t.Fatalf("expected 2 tapes to fail, got %v", err)
+	}
+	if report.Failed != 2 || report.Succeeded != 0 || len(report.Tapes[1].Errors) == 0 {
+		t.Errorf("expected the failures in the report, got %+v", report)
+	}
+	if !strings.Contains(out.String(), "0 succeeded, 2 failed in ") {
+		t.Errorf("expected a summary, got:\n%s", out.String())
+	}
+
+	b, err := os.ReadFile(m.Report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written ManifestReport
+	if err := json.Unmarshal(b, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Failed != 2 || written.Tapes[0].Tape != invalid {
+		t.Errorf("expected the report to be written, got %s", b)
+	}
+}
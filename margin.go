@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // decode the JPEG backdrops
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// marginFrame is the file name of the backdrop drawn in the margin, in the
+// frames directory.
+const marginFrame = "margin.png"
+
+// defaultMarginFill is the color of the margin when no fill is set.
+const defaultMarginFill = "#6B50FF"
+
+// shadowAlpha is the opacity of the drop shadow, right below the frames.
+const shadowAlpha = 0.5
+
+// isImageFill returns whether the fill of the margin is an image rather than
+// a color.
+func isImageFill(fill string) bool {
+	switch strings.ToLower(filepath.Ext(fill)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	default:
+		return false
+	}
+}
+
+// marginSize returns the size of the outputs with the margin.
+func marginSize(opts VideoOptions) (int, int) {
+	return opts.Width + 2*opts.Margin, opts.Height + 2*opts.Margin //nolint:gomnd
+}
+
+// MakeMargin draws the backdrop of the margin, with a hole for the frames in
+// its middle and, with a shadow, a drop shadow below the hole. The image of
+// the fill is scaled to cover the whole backdrop.
+func MakeMargin(opts VideoOptions) error {
+	width, height := marginSize(opts)
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	if isImageFill(opts.MarginFill) {
+		f, err := os.Open(opts.MarginFill)
+		if err != nil {
+			return err
+		}
+		defer f.Close() //nolint:errcheck
+		src, _, err := image.Decode(f)
+		if err != nil {
+			return fmt.Errorf("could not decode %s: %w", opts.MarginFill, err)
+		}
+		b := src.Bounds()
+		scale := math.Max(float64(width)/float64(b.Dx()), float64(height)/float64(b.Dy()))
+		w, h := int(math.Ceil(float64(b.Dx())*scale)), int(math.Ceil(float64(b.Dy())*scale))
+		dst := image.Rect((width-w)/2, (height-h)/2, (width-w)/2+w, (height-h)/2+h) //nolint:gomnd
+		draw.CatmullRom.Scale(img, dst, src, b, draw.Src, nil)
+	} else {
+		fill := opts.MarginFill
+		if fill == "" {
+			fill = defaultMarginFill
+		}
+		c, err := parseHexColor(fill)
+		if err != nil {
+			return err
+		}
+		draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+
+	frame := image.Rect(opts.Margin, opts.Margin, opts.Margin+opts.Width, opts.Margin+opts.Height)
+	if opts.Shadow {
+		drawShadow(img, frame, opts.Margin)
+	}
+	draw.Draw(img, frame, image.Transparent, image.Point{}, draw.Src)
+
+	f, err := os.Create(filepath.Join(opts.Input, marginFrame))
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	return png.Encode(f, img)
+}
+
+// drawShadow darkens the backdrop around the frame, a bit lower than it, and
+// less so further away from it.
+func drawShadow(img *image.NRGBA, frame image.Rectangle, margin int) {
+	blur := float64(margin) / 2                //nolint:gomnd
+	shadow := frame.Add(image.Pt(0, margin/6)) //nolint:gomnd
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			dx := math.Max(math.Max(float64(shadow.Min.X-x), float64(x-shadow.Max.X+1)), 0)
+			dy := math.Max(math.Max(float64(shadow.Min.Y-y), float64(y-shadow.Max.Y+1)), 0)
+			d := math.Hypot(dx, dy)
+			if d >= blur {
+				continue
+			}
+			a := shadowAlpha * (1 - d/blur) * (1 - d/blur)
+			c := img.NRGBAAt(x, y)
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(float64(c.R) * (1 - a)),
+				G: uint8(float64(c.G) * (1 - a)),
+				B: uint8(float64(c.B) * (1 - a)),
+				A: c.A,
+			})
+		}
+	}
+}
+
+// marginFilter returns the filter which pads the frames with the margin and
+// overlays its backdrop, once everything else is drawn. The stream is left
+// unlabeled, as with mergeFrames.
+func marginFilter(opts VideoOptions) string {
+	if opts.Margin <= 0 {
+		return ""
+	}
+	width, height := marginSize(opts)
+	return fmt.Sprintf("[unmargined];[unmargined]pad=%d:%d:%d:%d[margined];[margined][%d]overlay=0:0",
+		width, height, opts.Margin, opts.Margin, inputCount(opts)-1)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func decodeMargin(t *testing.T, opts VideoOptions) image.Image {
+	t.Helper()
+	if err := MakeMargin(opts); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(opts.Input, marginFrame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close() //nolint:errcheck
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestMakeMargin(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = t.TempDir()
+	opts.Margin = 60
+	opts.MarginFill = "#ff0000"
+	opts.Shadow = true
+
+	img := decodeMargin(t, opts)
+	if b := img.Bounds(); b.Dx() != 1320 || b.Dy() != 720 {
+		t.Fatalf("expected a backdrop of 1320x720, got %dx%d", b.Dx(), b.Dy())
+	}
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	if c := color.NRGBAModel.Convert(img.At(5, 5)); c != red {
+		t.Errorf("expected the fill in the corner, got %v", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(660, 360)); c != (color.NRGBA{}) {
+		t.Errorf("expected a hole for the frames, got %v", c)
+	}
+	// The shadow is right below the frames.
+	if c := color.NRGBAModel.Convert(img.At(660, 665)).(color.NRGBA); c.R >= 0xff || c.A != 0xff {
+		t.Errorf("expected a shadow below the frames, got %v", c)
+	}
+}
+
+func TestMakeMarginImage(t *testing.T) {
+	dir := t.TempDir()
+	backdrop := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		backdrop.SetNRGBA(x, 0, color.NRGBA{B: 0xff, A: 0xff})
+		backdrop.SetNRGBA(x, 1, color.NRGBA{B: 0xff, A: 0xff})
+	}
+	path := filepath.Join(dir, "backdrop.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, backdrop); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = dir
+	opts.Margin = 20
+	opts.MarginFill = path
+
+	img := decodeMargin(t, opts)
+	if c := color.NRGBAModel.Convert(img.At(0, 0)); c != (color.NRGBA{B: 0xff, A: 0xff}) {
+		t.Errorf("expected the image in the corner, got %v", c)
+	}
+}
+
+func TestMarginFilter(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = "frames"
+	opts.Output.MP4 = "out.mp4"
+
+	if filter := marginFilter(opts); filter != "" {
+		t.Errorf("expected no margin by default, got %s", filter)
+	}
+
+	opts.WindowBar = "Colorful"
+	opts.Margin = 40
+	cmd := strings.Join(MakeMP4(opts).Args, " ")
+	for _, want := range []string{
+		"-i frames/" + windowBarFrame + " -i frames/" + marginFrame,
+		"[unmargined];[unmargined]pad=1280:680:40:40[margined];[margined][3]overlay=0:0",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in the command: %s", want, cmd)
+		}
+	}
+
+	v := New()
+	_ = os.RemoveAll(v.Options.Video.Input)
+	ExecuteSetMarginFill(Command{Type: SET, Options: "MarginFill", Args: "#6B50FF"}, &v)
+	ExecuteSetMarginFill(Command{Type: SET, Options: "MarginFill", Args: "missing.png"}, &v)
+	ExecuteSetMarginFill(Command{Type: SET, Options: "MarginFill", Args: "purple"}, &v)
+	if v.Options.Video.MarginFill != "#6B50FF" || len(v.Errors) != 2 {
+		t.Errorf("expected errors for the missing image and the invalid color, got %v", v.Errors)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattn/go-isatty"
+)
+
+// openOutputs opens the rendered outputs with the default viewer of the
+// operating system. Only the first output is opened unless all is set.
+//
+// Nothing is opened with --quiet, or when stdout is not a terminal, since
+// there is most likely nobody around to look at the result (e.g. CI).
+func openOutputs(outputs []string, all bool) {
+	if quietFlag || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return
+	}
+
+	for _, output := range outputs {
+		if output == "" {
+			continue
+		}
+		// The viewers are given absolute paths, so that a path starting
+		// with - isn't read as an option.
+		path, err := filepath.Abs(output)
+		if err != nil {
+			path = output
+		}
+		cmd := openCommand(path)
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintln(os.Stderr, ErrorStyle.Render(fmt.Sprintf("could not open %s: %s", output, err)))
+		} else {
+			// The viewer outlives VHS, which doesn't wait for it.
+			_ = cmd.Process.Release()
+		}
+		if !all {
+			return
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import "os/exec"
+
+func openCommand(path string) *exec.Cmd {
+	return exec.Command("open", path) //nolint:gosec
+}
@@ -0,0 +1,10 @@
+//go:build dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import "os/exec"
+
+func openCommand(path string) *exec.Cmd {
+	return exec.Command("xdg-open", path) //nolint:gosec
+}
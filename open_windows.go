@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os/exec"
+
+// openCommand opens the file with its default viewer through the URL handler
+// of rundll32, rather than `cmd /c start`, which would read characters of the
+// path such as & and %VAR% as the syntax of cmd.
+func openCommand(path string) *exec.Cmd {
+	return exec.Command("rundll32", "url.dll,FileProtocolHandler", path) //nolint:gosec
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenCommand(t *testing.T) {
+	cmd := openCommand(`C:\demos\a&b %PATH%.gif`)
+	want := []string{"rundll32", "url.dll,FileProtocolHandler", `C:\demos\a&b %PATH%.gif`}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("expected the path to be passed as is, got %q", cmd.Args)
+	}
+}
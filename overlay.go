@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultOverlayDuration is how long a Caption or a Highlight is shown when no
+// duration is given.
+const defaultOverlayDuration = 3 * time.Second
+
+// highlightThickness is the thickness of the box of a Highlight, in pixels.
+const highlightThickness = 3
+
+// showKeysDuration is how long the keys pressed are shown once the command is
+// done, unless other keys are pressed.
+const showKeysDuration = time.Second
+
+// maxKeysLength is the maximum number of characters of the typed text which
+// are shown with Set ShowKeys. The end of a longer text is shown.
+const maxKeysLength = 32
+
+// captionFrameFormat is the file name of the text of a caption, in the frames
+// directory. The text is read from a file by ffmpeg so that it doesn't need to
+// be escaped in the filter.
+const captionFrameFormat = "caption-%d.txt"
+
+// Overlay is a caption or a highlighted box drawn by ffmpeg over the frames,
+// from Start to End of the recording.
+type Overlay struct {
+	// Caption is the file of the text of a caption, or empty for a box.
+	Caption string
+	// Keys places the caption at the bottom right, as the keys pressed.
+	Keys bool
+	// Box is the position and size of the box, in pixels of the output.
+	X, Y, Width, Height int
+	Color               string
+	FontSize            int
+	Start, End          time.Duration
+}
+
+// overlayStart returns the position of the recording so far, which is where an
+// overlay added now starts.
+func (v *VHS) overlayStart() time.Duration {
+	return time.Duration(v.frames) * time.Second / time.Duration(v.Options.Video.Framerate)
+}
+
+// overlayDuration returns the duration of the command, or the default one.
+func overlayDuration(c Command) time.Duration {
+	d, err := time.ParseDuration(c.Options)
+	if err != nil || d <= 0 {
+		return defaultOverlayDuration
+	}
+	return d
+}
+
+// ExecuteCaption shows the text at the bottom of the frames for the duration
+// of the command, while the tape goes on.
+func ExecuteCaption(c Command, v *VHS) {
+	video := &v.Options.Video
+	file := filepath.Join(video.Input, fmt.Sprintf(captionFrameFormat, len(video.Overlays)))
+	if err := os.WriteFile(file, []byte(c.Args), 0o600); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("could not write the caption: %w", err))
+		return
+	}
+	start := v.overlayStart()
+	video.Overlays = append(video.Overlays, Overlay{
+		Caption:  file,
+		FontSize: v.Options.FontSize,
+		Color:    v.Options.Theme.Foreground,
+		Start:    start,
+		End:      start + overlayDuration(c),
+	})
+	v.addSubtitle(c.Args, start, start+overlayDuration(c), false)
+}
+
+// ShowKey shows the keys of the command, which started at the given position
+// of the recording, at the bottom right of the frames. The previous keys are
+// hidden as the command starts.
+func (v *VHS) ShowKey(c Command, start time.Duration) {
+	video := &v.Options.Video
+	file := filepath.Join(video.Input, fmt.Sprintf(captionFrameFormat, len(video.Overlays)))
+	if err := os.WriteFile(file, []byte(keyLabel(c, v.Options.Secrets)), 0o600); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("could not write the keys: %w", err))
+		return
+	}
+	for i := len(video.Overlays) - 1; i >= 0; i-- {
+		if o := &video.Overlays[i]; o.Keys {
+			if o.End > start {
+				o.End = start
+			}
+			break
+		}
+	}
+	video.Overlays = append(video.Overlays, Overlay{
+		Caption:  file,
+		Keys:     true,
+		FontSize: v.Options.FontSize,
+		Color:    v.Options.Theme.Foreground,
+		Start:    start,
+		End:      v.overlayStart() + showKeysDuration,
+	})
+}
+
+// keyLabel returns the keys of a key command as they are shown, such as
+// Ctrl+C, Enter ×3 or the typed text, with the secrets masked.
+func keyLabel(c Command, secrets []string) string {
+	switch c.Type {
+	case TYPE:
+		text := []rune(maskSecrets(c.Args, secrets))
+		if len(text) > maxKeysLength {
+			return "…" + string(text[len(text)-maxKeysLength:])
+		}
+		return string(text)
+	case CTRL, ALT, SHIFT:
+		return c.Type.String() + "+" + c.Args
+	}
+	if n, err := strconv.Atoi(c.Args); err == nil && n > 1 {
+		return fmt.Sprintf("%s ×%d", c.Type, n)
+	}
+	return c.Type.String()
+}
+
+// ExecuteHighlight draws a box around the cells given as row,col,width,height,
+// counted from 0 as in the grid of Set ShowGrid, for the duration of the
+// command, while the tape goes on.
+func ExecuteHighlight(c Command, v *VHS) {
+	var cells [4]int
+	if parts := strings.Split(c.Args, ","); len(parts) == len(cells) {
+		for i, part := range parts {
+			cells[i], _ = strconv.Atoi(part)
+		}
+	}
+	row, col, width, height := cells[0], cells[1], cells[2], cells[3]
+	if row < 0 || col < 0 || width <= 0 || height <= 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Highlight %s`: expected row,col,width,height", c.Args))
+		return
+	}
+
+	video := &v.Options.Video
+	if video.Grid.Columns == 0 {
+		v.measureGrid()
+	}
+	x, y, cellWidth, cellHeight := terminalCells(*video)
+	start := v.overlayStart()
+	video.Overlays = append(video.Overlays, Overlay{
+		X:      x + int(float64(col)*cellWidth),
+		Y:      y + int(float64(row)*cellHeight),
+		Width:  int(math.Ceil(float64(width) * cellWidth)),
+		Height: int(math.Ceil(float64(height) * cellHeight)),
+		Color:  v.Options.Theme.Yellow,
+		Start:  start,
+		End:    start + overlayDuration(c),
+	})
+}
+
+// terminalCells returns the position of the terminal in the output, and the
+// size of its cells, once the frames are scaled to fit in the padding and the
+// window bar, and centered.
+func terminalCells(opts VideoOptions) (int, int, float64, float64) {
+	grid := opts.Grid
+	if grid.Columns <= 0 || grid.Rows <= 0 || grid.Width <= 0 || grid.Height <= 0 {
+		return opts.Padding, opts.Padding + windowBarHeight(opts), 0, 0
+	}
+	bar := windowBarHeight(opts)
+	maxWidth := float64(opts.Width - opts.Padding - opts.Padding)
+	maxHeight := float64(opts.Height - opts.Padding - opts.Padding - bar)
+	scale := math.Min(maxWidth/float64(grid.Width), maxHeight/float64(grid.Height))
+	width, height := float64(grid.Width)*scale, float64(grid.Height)*scale
+	x := (float64(opts.Width) - width) / 2                  //nolint:gomnd
+	y := (float64(opts.Height) - height + float64(bar)) / 2 //nolint:gomnd
+	return int(x), int(y), width / float64(grid.Columns), height / float64(grid.Rows)
+}
+
+// overlayFilter returns the filters which draw the overlays, each only
+// between its start and end, once the playback speed is applied.
+func overlayFilter(opts VideoOptions) string {
+	var b strings.Builder
+	speed := opts.PlaybackSpeed
+	if speed <= 0 {
+		speed = 1
+	}
+	for _, o := range opts.Overlays {
+		enable := fmt.Sprintf("enable='between(t,%.3f,%.3f)'", o.Start.Seconds()/speed, o.End.Seconds()/speed)
+		if o.Caption == "" {
+			fmt.Fprintf(&b, ",drawbox=x=%d:y=%d:w=%d:h=%d:t=%d:color=%s:%s",
+				o.X, o.Y, o.Width, o.Height, highlightThickness, o.Color, enable)
+			continue
+		}
+		margin := opts.Padding/4 + o.FontSize/2 //nolint:gomnd
+		x := "(w-text_w)/2"
+		if o.Keys {
+			x = fmt.Sprintf("w-text_w-%d", margin)
+		}
+		fmt.Fprintf(&b, ",drawtext=textfile='%s':expansion=none:fontsize=%d:fontcolor=%s:box=1:boxcolor=black@0.7:boxborderw=%d:x=%s:y=h-text_h-%d:%s",
+			filterPath(o.Caption), o.FontSize, o.Color, o.FontSize/2, x, margin, enable) //nolint:gomnd
+	}
+	return b.String()
+}
+
+// filterPath returns the path as an option of an ffmpeg filter, with forward
+// slashes and the colons of the drive letters of Windows escaped.
+func filterPath(path string) string {
+	return strings.ReplaceAll(filepath.ToSlash(path), ":", `\:`)
+}
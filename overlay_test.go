@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteOverlays(t *testing.T) {
+	v := New()
+	_ = os.RemoveAll(v.Options.Video.Input)
+	v.Options.Video.Input = t.TempDir()
+	v.Options.Video.Grid = GridOptions{Columns: 80, Rows: 20, Width: 1056, Height: 456}
+	v.frames = 2 * v.Options.Video.Framerate
+
+	ExecuteCaption(Command{Type: CAPTION, Options: "1s", Args: "Install the CLI"}, &v)
+	ExecuteHighlight(Command{Type: HIGHLIGHT, Args: "2,10,4,1"}, &v)
+	ExecuteHighlight(Command{Type: HIGHLIGHT, Args: "2,10,0,1"}, &v)
+
+	if len(v.Errors) != 1 || !strings.Contains(v.Errors[0].Error(), "2,10,0,1") {
+		t.Errorf("expected an error for the empty highlight, got %v", v.Errors)
+	}
+	overlays := v.Options.Video.Overlays
+	if len(overlays) != 2 {
+		t.Fatalf("expected 2 overlays, got %v", overlays)
+	}
+
+	caption := overlays[0]
+	b, err := os.ReadFile(caption.Caption)
+	if err != nil || string(b) != "Install the CLI" {
+		t.Errorf("expected the text of the caption in %s, got %q (%v)", caption.Caption, b, err)
+	}
+	if caption.Start != 2*time.Second || caption.End != 3*time.Second {
+		t.Errorf("expected the caption from 2s to 3s, got %s to %s", caption.Start, caption.End)
+	}
+
+	// The cells are 13.2x22.8 pixels, from the padding.
+	box := overlays[1]
+	if box.X != 72+132 || box.Y != 72+45 || box.Width != 53 || box.Height != 23 {
+		t.Errorf("expected the box around the cells, got %+v", box)
+	}
+	if box.End-box.Start != defaultOverlayDuration {
+		t.Errorf("expected the box to last %s, got %s", defaultOverlayDuration, box.End-box.Start)
+	}
+}
+
+func TestTerminalCells(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.WindowBar = "Colorful"
+	opts.Grid = GridOptions{Columns: 96, Rows: 10, Width: 2112, Height: 400}
+
+	// The terminal is scaled down by half to fit in the 1056 pixels between
+	// the padding, and centered below the window bar.
+	x, y, w, h := terminalCells(opts)
+	if x != 72 || y != 215 || w != 11 || h != 20 {
+		t.Errorf("expected cells of 11x20 from 72,215, got %gx%g from %d,%d", w, h, x, y)
+	}
+}
+
+func TestOverlayFilter(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.PlaybackSpeed = 2
+	opts.Overlays = []Overlay{
+		{Caption: "frames/caption-0.txt", FontSize: 22, Color: "#ffffff", Start: time.Second, End: 4 * time.Second},
+		{X: 10, Y: 20, Width: 30, Height: 40, Color: "#ffff00", Start: 2 * time.Second, End: 3 * time.Second},
+	}
+
+	filter := overlayFilter(opts)
+	for _, want := range []string{
+		",drawtext=textfile='frames/caption-0.txt':expansion=none:fontsize=22:fontcolor=#ffffff:",
+		"enable='between(t,0.500,2.000)'",
+		",drawbox=x=10:y=20:w=30:h=40:t=3:color=#ffff00:enable='between(t,1.000,1.500)'",
+	} {
+		if !strings.Contains(filter, want) {
+			t.Errorf("expected %q in the filter: %s", want, filter)
+		}
+	}
+
+	if got := filterPath(`C:\frames\caption-0.txt`); got != `C\:/frames/caption-0.txt` && got != `C\:\frames\caption-0.txt` {
+		t.Errorf("expected the drive letter to be escaped, got %s", got)
+	}
+}
+
+func TestShowKey(t *testing.T) {
+	v := New()
+	_ = os.RemoveAll(v.Options.Video.Input)
+	v.Options.Video.Input = t.TempDir()
+	v.Options.Secrets = []string{"hunter2"}
+
+	for i, tc := range []struct {
+		cmd  Command
+		want string
+	}{
+		{Command{Type: CTRL, Args: "Shift+T"}, "Ctrl+Shift+T"},
+		{Command{Type: ENTER, Args: "1"}, "Enter"},
+		{Command{Type: DOWN, Options: "100ms", Args: "3"}, "Down ×3"},
+		{Command{Type: TYPE, Args: "login hunter2"}, "login •••••••"},
+		{Command{Type: TYPE, Args: strings.Repeat("a", 30) + "bcdef"}, "…" + strings.Repeat("a", 27) + "bcdef"},
+	} {
+		v.frames = i * v.Options.Video.Framerate
+		v.ShowKey(tc.cmd, time.Duration(i)*time.Second-500*time.Millisecond)
+		o := v.Options.Video.Overlays[i]
+		if b, _ := os.ReadFile(o.Caption); string(b) != tc.want || !o.Keys {
+			t.Errorf("expected the keys %q, got %q", tc.want, b)
+		}
+	}
+
+	// Each keys are hidden when the next ones are pressed.
+	overlays := v.Options.Video.Overlays
+	if overlays[0].End != 500*time.Millisecond || overlays[4].End != 4*time.Second+showKeysDuration {
+		t.Errorf("expected the keys to end at the next ones, got %+v", overlays)
+	}
+	if !strings.Contains(overlayFilter(v.Options.Video), ":x=w-text_w-") {
+		t.Errorf("expected the keys at the right: %s", overlayFilter(v.Options.Video))
+	}
+}
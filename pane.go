@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/vhs/parser"
+)
+
+// The layouts of Split.
+const (
+	splitHorizontal = parser.SplitHorizontal
+	splitVertical   = parser.SplitVertical
+)
+
+// splitPositions are the positions of the panes of every layout of Split, as
+// with --compose.
+var splitPositions = map[string][2]string{
+	splitHorizontal: {"left", "right"},
+	splitVertical:   {"top", "bottom"},
+}
+
+// paneSet is the panes of a split recording, shared by all of them, along with
+// the one which receives the commands.
+type paneSet struct {
+	list  []*VHS
+	focus int
+}
+
+// ExecuteSplit splits the recording into two panes, side by side or one above
+// the other, each with its own shell. The first one is focused.
+func ExecuteSplit(c Command, v *VHS) {
+	if _, ok := splitPositions[c.Args]; !ok {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Split %s`: expected horizontal or vertical", c.Args))
+		return
+	}
+	v.Options.Split = c.Args
+}
+
+// ExecuteFocus sends the next commands to the pane, counted from 1.
+func ExecuteFocus(c Command, v *VHS) {
+	if v.panes == nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Focus %s`: the recording isn't split, use Split first", c.Args))
+		return
+	}
+	n, err := strconv.Atoi(c.Args)
+	if err != nil || n < 1 || n > len(v.panes.list) {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Focus %s`: expected a pane from 1 to %d", c.Args, len(v.panes.list)))
+		return
+	}
+	v.panes.focus = n - 1
+}
+
+// focused returns the pane which receives the commands, which is v itself
+// unless the recording is split.
+func (v *VHS) focused() *VHS {
+	if v.panes == nil {
+		return v
+	}
+	return v.panes.list[v.panes.focus]
+}
+
+// paneTargets returns the panes on which the command is executed: Hide, Show,
+// Speed and the settings apply to every pane, so that they stay in step, and
+// the other commands to the focused one.
+func (v *VHS) paneTargets(c Command) []*VHS {
+	if v.panes == nil {
+		return []*VHS{v}
+	}
+	switch c.Type {
+	case HIDE, SHOW, SET, SPEED:
+		return v.panes.list
+	default:
+		return []*VHS{v.focused()}
+	}
+}
+
+// splitErrors returns an error for every output which can't be composed from
+// the panes of a split recording.
+func splitErrors(opts *Options) []error {
+	if opts.Split == "" {
+		return nil
+	}
+	var errs []error
+	output := opts.Video.Output
+	for _, path := range []string{output.WebP, output.APNG, output.SVG, output.PNG, output.Cast, output.SRT, output.VTT, output.Frames} {
+		if path != "" {
+			errs = append(errs, fmt.Errorf("%s can't be used with Split, which only supports .gif, .mp4 and .webm outputs", path))
+		}
+	}
+	return errs
+}
+
+// splitSize shares the size of the outputs between the panes.
+func splitSize(opts *Options) {
+	switch opts.Split {
+	case splitHorizontal:
+		opts.Video.Width /= 2
+	case splitVertical:
+		opts.Video.Height /= 2
+	}
+}
+
+// startPanes starts the shell of the second pane of a split recording, with
+// the options of the first one.
+func (v *VHS) startPanes() (*VHS, error) {
+	v.panes = &paneSet{list: []*VHS{v}}
+
+	pane := New()
+	opts := *v.Options
+	opts.Video.Input = randomDir()
+	opts.Video.Overlays = nil
+	opts.Video.Chapters = nil
+	opts.Video.Audio = nil
+	pane.Options = &opts
+	pane.panes = v.panes
+	if err := pane.Start(); err != nil {
+		return nil, err
+	}
+	pane.Setup()
+
+	v.panes.list = append(v.panes.list, &pane)
+	return &pane, nil
+}
+
+// recordPanes begins capturing the frames of the other panes of a split
+// recording, and returns a function which waits for them to stop once ctx is
+// done.
+func (v *VHS) recordPanes(ctx context.Context) func() {
+	if v.panes == nil {
+		return func() {}
+	}
+	var chs []<-chan error
+	for _, pane := range v.panes.list[1:] {
+		ch := pane.Record(ctx)
+		go func() {
+			for err := range ch {
+				log.Print(err.Error())
+			}
+		}()
+		chs = append(chs, ch)
+	}
+	return func() {
+		for _, ch := range chs {
+			for range ch { //nolint:revive
+			}
+		}
+	}
+}
+
+// renderPanes renders every pane of a split recording to a video, and tiles
+// them into the outputs of the tape, as with --compose.
+func (v *VHS) renderPanes() error {
+	outputs := v.Options.Video.Output
+	dir, err := os.MkdirTemp(os.TempDir(), "vhs-split")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	positions := splitPositions[v.Options.Split]
+	panes := make([]ComposePane, len(v.panes.list))
+	for i, pane := range v.panes.list {
+		video := &pane.Options.Video
+		video.Output = VideoOutputs{MP4: filepath.Join(dir, fmt.Sprintf("pane-%d.mp4", i+1))}
+		if err := pane.Render(); err != nil {
+			return err
+		}
+		width, height := marginSize(*video)
+		panes[i] = ComposePane{
+			Position:   positions[i],
+			video:      video.Output.MP4,
+			width:      width,
+			height:     height,
+			background: video.BackgroundColor,
+			duration:   outputTime(*video, time.Duration(pane.totalFrames)*time.Second/time.Duration(video.Framerate)),
+		}
+	}
+
+	for _, output := range []string{outputs.GIF, outputs.MP4, outputs.WebM} {
+		if output == "" {
+			continue
+		}
+		fmt.Println("Composing " + output + "...")
+		if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+			return err
+		}
+		if b, err := MakeCompose(panes, output).CombinedOutput(); err != nil {
+			fmt.Println(string(b))
+			return fmt.Errorf("composing %s failed: %w", output, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPaneTargets(t *testing.T) {
+	v := New()
+	if targets := v.paneTargets(Command{Type: TYPE}); len(targets) != 1 || targets[0] != &v {
+		t.Fatalf("expected the commands of a recording without panes on itself, got %v", targets)
+	}
+
+	pane := New()
+	v.panes = &paneSet{list: []*VHS{&v, &pane}}
+	pane.panes = v.panes
+
+	ExecuteFocus(Command{Type: FOCUS, Args: "2"}, &v)
+	if len(v.Errors) > 0 || v.focused() != &pane {
+		t.Fatalf("expected the second pane to be focused, got errors %v", v.Errors)
+	}
+	if targets := v.paneTargets(Command{Type: TYPE}); len(targets) != 1 || targets[0] != &pane {
+		t.Errorf("expected Type on the focused pane, got %v", targets)
+	}
+	for _, c := range []CommandType{HIDE, SHOW, SET} {
+		if targets := v.paneTargets(Command{Type: c}); len(targets) != 2 {
+			t.Errorf("expected %s on every pane, got %v", c, targets)
+		}
+	}
+
+	ExecuteFocus(Command{Type: FOCUS, Args: "3"}, &v)
+	if len(v.Errors) != 1 || v.focused() != &pane {
+		t.Errorf("expected an error for a missing pane, got %v", v.Errors)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	opts := DefaultVHSOptions()
+	opts.Video.Output.GIF = "out.gif"
+	opts.Video.Output.MP4 = "out.mp4"
+	if errs := splitErrors(&opts); len(errs) != 0 {
+		t.Errorf("expected no errors without Split, got %v", errs)
+	}
+
+	ExecuteSplit(Command{Type: SPLIT, Args: splitVertical}, &VHS{Options: &opts})
+	if opts.Split != splitVertical {
+		t.Fatalf("expected a vertical split, got %q", opts.Split)
+	}
+	if errs := splitErrors(&opts); len(errs) != 0 {
+		t.Errorf("expected no errors for the GIF and MP4 outputs, got %v", errs)
+	}
+	opts.Video.Output.SVG = "out.svg"
+	if errs := splitErrors(&opts); len(errs) != 1 {
+		t.Errorf("expected an error for the SVG output, got %v", errs)
+	}
+
+	height := opts.Video.Height
+	splitSize(&opts)
+	if opts.Video.Height != height/2 {
+		t.Errorf("expected the height of a pane to be %d, got %d", height/2, opts.Video.Height)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// The formats of vhs parse.
+const (
+	parseFormatJSON = "json"
+	parseFormatText = "text"
+)
+
+var (
+	parseFormat string
+	parseCmd    = &cobra.Command{
+		Use:   "parse <file>...",
+		Short: "Print the commands of tape files as parsed by VHS, with their positions",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if parseFormat != parseFormatJSON && parseFormat != parseFormatText {
+				return fmt.Errorf("invalid --format %s: expected json or text", parseFormat)
+			}
+			vars, err := parseVars(varFlags)
+			if err != nil {
+				return err
+			}
+
+			valid := true
+			docs := make([]TapeDocument, 0, len(args))
+			for _, file := range args {
+				b, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				p := NewParser(NewLexer(string(b)), WithParserVars(vars))
+				doc := NewTapeDocument(file, p.Parse(), p.Tokens(), p.Errors(), p.Warnings())
+				valid = valid && doc.Valid
+				docs = append(docs, doc)
+			}
+
+			out := cmd.OutOrStdout()
+			if parseFormat == parseFormatJSON {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(docs); err != nil {
+					return err
+				}
+			} else {
+				for _, doc := range docs {
+					for _, c := range doc.Commands {
+						fmt.Fprintf(out, "%s:%d:%d: %s\n", doc.File, c.Line, c.Column, c.Command)
+					}
+					for _, d := range doc.Errors {
+						fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", doc.File, d.Line, d.Column, d.Message)
+					}
+				}
+			}
+
+			if !valid {
+				return errors.New("invalid tape file(s)")
+			}
+			return nil
+		},
+	}
+)
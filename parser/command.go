@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommandType is a type that represents a command.
+type CommandType TokenType
+
+// String returns the string representation of the command.
+func (c CommandType) String() string {
+	if len(c) < 1 {
+		return ""
+	}
+	s := string(c)
+	return string(s[0]) + strings.ToLower(s[1:])
+}
+
+// Command represents a command with options and arguments.
+type Command struct {
+	Type    CommandType `json:"type"`
+	Options string      `json:"options,omitempty"`
+	Args    string      `json:"args,omitempty"`
+}
+
+// String returns the string representation of the command.
+// This includes the options and arguments of the command.
+func (c Command) String() string {
+	if c.Options != "" {
+		return fmt.Sprintf("%s %s %s", c.Type, c.Options, c.Args)
+	}
+	return fmt.Sprintf("%s %s", c.Type, c.Args)
+}
+
+// Extension is the extension of the tape files.
+const Extension = ".tape"
+
+// The scopes of the Wait command.
+const (
+	WaitLine   = "Line"
+	WaitScreen = "Screen"
+)
+
+// PromptPattern matches the prompt of the shells, which Wait waits for when
+// no regular expression is given: the command is done once the prompt is back
+// on the last line.
+const PromptPattern = ">$"
+
+// The layouts of the Split command.
+const (
+	SplitHorizontal = "horizontal"
+	SplitVertical   = "vertical"
+)
+
+// The conditions of the Skip block.
+const (
+	SkipUnless = "unless"
+	SkipOn     = "on"
+	// SkipEnd is the argument of the Skip command which ends a block.
+	SkipEnd = "end"
+)
+
+// SpeedEnd is the argument of the Speed command which ends a block.
+const SpeedEnd = "end"
+
+// runtimeSettings are the settings which can be changed in the middle of the
+// tape, as they don't affect the dimensions of the frames.
+var runtimeSettings = map[string]bool{
+	"TypingSpeed":    true,
+	"TypingVariance": true,
+	"KeyDelay":       true,
+	"DefaultSleep":   true,
+	"ShowKeys":       true,
+}
+
+// IsRuntimeSetting returns whether the setting can be changed after the
+// recording has started.
+func IsRuntimeSetting(name string) bool {
+	return runtimeSettings[name]
+}
@@ -0,0 +1,28 @@
+package parser
+
+import "fmt"
+
+// ParserError represents an error with parsing a tape file.
+// It tracks the token causing the error and a human readable error message.
+type ParserError struct {
+	Token Token
+	Msg   string
+}
+
+// NewError returns a new ParserError with the given token and message.
+func NewError(token Token, msg string) ParserError {
+	return ParserError{
+		Token: token,
+		Msg:   msg,
+	}
+}
+
+// String returns a human readable error message printing the token line number
+// and message.
+func (e ParserError) String() string {
+	return fmt.Sprintf("%2d:%-2d │ %s", e.Token.Line, e.Token.Column, e.Msg)
+}
+
+func (e ParserError) Error() string {
+	return e.String()
+}
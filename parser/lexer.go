@@ -1,4 +1,6 @@
-package main
+package parser
+
+import "strings"
 
 // Lexer is a lexer that tokenizes the input.
 type Lexer struct {
@@ -17,6 +19,12 @@ func NewLexer(input string) *Lexer {
 	return l
 }
 
+// Offset returns the offset in the input of the character after the last
+// token.
+func (l *Lexer) Offset() int {
+	return l.pos
+}
+
 // readChar advances the lexer to the next character.
 func (l *Lexer) readChar() {
 	l.column++
@@ -43,6 +51,9 @@ func (l *Lexer) NextToken() Token {
 	case '%':
 		tok = l.newToken(PERCENT, l.ch)
 		l.readChar()
+	case ',':
+		tok = l.newToken(COMMA, l.ch)
+		l.readChar()
 	case '#':
 		tok.Type = COMMENT
 		tok.Literal = l.readComment()
@@ -65,11 +76,21 @@ func (l *Lexer) NextToken() Token {
 		tok.Type = STRING
 		tok.Literal = l.readString('"')
 		l.readChar()
+	case '/':
+		if l.peekChar() == '*' {
+			tok.Type = COMMENT
+			tok.Literal = l.readBlockComment()
+			l.readChar()
+			break
+		}
+		tok.Type = REGEX
+		tok.Literal = l.readRegex()
+		l.readChar()
 	default:
 		if isDigit(l.ch) || (isDot(l.ch) && isDigit(l.peekChar())) {
 			tok.Literal = l.readNumber()
 			tok.Type = NUMBER
-		} else if isLetter(l.ch) || isDot(l.ch) {
+		} else if isLetter(l.ch) || isDot(l.ch) || l.isVarReference() {
 			tok.Literal = l.readIdentifier()
 			tok.Type = LookupIdentifier(tok.Literal)
 		} else {
@@ -106,28 +127,122 @@ func (l *Lexer) readComment() string {
 	return l.input[pos:l.pos]
 }
 
+// readBlockComment reads a comment delimited by /* and */, which can span
+// multiple lines.
+// /* Foo */ => Token( Foo ).
+func (l *Lexer) readBlockComment() string {
+	l.readChar()
+	pos := l.pos + 1
+	for {
+		l.readChar()
+		if l.ch == 0 || (l.ch == '*' && l.peekChar() == '/') {
+			break
+		}
+		if l.ch == '\n' {
+			l.line++
+			l.column = 0
+		}
+	}
+	comment := l.input[pos:l.pos]
+	if l.ch != 0 {
+		// Leave the lexer on the closing slash.
+		l.readChar()
+	}
+	return comment
+}
+
 // readString reads a string from the input.
+// A backslash at the end of a line continues the string on the next line,
+// without the backslash and the newline.
 // "Foo" => Token(Foo).
 func (l *Lexer) readString(endChar byte) string {
+	var s strings.Builder
 	pos := l.pos + 1
 	for {
 		l.readChar()
+		if l.isLineContinuation() {
+			s.WriteString(l.input[pos:l.pos])
+			l.skipLineContinuation()
+			pos = l.pos + 1
+			continue
+		}
 		if l.ch == endChar || l.ch == 0 || isNewLine(l.ch) {
 			break
 		}
 	}
+	s.WriteString(l.input[pos:l.pos])
+	return s.String()
+}
+
+// isLineContinuation returns whether the current character is a backslash at
+// the end of a line.
+func (l *Lexer) isLineContinuation() bool {
+	if l.ch != '\\' {
+		return false
+	}
+	next := l.peekChar()
+	return next == '\n' || (next == '\r' && l.nextPos+1 < len(l.input) && l.input[l.nextPos+1] == '\n')
+}
+
+// skipLineContinuation moves the lexer from the backslash of a line
+// continuation onto the newline which ends it, and starts the next line.
+func (l *Lexer) skipLineContinuation() {
+	for l.ch != '\n' {
+		l.readChar()
+	}
+	l.line++
+	l.column = 0
+}
+
+// readRegex reads a regular expression delimited by slashes from the input.
+// A slash can be part of the expression if it is escaped with a backslash.
+// /Foo\/Bar/ => Token(Foo\/Bar).
+func (l *Lexer) readRegex() string {
+	pos := l.pos + 1
+	for {
+		l.readChar()
+		if l.ch == '\\' && l.peekChar() == '/' {
+			l.readChar()
+			continue
+		}
+		if l.ch == '/' || l.ch == 0 || isNewLine(l.ch) {
+			break
+		}
+	}
 	return l.input[pos:l.pos]
 }
 
-// readJSON reads a JSON object from the input.
+// readJSON reads a JSON object, or a block of commands, from the input. The
+// braces can be nested, and the ones in strings are skipped.
 // {"foo": "bar"} => Token({"foo": "bar"}).
 func (l *Lexer) readJSON() string {
 	pos := l.pos + 1
+	depth := 0
+	var quote byte
 	for {
 		l.readChar()
-		if l.ch == '}' || l.ch == 0 {
+		if l.ch == 0 {
 			break
 		}
+		if l.ch == '\n' {
+			l.line++
+			l.column = 0
+		}
+		switch {
+		case quote != 0:
+			if l.ch == quote || l.ch == '\n' {
+				quote = 0
+			}
+		case l.ch == '"' || l.ch == '\'' || l.ch == '`':
+			quote = l.ch
+		case l.ch == '{':
+			depth++
+		case l.ch == '}':
+			if depth == 0 {
+				return l.input[pos:l.pos]
+			}
+			depth--
+		}
 	}
 	return l.input[pos:l.pos]
 }
@@ -146,17 +261,39 @@ func (l *Lexer) readNumber() string {
 // Foo => Token(Foo).
 func (l *Lexer) readIdentifier() string {
 	pos := l.pos
-	for isLetter(l.ch) || isDot(l.ch) || isDash(l.ch) || isUnderscore(l.ch) || isSlash(l.ch) || isPercent(l.ch) || isDigit(l.ch) {
-		l.readChar()
+	for {
+		switch {
+		case l.isVarReference() || (l.ch == '{' && l.pos > pos):
+			// A ${NAME} reference to a variable, or a placeholder such as
+			// the {n} of shot-{n}.png.
+			for l.ch != '}' && l.ch != 0 && !isNewLine(l.ch) {
+				l.readChar()
+			}
+			if l.ch == '}' {
+				l.readChar()
+			}
+		case isLetter(l.ch) || isDot(l.ch) || isDash(l.ch) || isUnderscore(l.ch) || isSlash(l.ch) || isPercent(l.ch) || isDigit(l.ch):
+			l.readChar()
+		default:
+			return l.input[pos:l.pos]
+		}
 	}
-	return l.input[pos:l.pos]
 }
 
-// skipWhitespace skips whitespace characters.
+// isVarReference returns whether the current character starts a ${NAME}
+// reference to a variable.
+func (l *Lexer) isVarReference() bool {
+	return l.ch == '$' && l.peekChar() == '{'
+}
+
+// skipWhitespace skips whitespace characters and line continuations.
 // If it encounters a newline, it increments the line counter to keep track
 // of the token's line number.
 func (l *Lexer) skipWhitespace() {
-	for isWhitespace(l.ch) {
+	for isWhitespace(l.ch) || l.isLineContinuation() {
+		if l.ch == '\\' {
+			l.readChar()
+		}
 		// Note: we don't use isNewline since we don't want to double count \r\n on
 		// windows and increment the l.line.
 		if l.ch == '\n' {
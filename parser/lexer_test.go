@@ -1,4 +1,4 @@
-package main
+package parser
 
 import (
 	"os"
@@ -82,8 +82,58 @@ Sleep 2`
 	}
 }
 
+func TestCommentsAndLineContinuation(t *testing.T) {
+	input := `Type "echo # not a comment"
+Type "echo /* not a comment */"
+# Continued? \
+Enter
+/* A block
+comment */ Type "echo \
+hello \
+world"
+Sleep \
+1`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{TYPE, "Type", 1},
+		{STRING, "echo # not a comment", 1},
+		{TYPE, "Type", 2},
+		{STRING, "echo /* not a comment */", 2},
+		{COMMENT, " Continued? \\", 3},
+		{ENTER, "Enter", 4},
+		{COMMENT, " A block\ncomment ", 5},
+		{TYPE, "Type", 6},
+		{STRING, "echo hello world", 6},
+		{SLEEP, "Sleep", 9},
+		{NUMBER, "1", 10},
+		{EOF, "\x00", 10},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d", i, tt.expectedLine, tok.Line)
+		}
+	}
+}
+
 func TestLexTapeFile(t *testing.T) {
-	input, err := os.ReadFile("examples/fixtures/all.tape")
+	input, err := os.ReadFile("../examples/fixtures/all.tape")
 	if err != nil {
 		t.Fatal("could not read all.tape file")
 	}
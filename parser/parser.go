@@ -0,0 +1,1226 @@
+// Package parser reads the tapes of VHS into the commands to record.
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parser is the structure that manages the parsing of tokens.
+type Parser struct {
+	l        *Lexer
+	errors   []ParserError
+	warnings []ParserError
+	cur      Token
+	peek     Token
+	started  bool
+	vars     map[string]string
+	defaults map[string]string
+	sources  []string
+	sourced  []string
+	tokens   []Token
+
+	validKey   func(string) bool
+	validTheme func(string) error
+}
+
+// ParserOption is a function that can be used to modify the Parser before it
+// reads the first tokens.
+type ParserOption func(*Parser)
+
+// WithParserVars returns a ParserOption which sets the values of the
+// variables of the tape. They take precedence over the defaults of the Var
+// commands.
+func WithParserVars(vars map[string]string) ParserOption {
+	return func(p *Parser) {
+		p.vars = vars
+	}
+}
+
+// WithParserKeys returns a ParserOption which sets the function checking the
+// keys of the chords, such as Ctrl+Shift+A. Any key is accepted by default.
+func WithParserKeys(valid func(key string) bool) ParserOption {
+	return func(p *Parser) {
+		p.validKey = valid
+	}
+}
+
+// WithParserThemes returns a ParserOption which sets the function checking
+// the inline JSON themes of Set Theme. Any JSON is accepted by default.
+func WithParserThemes(valid func(theme string) error) ParserOption {
+	return func(p *Parser) {
+		p.validTheme = valid
+	}
+}
+
+// NewParser returns a new Parser.
+func NewParser(l *Lexer, opts ...ParserOption) *Parser {
+	p := &Parser{l: l, errors: []ParserError{}, warnings: []ParserError{}, defaults: map[string]string{}}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	// Read two tokens, so cur and peek are both set.
+	p.nextToken()
+	p.nextToken()
+
+	return p
+}
+
+// Parse takes an input string provided by the lexer and parses it into a
+// list of commands.
+func (p *Parser) Parse() []Command {
+	cmds := []Command{}
+
+	for p.cur.Type != EOF {
+		if p.cur.Type == COMMENT {
+			p.nextToken()
+			continue
+		}
+		if p.cur.Type == QUIET {
+			p.started = true
+			cmds = append(cmds, p.parseQuiet()...)
+			p.nextToken()
+			continue
+		}
+		if p.cur.Type == REPEAT {
+			p.started = true
+			cmds = append(cmds, p.parseRepeatBlock()...)
+			p.nextToken()
+			continue
+		}
+		if p.cur.Type == SKIP {
+			p.started = true
+			cmds = append(cmds, p.parseSkip()...)
+			p.nextToken()
+			continue
+		}
+		if p.cur.Type == SPEED || p.cur.Type == CUT {
+			p.started = true
+			cmds = append(cmds, p.parseSpeedBlock()...)
+			p.nextToken()
+			continue
+		}
+		tok := p.cur
+		var parsed []Command
+		if p.cur.Type == SOURCE {
+			parsed = p.parseSource()
+		} else {
+			parsed = []Command{p.parseCommand()}
+		}
+		for _, cmd := range parsed {
+			if p.started {
+				p.warnIgnored(tok, cmd)
+			} else if !IsConfiguration(cmd) {
+				p.started = true
+			}
+			cmds = append(cmds, cmd)
+			p.tokens = append(p.tokens, tok)
+		}
+		p.nextToken()
+	}
+
+	return cmds
+}
+
+// IsConfiguration returns whether the command configures the recording rather
+// than interacting with the terminal. These commands are evaluated before the
+// recording starts.
+func IsConfiguration(cmd Command) bool {
+	switch cmd.Type {
+	case SET, OUTPUT, REQUIRE, ENV, VAR, SETUP, TEARDOWN, SPLIT:
+		return true
+	default:
+		return false
+	}
+}
+
+// warnIgnored records a warning for commands which are only evaluated at the
+// top of the tape but appear after the recording has started.
+func (p *Parser) warnIgnored(tok Token, cmd Command) {
+	switch {
+	case cmd.Type == SET && !IsRuntimeSetting(cmd.Options) && cmd.Options != "":
+		p.warnings = append(p.warnings, NewError(tok, "Set "+cmd.Options+" is ignored after the first non-setting command"))
+	case cmd.Type == REQUIRE:
+		p.warnings = append(p.warnings, NewError(tok, "Require is ignored after the first non-setting command"))
+	case cmd.Type == ENV:
+		p.warnings = append(p.warnings, NewError(tok, "Env is ignored after the first non-setting command"))
+	case cmd.Type == SPLIT:
+		p.warnings = append(p.warnings, NewError(tok, "Split is ignored after the first non-setting command"))
+	case cmd.Type == SETUP || cmd.Type == TEARDOWN:
+		p.warnings = append(p.warnings, NewError(tok, cmd.Type.String()+" is ignored after the first non-setting command"))
+	}
+}
+
+// parseCommand parses a command.
+func (p *Parser) parseCommand() Command {
+	switch p.cur.Type {
+	case SPACE, BACKSPACE, ENTER, ESCAPE, TAB, DOWN, LEFT, RIGHT, UP:
+		return p.parseKeypress(p.cur.Type)
+	case SET:
+		return p.parseSet()
+	case OUTPUT:
+		return p.parseOutput()
+	case SLEEP:
+		return p.parseSleep()
+	case WAIT:
+		return p.parseWait()
+	case TYPE:
+		return p.parseType()
+	case COPY:
+		return p.parseCopy()
+	case EXPECT, EXPECT_NOT:
+		return p.parseExpect()
+	case PASTE:
+		return Command{Type: PASTE, Options: p.parseSpeed()}
+	case CTRL, ALT, SHIFT:
+		return p.parseChord()
+	case HIDE:
+		return p.parseHide()
+	case BREAKPOINT:
+		return Command{Type: BREAKPOINT}
+	case ABORT:
+		return p.parseAbort()
+	case FLASH:
+		return p.parseFlash()
+	case AUDIO:
+		return p.parseAudio()
+	case CHAPTER:
+		return p.parseChapter()
+	case SPLIT:
+		return p.parseSplit()
+	case FOCUS:
+		return p.parseFocus()
+	case CAPTION:
+		return p.parseCaption()
+	case HIGHLIGHT:
+		return p.parseHighlight()
+	case SCREENSHOT:
+		return p.parseScreenshot()
+	case REQUIRE:
+		return p.parseRequire()
+	case ENV:
+		return p.parseEnv()
+	case SETUP, TEARDOWN:
+		return p.parseScript()
+	case VAR:
+		return p.parseVar()
+	case SHOW:
+		return p.parseShow()
+	default:
+		p.errors = append(p.errors, NewError(p.cur, "Invalid command: "+p.cur.Literal))
+		return Command{Type: ILLEGAL}
+	}
+}
+
+// parseSpeed parses a typing speed indication.
+//
+// i.e. @<time>
+//
+// This is optional (defaults to 100ms), thus skips (rather than error-ing)
+// if the typing speed is not specified.
+func (p *Parser) parseSpeed() string {
+	if p.peek.Type == AT {
+		p.nextToken()
+		return p.parseTime()
+	}
+	return ""
+}
+
+// parseRepeat parses an optional repeat count for a command.
+//
+// i.e. Backspace [count]
+//
+// This is optional (defaults to 1), thus skips (rather than error-ing)
+// if the repeat count is not specified.
+func (p *Parser) parseRepeat() string {
+	if p.peek.Type == NUMBER {
+		count := p.peek.Literal
+		p.nextToken()
+		return count
+	}
+
+	return "1"
+}
+
+// parseTime parses a time argument.
+//
+// <number>[ms]
+func (p *Parser) parseTime() string {
+	var t string
+
+	if p.peek.Type == NUMBER {
+		t = p.peek.Literal
+		p.nextToken()
+	} else {
+		p.errors = append(p.errors, NewError(p.cur, "Expected time after "+p.cur.Literal))
+	}
+
+	// Allow TypingSpeed to have bare units (e.g. 50ms, 100ms)
+	if p.peek.Type == MILLISECONDS || p.peek.Type == SECONDS {
+		t += p.peek.Literal
+		p.nextToken()
+	} else {
+		t += "s"
+	}
+
+	return t
+}
+
+// parseChord parses a key chord.
+// A key chord takes one or more modifiers, in any order, and a key to press
+// while the modifiers are held down.
+//
+// Ctrl+<key>
+// Ctrl+Shift+<key>
+// Alt+<key>
+func (p *Parser) parseChord() Command {
+	line, first := p.cur.Line, p.cur.Type
+	modifiers := map[TokenType]bool{first: true}
+
+	var key string
+	for key == "" {
+		if p.peek.Type != PLUS || p.peek.Line != line {
+			p.errors = append(p.errors, NewError(p.cur, "Expected a key after "+p.cur.Literal+", e.g. "+p.cur.Literal+"+C"))
+			return Command{Type: CommandType(first)}
+		}
+		p.nextToken()
+
+		switch {
+		case p.peek.Line != line || p.peek.Type == EOF:
+			p.errors = append(p.errors, NewError(p.cur, "Expected a key after +"))
+			return Command{Type: CommandType(first)}
+		case IsModifier(p.peek.Type):
+			if modifiers[p.peek.Type] {
+				p.errors = append(p.errors, NewError(p.peek, p.peek.Literal+" is repeated"))
+			}
+			modifiers[p.peek.Type] = true
+		default:
+			key = p.peek.Literal
+			if p.validKey != nil && !p.validKey(key) {
+				p.errors = append(p.errors, NewError(p.peek, "Unknown key "+key))
+			}
+		}
+		p.nextToken()
+	}
+
+	if p.peek.Type == PLUS && p.peek.Line == line {
+		p.nextToken()
+		p.errors = append(p.errors, NewError(p.cur, "Expected a single key after the modifiers, got another one"))
+		if p.peek.Line == line {
+			p.nextToken()
+		}
+	}
+
+	// The modifiers can be given in any order, so the command is always
+	// written in the same one: the first modifier is the type of the
+	// command, and the others come before the key.
+	var cmd Command
+	var args []string
+	for _, m := range []TokenType{CTRL, ALT, SHIFT} {
+		switch {
+		case !modifiers[m]:
+		case cmd.Type == "":
+			cmd.Type = CommandType(m)
+		default:
+			args = append(args, m.String())
+		}
+	}
+	cmd.Args = strings.Join(append(args, key), "+")
+	return cmd
+}
+
+// parseKeypress parses a repeatable and time adjustable keypress command.
+// A keypress command takes an optional typing speed and optional count.
+//
+// Key[@<time>] [count]
+func (p *Parser) parseKeypress(ct TokenType) Command {
+	cmd := Command{Type: CommandType(ct)}
+	cmd.Options = p.parseSpeed()
+	cmd.Args = p.parseRepeat()
+	return cmd
+}
+
+// parseOutput parses an output command.
+// An output command takes a file path to which to output.
+//
+// Output <path>
+func (p *Parser) parseOutput() Command {
+	cmd := Command{Type: OUTPUT}
+
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, "Expected file path after output"))
+		return cmd
+	}
+
+	ext := filepath.Ext(p.peek.Literal)
+	if ext != "" {
+		cmd.Options = ext
+	} else {
+		cmd.Options = ".png"
+		if !strings.HasSuffix(p.peek.Literal, "/") {
+			p.errors = append(p.errors, NewError(p.peek, "Expected folder with trailing slash"))
+		}
+	}
+
+	cmd.Args = p.peek.Literal
+	p.nextToken()
+	return cmd
+}
+
+// parseSet parses a set command.
+// A set command takes a setting name and a value.
+//
+// Set <setting> <value>
+func (p *Parser) parseSet() Command {
+	cmd := Command{Type: SET}
+
+	// Set Var NAME value is the same as Var NAME value.
+	if p.peek.Type == VAR {
+		p.nextToken()
+		return p.parseVar()
+	}
+
+	if IsSetting(p.peek.Type) {
+		cmd.Options = p.peek.Literal
+	} else {
+		p.errors = append(p.errors, NewError(p.peek, "Unknown setting: "+p.peek.Literal))
+	}
+	p.nextToken()
+
+	switch p.cur.Type {
+	case LOOP_OFFSET:
+		cmd.Args = p.peek.Literal
+		p.nextToken()
+		// Allow LoopOffset without '%'
+		// Set LoopOffset 20
+		cmd.Args += "%"
+		if p.peek.Type == PERCENT {
+			p.nextToken()
+		}
+	case TYPING_VARIANCE:
+		cmd.Args = p.peek.Literal
+		p.nextToken()
+		// Allow TypingVariance as a percentage
+		// Set TypingVariance 30%
+		if p.peek.Type == PERCENT {
+			cmd.Args += "%"
+			p.nextToken()
+		}
+	case TYPING_SPEED, KEY_DELAY, DEFAULT_SLEEP:
+		cmd.Args = p.peek.Literal
+		p.nextToken()
+		// Allow TypingSpeed, KeyDelay and DefaultSleep to have bare units (e.g. 10ms)
+		// Set TypingSpeed 10ms
+		if p.peek.Type == MILLISECONDS || p.peek.Type == SECONDS {
+			cmd.Args += p.peek.Literal
+			p.nextToken()
+		} else {
+			cmd.Args += "s"
+		}
+	case MAX_FILE_SIZE:
+		cmd.Args = p.peek.Literal
+		p.nextToken()
+		// Allow MaxFileSize to have bare units
+		// Set MaxFileSize 5MB
+		if p.peek.Type == STRING && p.peek.Line == p.cur.Line {
+			cmd.Args += p.peek.Literal
+			p.nextToken()
+		}
+	case THEME:
+		cmd.Args = p.peek.Literal
+		p.nextToken()
+		// Report the mistakes of an inline theme right away, rather than
+		// once the recording has started.
+		if p.cur.Type == JSON && p.validTheme != nil {
+			if err := p.validTheme(cmd.Args); err != nil {
+				p.errors = append(p.errors, NewError(p.cur, "Invalid theme: "+err.Error()))
+			}
+		}
+	default:
+		cmd.Args = p.peek.Literal
+		p.nextToken()
+	}
+
+	return cmd
+}
+
+// parseSleep parses a sleep command.
+// A sleep command takes a time for how long to sleep.
+//
+// Sleep <time>
+func (p *Parser) parseSleep() Command {
+	cmd := Command{Type: SLEEP}
+	cmd.Args = p.parseTime()
+	return cmd
+}
+
+// parseWait parses a Wait command. The scope is Screen, to match anywhere on
+// the screen, unless it is Line, to match the last line only. Without a
+// regular expression, it waits for the prompt on the last line. The timeout
+// is optional.
+//
+// Wait[+Line|+Screen] [/<regex>/] [<time>]
+func (p *Parser) parseWait() Command {
+	cmd := Command{Type: WAIT}
+	name := p.cur.Literal
+	scope := ""
+	if p.peek.Type == PLUS && p.peek.Line == p.cur.Line {
+		p.nextToken()
+		if p.peek.Literal != WaitLine && p.peek.Literal != WaitScreen {
+			p.errors = append(p.errors, NewError(p.cur, "Expected Line or Screen after "+name+"+"))
+		} else {
+			scope = p.peek.Literal
+		}
+		if p.peek.Line == p.cur.Line {
+			p.nextToken()
+		}
+	}
+
+	// Without a regular expression, wait for the prompt to be back.
+	if p.peek.Type != REGEX || p.peek.Line != p.cur.Line {
+		if scope == "" {
+			scope = WaitLine
+		}
+		cmd.Args = scope + " /" + PromptPattern + "/"
+		if p.peek.Type == NUMBER && p.peek.Line == p.cur.Line {
+			cmd.Options = p.parseTime()
+		}
+		return cmd
+	}
+	if scope == "" {
+		scope = WaitScreen
+	}
+	p.nextToken()
+	if _, err := regexp.Compile(p.cur.Literal); err != nil {
+		p.errors = append(p.errors, NewError(p.cur, "Invalid regular expression /"+p.cur.Literal+"/: "+err.Error()))
+	}
+	cmd.Args = scope + " /" + p.cur.Literal + "/"
+
+	if p.peek.Type == NUMBER && p.peek.Line == p.cur.Line {
+		cmd.Options = p.parseTime()
+	}
+	return cmd
+}
+
+// parseHide parses a Hide command.
+//
+// Hide
+// ...
+func (p *Parser) parseHide() Command {
+	cmd := Command{Type: HIDE}
+	return cmd
+}
+
+// parseFlash parses a Flash command.
+// A flash command takes an optional duration for how long to flash.
+//
+// Flash[@<time>]
+func (p *Parser) parseFlash() Command {
+	cmd := Command{Type: FLASH}
+	cmd.Options = p.parseSpeed()
+	return cmd
+}
+
+// parseAudio parses an Audio command, with the audio file and how long after
+// the command it starts playing.
+//
+// Audio "<path>" [<time>]
+func (p *Parser) parseAudio() Command {
+	cmd := Command{Type: AUDIO}
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, "Expected the path of the audio file after Audio"))
+		return cmd
+	}
+	cmd.Args = p.peek.Literal
+	p.nextToken()
+	cmd.Options = p.parseOverlayDuration()
+	return cmd
+}
+
+// parseChapter parses a Chapter command, with the title of the chapter.
+//
+// Chapter "<title>"
+func (p *Parser) parseChapter() Command {
+	cmd := Command{Type: CHAPTER}
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, "Expected the title of the chapter after Chapter"))
+		return cmd
+	}
+	cmd.Args = p.peek.Literal
+	p.nextToken()
+	return cmd
+}
+
+// parseSplit parses a Split command, with the layout of the panes.
+//
+// Split horizontal|vertical
+func (p *Parser) parseSplit() Command {
+	cmd := Command{Type: SPLIT}
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, "Expected horizontal or vertical after Split"))
+		return cmd
+	}
+	p.nextToken()
+	if p.cur.Literal != SplitHorizontal && p.cur.Literal != SplitVertical {
+		p.errors = append(p.errors, NewError(p.cur, "Expected horizontal or vertical after Split"))
+		return cmd
+	}
+	cmd.Args = p.cur.Literal
+	return cmd
+}
+
+// parseFocus parses a Focus command, with the pane, counted from 1, which
+// receives the next commands.
+//
+// Focus <number>
+func (p *Parser) parseFocus() Command {
+	cmd := Command{Type: FOCUS}
+	if p.peek.Type != NUMBER {
+		p.errors = append(p.errors, NewError(p.cur, "Expected the number of a pane after Focus"))
+		return cmd
+	}
+	p.nextToken()
+	if n, err := strconv.Atoi(p.cur.Literal); err != nil || n < 1 {
+		p.errors = append(p.errors, NewError(p.cur, "Expected the number of a pane after Focus"))
+		return cmd
+	}
+	cmd.Args = p.cur.Literal
+	return cmd
+}
+
+// parseCaption parses a Caption command, with the text of the caption and
+// how long it is shown.
+//
+// Caption "<text>" [<time>]
+func (p *Parser) parseCaption() Command {
+	cmd := Command{Type: CAPTION}
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, "Expected the text of the caption after Caption"))
+		return cmd
+	}
+	cmd.Args = p.peek.Literal
+	p.nextToken()
+	cmd.Options = p.parseOverlayDuration()
+	return cmd
+}
+
+// parseHighlight parses a Highlight command, with the row and the column of
+// the top left cell of the box, its width and height in cells, and how long it
+// is shown.
+//
+// Highlight <row>,<col>,<width>,<height> [<time>]
+func (p *Parser) parseHighlight() Command {
+	cmd := Command{Type: HIGHLIGHT}
+	cells := make([]string, 0, 4) //nolint:gomnd
+	for i := 0; i < 4; i++ {
+		if i > 0 {
+			if p.peek.Type != COMMA {
+				break
+			}
+			p.nextToken()
+		}
+		if p.peek.Type != NUMBER || strings.Contains(p.peek.Literal, ".") {
+			break
+		}
+		cells = append(cells, p.peek.Literal)
+		p.nextToken()
+	}
+	if len(cells) != 4 { //nolint:gomnd
+		p.errors = append(p.errors, NewError(p.cur, "Expected row,col,width,height after Highlight"))
+		return cmd
+	}
+	cmd.Args = strings.Join(cells, ",")
+	cmd.Options = p.parseOverlayDuration()
+	return cmd
+}
+
+// parseOverlayDuration parses the optional duration of a Caption or a
+// Highlight, on the same line.
+func (p *Parser) parseOverlayDuration() string {
+	if p.peek.Type != NUMBER || p.peek.Line != p.cur.Line {
+		return ""
+	}
+	return p.parseTime()
+}
+
+// parseQuiet parses a Quiet block. The commands of the block are wrapped
+// between two Quiet commands which turn the quiet mode on and off.
+//
+// Quiet { <commands> }
+func (p *Parser) parseQuiet() []Command {
+	quiet := p.cur
+	if p.peek.Type != JSON {
+		p.errors = append(p.errors, NewError(p.cur, p.cur.Literal+" expects a block of commands: Quiet { ... }"))
+		p.tokens = append(p.tokens, quiet, quiet)
+		return []Command{{Type: QUIET, Args: "on"}, {Type: QUIET, Args: "off"}}
+	}
+	p.nextToken()
+
+	block := p.block()
+	cmds := []Command{{Type: QUIET, Args: "on"}}
+	p.tokens = append(p.tokens, quiet)
+	for i, cmd := range block.Parse() {
+		if cmd.Type == OUTPUT || cmd.Type == REQUIRE || cmd.Type == ENV {
+			p.errors = append(p.errors, NewError(p.cur, cmd.Type.String()+" is not allowed in a Quiet block"))
+			continue
+		}
+		cmds = append(cmds, cmd)
+		p.tokens = append(p.tokens, block.tokens[i])
+	}
+	cmds = append(cmds, Command{Type: QUIET, Args: "off"})
+	p.tokens = append(p.tokens, quiet)
+
+	p.errors = append(p.errors, block.errors...)
+	p.warnings = append(p.warnings, block.warnings...)
+	p.sourced = append(p.sourced, block.sourced...)
+	return cmds
+}
+
+// The limits of a Repeat block, which is expanded as the tape is parsed, so
+// that nested blocks can't exhaust the memory.
+const (
+	maxRepeatCount      = 10000
+	maxRepeatedCommands = 100000
+)
+
+// parseRepeatBlock parses a Repeat block, whose commands are repeated the
+// given number of times. Blocks can be nested.
+//
+// Repeat <count> { <commands> }
+func (p *Parser) parseRepeatBlock() []Command {
+	repeat := p.cur
+	if p.peek.Type != NUMBER {
+		p.errors = append(p.errors, NewError(p.cur, p.cur.Literal+" expects a number of times: Repeat 3 { ... }"))
+		if p.peek.Type == JSON {
+			p.nextToken()
+		}
+		return nil
+	}
+	p.nextToken()
+	count, err := strconv.Atoi(p.cur.Literal)
+	if err != nil {
+		p.errors = append(p.errors, NewError(p.cur, "Invalid number of times "+p.cur.Literal+" for "+repeat.Literal))
+	}
+	if p.peek.Type != JSON {
+		p.errors = append(p.errors, NewError(p.cur, repeat.Literal+" expects a block of commands: Repeat 3 { ... }"))
+		return nil
+	}
+	p.nextToken()
+
+	block := p.block()
+	var cmds []Command
+	var tokens []Token
+	for i, cmd := range block.Parse() {
+		if cmd.Type == OUTPUT || cmd.Type == REQUIRE || cmd.Type == ENV {
+			p.errors = append(p.errors, NewError(p.cur, cmd.Type.String()+" is not allowed in a Repeat block"))
+			continue
+		}
+		cmds = append(cmds, cmd)
+		tokens = append(tokens, block.tokens[i])
+	}
+	p.errors = append(p.errors, block.errors...)
+	p.warnings = append(p.warnings, block.warnings...)
+	p.sourced = append(p.sourced, block.sourced...)
+
+	if count > maxRepeatCount {
+		p.errors = append(p.errors, NewError(repeat, fmt.Sprintf("%s can't run a block more than %d times", repeat.Literal, maxRepeatCount)))
+		return nil
+	}
+	if count*len(cmds) > maxRepeatedCommands {
+		p.errors = append(p.errors, NewError(repeat, fmt.Sprintf("%s expands to more than %d commands", repeat.Literal, maxRepeatedCommands)))
+		return nil
+	}
+
+	var repeated []Command
+	for i := 0; i < count; i++ {
+		repeated = append(repeated, cmds...)
+		p.tokens = append(p.tokens, tokens...)
+	}
+	return repeated
+}
+
+// parseSkip parses a Skip block, whose commands are skipped unless a program
+// is installed, or on an operating system. The commands of the block are
+// wrapped between two Skip commands which start and end the block.
+//
+// Skip unless <program> { <commands> }
+// Skip on <os> { <commands> }
+func (p *Parser) parseSkip() []Command {
+	skip := p.cur
+	usage := " expects a condition and a block of commands: Skip unless <program> { ... } or Skip on <os> { ... }"
+	if p.peek.Type != STRING || (p.peek.Literal != SkipUnless && p.peek.Literal != SkipOn) {
+		p.errors = append(p.errors, NewError(p.cur, skip.Literal+usage))
+		p.skipLine(skip)
+		return nil
+	}
+	p.nextToken()
+	kind := p.cur.Literal
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, skip.Literal+usage))
+		p.skipLine(skip)
+		return nil
+	}
+	p.nextToken()
+	condition := kind + " " + p.cur.Literal
+	if p.peek.Type != JSON {
+		p.errors = append(p.errors, NewError(p.cur, skip.Literal+usage))
+		p.skipLine(skip)
+		return nil
+	}
+	p.nextToken()
+
+	block := p.block()
+	cmds := []Command{{Type: SKIP, Args: condition}}
+	p.tokens = append(p.tokens, skip)
+	for i, cmd := range block.Parse() {
+		if cmd.Type == OUTPUT || cmd.Type == REQUIRE || cmd.Type == ENV {
+			p.errors = append(p.errors, NewError(p.cur, cmd.Type.String()+" is not allowed in a Skip block"))
+			continue
+		}
+		cmds = append(cmds, cmd)
+		p.tokens = append(p.tokens, block.tokens[i])
+	}
+	cmds = append(cmds, Command{Type: SKIP, Args: SkipEnd})
+	p.tokens = append(p.tokens, skip)
+
+	p.errors = append(p.errors, block.errors...)
+	p.warnings = append(p.warnings, block.warnings...)
+	p.sourced = append(p.sourced, block.sourced...)
+	return cmds
+}
+
+// parseSpeedBlock parses a Speed block, whose frames are sped up by the
+// factor in the outputs, or a Cut block, whose frames are left out of them.
+// The commands of the block are wrapped between two Speed commands which
+// start and end the block.
+//
+// Speed <factor> { <commands> }
+// Cut { <commands> }
+func (p *Parser) parseSpeedBlock() []Command {
+	speed := p.cur
+	factor := "0"
+	if speed.Type == SPEED {
+		if p.peek.Type != NUMBER {
+			p.errors = append(p.errors, NewError(p.cur, speed.Literal+" expects a factor: Speed 4 { ... }"))
+			p.skipLine(speed)
+			return nil
+		}
+		p.nextToken()
+		if f, err := strconv.ParseFloat(p.cur.Literal, 64); err != nil || f <= 0 {
+			p.errors = append(p.errors, NewError(p.cur, "Invalid factor "+p.cur.Literal+" for "+speed.Literal))
+		}
+		factor = p.cur.Literal
+	}
+	if p.peek.Type != JSON {
+		p.errors = append(p.errors, NewError(p.cur, speed.Literal+" expects a block of commands: "+speed.Literal+" { ... }"))
+		p.skipLine(speed)
+		return nil
+	}
+	p.nextToken()
+
+	block := p.block()
+	cmds := []Command{{Type: SPEED, Args: factor}}
+	p.tokens = append(p.tokens, speed)
+	for i, cmd := range block.Parse() {
+		if cmd.Type == OUTPUT || cmd.Type == REQUIRE || cmd.Type == ENV {
+			p.errors = append(p.errors, NewError(p.cur, cmd.Type.String()+" is not allowed in a "+speed.Literal+" block"))
+			continue
+		}
+		cmds = append(cmds, cmd)
+		p.tokens = append(p.tokens, block.tokens[i])
+	}
+	cmds = append(cmds, Command{Type: SPEED, Args: SpeedEnd})
+	p.tokens = append(p.tokens, speed)
+
+	p.errors = append(p.errors, block.errors...)
+	p.warnings = append(p.warnings, block.warnings...)
+	p.sourced = append(p.sourced, block.sourced...)
+	return cmds
+}
+
+// skipLine skips the rest of the line of the token, along with a block which
+// starts on it.
+func (p *Parser) skipLine(tok Token) {
+	for p.peek.Type != EOF && p.peek.Line == tok.Line {
+		p.nextToken()
+	}
+}
+
+// block returns a parser of the block of commands of the current token, with
+// the variables of this parser. The commands of the block are reported at
+// their position in the tape.
+func (p *Parser) block() *Parser {
+	l := NewLexer(strings.TrimSuffix(strings.TrimPrefix(p.cur.Literal, "{"), "}"))
+	l.line, l.column = p.cur.Line, p.cur.Column+1
+	block := NewParser(l, func(block *Parser) {
+		block.vars, block.defaults, block.sources = p.vars, p.defaults, p.sources
+		block.validKey, block.validTheme = p.validKey, p.validTheme
+	})
+	block.started = true
+	return block
+}
+
+// parseSource parses a Source command, which includes the commands of another
+// tape file, except for its outputs. The arguments are available to the
+// sourced tape as the ${1}, ${2}, ... variables.
+//
+// Source <path>.tape [args...]
+func (p *Parser) parseSource() []Command {
+	line := p.cur.Line
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, p.cur.Literal+" expects a tape file"))
+		return nil
+	}
+	p.nextToken()
+	tok := p.cur
+	path := tok.Literal
+
+	var args []string
+	for p.peek.Line == line && (p.peek.Type == STRING || p.peek.Type == NUMBER) {
+		p.nextToken()
+		args = append(args, p.cur.Literal)
+	}
+
+	if filepath.Ext(path) != Extension {
+		p.errors = append(p.errors, NewError(tok, "Expected source to be a .tape file"))
+		return nil
+	}
+
+	abs, _ := filepath.Abs(path)
+	for i, source := range p.sources {
+		if s, _ := filepath.Abs(source); s == abs {
+			chain := append(append([]string{}, p.sources[i:]...), path)
+			p.errors = append(p.errors, NewError(tok, "cyclic source detected: "+strings.Join(chain, " -> ")))
+			return nil
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		p.errors = append(p.errors, NewError(tok, "Failed to read source "+path+": "+err.Error()))
+		return nil
+	}
+
+	// The sourced tape sees the variables of this one, but the ones it
+	// defines don't leak out of it.
+	vars := make(map[string]string, len(p.vars)+len(args))
+	for name, value := range p.vars {
+		vars[name] = value
+	}
+	for i, arg := range args {
+		vars[strconv.Itoa(i+1)] = arg
+	}
+	defaults := make(map[string]string, len(p.defaults))
+	for name, value := range p.defaults {
+		defaults[name] = value
+	}
+
+	source := NewParser(NewLexer(string(b)), func(source *Parser) {
+		source.vars, source.defaults = vars, defaults
+		source.validKey, source.validTheme = p.validKey, p.validTheme
+		source.sources = append(append([]string{}, p.sources...), path)
+	})
+
+	var cmds []Command
+	for _, cmd := range source.Parse() {
+		if cmd.Type != OUTPUT {
+			cmds = append(cmds, cmd)
+		}
+	}
+	p.sourced = append(append(p.sourced, path), source.sourced...)
+
+	// The errors are reported on the Source command, since the positions are
+	// the ones of the sourced tape.
+	for _, err := range source.errors {
+		p.errors = append(p.errors, NewError(tok, fmt.Sprintf("%s:%d:%d: %s", path, err.Token.Line, err.Token.Column, err.Msg)))
+	}
+	return cmds
+}
+
+// parseScreenshot parses a Screenshot command.
+// A Screenshot command takes the path of the PNG image to save.
+//
+// Screenshot <path>.png
+func (p *Parser) parseScreenshot() Command {
+	cmd := Command{Type: SCREENSHOT}
+
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.cur, "Expected file path after screenshot"))
+		return cmd
+	}
+
+	if filepath.Ext(p.peek.Literal) != ".png" {
+		p.errors = append(p.errors, NewError(p.peek, "Expected screenshot to be a .png file"))
+	}
+
+	cmd.Args = p.peek.Literal
+	p.nextToken()
+	return cmd
+}
+
+// parseVar parses a Var command.
+// A Var command defines the default value of a variable, which replaces the
+// ${NAME} references which follow it unless a value is given with --var.
+//
+// Var <name> <default>
+func (p *Parser) parseVar() Command {
+	cmd := Command{Type: VAR}
+
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.peek, p.cur.Literal+" expects a variable name"))
+		return cmd
+	}
+	if !VarName.MatchString(p.peek.Literal) {
+		p.errors = append(p.errors, NewError(p.peek, "Invalid variable name "+p.peek.Literal))
+	}
+	cmd.Options = p.peek.Literal
+	p.nextToken()
+
+	if p.peek.Type != STRING && p.peek.Type != NUMBER {
+		p.errors = append(p.errors, NewError(p.cur, "Var "+cmd.Options+" expects a default value"))
+		return cmd
+	}
+	cmd.Args = p.peek.Literal
+	p.defaults[cmd.Options] = cmd.Args
+	p.nextToken()
+
+	return cmd
+}
+
+// VarName matches the valid names of variables.
+var VarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseEnv parses an Env command.
+// An Env command takes the name and value of an environment variable of the
+// shell.
+//
+// Env <key> <value>
+func (p *Parser) parseEnv() Command {
+	cmd := Command{Type: ENV}
+
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.peek, p.cur.Literal+" expects a variable name"))
+		return cmd
+	}
+	if p.peek.Literal == "" || strings.ContainsAny(p.peek.Literal, "= ") {
+		p.errors = append(p.errors, NewError(p.peek, p.cur.Literal+" expects a variable name"))
+	}
+	cmd.Options = p.peek.Literal
+	p.nextToken()
+
+	if p.peek.Type != STRING && p.peek.Type != NUMBER {
+		p.errors = append(p.errors, NewError(p.cur, "Env "+cmd.Options+" expects a value"))
+		return cmd
+	}
+	cmd.Args = p.peek.Literal
+	p.nextToken()
+
+	return cmd
+}
+
+// parseScript parses a Setup or Teardown command, whose block is a script of
+// the shell rather than commands of the tape.
+//
+// Setup { <script> }
+// Teardown { <script> }
+func (p *Parser) parseScript() Command {
+	cmd := Command{Type: CommandType(p.cur.Type)}
+	if p.peek.Type != JSON {
+		p.errors = append(p.errors, NewError(p.cur, p.cur.Literal+" expects a block of shell commands: "+p.cur.Literal+" { ... }"))
+		return cmd
+	}
+	p.nextToken()
+	cmd.Args = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(p.cur.Literal, "{"), "}"))
+	return cmd
+}
+
+// parseRequire parses a Require command.
+//
+// ...
+// Require
+func (p *Parser) parseRequire() Command {
+	cmd := Command{Type: REQUIRE}
+
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.peek, p.cur.Literal+" expects one string"))
+	}
+
+	cmd.Args = p.peek.Literal
+	p.nextToken()
+
+	return cmd
+}
+
+// parseShow parses a Show command.
+//
+// ...
+// Show
+func (p *Parser) parseShow() Command {
+	cmd := Command{Type: SHOW}
+	return cmd
+}
+
+// parseType parses a type command.
+// A type command takes a string to type.
+//
+// Type "string"
+func (p *Parser) parseType() Command {
+	cmd := Command{Type: TYPE}
+
+	cmd.Options = p.parseSpeed()
+
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.peek, p.cur.Literal+" expects string"))
+	}
+
+	for p.peek.Type == STRING {
+		p.nextToken()
+		cmd.Args += p.cur.Literal
+
+		// If the next token is a string, add a space between them.
+		// Since tokens must be separated by a whitespace, this is most likely
+		// what the user intended.
+		//
+		// Although it is possible that there may be multiple spaces / tabs between
+		// the tokens, however if the user was intending to type multiple spaces
+		// they would need to use a string literal.
+
+		if p.peek.Type == STRING {
+			cmd.Args += " "
+		}
+	}
+
+	return cmd
+}
+
+// parseExpect parses an Expect or ExpectNot command, which takes a text or a
+// regular expression to look for on the screen. The text is kept quoted, so
+// that it can be told apart from a regular expression.
+//
+// Expect "<string>"
+// ExpectNot /<regex>/
+func (p *Parser) parseExpect() Command {
+	cmd := Command{Type: CommandType(p.cur.Type)}
+
+	switch p.peek.Type {
+	case STRING:
+		p.nextToken()
+		cmd.Args = strconv.Quote(p.cur.Literal)
+	case REGEX:
+		p.nextToken()
+		if _, err := regexp.Compile(p.cur.Literal); err != nil {
+			p.errors = append(p.errors, NewError(p.cur, "Invalid regular expression /"+p.cur.Literal+"/: "+err.Error()))
+		}
+		cmd.Args = "/" + p.cur.Literal + "/"
+	default:
+		p.errors = append(p.errors, NewError(p.cur, p.cur.Literal+" expects a string or a regular expression, e.g. "+p.cur.Literal+" /Done/"))
+	}
+	return cmd
+}
+
+// parseAbort parses an Abort command, with an optional message on the same
+// line.
+//
+// Abort [message]
+func (p *Parser) parseAbort() Command {
+	cmd := Command{Type: ABORT}
+	if p.peek.Type == STRING && p.peek.Line == p.cur.Line {
+		p.nextToken()
+		cmd.Args = p.cur.Literal
+	}
+	return cmd
+}
+
+// parseCopy parses a copy command.
+// A copy command takes a string to copy to the clipboard.
+//
+// Copy "<string>"
+func (p *Parser) parseCopy() Command {
+	cmd := Command{Type: COPY}
+
+	if p.peek.Type != STRING {
+		p.errors = append(p.errors, NewError(p.peek, p.cur.Literal+" expects string"))
+		return cmd
+	}
+
+	p.nextToken()
+	cmd.Args = p.cur.Literal
+	return cmd
+}
+
+// Errors returns any errors that occurred during parsing.
+func (p *Parser) Errors() []ParserError {
+	return p.errors
+}
+
+// Warnings returns any warnings that occurred during parsing.
+// Warnings do not prevent a tape from running, they point out commands which
+// will likely not behave as intended.
+func (p *Parser) Warnings() []ParserError {
+	return p.warnings
+}
+
+// Tokens returns the first token of every parsed command, e.g. to report on
+// which line it is. The commands included with Source have the token of the
+// Source command.
+func (p *Parser) Tokens() []Token {
+	return p.tokens
+}
+
+// Sourced returns the paths of the tape files included with Source, including
+// the ones they source themselves.
+func (p *Parser) Sourced() []string {
+	return p.sourced
+}
+
+// nextToken gets the next token from the lexer
+// and updates the parser tokens accordingly.
+func (p *Parser) nextToken() {
+	p.cur = p.peek
+	p.peek = p.l.NextToken()
+	if p.peek.Type == STRING {
+		p.peek.Literal = p.expandVars(p.peek.Literal)
+	}
+}
+
+// expandVars returns the string with its ${NAME} references to the variables
+// of Var and --var replaced by their values. Anything else is left as is for
+// the shell, such as $NAME, ${PWD} or ${NAME:-default}, and $${ is written as
+// ${ to keep a reference from being replaced.
+func (p *Parser) expandVars(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		j := strings.Index(s[i:], "}")
+		if j < 0 {
+			break
+		}
+		name := s[i+2 : i+j]
+		value, ok := p.vars[name]
+		if !ok {
+			value, ok = p.defaults[name]
+		}
+		if !ok {
+			value = s[i : i+j+1]
+		}
+		b.WriteString(s[:i])
+		b.WriteString(value)
+		s = s[i+j+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
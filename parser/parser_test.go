@@ -0,0 +1,1063 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParser(t *testing.T) {
+	input := `
+Set TypingSpeed 100ms
+Set SleepScale 0.25
+Set KeyLog "demo.keys"
+Set Timezone "UTC"
+Set KeyDelay 50ms
+Set KeyDelay 1
+Set DefaultSleep 500ms
+Type "echo 'Hello, World!'"
+Enter
+Backspace@0.1 5
+Backspace@.1 5
+Backspace@1 5
+Backspace@100ms 5
+Right 3
+Left 3
+Up@50ms
+Down 2
+Ctrl+C
+Ctrl+L
+Sleep 100ms
+Sleep 3
+Flash
+Flash@200ms`
+
+	expected := []Command{
+		{Type: SET, Options: "TypingSpeed", Args: "100ms"},
+		{Type: SET, Options: "SleepScale", Args: "0.25"},
+		{Type: SET, Options: "KeyLog", Args: "demo.keys"},
+		{Type: SET, Options: "Timezone", Args: "UTC"},
+		{Type: SET, Options: "KeyDelay", Args: "50ms"},
+		{Type: SET, Options: "KeyDelay", Args: "1s"},
+		{Type: SET, Options: "DefaultSleep", Args: "500ms"},
+		{Type: TYPE, Options: "", Args: "echo 'Hello, World!'"},
+		{Type: ENTER, Options: "", Args: "1"},
+		{Type: BACKSPACE, Options: "0.1s", Args: "5"},
+		{Type: BACKSPACE, Options: ".1s", Args: "5"},
+		{Type: BACKSPACE, Options: "1s", Args: "5"},
+		{Type: BACKSPACE, Options: "100ms", Args: "5"},
+		{Type: RIGHT, Options: "", Args: "3"},
+		{Type: LEFT, Options: "", Args: "3"},
+		{Type: UP, Options: "50ms", Args: "1"},
+		{Type: DOWN, Options: "", Args: "2"},
+		{Type: CTRL, Options: "", Args: "C"},
+		{Type: CTRL, Options: "", Args: "L"},
+		{Type: SLEEP, Args: "100ms"},
+		{Type: SLEEP, Args: "3s"},
+		{Type: FLASH, Options: "", Args: ""},
+		{Type: FLASH, Options: "200ms", Args: ""},
+	}
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d", len(expected), len(cmds))
+	}
+
+	for i, cmd := range cmds {
+		if cmd.Type != expected[i].Type {
+			t.Errorf("Expected command %d to be %s, got %s", i, expected[i].Type, cmd.Type)
+		}
+		if cmd.Args != expected[i].Args {
+			t.Errorf("Expected command %d to have args %s, got %s", i, expected[i].Args, cmd.Args)
+		}
+		if cmd.Options != expected[i].Options {
+			t.Errorf("Expected command %d to have options %s, got %s", i, expected[i].Options, cmd.Options)
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	input := `
+Type Enter
+Type "echo 'Hello, World!'" Enter
+Foo
+Sleep Bar`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	_ = p.Parse()
+
+	expectedErrors := []string{
+		" 2:6  │ Type expects string",
+		" 4:1  │ Invalid command: Foo",
+		" 5:1  │ Expected time after Sleep",
+		" 5:7  │ Invalid command: Bar",
+	}
+
+	if len(p.errors) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors, got %d", len(expectedErrors), len(p.errors))
+	}
+
+	for i, err := range p.errors {
+		if err.String() != expectedErrors[i] {
+			t.Errorf("Expected error %d to be [%s], got (%s)", i, expectedErrors[i], err)
+		}
+	}
+}
+
+func TestParseTapeFile(t *testing.T) {
+	input, err := os.ReadFile("../examples/fixtures/all.tape")
+	if err != nil {
+		t.Fatal("could not read fixture file")
+	}
+
+	expected := []Command{
+		{Type: OUTPUT, Options: ".gif", Args: "examples/fixtures/all.gif"},
+		{Type: OUTPUT, Options: ".mp4", Args: "examples/fixtures/all.mp4"},
+		{Type: OUTPUT, Options: ".webm", Args: "examples/fixtures/all.webm"},
+		{Type: SET, Options: "Shell", Args: "fish"},
+		{Type: SET, Options: "FontSize", Args: "22"},
+		{Type: SET, Options: "FontFamily", Args: "DejaVu Sans Mono"},
+		{Type: SET, Options: "Height", Args: "600"},
+		{Type: SET, Options: "Width", Args: "1200"},
+		{Type: SET, Options: "LetterSpacing", Args: "1"},
+		{Type: SET, Options: "LineHeight", Args: "1.2"},
+		{Type: SET, Options: "Theme", Args: "{ \"name\": \"Whimsy\", \"black\": \"#535178\", \"red\": \"#ef6487\", \"green\": \"#5eca89\", \"yellow\": \"#fdd877\", \"blue\": \"#65aef7\", \"purple\": \"#aa7ff0\", \"cyan\": \"#43c1be\", \"white\": \"#ffffff\", \"brightBlack\": \"#535178\", \"brightRed\": \"#ef6487\", \"brightGreen\": \"#5eca89\", \"brightYellow\": \"#fdd877\", \"brightBlue\": \"#65aef7\", \"brightPurple\": \"#aa7ff0\", \"brightCyan\": \"#43c1be\", \"brightWhite\": \"#ffffff\", \"background\": \"#29283b\", \"foreground\": \"#b3b0d6\", \"selectionBackground\": \"#3d3c58\", \"cursorColor\": \"#b3b0d6\" }"},
+		{Type: SET, Options: "Theme", Args: "Catppuccin Mocha"},
+		{Type: SET, Options: "Padding", Args: "50"},
+		{Type: SET, Options: "Framerate", Args: "60"},
+		{Type: SET, Options: "PlaybackSpeed", Args: "2"},
+		{Type: SET, Options: "TypingSpeed", Args: ".1s"},
+		{Type: SET, Options: "LoopOffset", Args: "60.4%"},
+		{Type: SET, Options: "LoopOffset", Args: "20.99%"},
+		{Type: SLEEP, Options: "", Args: "1s"},
+		{Type: SLEEP, Options: "", Args: "500ms"},
+		{Type: SLEEP, Options: "", Args: ".5s"},
+		{Type: SLEEP, Options: "", Args: "0.5s"},
+		{Type: TYPE, Options: ".5s", Args: "All"},
+		{Type: TYPE, Options: "500ms", Args: "All"},
+		{Type: TYPE, Options: "", Args: "Double Quote"},
+		{Type: TYPE, Options: "", Args: "\"Single\" Quote"},
+		{Type: TYPE, Options: "", Args: `"Backtick" 'Quote'`},
+		{Type: BACKSPACE, Options: "", Args: "1"},
+		{Type: BACKSPACE, Options: "", Args: "2"},
+		{Type: BACKSPACE, Options: "1s", Args: "3"},
+		{Type: DOWN, Options: "", Args: "1"},
+		{Type: DOWN, Options: "", Args: "2"},
+		{Type: DOWN, Options: "1s", Args: "3"},
+		{Type: ENTER, Options: "", Args: "1"},
+		{Type: ENTER, Options: "", Args: "2"},
+		{Type: ENTER, Options: "1s", Args: "3"},
+		{Type: SPACE, Options: "", Args: "1"},
+		{Type: SPACE, Options: "", Args: "2"},
+		{Type: SPACE, Options: "1s", Args: "3"},
+		{Type: TAB, Options: "", Args: "1"},
+		{Type: TAB, Options: "", Args: "2"},
+		{Type: TAB, Options: "1s", Args: "3"},
+		{Type: LEFT, Options: "", Args: "1"},
+		{Type: LEFT, Options: "", Args: "2"},
+		{Type: LEFT, Options: "1s", Args: "3"},
+		{Type: RIGHT, Options: "", Args: "1"},
+		{Type: RIGHT, Options: "", Args: "2"},
+		{Type: RIGHT, Options: "1s", Args: "3"},
+		{Type: UP, Options: "", Args: "1"},
+		{Type: UP, Options: "", Args: "2"},
+		{Type: UP, Options: "1s", Args: "3"},
+		{Type: DOWN, Options: "", Args: "1"},
+		{Type: DOWN, Options: "", Args: "2"},
+		{Type: DOWN, Options: "1s", Args: "3"},
+		{Type: CTRL, Options: "", Args: "C"},
+		{Type: CTRL, Options: "", Args: "L"},
+		{Type: CTRL, Options: "", Args: "R"},
+		{Type: HIDE, Options: "", Args: ""},
+		{Type: SHOW, Options: "", Args: ""},
+	}
+
+	l := NewLexer(string(input))
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d", len(expected), len(cmds))
+	}
+
+	for i, cmd := range cmds {
+		if cmd.Type != expected[i].Type {
+			t.Errorf("Expected command %d to be %s, got %s", i, expected[i].Type, cmd.Type)
+		}
+		if cmd.Args != expected[i].Args {
+			t.Errorf("Expected command %d to have args %s, got %s", i, expected[i].Args, cmd.Args)
+		}
+		if cmd.Options != expected[i].Options {
+			t.Errorf("Expected command %d to have options %s, got %s", i, expected[i].Options, cmd.Options)
+		}
+	}
+}
+
+func TestParseInvalidUTF8(t *testing.T) {
+	input := "Type \"caf\xe9 \xff\xfe\"\nEnter\n\xc3\x28"
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	if len(cmds) != 4 {
+		t.Fatalf("Expected 4 commands, got %d", len(cmds))
+	}
+	if cmds[0].Type != TYPE || cmds[0].Args != "caf\xe9 \xff\xfe" {
+		t.Errorf("Expected invalid bytes to be preserved, got %q", cmds[0].Args)
+	}
+	if cmds[1].Type != ENTER {
+		t.Errorf("Expected command 1 to be %s, got %s", ENTER, cmds[1].Type)
+	}
+	if len(p.Errors()) != 2 {
+		t.Fatalf("Expected 2 errors, got %d", len(p.Errors()))
+	}
+}
+
+func TestParserWarnings(t *testing.T) {
+	input := `
+Set FontSize 22
+Require git
+Type "echo 'Hello, World!'"
+Set TypingSpeed 100ms
+Set KeyDelay 0
+Set FontSize 42
+Require gum
+Output out.gif`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	_ = p.Parse()
+
+	expectedWarnings := []string{
+		" 7:1  │ Set FontSize is ignored after the first non-setting command",
+		" 8:1  │ Require is ignored after the first non-setting command",
+	}
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expected no errors, got %d", len(p.Errors()))
+	}
+
+	if len(p.Warnings()) != len(expectedWarnings) {
+		t.Fatalf("Expected %d warnings, got %d", len(expectedWarnings), len(p.Warnings()))
+	}
+
+	for i, warn := range p.Warnings() {
+		if warn.String() != expectedWarnings[i] {
+			t.Errorf("Expected warning %d to be [%s], got (%s)", i, expectedWarnings[i], warn)
+		}
+	}
+}
+
+func TestParseQuiet(t *testing.T) {
+	input := `Type "ls"
+Quiet {
+  Type "make"
+  Enter
+  Sleep 2s
+  Set FontSize 42
+}
+Type "done"`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: TYPE, Options: "", Args: "ls"},
+		{Type: QUIET, Options: "", Args: "on"},
+		{Type: TYPE, Options: "", Args: "make"},
+		{Type: ENTER, Options: "", Args: "1"},
+		{Type: SLEEP, Options: "", Args: "2s"},
+		{Type: SET, Options: "FontSize", Args: "42"},
+		{Type: QUIET, Options: "", Args: "off"},
+		{Type: TYPE, Options: "", Args: "done"},
+	}
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expected no errors, got %v", p.Errors())
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	expectedWarning := " 6:3  │ Set FontSize is ignored after the first non-setting command"
+	if len(p.Warnings()) != 1 || p.Warnings()[0].String() != expectedWarning {
+		t.Errorf("Expected warning [%s], got %v", expectedWarning, p.Warnings())
+	}
+}
+
+func TestParseOutputTxt(t *testing.T) {
+	p := NewParser(NewLexer("Output screen.txt\nOutput golden.ascii"))
+	cmds := p.Parse()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expected no errors, got %v", p.Errors())
+	}
+	if len(cmds) != 2 || cmds[0].Options != ".txt" || cmds[1].Options != ".ascii" {
+		t.Fatalf("Expected the .txt and .ascii outputs, got %v", cmds)
+	}
+	if len(p.Warnings()) != 0 {
+		t.Errorf("Expected no warnings, got %v", p.Warnings())
+	}
+}
+
+func TestParseSetupTeardown(t *testing.T) {
+	input := `Set WorkingDirectory "demo"
+Setup {
+  mkdir -p fixtures
+  if [ ! -f fixtures/a.txt ]; then echo "a" > fixtures/a.txt; fi
+}
+Teardown { rm -rf fixtures }
+Type "ls fixtures"
+Setup { touch late }`
+
+	p := NewParser(NewLexer(input))
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SET, Options: "WorkingDirectory", Args: "demo"},
+		{Type: SETUP, Args: "mkdir -p fixtures\n  if [ ! -f fixtures/a.txt ]; then echo \"a\" > fixtures/a.txt; fi"},
+		{Type: TEARDOWN, Args: "rm -rf fixtures"},
+		{Type: TYPE, Args: "ls fixtures"},
+		{Type: SETUP, Args: "touch late"},
+	}
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expected no errors, got %v", p.Errors())
+	}
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Warnings()) != 1 || p.Warnings()[0].Msg != "Setup is ignored after the first non-setting command" {
+		t.Errorf("Expected a warning for the late Setup, got %v", p.Warnings())
+	}
+
+	p = NewParser(NewLexer(`Setup "mkdir fixtures"`))
+	p.Parse()
+	if len(p.Errors()) == 0 {
+		t.Error("Expected an error for a Setup without a block")
+	}
+}
+
+func TestParseRepeat(t *testing.T) {
+	input := `Repeat 2 {
+  Type "}"
+  Repeat 2 { Down }
+  Enter
+}
+Repeat 0 { Type "never" }
+Repeat { Enter }`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: TYPE, Options: "", Args: "}"},
+		{Type: DOWN, Options: "", Args: "1"},
+		{Type: DOWN, Options: "", Args: "1"},
+		{Type: ENTER, Options: "", Args: "1"},
+		{Type: TYPE, Options: "", Args: "}"},
+		{Type: DOWN, Options: "", Args: "1"},
+		{Type: DOWN, Options: "", Args: "1"},
+		{Type: ENTER, Options: "", Args: "1"},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Tokens()) != len(cmds) || p.Tokens()[3].Line != 4 {
+		t.Errorf("Expected a token on the line of every command, got %v", p.Tokens())
+	}
+
+	if len(p.Errors()) != 1 || p.Errors()[0].Msg != "Repeat expects a number of times: Repeat 3 { ... }" {
+		t.Errorf("Expected an error for the Repeat without a count, got %v", p.Errors())
+	}
+}
+
+func TestParseRepeatLimits(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"Repeat 100000 { Enter }", "Repeat can't run a block more than 10000 times"},
+		{"Repeat 10000 { Repeat 10000 { Enter } }", "Repeat expands to more than 100000 commands"},
+	}
+	for _, tc := range tests {
+		p := NewParser(NewLexer(tc.input))
+		if cmds := p.Parse(); len(cmds) != 0 {
+			t.Errorf("%s: expected no commands, got %d", tc.input, len(cmds))
+		}
+		if errs := p.Errors(); len(errs) != 1 || errs[0].Msg != tc.err {
+			t.Errorf("%s: expected the error %q, got %v", tc.input, tc.err, errs)
+		}
+	}
+
+	p := NewParser(NewLexer("Repeat 10000 { Repeat 10 { Enter } }"))
+	if cmds := p.Parse(); len(cmds) != maxRepeatedCommands || len(p.Errors()) != 0 {
+		t.Errorf("expected %d commands within the limits, got %d and %v", maxRepeatedCommands, len(cmds), p.Errors())
+	}
+}
+
+func TestParseExpect(t *testing.T) {
+	input := `Expect "ok"
+ExpectNot /FAIL|panic/
+Expect`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: EXPECT, Options: "", Args: `"ok"`},
+		{Type: EXPECT_NOT, Options: "", Args: "/FAIL|panic/"},
+		{Type: EXPECT, Options: "", Args: ""},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Errors()) != 1 || p.Errors()[0].Msg != "Expect expects a string or a regular expression, e.g. Expect /Done/" {
+		t.Errorf("Expected an error for the Expect without a pattern, got %v", p.Errors())
+	}
+}
+
+func TestParseCopyPaste(t *testing.T) {
+	input := `Copy "echo hello"
+Paste
+Paste@1s
+Copy`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: COPY, Options: "", Args: "echo hello"},
+		{Type: PASTE, Options: "", Args: ""},
+		{Type: PASTE, Options: "1s", Args: ""},
+		{Type: COPY, Options: "", Args: ""},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Errors()) != 1 || p.Errors()[0].Msg != "Copy expects string" {
+		t.Errorf("Expected an error for the Copy without text, got %v", p.Errors())
+	}
+}
+
+func TestParseScreenshot(t *testing.T) {
+	input := `Set ScreenshotDir "shots/"
+Screenshot intro.png
+Screenshot shot-{n}.png
+Screenshot intro.gif`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SET, Options: "ScreenshotDir", Args: "shots/"},
+		{Type: SCREENSHOT, Options: "", Args: "intro.png"},
+		{Type: SCREENSHOT, Options: "", Args: "shot-{n}.png"},
+		{Type: SCREENSHOT, Options: "", Args: "intro.gif"},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Errors()) != 1 || p.Errors()[0].Msg != "Expected screenshot to be a .png file" {
+		t.Errorf("Expected an error for the .gif screenshot, got %v", p.Errors())
+	}
+}
+
+func TestParseOverlays(t *testing.T) {
+	input := `Caption "Install the CLI" 3s
+Caption "Done"
+Highlight 2,4,10,1 500ms
+Highlight 0,0,80,1
+Sleep 1s
+Highlight 2,4
+Caption`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: CAPTION, Options: "3s", Args: "Install the CLI"},
+		{Type: CAPTION, Options: "", Args: "Done"},
+		{Type: HIGHLIGHT, Options: "500ms", Args: "2,4,10,1"},
+		{Type: HIGHLIGHT, Options: "", Args: "0,0,80,1"},
+		{Type: SLEEP, Options: "", Args: "1s"},
+		{Type: HIGHLIGHT, Options: "", Args: ""},
+		{Type: CAPTION, Options: "", Args: ""},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	errs := p.Errors()
+	if len(errs) != 2 ||
+		errs[0].Msg != "Expected row,col,width,height after Highlight" ||
+		errs[1].Msg != "Expected the text of the caption after Caption" {
+		t.Errorf("Expected errors for the incomplete Highlight and Caption, got %v", errs)
+	}
+}
+
+func TestParseTypingVariance(t *testing.T) {
+	p := NewParser(NewLexer("Set TypingVariance 30%\nSet TypingVariance 0.2\nSet TypingSeed 42"))
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SET, Options: "TypingVariance", Args: "30%"},
+		{Type: SET, Options: "TypingVariance", Args: "0.2"},
+		{Type: SET, Options: "TypingSeed", Args: "42"},
+	}
+	if len(p.Errors()) != 0 || len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %v (%v)", len(expected), cmds, p.Errors())
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+}
+
+func TestParseMaxFileSize(t *testing.T) {
+	p := NewParser(NewLexer("Set MaxFileSize 5MB\nSet MaxFileSize 1.5MiB\nSet MaxFileSize 1000000\nSet MaxFileSize \"10 MB\"\nType hello"))
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SET, Options: "MaxFileSize", Args: "5MB"},
+		{Type: SET, Options: "MaxFileSize", Args: "1.5MiB"},
+		{Type: SET, Options: "MaxFileSize", Args: "1000000"},
+		{Type: SET, Options: "MaxFileSize", Args: "10 MB"},
+		{Type: TYPE, Options: "", Args: "hello"},
+	}
+	if len(p.Errors()) != 0 || len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %v (%v)", len(expected), cmds, p.Errors())
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+}
+
+func TestParseAudio(t *testing.T) {
+	input := `Set Audio "narration.mp3"
+Audio "ding.wav" 500ms
+Audio`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SET, Options: "Audio", Args: "narration.mp3"},
+		{Type: AUDIO, Options: "500ms", Args: "ding.wav"},
+		{Type: AUDIO, Options: "", Args: ""},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Errors()) != 1 || p.Errors()[0].Msg != "Expected the path of the audio file after Audio" {
+		t.Errorf("Expected an error for the Audio without file, got %v", p.Errors())
+	}
+}
+
+func TestParseChapter(t *testing.T) {
+	input := `Chapter "Install"
+Chapter`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: CHAPTER, Options: "", Args: "Install"},
+		{Type: CHAPTER, Options: "", Args: ""},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Errors()) != 1 || p.Errors()[0].Msg != "Expected the title of the chapter after Chapter" {
+		t.Errorf("Expected an error for the Chapter without title, got %v", p.Errors())
+	}
+}
+
+func TestParseSplit(t *testing.T) {
+	input := `Split horizontal
+Focus 2
+Split diagonal
+Focus 0`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SPLIT, Options: "", Args: "horizontal"},
+		{Type: FOCUS, Options: "", Args: "2"},
+		{Type: SPLIT, Options: "", Args: ""},
+		{Type: FOCUS, Options: "", Args: ""},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	errs := p.Errors()
+	if len(errs) != 2 || errs[0].Msg != "Expected horizontal or vertical after Split" || errs[1].Msg != "Expected the number of a pane after Focus" {
+		t.Errorf("Expected errors for the invalid Split and Focus, got %v", errs)
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	input := `Env NO_COLOR 1
+Env GREETING "Hello, World!"
+Env "" value
+Env KEY`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: ENV, Options: "NO_COLOR", Args: "1"},
+		{Type: ENV, Options: "GREETING", Args: "Hello, World!"},
+	}
+
+	if len(cmds) < len(expected) {
+		t.Fatalf("Expected at least %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range expected {
+		if cmds[i] != cmd {
+			t.Errorf("Expected command %d to be %v, got %v", i, cmd, cmds[i])
+		}
+	}
+
+	if len(p.Errors()) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(p.Errors()), p.Errors())
+	}
+	if p.Errors()[0].Msg != "Env expects a variable name" {
+		t.Errorf("Expected an error for the empty name, got %q", p.Errors()[0].Msg)
+	}
+	if p.Errors()[1].Msg != "Env KEY expects a value" {
+		t.Errorf("Expected an error for the missing value, got %q", p.Errors()[1].Msg)
+	}
+}
+
+func TestParseVar(t *testing.T) {
+	input := `Var VERSION 1.0.0
+Set Var REPO vhs
+Output demo-${VERSION}.gif
+Type "echo $HOME ${REPO}@${VERSION}"
+Type "cd ${PWD} ${MISSING}"
+Type "echo ${NAME:-default} ${#files[@]} $${VERSION} ${VERSION"`
+
+	l := NewLexer(input)
+	p := NewParser(l, WithParserVars(map[string]string{"VERSION": "2.0.0"}))
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: VAR, Options: "VERSION", Args: "1.0.0"},
+		{Type: VAR, Options: "REPO", Args: "vhs"},
+		{Type: OUTPUT, Options: ".gif", Args: "demo-2.0.0.gif"},
+		{Type: TYPE, Options: "", Args: "echo $HOME vhs@2.0.0"},
+		{Type: TYPE, Options: "", Args: "cd ${PWD} ${MISSING}"},
+		{Type: TYPE, Options: "", Args: "echo ${NAME:-default} ${#files[@]} ${VERSION} ${VERSION"},
+	}
+
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+
+	if len(p.Errors()) > 0 {
+		t.Errorf("Expected the undefined variables to be left as is, got %v", p.Errors())
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common.tape")
+	if err := os.WriteFile(common, []byte("Output common.gif\nSet FontSize 20\nType \"cd ${1}\"\nEnter"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLexer(fmt.Sprintf("Source %q \"my project\"\nType \"ls\"", common))
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SET, Options: "FontSize", Args: "20"},
+		{Type: TYPE, Options: "", Args: "cd my project"},
+		{Type: ENTER, Options: "", Args: "1"},
+		{Type: TYPE, Options: "", Args: "ls"},
+	}
+
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Expected no errors, got %v", p.Errors())
+	}
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+}
+
+func TestParseInclude(t *testing.T) {
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common.tape")
+	if err := os.WriteFile(common, []byte("Set FontSize 20"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewParser(NewLexer(fmt.Sprintf("Include %q\nInclude", common)))
+	cmds := p.Parse()
+
+	if len(cmds) != 1 || cmds[0] != (Command{Type: SET, Options: "FontSize", Args: "20"}) {
+		t.Errorf("Expected the commands of the included tape, got %v", cmds)
+	}
+	if len(p.Errors()) != 1 || p.Errors()[0].Msg != "Include expects a tape file" {
+		t.Errorf("Expected an error for the Include without a tape, got %v", p.Errors())
+	}
+}
+
+func TestParseSourceCycle(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.tape")
+	b := filepath.Join(dir, "b.tape")
+	if err := os.WriteFile(a, []byte(fmt.Sprintf("Source %q", b)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte(fmt.Sprintf("Source %q", a)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLexer(fmt.Sprintf("Source %q", a))
+	p := NewParser(l)
+	_ = p.Parse()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf("Expected 1 error, got %v", p.Errors())
+	}
+	cycle := "cyclic source detected: " + a + " -> " + b + " -> " + a
+	if !strings.HasSuffix(p.Errors()[0].Msg, cycle) {
+		t.Errorf("Expected the error to end with %q, got %q", cycle, p.Errors()[0].Msg)
+	}
+}
+
+func TestParseChord(t *testing.T) {
+	input := `Ctrl+Shift+Left
+Ctrl+Alt+F2
+Shift+Ctrl+T
+Alt+.
+Shift+Tab`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: CTRL, Options: "", Args: "Shift+Left"},
+		{Type: CTRL, Options: "", Args: "Alt+F2"},
+		{Type: CTRL, Options: "", Args: "Shift+T"},
+		{Type: ALT, Options: "", Args: "."},
+		{Type: SHIFT, Options: "", Args: "Tab"},
+	}
+
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Expected no errors, got %v", p.Errors())
+	}
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Errorf("Expected command %d to be %v, got %v", i, expected[i], cmd)
+		}
+	}
+}
+
+func TestParseChordErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		msg    string
+		column int
+	}{
+		{"Ctrl+A+B", "Expected a single key after the modifiers, got another one", 7},
+		{"Ctrl+Shift+", "Expected a key after +", 11},
+		{"Ctrl+Shift", "Expected a key after Shift, e.g. Shift+C", 6},
+		{"Ctrl+Ctrl+C", "Ctrl is repeated", 6},
+		{"Alt+Foo", "Unknown key Foo", 5},
+	}
+
+	// The keys are checked by the function of WithParserKeys.
+	validKey := func(key string) bool {
+		return len(key) == 1 || key == "Enter"
+	}
+
+	for _, tc := range tests {
+		l := NewLexer(tc.input)
+		p := NewParser(l, WithParserKeys(validKey))
+		_ = p.Parse()
+
+		if len(p.Errors()) != 1 {
+			t.Errorf("%s: expected 1 error, got %v", tc.input, p.Errors())
+			continue
+		}
+		err := p.Errors()[0]
+		if err.Msg != tc.msg || err.Token.Line != 1 || err.Token.Column != tc.column {
+			t.Errorf("%s: expected %q at 1:%d, got %q at %d:%d", tc.input, tc.msg, tc.column, err.Msg, err.Token.Line, err.Token.Column)
+		}
+	}
+}
+
+func TestParseWait(t *testing.T) {
+	input := `Wait /Done/
+Wait+Line />$/ 10s
+WaitFor+Screen /a\/b/ 500ms
+Wait+Column /x/
+Wait /[/
+Wait
+Wait 30s`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: WAIT, Args: "Screen /Done/"},
+		{Type: WAIT, Options: "10s", Args: "Line />$/"},
+		{Type: WAIT, Options: "500ms", Args: `Screen /a\/b/`},
+	}
+
+	if len(cmds) < len(expected) {
+		t.Fatalf("Expected at least %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range expected {
+		if cmds[i] != cmd {
+			t.Errorf("Expected command %d to be %v, got %v", i, cmd, cmds[i])
+		}
+	}
+
+	prompt := []Command{
+		{Type: WAIT, Args: "Line />$/"},
+		{Type: WAIT, Options: "30s", Args: "Line />$/"},
+	}
+	if got := cmds[len(cmds)-2:]; got[0] != prompt[0] || got[1] != prompt[1] {
+		t.Errorf("Expected the Wait without regular expression to wait for the prompt, got %v", got)
+	}
+
+	errs := p.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Msg != "Expected Line or Screen after Wait+" {
+		t.Errorf("Expected an error for the unknown scope, got %q", errs[0].Msg)
+	}
+	if !strings.HasPrefix(errs[1].Msg, "Invalid regular expression /[/") {
+		t.Errorf("Expected an error for the invalid regular expression, got %q", errs[1].Msg)
+	}
+}
+
+func TestParseAbort(t *testing.T) {
+	input := `Abort
+Skip on windows { Abort "needs a POSIX shell" }
+Abort "done"`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expected no errors, got %v", p.Errors())
+	}
+
+	expected := []Command{
+		{Type: ABORT, Options: "", Args: ""},
+		{Type: SKIP, Options: "", Args: "on windows"},
+		{Type: ABORT, Options: "", Args: "needs a POSIX shell"},
+		{Type: SKIP, Options: "", Args: "end"},
+		{Type: ABORT, Options: "", Args: "done"},
+	}
+	if len(cmds) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range expected {
+		if cmds[i] != cmd {
+			t.Errorf("Expected command %d to be %v, got %v", i, cmd, cmds[i])
+		}
+	}
+}
+
+func TestParseSkip(t *testing.T) {
+	input := `Skip unless gh {
+  Type "gh pr list"
+  Skip on windows { Enter }
+}
+Skip if gh { Enter }
+Skip unless gh`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SKIP, Options: "", Args: "unless gh"},
+		{Type: TYPE, Options: "", Args: "gh pr list"},
+		{Type: SKIP, Options: "", Args: "on windows"},
+		{Type: ENTER, Options: "", Args: "1"},
+		{Type: SKIP, Options: "", Args: "end"},
+		{Type: SKIP, Options: "", Args: "end"},
+	}
+
+	if len(cmds) < len(expected) {
+		t.Fatalf("Expected at least %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range expected {
+		if cmds[i] != cmd {
+			t.Errorf("Expected command %d to be %v, got %v", i, cmd, cmds[i])
+		}
+	}
+
+	if len(p.Errors()) != 2 {
+		t.Errorf("Expected errors for the Skip without condition and without block, got %v", p.Errors())
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	input := `Speed 8 { Sleep 1s }
+Cut {
+  Sleep 2s
+}
+Speed { Sleep 1s }
+Speed 0 { Enter }`
+
+	l := NewLexer(input)
+	p := NewParser(l)
+
+	cmds := p.Parse()
+
+	expected := []Command{
+		{Type: SPEED, Options: "", Args: "8"},
+		{Type: SLEEP, Options: "", Args: "1s"},
+		{Type: SPEED, Options: "", Args: "end"},
+		{Type: SPEED, Options: "", Args: "0"},
+		{Type: SLEEP, Options: "", Args: "2s"},
+		{Type: SPEED, Options: "", Args: "end"},
+	}
+
+	if len(cmds) < len(expected) {
+		t.Fatalf("Expected at least %d commands, got %d: %v", len(expected), len(cmds), cmds)
+	}
+	for i, cmd := range expected {
+		if cmds[i] != cmd {
+			t.Errorf("Expected command %d to be %v, got %v", i, cmd, cmds[i])
+		}
+	}
+
+	errs := p.Errors()
+	if len(errs) != 2 || errs[0].Msg != "Speed expects a factor: Speed 4 { ... }" || errs[1].Msg != "Invalid factor 0 for Speed" {
+		t.Errorf("Expected errors for the Speed without factor and with a factor of 0, got %v", errs)
+	}
+}
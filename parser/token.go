@@ -0,0 +1,300 @@
+package parser
+
+import "strings"
+
+// Type represents a token's type.
+type TokenType string
+
+// Token represents a lexer token.
+type Token struct {
+	Type    TokenType
+	Literal string
+	Line    int
+	Column  int
+}
+
+// Tokens for the VHS language
+const (
+	AT              = "@"
+	EQUAL           = "="
+	PLUS            = "+"
+	PERCENT         = "%"
+	COMMA           = ","
+	SLASH           = "/"
+	DOT             = "."
+	DASH            = "-"
+	PX              = "PX"
+	EM              = "EM"
+	EOF             = "EOF"
+	ILLEGAL         = "ILLEGAL"
+	SPACE           = "SPACE"
+	BACKSPACE       = "BACKSPACE"
+	CTRL            = "CTRL"
+	ALT             = "ALT"
+	SHIFT           = "SHIFT"
+	ENTER           = "ENTER"
+	NUMBER          = "NUMBER"
+	SET             = "SET"
+	SLEEP           = "SLEEP"
+	WAIT            = "WAIT"
+	COPY            = "COPY"
+	PASTE           = "PASTE"
+	EXPECT          = "EXPECT"
+	EXPECT_NOT      = "EXPECT_NOT" //nolint:revive
+	STRING          = "STRING"
+	JSON            = "JSON"
+	REGEX           = "REGEX"
+	TYPE            = "TYPE"
+	DOWN            = "DOWN"
+	LEFT            = "LEFT"
+	RIGHT           = "RIGHT"
+	UP              = "UP"
+	TAB             = "TAB"
+	ESCAPE          = "ESCAPE"
+	DELETE          = "DELETE"
+	HOME            = "HOME"
+	INSERT          = "INSERT"
+	END             = "END"
+	HIDE            = "HIDE"
+	REQUIRE         = "REQUIRE"
+	SHOW            = "SHOW"
+	QUIET           = "QUIET"
+	REPEAT          = "REPEAT"
+	SKIP            = "SKIP"
+	SPEED           = "SPEED"
+	CUT             = "CUT"
+	SCREENSHOT      = "SCREENSHOT"
+	BREAKPOINT      = "BREAKPOINT"
+	ABORT           = "ABORT"
+	ENV             = "ENV"
+	SETUP           = "SETUP"
+	TEARDOWN        = "TEARDOWN"
+	VAR             = "VAR"
+	SOURCE          = "SOURCE"
+	OUTPUT          = "OUTPUT"
+	MILLISECONDS    = "MILLISECONDS"
+	SECONDS         = "SECONDS"
+	MINUTES         = "MINUTES"
+	COMMENT         = "COMMENT"
+	SHELL           = "SHELL"
+	FONT_FAMILY     = "FONT_FAMILY" //nolint:revive
+	FONT_FILE       = "FONT_FILE"   //nolint:revive
+	FONT_SIZE       = "FONT_SIZE"   //nolint:revive
+	FRAMERATE       = "FRAMERATE"
+	PLAYBACK_SPEED  = "PLAYBACK_SPEED" //nolint:revive
+	LOOPS           = "LOOPS"
+	HEIGHT          = "HEIGHT"
+	WIDTH           = "WIDTH"
+	LETTER_SPACING  = "LETTER_SPACING"  //nolint:revive
+	LINE_HEIGHT     = "LINE_HEIGHT"     //nolint:revive
+	TYPING_SPEED    = "TYPING_SPEED"    //nolint:revive
+	TYPING_VARIANCE = "TYPING_VARIANCE" //nolint:revive
+	TYPING_SEED     = "TYPING_SEED"     //nolint:revive
+	PADDING         = "PADDING"
+	THEME           = "THEME"
+	LOOP_OFFSET     = "LOOP_OFFSET" //nolint:revive
+	SLEEP_SCALE     = "SLEEP_SCALE" //nolint:revive
+	KEY_LOG         = "KEY_LOG"     //nolint:revive
+	FLASH           = "FLASH"
+	CAPTION         = "CAPTION"
+	CHAPTER         = "CHAPTER"
+	SPLIT           = "SPLIT"
+	FOCUS           = "FOCUS"
+	AUDIO           = "AUDIO"
+	HIGHLIGHT       = "HIGHLIGHT"
+	FLASH_COLOR     = "FLASH_COLOR"  //nolint:revive
+	SHOW_GRID       = "SHOW_GRID"    //nolint:revive
+	SHOW_KEYS       = "SHOW_KEYS"    //nolint:revive
+	PROGRESS_BAR    = "PROGRESS_BAR" //nolint:revive
+	TIMEZONE        = "TIMEZONE"
+	HTML_FULL       = "HTML_FULL" //nolint:revive
+	CRT             = "CRT"
+	CRT_INTENSITY   = "CRT_INTENSITY" //nolint:revive
+	WEBP_QUALITY    = "WEBP_QUALITY"  //nolint:revive
+	WEBP_LOSSLESS   = "WEBP_LOSSLESS" //nolint:revive
+	SECRET          = "SECRET"
+	SSH             = "SSH"
+	CONTAINER       = "CONTAINER"
+
+	FRAMERATE_FROM_TYPING = "FRAMERATE_FROM_TYPING" //nolint:revive
+	TITLE                 = "TITLE"
+	SCREENSHOT_DIR        = "SCREENSHOT_DIR"    //nolint:revive
+	SCREENSHOT_DIGITS     = "SCREENSHOT_DIGITS" //nolint:revive
+	KEY_DELAY             = "KEY_DELAY"         //nolint:revive
+	CURSOR_COLOR          = "CURSOR_COLOR"      //nolint:revive
+	CURSOR_BLINK          = "CURSOR_BLINK"      //nolint:revive
+	CURSOR_STYLE          = "CURSOR_STYLE"      //nolint:revive
+	DEFAULT_SLEEP         = "DEFAULT_SLEEP"     //nolint:revive
+	WINDOW_BAR            = "WINDOW_BAR"        //nolint:revive
+	WINDOW_BAR_SIZE       = "WINDOW_BAR_SIZE"   //nolint:revive
+	WINDOW_TITLE          = "WINDOW_TITLE"      //nolint:revive
+	WINDOW_BAR_TITLE      = "WINDOW_BAR_TITLE"  //nolint:revive
+	BORDER_RADIUS         = "BORDER_RADIUS"     //nolint:revive
+	MARGIN                = "MARGIN"
+	MARGIN_FILL           = "MARGIN_FILL" //nolint:revive
+	SHADOW                = "SHADOW"
+	MAX_FILE_SIZE         = "MAX_FILE_SIZE"     //nolint:revive
+	WORKING_DIRECTORY     = "WORKING_DIRECTORY" //nolint:revive
+)
+
+// Keywords maps the keywords of the tapes to their tokens.
+var Keywords = map[string]TokenType{
+	"em":             EM,
+	"px":             PX,
+	"ms":             MILLISECONDS,
+	"s":              SECONDS,
+	"m":              MINUTES,
+	"Set":            SET,
+	"Sleep":          SLEEP,
+	"Wait":           WAIT,
+	"WaitFor":        WAIT,
+	"Copy":           COPY,
+	"Paste":          PASTE,
+	"Expect":         EXPECT,
+	"ExpectNot":      EXPECT_NOT,
+	"Type":           TYPE,
+	"Enter":          ENTER,
+	"Space":          SPACE,
+	"Backspace":      BACKSPACE,
+	"Ctrl":           CTRL,
+	"Alt":            ALT,
+	"Shift":          SHIFT,
+	"Down":           DOWN,
+	"Left":           LEFT,
+	"Right":          RIGHT,
+	"Up":             UP,
+	"Tab":            TAB,
+	"Escape":         ESCAPE,
+	"End":            END,
+	"Hide":           HIDE,
+	"Require":        REQUIRE,
+	"Show":           SHOW,
+	"Quiet":          QUIET,
+	"Repeat":         REPEAT,
+	"Skip":           SKIP,
+	"Speed":          SPEED,
+	"Cut":            CUT,
+	"Screenshot":     SCREENSHOT,
+	"Breakpoint":     BREAKPOINT,
+	"Abort":          ABORT,
+	"Env":            ENV,
+	"Setup":          SETUP,
+	"Teardown":       TEARDOWN,
+	"Var":            VAR,
+	"Source":         SOURCE,
+	"Include":        SOURCE,
+	"Output":         OUTPUT,
+	"Shell":          SHELL,
+	"FontFamily":     FONT_FAMILY,
+	"FontFile":       FONT_FILE,
+	"FontSize":       FONT_SIZE,
+	"Framerate":      FRAMERATE,
+	"Height":         HEIGHT,
+	"LetterSpacing":  LETTER_SPACING,
+	"LineHeight":     LINE_HEIGHT,
+	"PlaybackSpeed":  PLAYBACK_SPEED,
+	"Loops":          LOOPS,
+	"TypingSpeed":    TYPING_SPEED,
+	"TypingVariance": TYPING_VARIANCE,
+	"TypingSeed":     TYPING_SEED,
+	"Padding":        PADDING,
+	"Theme":          THEME,
+	"Width":          WIDTH,
+	"LoopOffset":     LOOP_OFFSET,
+	"SleepScale":     SLEEP_SCALE,
+	"KeyLog":         KEY_LOG,
+	"Flash":          FLASH,
+	"Caption":        CAPTION,
+	"Chapter":        CHAPTER,
+	"Split":          SPLIT,
+	"Focus":          FOCUS,
+	"Audio":          AUDIO,
+	"Highlight":      HIGHLIGHT,
+	"FlashColor":     FLASH_COLOR,
+	"ShowGrid":       SHOW_GRID,
+	"ShowKeys":       SHOW_KEYS,
+	"ProgressBar":    PROGRESS_BAR,
+	"Timezone":       TIMEZONE,
+	"HtmlFull":       HTML_FULL,
+	"Crt":            CRT,
+	"CrtIntensity":   CRT_INTENSITY,
+	"WebPQuality":    WEBP_QUALITY,
+	"WebPLossless":   WEBP_LOSSLESS,
+	"Secret":         SECRET,
+	"SSH":            SSH,
+	"Container":      CONTAINER,
+
+	"FrameRateFromTyping": FRAMERATE_FROM_TYPING,
+	"Title":               TITLE,
+	"ScreenshotDir":       SCREENSHOT_DIR,
+	"ScreenshotDigits":    SCREENSHOT_DIGITS,
+	"KeyDelay":            KEY_DELAY,
+	"CursorColor":         CURSOR_COLOR,
+	"CursorBlink":         CURSOR_BLINK,
+	"CursorStyle":         CURSOR_STYLE,
+	"DefaultSleep":        DEFAULT_SLEEP,
+	"WindowBar":           WINDOW_BAR,
+	"WindowBarSize":       WINDOW_BAR_SIZE,
+	"WindowTitle":         WINDOW_TITLE,
+	"WindowBarTitle":      WINDOW_BAR_TITLE,
+	"BorderRadius":        BORDER_RADIUS,
+	"Margin":              MARGIN,
+	"MarginFill":          MARGIN_FILL,
+	"Shadow":              SHADOW,
+	"MaxFileSize":         MAX_FILE_SIZE,
+	"WorkingDirectory":    WORKING_DIRECTORY,
+}
+
+// IsSetting returns whether a token is a setting.
+func IsSetting(t TokenType) bool {
+	switch t {
+	case SHELL, FONT_FAMILY, FONT_FILE, FONT_SIZE, LETTER_SPACING, LINE_HEIGHT,
+		FRAMERATE, TYPING_SPEED, TYPING_VARIANCE, TYPING_SEED, THEME, PLAYBACK_SPEED, LOOPS,
+		HEIGHT, WIDTH, PADDING, LOOP_OFFSET, SLEEP_SCALE, KEY_LOG,
+		FLASH_COLOR, SHOW_GRID, SHOW_KEYS, PROGRESS_BAR, AUDIO, TIMEZONE, HTML_FULL, CRT, CRT_INTENSITY,
+		WEBP_QUALITY, WEBP_LOSSLESS, SECRET, SSH, CONTAINER,
+		FRAMERATE_FROM_TYPING, TITLE, SCREENSHOT_DIR, SCREENSHOT_DIGITS, KEY_DELAY,
+		CURSOR_COLOR, CURSOR_BLINK, CURSOR_STYLE, DEFAULT_SLEEP, WINDOW_BAR, WINDOW_BAR_SIZE, WINDOW_TITLE, WINDOW_BAR_TITLE, BORDER_RADIUS,
+		MARGIN, MARGIN_FILL, SHADOW, MAX_FILE_SIZE,
+		WORKING_DIRECTORY:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsCommand returns whether the string is a command
+func IsCommand(t TokenType) bool {
+	switch t {
+	case TYPE, SLEEP, WAIT, COPY, PASTE,
+		UP, DOWN, RIGHT, LEFT,
+		ENTER, BACKSPACE, DELETE, TAB,
+		ESCAPE, HOME, INSERT, END, CTRL, ALT, SHIFT:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsModifier returns whether the token is a modifier key.
+func IsModifier(t TokenType) bool {
+	return t == CTRL || t == ALT || t == SHIFT
+}
+
+// String converts a token to it's human readable string format.
+func (t TokenType) String() string {
+	if IsCommand(t) || IsSetting(t) {
+		return strings.ToTitle(string(t[0])) + strings.ToLower(string(t[1:]))
+	}
+	return string(t)
+}
+
+// LookupIdentifier returns whether the identifier is a keyword.
+// In `vhs`, there are no _actual_ identifiers, i.e. there are no variables.
+// Instead, identifiers are simply strings (i.e. bare words).
+func LookupIdentifier(ident string) TokenType {
+	if t, ok := Keywords[ident]; ok {
+		return t
+	}
+	return STRING
+}
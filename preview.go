@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultPreviewDir is the directory of vhs themes preview when none is given.
+const defaultPreviewDir = "themes"
+
+// previewGallery is the file name of the gallery of vhs themes preview.
+const previewGallery = "README.md"
+
+var (
+	previewDir       string
+	themesPreviewCmd = &cobra.Command{
+		Use:   "preview [theme]...",
+		Short: "Render a sample of every theme, or of the given ones, into a gallery",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			themes := args
+			if len(themes) == 0 {
+				var err error
+				themes, err = sortedThemeNames()
+				if err != nil {
+					return err
+				}
+			}
+			for _, theme := range themes {
+				if _, err := findTheme(theme); err != nil {
+					return err
+				}
+			}
+			if !skipDependencyCheck() {
+				if err := ensureDependencies(); err != nil {
+					return err
+				}
+			}
+			return PreviewThemes(cmd.Context(), themes, previewDir, jobs, cmd.OutOrStdout())
+		},
+	}
+)
+
+// PreviewThemes renders a sample tape with each of the themes to a PNG image
+// of the directory, and writes a Markdown gallery of the images along with
+// them.
+func PreviewThemes(ctx context.Context, themes []string, dir string, jobs int, out io.Writer) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tapes, err := os.MkdirTemp("", "vhs-preview")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tapes) //nolint:errcheck
+
+	files := make([]string, 0, len(themes))
+	for _, theme := range themes {
+		file := filepath.Join(tapes, themeSlug(theme)+extension)
+		image := filepath.Join(dir, themeSlug(theme)+".png")
+		if err := os.WriteFile(file, []byte(previewTape(theme, image)), 0o600); err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+	if err := RunBatch(ctx, files, jobs, nil, out); err != nil {
+		return err
+	}
+
+	gallery := filepath.Join(dir, previewGallery)
+	if err := os.WriteFile(gallery, []byte(previewMarkdown(themes)), 0o644); err != nil { //nolint:gosec
+		return err
+	}
+	fmt.Fprintln(out, StringStyle.Render("Gallery: "+gallery))
+	return nil
+}
+
+// previewTape returns the sample tape of a theme, which prints its colors and
+// saves the last frame to the image.
+func previewTape(theme, image string) string {
+	return fmt.Sprintf(`Output "%s"
+Set Theme "%s"
+Set FontSize 16
+Set Width 600
+Set Height 300
+Set Padding 20
+Type "echo 'Hello, %s!'"
+Enter
+Type `+"`"+`printf '\e[31mred \e[32mgreen \e[33myellow \e[34mblue \e[35mmagenta \e[36mcyan\e[0m\n'`+"`"+`
+Enter
+Sleep 500ms
+`, image, theme, strings.NewReplacer("'", "", `"`, "").Replace(theme))
+}
+
+// previewMarkdown returns the gallery of the previews of the themes.
+func previewMarkdown(themes []string) string {
+	var s strings.Builder
+	s.WriteString("# Themes\n")
+	for _, theme := range themes {
+		fmt.Fprintf(&s, "\n## %s\n\n![%s](./%s.png)\n", theme, theme, themeSlug(theme))
+	}
+	return s.String()
+}
+
+// themeSlug returns the name of the theme as a file name, e.g. catppuccin-mocha.
+func themeSlug(theme string) string {
+	var s strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(theme) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			s.WriteRune(r)
+			dash = false
+		} else if !dash && s.Len() > 0 {
+			s.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(s.String(), "-")
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The formats of the progress of --progress.
+const (
+	progressFormatBar  = "bar"
+	progressFormatJSON = "json"
+)
+
+// Progress is the progress of the evaluation of a tape, reported before every
+// command, once they are all executed, and while every output is encoded.
+type Progress struct {
+	// Command is the number of commands executed so far.
+	Command int
+	// Total is the number of commands of the tape.
+	Total int
+	// Name is the command which starts, if any.
+	Name string
+	// Frames is the number of frames captured so far.
+	Frames int
+	// Output is the output being encoded, once the commands are executed.
+	Output string
+	// Percent is how much of the output is encoded, from 0 to 100.
+	Percent float64
+	// Elapsed is the time since the evaluation started.
+	Elapsed time.Duration
+}
+
+// ProgressFunc is called with the progress of the evaluation of a tape.
+type ProgressFunc func(Progress)
+
+// WithProgress returns an EvaluatorOption which reports the progress of the
+// evaluation to the given function.
+func WithProgress(fn ProgressFunc) EvaluatorOption {
+	return func(v *VHS) {
+		v.progress = fn
+	}
+}
+
+// progressBarWidth is the number of cells of the progress bar.
+const progressBarWidth = 30
+
+// progressBar draws the progress of the evaluation on a single line which is
+// redrawn after every command. When it is not drawn on a terminal, only the
+// final line is printed by Done.
+type progressBar struct {
+	mu    sync.Mutex
+	out   io.Writer
+	tty   bool
+	last  Progress
+	drawn bool
+}
+
+// newProgressBar returns a progress bar drawn on out.
+func newProgressBar(out io.Writer, tty bool) *progressBar {
+	return &progressBar{out: out, tty: tty}
+}
+
+// Report is a ProgressFunc which updates the progress bar. The bar is removed
+// once every command is executed, since the rendering prints its own log.
+func (b *progressBar) Report(p Progress) {
+	if p.Output != "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last = p
+	b.clear()
+	if b.tty && p.Command < p.Total {
+		b.draw()
+	}
+}
+
+// Done clears the progress bar and prints the number of commands executed and
+// how long they took.
+func (b *progressBar) Done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	fmt.Fprintf(b.out, "Done: %d commands in %s\n", b.last.Command, b.last.Elapsed.Round(time.Millisecond))
+}
+
+// Writer returns a writer which clears the progress bar before writing to w, and
+// redraws it afterwards, so that the bar stays below the log of the commands.
+func (b *progressBar) Writer(w io.Writer) io.Writer {
+	return progressWriter{bar: b, w: w}
+}
+
+// draw draws the bar, without a trailing newline so that it can be cleared.
+func (b *progressBar) draw() {
+	if b.last.Total <= 0 {
+		return
+	}
+	filled := progressBarWidth * b.last.Command / b.last.Total
+	fmt.Fprintf(b.out, "[%s%s] %d/%d %s",
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		b.last.Command, b.last.Total, b.last.Elapsed.Truncate(100*time.Millisecond)) //nolint:gomnd
+	b.drawn = true
+}
+
+// clear erases the bar from the current line, if it is drawn.
+func (b *progressBar) clear() {
+	if !b.drawn {
+		return
+	}
+	fmt.Fprint(b.out, "\r\x1b[K")
+	b.drawn = false
+}
+
+type progressWriter struct {
+	bar *progressBar
+	w   io.Writer
+}
+
+func (pw progressWriter) Write(p []byte) (int, error) {
+	pw.bar.mu.Lock()
+	defer pw.bar.mu.Unlock()
+	drawn := pw.bar.drawn
+	pw.bar.clear()
+	n, err := pw.w.Write(p)
+	if drawn {
+		pw.bar.draw()
+	}
+	return n, err
+}
+
+// progressEvent is the JSON representation of a progress event of
+// --progress json.
+type progressEvent struct {
+	Event   string  `json:"event"`
+	Command int     `json:"command,omitempty"`
+	Total   int     `json:"total,omitempty"`
+	Name    string  `json:"name,omitempty"`
+	Frames  int     `json:"frames,omitempty"`
+	Output  string  `json:"output,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+	Elapsed float64 `json:"elapsed"`
+}
+
+// jsonProgress writes the progress of the evaluation as JSON events, one per
+// line, for the tools which wrap VHS.
+//
+//	{"event":"command","command":2,"total":5,"name":"Type hello","frames":30,"elapsed":1.52}
+//	{"event":"encoding","output":"demo.gif","percent":42.5,"elapsed":6.1}
+//	{"event":"done","command":5,"total":5,"frames":240,"elapsed":9.03}
+type jsonProgress struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	last Progress
+}
+
+// newJSONProgress returns a progress which writes its events to out.
+func newJSONProgress(out io.Writer) *jsonProgress {
+	return &jsonProgress{enc: json.NewEncoder(out)}
+}
+
+// Report is a ProgressFunc which writes an encoding event while an output is
+// encoded, and a command event otherwise.
+func (j *jsonProgress) Report(p Progress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if p.Output != "" {
+		j.write(progressEvent{Event: "encoding", Output: p.Output, Percent: p.Percent, Elapsed: p.Elapsed.Seconds()})
+		return
+	}
+	j.last = p
+	if p.Command < p.Total {
+		j.write(progressEvent{
+			Event:   "command",
+			Command: p.Command,
+			Total:   p.Total,
+			Name:    p.Name,
+			Frames:  p.Frames,
+			Elapsed: p.Elapsed.Seconds(),
+		})
+	}
+}
+
+// Done writes the done event, with the number of commands executed and
+// frames captured.
+func (j *jsonProgress) Done() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.write(progressEvent{
+		Event:   "done",
+		Command: j.last.Command,
+		Total:   j.last.Total,
+		Frames:  j.last.Frames,
+		Elapsed: j.last.Elapsed.Seconds(),
+	})
+}
+
+func (j *jsonProgress) write(e progressEvent) {
+	e.Elapsed = math.Round(e.Elapsed*1000) / 1000 //nolint:gomnd
+	_ = j.enc.Encode(e)
+}
+
+// runEncoder runs the ffmpeg command, which encodes the given duration of the
+// recording, and reports how much of it is encoded, if report isn't nil. The
+// output of ffmpeg is returned as with CombinedOutput.
+func runEncoder(cmd *exec.Cmd, duration time.Duration, report func(percent float64)) ([]byte, error) {
+	if report == nil || duration <= 0 {
+		return cmd.CombinedOutput()
+	}
+
+	// ffmpeg writes its progress as key=value lines to stdout, and its log
+	// to stderr as usual.
+	cmd.Args = append([]string{cmd.Args[0], "-progress", "pipe:1", "-nostats"}, cmd.Args[1:]...)
+	var out bytes.Buffer
+	cmd.Stderr = &out
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "out_time_us":
+			us, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			report(encodedPercent(time.Duration(us)*time.Microsecond, duration))
+		case "progress":
+			if value == "end" {
+				report(100) //nolint:gomnd
+			}
+		}
+	}
+	return out.Bytes(), cmd.Wait()
+}
+
+// encodedPercent returns how much of the duration is encoded, from 0 to 100,
+// rounded to a tenth.
+func encodedPercent(encoded, duration time.Duration) float64 {
+	percent := math.Round(float64(encoded)/float64(duration)*1000) / 10 //nolint:gomnd
+	return math.Max(0, math.Min(percent, 100))                          //nolint:gomnd
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProgressBar(t *testing.T) {
+	var stderr, stdout bytes.Buffer
+	bar := newProgressBar(&stderr, true)
+	w := bar.Writer(&stdout)
+
+	bar.Report(Progress{Command: 1, Total: 2, Elapsed: time.Second})
+	if got := stderr.String(); got != "[===============               ] 1/2 1s" {
+		t.Errorf("unexpected progress bar %q", got)
+	}
+
+	stderr.Reset()
+	fmt.Fprintln(w, "Type hello")
+	if got := stderr.String(); got != "\r\x1b[K[===============               ] 1/2 1s" {
+		t.Errorf("expected the bar to be redrawn after the log, got %q", got)
+	}
+	if stdout.String() != "Type hello\n" {
+		t.Errorf("unexpected log %q", stdout.String())
+	}
+
+	stderr.Reset()
+	bar.Report(Progress{Command: 2, Total: 2, Elapsed: 1500 * time.Millisecond})
+	bar.Done()
+	if got := stderr.String(); got != "\r\x1b[KDone: 2 commands in 1.5s\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestProgressBarNotTTY(t *testing.T) {
+	var stderr bytes.Buffer
+	bar := newProgressBar(&stderr, false)
+
+	bar.Report(Progress{Command: 1, Total: 3})
+	bar.Report(Progress{Command: 3, Total: 3, Elapsed: 2 * time.Second})
+	bar.Done()
+
+	if got := stderr.String(); got != "Done: 3 commands in 2s\n" {
+		t.Errorf("expected a single done line, got %q", got)
+	}
+}
+
+func TestJSONProgress(t *testing.T) {
+	var stderr bytes.Buffer
+	events := newJSONProgress(&stderr)
+
+	events.Report(Progress{Command: 1, Total: 2, Name: "Type hello", Frames: 30, Elapsed: 1520 * time.Millisecond})
+	events.Report(Progress{Command: 2, Total: 2, Frames: 60, Elapsed: 3 * time.Second})
+	events.Report(Progress{Output: "demo.gif", Percent: 42.5, Elapsed: 4 * time.Second})
+	events.Done()
+
+	expected := `{"event":"command","command":1,"total":2,"name":"Type hello","frames":30,"elapsed":1.52}
+{"event":"encoding","output":"demo.gif","percent":42.5,"elapsed":4}
+{"event":"done","command":2,"total":2,"frames":60,"elapsed":3}
+`
+	if got := stderr.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestEncodedPercent(t *testing.T) {
+	tests := []struct {
+		encoded  time.Duration
+		expected float64
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{1234 * time.Millisecond, 12.3},
+		{10 * time.Second, 100},
+		{11 * time.Second, 100},
+	}
+	for _, tc := range tests {
+		if got := encodedPercent(tc.encoded, 10*time.Second); got != tc.expected {
+			t.Errorf("encodedPercent(%s): expected %v, got %v", tc.encoded, tc.expected, got)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -8,6 +9,8 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/keygen"
 	gap "github.com/muesli/go-app-paths"
@@ -21,14 +24,36 @@ const (
 	ghostPort = 22
 )
 
+// publishTimeout is how long publishing can take, including the retries. A
+// timeout of 0 disables it.
+var publishTimeout time.Duration
+
+// defaultPublishTimeout is the default of the --publish-timeout flag.
+const defaultPublishTimeout = 2 * time.Minute
+
+// publishContext returns the context of the upload, which ends after the
+// --publish-timeout.
+func publishContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if publishTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, publishTimeout)
+}
+
 var publishCmd = &cobra.Command{
 	Use:           "publish <gif>",
-	Short:         "Publish your GIF to vhs.charm.sh and get a shareable URL",
+	Short:         "Publish your GIF to vhs.charm.sh, or your own storage, and get a shareable URL",
 	Args:          cobra.ExactArgs(1),
 	SilenceUsage:  true,
 	SilenceErrors: true, // we print our own errors
 	RunE: func(cmd *cobra.Command, args []string) error {
-		url, err := Publish(cmd.Context(), args[0])
+		p, err := configuredPublisher()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := publishContext(cmd.Context())
+		defer cancel()
+		url, err := Publish(ctx, p, args[0])
 		if err != nil {
 			return err
 		}
@@ -113,10 +138,88 @@ func sshSession() (*ssh.Session, error) {
 	return s, nil
 }
 
-// Publish publishes the given GIF file to the web.
-func Publish(ctx context.Context, path string) (string, error) {
+const (
+	// publishAttempts is how many times an upload is attempted before giving
+	// up, when the connection fails.
+	publishAttempts = 3
+	// publishBackoff is the pause before the second attempt, which doubles
+	// before every following attempt.
+	publishBackoff = time.Second
+)
+
+// PublishRejectedError is returned when the server refuses the file, in which
+// case the upload is not attempted again.
+type PublishRejectedError struct {
+	Path   string
+	Reason string
+}
+
+func (e PublishRejectedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("server rejected %s", e.Path)
+	}
+	return fmt.Sprintf("server rejected %s: %s", e.Path, e.Reason)
+}
+
+// configuredPublisher returns the publisher of --publish-to, or of the
+// [publish] table of the configuration files.
+func configuredPublisher() (Publisher, error) {
+	config, err := loadPublishConfig(".")
+	if err != nil {
+		return nil, err
+	}
+	return newPublisher(publishTo, config)
+}
+
+// Publish publishes the given GIF file to the web with the publisher. The
+// upload is attempted again, with an exponential backoff, if the connection
+// fails, but not if the server rejects the file. It stops once the context is
+// done.
+func Publish(ctx context.Context, p Publisher, path string) (string, error) {
 	fmt.Println("Publishing GIF...")
 
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return retryPublish(ctx, publishAttempts, publishBackoff, func(ctx context.Context) (string, error) {
+		return p.Upload(ctx, path)
+	})
+}
+
+// retryPublish calls upload until it succeeds, up to the given number of
+// attempts, unless the file is rejected or the context is done.
+func retryPublish(ctx context.Context, attempts int, backoff time.Duration, upload func(context.Context) (string, error)) (string, error) {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var url string
+		url, err = upload(ctx)
+		if err == nil {
+			return url, nil
+		}
+
+		var rejected PublishRejectedError
+		if errors.As(err, &rejected) {
+			return "", err
+		}
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("upload canceled: %w", ctx.Err())
+		}
+		if attempt == attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("upload canceled: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return "", fmt.Errorf("upload failed after %d attempts: %w", attempts, err)
+}
+
+// upload sends the file to the server over SSH and returns its URL.
+func upload(ctx context.Context, path string) (string, error) {
 	s, err := sshSession()
 	if err != nil {
 		return "", err
@@ -124,9 +227,14 @@ func Publish(ctx context.Context, path string) (string, error) {
 	defer s.Close() //nolint:errcheck
 
 	// Close connection when context is done
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		<-ctx.Done()
-		_ = s.Close()
+		select {
+		case <-ctx.Done():
+			_ = s.Close()
+		case <-done:
+		}
 	}()
 
 	in, err := s.StdinPipe()
@@ -137,6 +245,8 @@ func Publish(ctx context.Context, path string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	var stderr bytes.Buffer
+	s.Stderr = &stderr
 
 	f, err := os.Open(path)
 	if err != nil {
@@ -159,5 +269,14 @@ func Publish(ctx context.Context, path string) (string, error) {
 		return "", err
 	}
 
+	// The server exits with an error when it refuses the file.
+	if err := s.Wait(); err != nil {
+		var exit *ssh.ExitError
+		if errors.As(err, &exit) {
+			return "", PublishRejectedError{Path: path, Reason: strings.TrimSpace(stderr.String())}
+		}
+		return "", err
+	}
+
 	return string(b), nil
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryPublish(t *testing.T) {
+	t.Run("transient", func(t *testing.T) {
+		var calls int
+		url, err := retryPublish(context.Background(), 3, time.Millisecond, func(context.Context) (string, error) {
+			calls++
+			if calls < 3 {
+				return "", errors.New("connection reset by peer")
+			}
+			return "https://vhs.charm.sh/demo.gif", nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != 3 || url != "https://vhs.charm.sh/demo.gif" {
+			t.Errorf("expected the third attempt to succeed, got %q after %d attempts", url, calls)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		var calls int
+		_, err := retryPublish(context.Background(), 3, time.Millisecond, func(context.Context) (string, error) {
+			calls++
+			return "", errors.New("connection reset by peer")
+		})
+		if calls != 3 {
+			t.Errorf("expected 3 attempts, got %d", calls)
+		}
+		if err == nil || err.Error() != "upload failed after 3 attempts: connection reset by peer" {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		var calls int
+		_, err := retryPublish(context.Background(), 3, time.Millisecond, func(context.Context) (string, error) {
+			calls++
+			return "", PublishRejectedError{Path: "demo.gif", Reason: "file too large"}
+		})
+		if calls != 1 {
+			t.Errorf("expected a single attempt, got %d", calls)
+		}
+		if err == nil || err.Error() != "server rejected demo.gif: file too large" {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := retryPublish(ctx, 3, time.Hour, func(context.Context) (string, error) {
+			return "", errors.New("connection reset by peer")
+		})
+		if err == nil || !strings.HasPrefix(err.Error(), "upload canceled") {
+			t.Errorf("expected the upload to be canceled, got %v", err)
+		}
+	})
+}
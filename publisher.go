@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Publisher uploads a file and returns its public URL. An error wrapping
+// PublishRejectedError means that the upload shouldn't be attempted again.
+type Publisher interface {
+	Upload(ctx context.Context, path string) (string, error)
+}
+
+// publishTo is the destination of the published GIFs, set by --publish-to.
+var publishTo string
+
+// charmPublisher uploads to vhs.charm.sh over SSH.
+type charmPublisher struct{}
+
+// Upload uploads the file to vhs.charm.sh.
+func (charmPublisher) Upload(ctx context.Context, path string) (string, error) {
+	return upload(ctx, path)
+}
+
+// newPublisher returns the publisher of the destination, which is either
+// empty for vhs.charm.sh, an s3://bucket/prefix, a gs://bucket/prefix, or the
+// http(s):// URL of a directory where the files are PUT. If the destination
+// is empty, the to key of the [publish] table of the configuration is used.
+// The other keys of the table are:
+//
+//	region    the region of the bucket, for S3 (us-east-1)
+//	endpoint  the endpoint of an S3 compatible storage, such as MinIO
+//	url       the public URL of the bucket or directory
+//
+// The credentials are read from the environment.
+func newPublisher(to string, config map[string]string) (Publisher, error) {
+	if to == "" {
+		to = config["to"]
+	}
+	if to == "" || to == "charm" {
+		return charmPublisher{}, nil
+	}
+	u, err := url.Parse(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publish destination %s: %w", to, err)
+	}
+	publicURL := config["url"]
+
+	switch u.Scheme {
+	case "s3":
+		p := &s3Publisher{
+			endpoint:  config["endpoint"],
+			region:    config["region"],
+			bucket:    u.Host,
+			prefix:    strings.TrimPrefix(u.Path, "/"),
+			publicURL: publicURL,
+			accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			token:     os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if p.region == "" {
+			p.region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+		}
+		return p, p.check()
+	case "gs":
+		// GCS accepts the signatures of S3 with HMAC keys of a service account.
+		p := &s3Publisher{
+			endpoint:  "https://storage.googleapis.com",
+			region:    "auto",
+			bucket:    u.Host,
+			prefix:    strings.TrimPrefix(u.Path, "/"),
+			publicURL: publicURL,
+			accessKey: os.Getenv("GCS_ACCESS_KEY_ID"),
+			secretKey: os.Getenv("GCS_SECRET_ACCESS_KEY"),
+		}
+		return p, p.check()
+	case "http", "https":
+		return &httpPublisher{url: to, publicURL: publicURL, token: os.Getenv("VHS_PUBLISH_TOKEN")}, nil
+	default:
+		return nil, fmt.Errorf("invalid publish destination %s: expected s3://, gs://, http:// or https://", to)
+	}
+}
+
+// firstNonEmpty returns the first of the values which isn't empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// putFile sends the file with a PUT request, signed by sign if it isn't nil.
+// The server rejects the file if it answers with a client error, in which
+// case it isn't sent again.
+func putFile(ctx context.Context, target, file string, sign func(*http.Request, []byte)) (*http.Response, []byte, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(file)); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	if sign != nil {
+		sign(req, b)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil, nil, PublishRejectedError{Path: file, Reason: resp.Status}
+	case resp.StatusCode >= 300:
+		return nil, nil, fmt.Errorf("upload of %s failed: %s", file, resp.Status)
+	}
+	return resp, body, nil
+}
+
+// s3Publisher uploads to a bucket of S3, or of a storage compatible with S3.
+type s3Publisher struct {
+	// endpoint is the URL of the storage, whose buckets are in the path. If
+	// empty, the bucket is a subdomain of the endpoint of S3 in the region.
+	endpoint  string
+	region    string
+	bucket    string
+	prefix    string
+	publicURL string
+	accessKey string
+	secretKey string
+	token     string
+}
+
+// check returns an error if the bucket or the credentials are missing.
+func (p *s3Publisher) check() error {
+	if p.bucket == "" {
+		return errors.New("the publish destination has no bucket")
+	}
+	if p.accessKey == "" || p.secretKey == "" {
+		return errors.New("the credentials of the bucket aren't set")
+	}
+	return nil
+}
+
+// objectURL returns the URL of the object of the key.
+func (p *s3Publisher) objectURL(key string) string {
+	if p.endpoint != "" {
+		return strings.TrimSuffix(p.endpoint, "/") + "/" + p.bucket + "/" + escapePath(key)
+	}
+	return "https://" + p.bucket + ".s3." + p.region + ".amazonaws.com/" + escapePath(key)
+}
+
+// Upload uploads the file to the bucket, under the prefix, and returns its
+// public URL.
+func (p *s3Publisher) Upload(ctx context.Context, file string) (string, error) {
+	key := path.Join(p.prefix, filepath.Base(file))
+	_, _, err := putFile(ctx, p.objectURL(key), file, func(req *http.Request, b []byte) {
+		sum := sha256.Sum256(b)
+		if p.token != "" {
+			req.Header.Set("X-Amz-Security-Token", p.token)
+		}
+		signV4(req, hex.EncodeToString(sum[:]), p.region, p.accessKey, p.secretKey, time.Now())
+	})
+	if err != nil {
+		return "", err
+	}
+	if p.publicURL != "" {
+		return strings.TrimSuffix(p.publicURL, "/") + "/" + escapePath(key), nil
+	}
+	return p.objectURL(key), nil
+}
+
+// escapePath escapes each segment of the path, the way S3 expects in its
+// signatures.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(s), "+", "%2B")
+	}
+	return strings.Join(segments, "/")
+}
+
+// signV4 signs the request for S3 with the version 4 of the signatures of AWS,
+// over the host and every header of the request.
+func signV4(req *http.Request, payloadHash, region, accessKey, secretKey string, t time.Time) {
+	t = t.UTC()
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + t.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 returns the HMAC of the data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// httpPublisher uploads the files to a directory of a web server with PUT
+// requests, with the token as a bearer token if it isn't empty.
+type httpPublisher struct {
+	url       string
+	publicURL string
+	token     string
+}
+
+// Upload sends the file to the directory. Its URL is the one answered by the
+// server if it answers one, or the one in the public URL of the directory,
+// or else the one where it was sent.
+func (p *httpPublisher) Upload(ctx context.Context, file string) (string, error) {
+	name := url.PathEscape(filepath.Base(file))
+	target := strings.TrimSuffix(p.url, "/") + "/" + name
+	_, body, err := putFile(ctx, target, file, func(req *http.Request, _ []byte) {
+		if p.token != "" {
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if answer := strings.TrimSpace(string(body)); strings.HasPrefix(answer, "http://") || strings.HasPrefix(answer, "https://") {
+		return answer, nil
+	}
+	if p.publicURL != "" {
+		return strings.TrimSuffix(p.publicURL, "/") + "/" + name, nil
+	}
+	return target, nil
+}
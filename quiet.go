@@ -0,0 +1,64 @@
+package main
+
+import "fmt"
+
+// ExecuteQuiet turns the quiet mode on or off. In quiet mode, the commands
+// are typed visibly but once Enter is pressed the recording is paused until
+// the end of the block, where the lines printed by the commands are removed
+// from the screen before the recording resumes.
+func ExecuteQuiet(c Command, v *VHS) {
+	if c.Args == "on" {
+		v.quiet = true
+		return
+	}
+
+	v.quiet = false
+	if v.quietRow < 0 {
+		return
+	}
+	if err := v.clearOutput(v.quietRow); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("failed to clear the output of the Quiet block: %w", err))
+	}
+	v.quietRow = -1
+	if v.quietPaused {
+		v.quietPaused = false
+		v.ResumeRecording()
+	}
+}
+
+// hideOutput remembers the line on which the command was typed and pauses
+// the recording so that its output is not captured. This only happens on the
+// first Enter of the block.
+func (v *VHS) hideOutput() {
+	if v.quietRow >= 0 {
+		return
+	}
+	res, err := v.Page.Eval("() => term.buffer.active.baseY + term.buffer.active.cursorY")
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("failed to read the cursor position: %w", err))
+		return
+	}
+	v.quietRow = res.Value.Int()
+	if v.recording {
+		v.quietPaused = true
+		v.PauseRecording()
+	}
+}
+
+// clearOutput deletes the lines between the given line and the cursor so that
+// the prompt moves back up right below the command. Lines which have already
+// scrolled off the screen can not be removed.
+func (v *VHS) clearOutput(row int) error {
+	_, err := v.Page.Eval(`(row) => new Promise((resolve) => {
+		const buffer = term.buffer.active;
+		const start = Math.max(row + 1, buffer.baseY);
+		const lines = buffer.baseY + buffer.cursorY - start;
+		if (lines <= 0) {
+			resolve();
+			return;
+		}
+		const top = start - buffer.baseY + 1;
+		term.write("\x1b[" + top + ";1H\x1b[" + lines + "M\x1b[" + top + ";" + (buffer.cursorX + 1) + "H", resolve);
+	})`, row)
+	return err
+}
@@ -1,69 +1,144 @@
 package main
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/creack/pty"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
-// sleepThreshold is the time at which if there has been no activity in the
-// tape file we insert a Sleep command
+// sleepThreshold is the pause between two key presses from which a Sleep
+// command is inserted.
 const sleepThreshold = 500 * time.Millisecond
 
-// EscapeSequences is a map of escape sequences to their VHS commands.
+// sleepPrecision and speedPrecision are the precisions to which the pauses
+// and the typing speeds of a recording are rounded.
+const (
+	sleepPrecision = 100 * time.Millisecond
+	speedPrecision = 10 * time.Millisecond
+)
+
+// EscapeSequences is a map of the sequences sent by the keys of the terminal
+// to their tape commands. The keys which have no command are commented out.
 var EscapeSequences = map[string]string{
-	"\x1b[A":  UP,
-	"\x1b[B":  DOWN,
-	"\x1b[C":  RIGHT,
-	"\x1b[D":  LEFT,
-	"\x1b[1~": HOME,
-	"\x1b[2~": INSERT,
-	"\x1b[3~": DELETE,
-	"\x1b[4~": END,
-	"\x01":    CTRL + "+A",
-	"\x02":    CTRL + "+B",
-	"\x03":    CTRL + "+C",
-	"\x04":    CTRL + "+D",
-	"\x05":    CTRL + "+E",
-	"\x06":    CTRL + "+F",
-	"\x07":    CTRL + "+G",
-	"\x08":    BACKSPACE,
-	"\x09":    TAB,
-	"\x0b":    CTRL + "+K",
-	"\x0c":    CTRL + "+L",
-	"\x0d":    ENTER,
-	"\x0e":    CTRL + "+N",
-	"\x0f":    CTRL + "+O",
-	"\x10":    CTRL + "+P",
-	"\x11":    CTRL + "+Q",
-	"\x12":    CTRL + "+R",
-	"\x13":    CTRL + "+S",
-	"\x14":    CTRL + "+T",
-	"\x15":    CTRL + "+U",
-	"\x16":    CTRL + "+V",
-	"\x17":    CTRL + "+W",
-	"\x18":    CTRL + "+X",
-	"\x19":    CTRL + "+Y",
-	"\x1a":    CTRL + "+Z",
-	"\x1b":    ESCAPE,
-	"\x7f":    BACKSPACE,
+	"\x1b[A":  "Up",
+	"\x1b[B":  "Down",
+	"\x1b[C":  "Right",
+	"\x1b[D":  "Left",
+	"\x1bOA":  "Up",
+	"\x1bOB":  "Down",
+	"\x1bOC":  "Right",
+	"\x1bOD":  "Left",
+	"\x1b[Z":  "Shift+Tab",
+	"\x1b[H":  "# Home",
+	"\x1b[F":  "# End",
+	"\x1b[1~": "# Home",
+	"\x1b[2~": "# Insert",
+	"\x1b[3~": "# Delete",
+	"\x1b[4~": "# End",
+	"\x1b[5~": "# PageUp",
+	"\x1b[6~": "# PageDown",
+	"\x00":    "Ctrl+@",
+	"\x01":    "Ctrl+A",
+	"\x02":    "Ctrl+B",
+	"\x03":    "Ctrl+C",
+	"\x04":    "Ctrl+D",
+	"\x05":    "Ctrl+E",
+	"\x06":    "Ctrl+F",
+	"\x07":    "Ctrl+G",
+	"\x08":    "Backspace",
+	"\x09":    "Tab",
+	"\x0a":    "Ctrl+J",
+	"\x0b":    "Ctrl+K",
+	"\x0c":    "Ctrl+L",
+	"\x0d":    "Enter",
+	"\x0e":    "Ctrl+N",
+	"\x0f":    "Ctrl+O",
+	"\x10":    "Ctrl+P",
+	"\x11":    "Ctrl+Q",
+	"\x12":    "Ctrl+R",
+	"\x13":    "Ctrl+S",
+	"\x14":    "Ctrl+T",
+	"\x15":    "Ctrl+U",
+	"\x16":    "Ctrl+V",
+	"\x17":    "Ctrl+W",
+	"\x18":    "Ctrl+X",
+	"\x19":    "Ctrl+Y",
+	"\x1a":    "Ctrl+Z",
+	"\x1b":    "Escape",
+	"\x1c":    "# Ctrl+\\",
+	"\x1d":    "Ctrl+]",
+	"\x7f":    "Backspace",
+}
+
+// escapeSequences are the sequences of EscapeSequences, the longest first so
+// that an arrow isn't read as Escape followed by text.
+var escapeSequences = func() []string {
+	seqs := make([]string, 0, len(EscapeSequences))
+	for seq := range EscapeSequences {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		if len(seqs[i]) != len(seqs[j]) {
+			return len(seqs[i]) > len(seqs[j])
+		}
+		return seqs[i] < seqs[j]
+	})
+	return seqs
+}()
+
+// keystroke is what was read from the terminal at once, along with the time
+// since the recording started. A paste is a single keystroke.
+type keystroke struct {
+	at   time.Duration
+	data string
+}
+
+// recordedKey is a key press of a recording: either a command such as Enter
+// or Ctrl+C, or a character to type.
+type recordedKey struct {
+	at      time.Duration
+	command string
+	text    string
 }
 
 // Record is a command that starts a pseudo-terminal for the user to begin
-// writing to, it records all the key presses on stdin and uses them to write
-// Tape commands.
+// writing to, it records all the key presses on stdin along with their timing
+// and uses them to write Tape commands.
 //
 // vhs record > file.tape
 func Record(cmd *cobra.Command, args []string) error {
+	shell, err := recordShell(shell, os.Getenv("SHELL"))
+	if err != nil {
+		return err
+	}
+	if keepTape && recordOutput == "" {
+		return errors.New("--keep-tape can only be used with --output")
+	}
+	// Fail before the recording, rather than once it is done, if it can't be
+	// rendered.
+	if recordOutput != "" {
+		if _, err := WithOutputs([]string{recordOutput}); err != nil {
+			return err
+		}
+		if !skipDependencyCheck() {
+			if err := ensureDependencies(); err != nil {
+				return err
+			}
+		}
+	}
 	command := exec.Command(shell)
 
 	terminal, err := pty.Start(command)
@@ -80,91 +155,251 @@ func Record(cmd *cobra.Command, args []string) error {
 		panic(err)
 	}
 
-	// We'll need to display the stdin on the screen but we'll also need a copy to
-	// analyze later and create a tape file.
-	var tape = &bytes.Buffer{}
-	in := io.MultiWriter(tape, terminal)
-
+	// Keep what is typed, and when, to write the tape once the shell exits,
+	// while passing it on to the PTY. Its output goes to stderr, so that
+	// stdout is reserved for the output tape file.
+	var (
+		mu         sync.Mutex
+		keystrokes []keystroke
+	)
+	start := time.Now()
 	go func() {
-		var length int
+		buf := make([]byte, 1024) //nolint:gomnd
 		for {
-			length = tape.Len()
-			time.Sleep(sleepThreshold)
-			if length == tape.Len() {
-				// Tape has not changed in a while, write a Sleep command.
-				tape.WriteString(fmt.Sprintf("\n%s\n", SLEEP))
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				mu.Lock()
+				keystrokes = append(keystrokes, keystroke{at: time.Since(start), data: string(buf[:n])})
+				mu.Unlock()
+				_, _ = terminal.Write(buf[:n])
+			}
+			if err != nil {
+				return
 			}
 		}
 	}()
-
-	// Write to the buffer and PTY's stdin and stderr so that stdout is reserved
-	// for the output tape file.
-	go func() { _, _ = io.Copy(in, os.Stdin) }()
 	_, _ = io.Copy(os.Stderr, terminal)
 
 	// PTY cleanup and restore terminal
 	_ = terminal.Close()
 	_ = term.Restore(int(os.Stdin.Fd()), prevState)
 
-	fmt.Println(inputToTape(tape.String()))
+	mu.Lock()
+	tape := recordedTape(shell, keystrokesToTape(keystrokes, !noTiming))
+	mu.Unlock()
+	if recordOutput == "" {
+		fmt.Println(tape)
+		return nil
+	}
+	return renderRecording(cmd, tape, recordOutput, keepTape)
+}
+
+// recordingTape returns the file of the tape of a recording rendered to the
+// output, next to it: demo.tape for demo.gif.
+func recordingTape(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + extension
+}
+
+// renderRecording renders the tape of a recording to the output, and writes
+// the tape next to it if asked to. The tape is written anyway if the
+// rendering fails, so that the recording isn't lost.
+func renderRecording(cmd *cobra.Command, tape, output string, keep bool) error {
+	tape = "Output " + quote(output) + "\n" + tape
+	file := recordingTape(output)
+	if keep {
+		if err := os.WriteFile(file, []byte(tape), 0o644); err != nil { //nolint:gosec
+			return err
+		}
+		fmt.Fprintln(os.Stderr, FileStyle.Render("File: "+file))
+	}
+
+	if errs := Evaluate(cmd.Context(), tape, os.Stderr); len(errs) > 0 {
+		printErrors(os.Stderr, tape, errs)
+		if !keep {
+			if err := os.WriteFile(file, []byte(tape), 0o644); err == nil { //nolint:gosec
+				fmt.Fprintln(os.Stderr, FileStyle.Render("The tape of the recording is kept in "+file))
+			}
+		}
+		return errors.New("recording failed")
+	}
 	return nil
 }
 
-// inputToTape takes input from a PTY stdin and converts it into a tape file.
-func inputToTape(input string) string {
-	// If the user exited the shell by typing exit don't record this in the
-	// command.
-	//
-	// NOTE: this is not very robust as if someone types exii<BS>t it will not work
-	// correctly and the exit will show up. In this case, the user should edit the
-	// tape file.
-	s := strings.TrimSuffix(strings.TrimSpace(input), "exit")
-	for sequence, command := range EscapeSequences {
-		s = strings.ReplaceAll(s, sequence, "\n"+command+"\n")
-	}
-
-	s = strings.ReplaceAll(s, "\n\n", "\n")
-
-	var sanitized strings.Builder
-	lines := strings.Split(s, "\n")
-
-	for i := 0; i < len(lines)-1; i++ {
-		// Group repeated commands to compress file and make it more readable.
-		repeat := 1
-		for lines[i] == lines[i+repeat] {
-			repeat++
+// recordShells are the shells which can be recorded.
+var recordShells = []string{bash, zsh, fish, pwsh}
+
+// recordShell returns the shell to record: the one given with --shell, or
+// else the login shell of the user if it can be recorded and is installed, or
+// else bash.
+func recordShell(flag, env string) (string, error) {
+	if flag != "" {
+		for _, s := range recordShells {
+			if flag == s {
+				return flag, nil
+			}
+		}
+		return "", fmt.Errorf("unsupported shell %s: expected one of %s", flag, strings.Join(recordShells, ", "))
+	}
+
+	name := filepath.Base(env)
+	for _, s := range recordShells {
+		if env != "" && name == s {
+			if _, err := exec.LookPath(env); err == nil {
+				return name, nil
+			}
 		}
-		i += repeat - 1
-
-		// We've encountered some non-command, assume that we need to type these
-		// characters.
-		if TokenType(lines[i]) == SLEEP {
-			sleep := sleepThreshold * time.Duration(repeat)
-			sanitized.WriteString(fmt.Sprintf("%s %s", TokenType(SLEEP), sleep))
-		} else if strings.HasPrefix(lines[i], CTRL) {
-			for j := 0; j < repeat; j++ {
-				sanitized.WriteString("Ctrl" + strings.TrimPrefix(lines[i], CTRL) + "\n")
+	}
+	return bash, nil
+}
+
+// recordSetup are the commands which set up the shells for the replay of a
+// recording. The autosuggestions of fish and PowerShell are based on their
+// history, so they are turned off so that the replay shows what was typed.
+var recordSetup = map[string]string{
+	fish: "set -g fish_autosuggestion_enabled 0",
+	pwsh: "Set-PSReadLineOption -PredictionSource None",
+}
+
+// recordedTape returns the tape of a recording of the shell: the setting of
+// the shell, its setup if any, followed by the commands typed.
+func recordedTape(shell, commands string) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "Set Shell %s\n", shell)
+	if setup, ok := recordSetup[shell]; ok {
+		fmt.Fprintf(&s, "\nHide\n%s %s\n%s\nCtrl+L\nShow\n", TokenType(TYPE), quote(setup), TokenType(ENTER))
+	}
+	s.WriteString("\n")
+	s.WriteString(commands)
+	return s.String()
+}
+
+// recordedKeys splits the keystrokes into key presses, with the escape
+// sequences and the control characters read as their commands.
+func recordedKeys(keystrokes []keystroke) []recordedKey {
+	var keys []recordedKey
+	for _, k := range keystrokes {
+		data := k.data
+		for len(data) > 0 {
+			key := recordedKey{at: k.at}
+			n := 0
+			for _, seq := range escapeSequences {
+				if strings.HasPrefix(data, seq) {
+					key.command, n = EscapeSequences[seq], len(seq)
+					break
+				}
+			}
+			switch {
+			case key.command == "Escape" && len(data) > 2 && (data[1] == '[' || data[1] == 'O'):
+				// An escape sequence of a key which isn't known, skipped up
+				// to its final byte.
+				n = 2
+				for n < len(data) && (data[n] < 0x40 || data[n] > 0x7e) {
+					n++
+				}
+				n++
+				key.command = ""
+			case key.command == "Escape" && len(data) > 1 && data[1] > ' ' && data[1] < 0x7f:
+				key.command, n = "Alt+"+data[1:2], 2
+			case key.command == "":
+				r, size := utf8.DecodeRuneInString(data)
+				key.text, n = string(r), size
 			}
-			continue
-		} else if IsCommand(TokenType(lines[i])) {
-			sanitized.WriteString(fmt.Sprint(TokenType(lines[i])))
-			if repeat > 1 {
-				sanitized.WriteString(fmt.Sprint(" ", repeat))
+			if n > len(data) {
+				n = len(data)
 			}
-		} else {
-			sanitized.WriteString(fmt.Sprintln(TokenType(TYPE), quote(lines[i])))
-			continue
+			data = data[n:]
+			if key.command != "" || key.text != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return trimExit(keys)
+}
+
+// trimExit removes the exit command, or the Ctrl+D, which ends the recording.
+func trimExit(keys []recordedKey) []recordedKey {
+	n := len(keys)
+	if n > 0 && keys[n-1].command == "Ctrl+D" {
+		return keys[:n-1]
+	}
+	if n >= 5 && keys[n-1].command == "Enter" {
+		var typed string
+		for _, k := range keys[n-5 : n-1] {
+			typed += k.text
+		}
+		if typed == "exit" {
+			return keys[:n-5]
 		}
-		sanitized.WriteRune('\n')
 	}
+	return keys
+}
+
+// keystrokesToTape converts the keystrokes of a recording to the commands of
+// a tape. Consecutive characters are typed by the same Type command, and
+// consecutive presses of the same key are repeated. With timing, the pauses
+// are Sleep commands and the typing speed of each Type command is kept with
+// @<time> when it isn't the default one.
+func keystrokesToTape(keystrokes []keystroke, timing bool) string {
+	keys := recordedKeys(keystrokes)
+
+	var s strings.Builder
+	for i := 0; i < len(keys); {
+		if i > 0 && timing {
+			if pause := keys[i].at - keys[i-1].at; pause >= sleepThreshold {
+				fmt.Fprintf(&s, "%s %s\n", TokenType(SLEEP), formatDuration(pause.Round(sleepPrecision).String()))
+			}
+		}
+		// The keys of the group, up to the next pause.
+		j := i + 1
+		for j < len(keys) && (!timing || keys[j].at-keys[j-1].at < sleepThreshold) &&
+			(keys[i].command == keys[j].command) && (keys[i].command == "" || keys[i].command[0] != '#') {
+			j++
+		}
 
-	return sanitized.String()
+		switch {
+		case keys[i].command == "":
+			var text strings.Builder
+			for _, k := range keys[i:j] {
+				text.WriteString(k.text)
+			}
+			s.WriteString(TokenType(TYPE).String())
+			if timing && j-i > 1 {
+				speed := ((keys[j-1].at - keys[i].at) / time.Duration(j-i-1)).Round(speedPrecision)
+				switch speed {
+				case defaultTypingSpeed:
+				case 0:
+					// Pasted, or typed faster than the precision.
+					s.WriteString("@0ms")
+				default:
+					fmt.Fprintf(&s, "@%s", formatDuration(speed.String()))
+				}
+			}
+			fmt.Fprintf(&s, " %s\n", quote(text.String()))
+		case strings.HasPrefix(keys[i].command, "Ctrl+") || strings.HasPrefix(keys[i].command, "Alt+") ||
+			strings.HasPrefix(keys[i].command, "Shift+") || keys[i].command[0] == '#':
+			// Chords and comments can't be repeated.
+			for k := i; k < j; k++ {
+				s.WriteString(keys[k].command + "\n")
+			}
+		case j-i > 1:
+			fmt.Fprintf(&s, "%s %d\n", keys[i].command, j-i)
+		default:
+			s.WriteString(keys[i].command + "\n")
+		}
+		i = j
+	}
+	return s.String()
 }
 
-// quote wraps a string in (single or double) quotes
+// quote wraps a string in double quotes, or single quotes or backticks if it
+// contains double quotes.
 func quote(s string) string {
-	if strings.ContainsRune(s, '"') {
+	switch {
+	case !strings.ContainsRune(s, '"'):
+		return fmt.Sprintf(`"%s"`, s)
+	case !strings.ContainsRune(s, '\''):
 		return fmt.Sprintf(`'%s'`, s)
+	default:
+		return "`" + s + "`"
 	}
-	return fmt.Sprintf(`"%s"`, s)
 }
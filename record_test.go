@@ -1,32 +1,17 @@
 package main
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
 )
 
-func TestInputToTape(t *testing.T) {
-	input := `echo "Hello,.
-BACKSPACE
-LEFT
-LEFT
-RIGHT
-RIGHT
- world"
-ENTER
-ENTER
-ENTER
-ls
-ENTER
-ENTER
-BACKSPACE
-CTRL+C
-CTRL+C
-CTRL+C
-CTRL+W
-CTRL+A
-CTRL+E
-exit
-`
+func TestKeystrokesToTape(t *testing.T) {
+	input := "echo \"Hello,.\x7f\x1b[D\x1b[D\x1b[C\x1b[C world\"\r\r\rls\r\r\x7f\x03\x03\x03\x17\x01\x05exit\r"
 
 	want := `Type 'echo "Hello,.'
 Backspace
@@ -45,8 +30,115 @@ Ctrl+A
 Ctrl+E
 `
 
-	got := inputToTape(input)
+	got := keystrokesToTape([]keystroke{{data: input}}, false)
 	if want != got {
 		t.Fatalf("want:\n%s\ngot:\n%s\n", want, got)
 	}
 }
+
+func TestKeystrokesToTapeKeys(t *testing.T) {
+	input := "\x1bOA\x1b\x1bb\x1b[Z\x1b[3~\x1b[1;5A\x1b\x1c\x04"
+
+	want := `Up
+Escape
+Alt+b
+Shift+Tab
+# Delete
+Escape
+# Ctrl+\
+`
+
+	if got := keystrokesToTape([]keystroke{{data: input}}, false); want != got {
+		t.Fatalf("want:\n%s\ngot:\n%s\n", want, got)
+	}
+}
+
+func TestKeystrokesToTapeTiming(t *testing.T) {
+	ms := time.Millisecond
+	keystrokes := []keystroke{
+		{at: 0, data: "l"},
+		{at: 100 * ms, data: "s"},
+		{at: 150 * ms, data: "\r"},
+		{at: 1420 * ms, data: "e"},
+		{at: 1470 * ms, data: "c"},
+		{at: 1520 * ms, data: "h"},
+		{at: 1570 * ms, data: "o"},
+		{at: 2200 * ms, data: "git status --short"},
+		{at: 2300 * ms, data: "\r"},
+		{at: 2400 * ms, data: "\r"},
+	}
+
+	want := `Type@100ms "ls"
+Enter
+Sleep 1300ms
+Type "echo"
+Sleep 600ms
+Type@0ms "git status --short"
+Enter 2
+`
+
+	if got := keystrokesToTape(keystrokes, true); want != got {
+		t.Fatalf("want:\n%s\ngot:\n%s\n", want, got)
+	}
+
+	want = `Type "ls"
+Enter
+Type "echogit status --short"
+Enter 2
+`
+	if got := keystrokesToTape(keystrokes, false); want != got {
+		t.Fatalf("want without timing:\n%s\ngot:\n%s\n", want, got)
+	}
+}
+
+func TestRecordShell(t *testing.T) {
+	if s, err := recordShell("zsh", "/bin/bash"); err != nil || s != "zsh" {
+		t.Errorf("expected the shell of the flag, got %q, %v", s, err)
+	}
+	if _, err := recordShell("tcsh", ""); err == nil || err.Error() != "unsupported shell tcsh: expected one of bash, zsh, fish, pwsh" {
+		t.Errorf("expected an error for an unsupported shell, got %v", err)
+	}
+	if s, _ := recordShell("", ""); s != "bash" {
+		t.Errorf("expected bash without $SHELL, got %q", s)
+	}
+	if s, _ := recordShell("", "/nonexistent/zsh"); s != "bash" {
+		t.Errorf("expected bash when $SHELL is not installed, got %q", s)
+	}
+	if s, _ := recordShell("", "/bin/tcsh"); s != "bash" {
+		t.Errorf("expected bash when $SHELL can't be recorded, got %q", s)
+	}
+}
+
+func TestRecordedTape(t *testing.T) {
+	if got, want := recordedTape("zsh", "Type \"ls\"\nEnter\n"), "Set Shell zsh\n\nType \"ls\"\nEnter\n"; got != want {
+		t.Errorf("want:\n%q\ngot:\n%q", want, got)
+	}
+
+	want := "Set Shell fish\n\nHide\nType \"set -g fish_autosuggestion_enabled 0\"\nEnter\nCtrl+L\nShow\n\nType \"ls\"\nEnter\n"
+	if got := recordedTape("fish", "Type \"ls\"\nEnter\n"); got != want {
+		t.Errorf("want:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestRecordingTape(t *testing.T) {
+	if got := recordingTape(filepath.Join("out", "demo.gif")); got != filepath.Join("out", "demo.tape") {
+		t.Errorf("expected the tape next to the output, got %q", got)
+	}
+}
+
+func TestRenderRecordingKeepsTapeOnFailure(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "demo.gif")
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+
+	if err := renderRecording(cmd, "Set Shell bash\n\nFoo\n", output, false); err == nil {
+		t.Fatal("expected an error for an invalid tape")
+	}
+	b, err := os.ReadFile(recordingTape(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Output \"" + output + "\"\nSet Shell bash\n\nFoo\n"; string(b) != want {
+		t.Errorf("want:\n%q\ngot:\n%q", want, b)
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTapeSize is the maximum size of a tape uploaded to the render API.
+const maxTapeSize = 1 << 20
+
+// renderFormats are the formats the render API can render, with their
+// content type.
+var renderFormats = map[string]string{
+	"gif":  "image/gif",
+	"mp4":  "video/mp4",
+	"webm": "video/webm",
+	"webp": "image/webp",
+	"apng": "image/apng",
+	"svg":  "image/svg+xml",
+	"png":  "image/png",
+	"cast": "application/x-asciicast",
+}
+
+// The statuses of a render job.
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// errQueueFull is returned when there are already as many jobs waiting as the
+// queue can hold.
+var errQueueFull = errors.New("the render queue is full")
+
+// renderJob is a tape rendered by the render API, or the tapes of a
+// repository, in which case it has no format and its output is the directory
+// of its artifacts.
+type renderJob struct {
+	id     string
+	tape   string
+	format string
+	output string
+	// temporary is set if the output is removed along with the job.
+	temporary bool
+
+	mu        sync.Mutex
+	status    string
+	progress  Progress
+	log       bytes.Buffer
+	errs      []string
+	artifacts []string
+	// changed is closed and replaced whenever the job changes, so that the
+	// event streams know when to send an update.
+	changed chan struct{}
+}
+
+// renderJobStatus is the JSON representation of a render job.
+type renderJobStatus struct {
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	Format    string   `json:"format"`
+	Command   int      `json:"command"`
+	Total     int      `json:"total"`
+	Errors    []string `json:"errors,omitempty"`
+	Events    string   `json:"events"`
+	Download  string   `json:"download,omitempty"`
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// update changes the job and notifies the event streams.
+func (j *renderJob) update(fn func(j *renderJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(j)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// Write appends the log of the evaluation to the job.
+func (j *renderJob) Write(b []byte) (int, error) {
+	j.update(func(j *renderJob) { j.log.Write(b) })
+	return len(b), nil
+}
+
+// snapshot returns the status of the job, along with the channel closed on
+// its next change.
+func (j *renderJob) snapshot() (renderJobStatus, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := renderJobStatus{
+		ID:      j.id,
+		Status:  j.status,
+		Format:  j.format,
+		Command: j.progress.Command,
+		Total:   j.progress.Total,
+		Errors:  append([]string(nil), j.errs...),
+		Events:  "/jobs/" + j.id + "/events",
+	}
+	if j.status == jobDone && j.format != "" {
+		s.Download = "/jobs/" + j.id + "/output"
+	}
+	for _, file := range j.artifacts {
+		s.Artifacts = append(s.Artifacts, "/jobs/"+j.id+"/artifacts/"+filepath.ToSlash(file))
+	}
+	return s, j.changed
+}
+
+// finished reports whether the job is done or failed.
+func (s renderJobStatus) finished() bool {
+	return s.Status == jobDone || s.Status == jobFailed
+}
+
+// renderQueue runs the renders of the server, at most as many at a time as it
+// has slots, and keeps the finished jobs for a while so that their output can
+// be downloaded.
+type renderQueue struct {
+	// ctx is the context of the server, which cancels the renders when it
+	// stops.
+	ctx     context.Context //nolint:containedctx
+	dir     string
+	slots   chan struct{}
+	waiting chan struct{}
+	ttl     time.Duration
+	// render renders the job to its output file.
+	render func(ctx context.Context, j *renderJob) []error
+
+	mu   sync.Mutex
+	jobs map[string]*renderJob
+}
+
+// newRenderQueue returns a queue which runs up to concurrency renders at once,
+// with up to size renders waiting for a slot. The outputs are written to dir
+// and removed after ttl.
+func newRenderQueue(ctx context.Context, dir string, concurrency, size int, ttl time.Duration) *renderQueue {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &renderQueue{
+		ctx:     ctx,
+		dir:     dir,
+		slots:   make(chan struct{}, concurrency),
+		waiting: make(chan struct{}, concurrency+size),
+		ttl:     ttl,
+		render:  evaluateJob,
+		jobs:    map[string]*renderJob{},
+	}
+}
+
+// evaluateJob renders the job with VHS. The outputs of the tape are replaced
+// by the output of the job, see sandboxOutputs.
+func evaluateJob(ctx context.Context, j *renderJob) []error {
+	return Evaluate(ctx, j.tape, j, WithProgress(func(p Progress) {
+		// The status of a job only counts its commands.
+		if p.Output != "" {
+			return
+		}
+		j.update(func(j *renderJob) { j.progress = p })
+	}), func(v *VHS) {
+		sandboxOutputs(v)
+		setOutput(Command{Type: OUTPUT, Options: "." + j.format, Args: j.output}, v)
+	})
+}
+
+// acquire waits for a free slot, or returns an error if the queue is full or
+// the context is done.
+func (q *renderQueue) acquire(ctx context.Context) error {
+	if err := q.enqueue(); err != nil {
+		return err
+	}
+	return q.wait(ctx)
+}
+
+// enqueue takes a place in the queue, or returns an error if it is full.
+func (q *renderQueue) enqueue() error {
+	select {
+	case q.waiting <- struct{}{}:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// wait waits for a free slot once in the queue.
+func (q *renderQueue) wait(ctx context.Context) error {
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		<-q.waiting
+		return ctx.Err()
+	}
+}
+
+// release frees the slot taken by acquire.
+func (q *renderQueue) release() {
+	<-q.slots
+	<-q.waiting
+}
+
+// newToken returns a random token for the render API, for the servers which
+// aren't given one.
+func newToken() (string, error) {
+	b := make([]byte, 32) //nolint:gomnd
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newJobID returns a random id for a job.
+func newJobID() (string, error) {
+	b := make([]byte, 8) //nolint:gomnd
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// submit queues the tape to be rendered in the given format.
+func (q *renderQueue) submit(tape, format string) (*renderJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	j := &renderJob{
+		id:        id,
+		tape:      tape,
+		format:    format,
+		output:    filepath.Join(q.dir, id+"."+format),
+		temporary: true,
+	}
+	return j, q.start(j, q.render)
+}
+
+// submitRepo queues the rendering of the tapes of a repository. The
+// artifacts are copied to a directory of the queue, removed along with the
+// job, unless the options have their own directory.
+func (q *renderQueue) submitRepo(opts RepoOptions) (*renderJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	j := &renderJob{id: id, output: opts.Artifacts}
+	if j.output == "" {
+		j.output, j.temporary = filepath.Join(q.dir, id), true
+	}
+	opts.Artifacts = j.output
+	return j, q.start(j, func(ctx context.Context, j *renderJob) []error {
+		artifacts, err := RenderRepo(ctx, opts, j)
+		j.update(func(j *renderJob) { j.artifacts = artifacts })
+		if err != nil {
+			return []error{err}
+		}
+		return nil
+	})
+}
+
+// start queues the job, to be run by render once there is a free slot.
+func (q *renderQueue) start(j *renderJob, render func(ctx context.Context, j *renderJob) []error) error {
+	j.status, j.changed = jobQueued, make(chan struct{})
+	if err := q.enqueue(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	q.jobs[j.id] = j
+	q.mu.Unlock()
+
+	go func() {
+		defer time.AfterFunc(q.ttl, func() { q.remove(j.id) })
+		if err := q.wait(q.ctx); err != nil {
+			j.update(func(j *renderJob) { j.status, j.errs = jobFailed, []string{err.Error()} })
+			return
+		}
+		defer q.release()
+
+		j.update(func(j *renderJob) { j.status = jobRunning })
+		errs := render(q.ctx, j)
+		j.update(func(j *renderJob) {
+			j.status = jobDone
+			for _, err := range errs {
+				j.status = jobFailed
+				j.errs = append(j.errs, err.Error())
+			}
+		})
+	}()
+	return nil
+}
+
+// job returns the job with the given id.
+func (q *renderQueue) job(id string) (*renderJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	return j, ok
+}
+
+// remove forgets the job and removes its output.
+func (q *renderQueue) remove(id string) {
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	delete(q.jobs, id)
+	q.mu.Unlock()
+	if ok && j.temporary {
+		_ = os.RemoveAll(j.output)
+	}
+}
+
+// renderAPI returns the handler of the render API. The requests must have the
+// token as a bearer token, and none is accepted if it is empty. The requests
+// of browsers, which carry an Origin, are refused as well: the tapes type in
+// the shell of the server, which no web page should be able to do.
+//
+//	POST /render?format=gif[&wait=true]  queues the tape of the body
+//	GET  /jobs/<id>                      returns the status of a job
+//	GET  /jobs/<id>/events               streams the progress of a job
+//	GET  /jobs/<id>/output               downloads the output of a job
+//	GET  /jobs/<id>/artifacts/<path>     downloads an artifact of a repository
+//
+// The webhook, if any, is served on /webhook, without the token since it
+// checks the signatures of the requests itself.
+func renderAPI(q *renderQueue, token string, webhook http.Handler) http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("/render", q.serveRender)
+	api.HandleFunc("/jobs/", q.serveJob)
+
+	mux := http.NewServeMux()
+	if webhook != nil {
+		mux.Handle("/webhook", webhook)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			http.Error(w, "cross-origin requests are refused", http.StatusForbidden)
+			return
+		}
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+	return mux
+}
+
+// isTapeContentType returns whether the content type can be the one of a
+// tape. The ones of HTML forms are refused, since browsers send them across
+// origins without asking the server first.
+func isTapeContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "text/plain", "application/x-www-form-urlencoded", "multipart/form-data":
+		return false
+	default:
+		return true
+	}
+}
+
+// serveRender queues the tape of the body. The job is returned right away,
+// unless wait is set, in which case the output is returned once rendered.
+func (q *renderQueue) serveRender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isTapeContentType(r.Header.Get("Content-Type")) {
+		http.Error(w, "the tape must be sent as application/octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "gif"
+	}
+	if _, ok := renderFormats[format]; !ok {
+		http.Error(w, fmt.Sprintf("unsupported format %q", format), http.StatusBadRequest)
+		return
+	}
+	tape, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTapeSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	j, err := q.submit(string(tape), format)
+	if errors.Is(err, errQueueFull) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("wait") != "true" {
+		s, _ := j.snapshot()
+		w.Header().Set("Location", "/jobs/"+j.id)
+		writeJSON(w, http.StatusAccepted, s)
+		return
+	}
+	for {
+		s, changed := j.snapshot()
+		if s.finished() {
+			q.serveOutput(w, r, j)
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// serveJob serves the status, the events and the output of a job.
+func (q *renderQueue) serveJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	j, ok := q.job(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case action == "":
+		s, _ := j.snapshot()
+		writeJSON(w, http.StatusOK, s)
+	case action == "events":
+		serveEvents(w, r, j)
+	case action == "output" && j.format != "":
+		q.serveOutput(w, r, j)
+	case strings.HasPrefix(action, "artifacts/") && j.format == "":
+		if s, _ := j.snapshot(); !s.finished() {
+			http.Error(w, "the job is "+s.Status, http.StatusConflict)
+			return
+		}
+		http.StripPrefix("/jobs/"+id+"/artifacts", http.FileServer(http.Dir(j.output))).ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveOutput serves the output of a finished job, or its errors and log if
+// it failed.
+func (q *renderQueue) serveOutput(w http.ResponseWriter, r *http.Request, j *renderJob) {
+	s, _ := j.snapshot()
+	switch s.Status {
+	case jobDone:
+		w.Header().Set("Content-Type", renderFormats[j.format])
+		http.ServeFile(w, r, j.output)
+	case jobFailed:
+		j.mu.Lock()
+		log := j.log.String()
+		j.mu.Unlock()
+		http.Error(w, strings.Join(append(s.Errors, log), "\n"), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, "the job is "+s.Status, http.StatusConflict)
+	}
+}
+
+// serveEvents streams the progress of the job as server-sent events: a
+// status event whenever the job changes and a log event for every line of its
+// log, until the job is finished.
+func serveEvents(w http.ResponseWriter, r *http.Request, j *renderJob) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	var sent int
+	for {
+		s, changed := j.snapshot()
+		j.mu.Lock()
+		log := j.log.String()[sent:]
+		j.mu.Unlock()
+		if i := strings.LastIndex(log, "\n"); i >= 0 || s.finished() {
+			if !s.finished() {
+				log = log[:i+1]
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(log, "\n"), "\n") {
+				if line != "" {
+					fmt.Fprintf(w, "event: log\ndata: %s\n\n", line)
+				}
+			}
+			sent += len(log)
+		}
+		b, _ := json.Marshal(s)
+		fmt.Fprintf(w, "event: status\ndata: %s\n\n", b)
+		flusher.Flush()
+		if s.finished() {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRenderAPI returns a render API whose renders write the tape to the
+// output, or fail if the tape is "fail", once release is closed.
+func newTestRenderAPI(t *testing.T, token string) (*httptest.Server, *renderQueue, chan struct{}) {
+	t.Helper()
+	release := make(chan struct{})
+	q := newRenderQueue(context.Background(), t.TempDir(), 1, 1, time.Minute)
+	q.render = func(ctx context.Context, j *renderJob) []error {
+		<-release
+		_, _ = j.Write([]byte("Type " + j.tape + "\n"))
+		if j.tape == "fail" {
+			return []error{errors.New("render failed")}
+		}
+		if err := os.WriteFile(j.output, []byte(j.tape), 0o600); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+	srv := httptest.NewServer(renderAPI(q, token, nil))
+	t.Cleanup(srv.Close)
+	return srv, q, release
+}
+
+// testToken is the token of the render APIs of the tests.
+const testToken = "secret"
+
+// apiRequest sends a request to the render API with the token, and the tape
+// of the body, if any, as application/octet-stream.
+func apiRequest(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRenderAPI(t *testing.T) {
+	srv, _, release := newTestRenderAPI(t, testToken)
+
+	resp := apiRequest(t, http.MethodPost, srv.URL+"/render?format=gif", "hello")
+	var job renderJobStatus
+	_ = json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || job.Status != jobQueued && job.Status != jobRunning {
+		t.Fatalf("expected the job to be queued, got %d %+v", resp.StatusCode, job)
+	}
+
+	// The output isn't available before the render.
+	resp = apiRequest(t, http.MethodGet, srv.URL+"/jobs/"+job.ID+"/output", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected a conflict before the render, got %d", resp.StatusCode)
+	}
+
+	resp = apiRequest(t, http.MethodGet, srv.URL+job.Events, "")
+	close(release)
+	events, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(events), "event: log\ndata: Type hello\n") || !strings.Contains(string(events), `"status":"done"`) {
+		t.Errorf("expected the log and the final status, got:\n%s", events)
+	}
+
+	resp = apiRequest(t, http.MethodGet, srv.URL+"/jobs/"+job.ID+"/output", "")
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "hello" || resp.Header.Get("Content-Type") != "image/gif" {
+		t.Errorf("expected the output, got %q (%s)", b, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestRenderAPIWait(t *testing.T) {
+	srv, _, release := newTestRenderAPI(t, testToken)
+	close(release)
+
+	resp := apiRequest(t, http.MethodPost, srv.URL+"/render?format=mp4&wait=true", "hello")
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(b) != "hello" {
+		t.Errorf("expected the output, got %d %q", resp.StatusCode, b)
+	}
+
+	resp = apiRequest(t, http.MethodPost, srv.URL+"/render?wait=true", "fail")
+	b, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(string(b), "render failed") {
+		t.Errorf("expected the errors of the render, got %d %q", resp.StatusCode, b)
+	}
+}
+
+func TestRenderAPIErrors(t *testing.T) {
+	srv, _, release := newTestRenderAPI(t, testToken)
+	defer close(release)
+
+	post := func(query, token string, header ...string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/render"+query, strings.NewReader("hello"))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized without the token, got %d", code)
+	}
+	if code := post("", testToken, "Origin", "https://example.com"); code != http.StatusForbidden {
+		t.Errorf("expected a cross-origin request to be refused, got %d", code)
+	}
+	for _, contentType := range []string{"text/plain;charset=UTF-8", "application/x-www-form-urlencoded", "multipart/form-data; boundary=x"} {
+		if code := post("", testToken, "Content-Type", contentType); code != http.StatusUnsupportedMediaType {
+			t.Errorf("expected %s to be refused, got %d", contentType, code)
+		}
+	}
+	if code := post("?format=bmp", testToken); code != http.StatusBadRequest {
+		t.Errorf("expected a bad request for an unknown format, got %d", code)
+	}
+
+	// One job runs and one waits, so the third one doesn't fit in the queue.
+	for i := 0; i < 2; i++ {
+		if code := post("", testToken, "Content-Type", "application/octet-stream"); code != http.StatusAccepted {
+			t.Fatalf("expected the job to be accepted, got %d", code)
+		}
+	}
+	if code := post("", testToken); code != http.StatusServiceUnavailable {
+		t.Errorf("expected the queue to be full, got %d", code)
+	}
+}
+
+func TestRenderAPIWithoutToken(t *testing.T) {
+	srv, _, release := newTestRenderAPI(t, "")
+	defer close(release)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/render", strings.NewReader("hello"))
+	req.Header.Set("Authorization", "Bearer ")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected every request to be refused without a token, got %d", resp.StatusCode)
+	}
+}
+
+func TestSandboxOutputs(t *testing.T) {
+	v := New()
+	input := v.Options.Video.Input
+	for _, cmd := range []Command{
+		{Type: OUTPUT, Options: ".webp", Args: "/etc/demo.webp"},
+		{Type: OUTPUT, Options: ".png", Args: "/etc/frames/"},
+		{Type: OUTPUT, Options: ".txt", Args: "/etc/screen.txt"},
+		{Type: SET, Options: "KeyLog", Args: "/etc/keys.log"},
+		{Type: SET, Options: "ScreenshotDir", Args: "/etc"},
+	} {
+		executeCommand(cmd, &v)
+	}
+
+	sandboxOutputs(&v)
+	if v.Options.Video.Output != (VideoOutputs{}) || v.Options.Test != (TestOptions{}) || v.Options.KeyLog != "" || v.Options.ScreenshotDir != "" {
+		t.Errorf("expected every output to be cleared, got %+v", v.Options)
+	}
+	if v.Options.Video.Input == "/etc/frames/" || !v.Options.Video.CleanupFrames {
+		t.Errorf("expected the frames in a temporary directory, got %s", v.Options.Video.Input)
+	}
+	_ = os.RemoveAll(input)
+	_ = os.RemoveAll(v.Options.Video.Input)
+
+	// The commands which come later are ignored too.
+	ExecuteOutput(Command{Type: OUTPUT, Options: ".gif", Args: "/etc/demo.gif"}, &v)
+	ExecuteSetKeyLog(Command{Type: SET, Options: "KeyLog", Args: "/etc/keys.log"}, &v)
+	if v.Options.Video.Output.GIF != "" || v.Options.KeyLog != "" {
+		t.Errorf("expected the later outputs to be ignored, got %+v", v.Options.Video.Output)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// RepoOptions are the options of the rendering of the tapes of a repository.
+type RepoOptions struct {
+	// URL is the URL of the repository, as given to git.
+	URL string
+	// Ref is the branch, tag or commit to render.
+	Ref string
+	// Path is the directory of the repository with the tapes.
+	Path string
+	// Artifacts is the directory where the outputs are copied.
+	Artifacts string
+}
+
+// renderTapes renders the tape files in dir. It runs VHS itself rather than
+// evaluating the tapes in this process, since their outputs are relative to
+// the directory of the checkout.
+var renderTapes = func(ctx context.Context, dir string, files []string, out io.Writer) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, exe, files...) //nolint:gosec
+	cmd.Dir = dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// RenderRepo checks out the repository, renders the tapes found under its
+// path and copies the files they wrote to the artifacts directory. It returns
+// the artifacts, relative to that directory.
+func RenderRepo(ctx context.Context, opts RepoOptions, out io.Writer) ([]string, error) {
+	if opts.Ref == "" {
+		opts.Ref = "HEAD"
+	}
+	if strings.HasPrefix(opts.URL, "-") || strings.HasPrefix(opts.Ref, "-") {
+		return nil, errors.New("the repository and its ref can't start with a dash")
+	}
+
+	dir, err := os.MkdirTemp("", "vhs-repo")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fmt.Fprintln(out, FaintStyle.Render("Checkout: "+opts.URL+" "+opts.Ref))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"fetch", "-q", "--depth", "1", "--", opts.URL, opts.Ref},
+		{"checkout", "-q", "FETCH_HEAD"},
+	} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		if b, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("git %s: %w\n%s", args[0], err, strings.TrimSpace(string(b)))
+		}
+	}
+
+	files, err := findTapes(dir, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no tape file in %s", opts.Path)
+	}
+
+	before, err := modTimes(dir)
+	if err != nil {
+		return nil, err
+	}
+	renderErr := renderTapes(ctx, dir, files, out)
+
+	// Copy what was written, even if some tapes failed, so that the outputs of
+	// the others are kept.
+	after, err := modTimes(dir)
+	if err != nil {
+		return nil, err
+	}
+	written := make([]string, 0, len(after))
+	for file := range after {
+		written = append(written, file)
+	}
+	sort.Strings(written)
+	var artifacts []string
+	for _, file := range written {
+		if t, ok := before[file]; ok && t.Equal(after[file]) {
+			continue
+		}
+		dst := filepath.Join(opts.Artifacts, file)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil { //nolint:gomnd
+			return artifacts, err
+		}
+		if err := copyFile(filepath.Join(dir, file), dst); err != nil {
+			return artifacts, err
+		}
+		fmt.Fprintln(out, FaintStyle.Render("Artifact: "+file))
+		artifacts = append(artifacts, file)
+	}
+	return artifacts, renderErr
+}
+
+// findTapes returns the tape files under path in the checkout, relative to
+// the checkout. The path can't be outside of it.
+func findTapes(dir, path string) ([]string, error) {
+	root := filepath.Join(dir, path)
+	if rel, err := filepath.Rel(dir, root); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is outside of the repository", path)
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && filepath.Ext(path) == extension {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// modTimes returns the modification time of every file of the checkout,
+// relative to it, to find the files written by the tapes.
+func modTimes(dir string) (map[string]time.Time, error) {
+	times := map[string]time.Time{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		times[rel] = info.ModTime()
+		return nil
+	})
+	return times, err
+}
+
+var repoOptions RepoOptions
+
+var renderCmd = &cobra.Command{
+	Use:   "render --from-repo <url>",
+	Short: "Render the tapes of a repository and collect their outputs",
+	Long: `Render the tapes of a repository and collect their outputs.
+
+The repository is checked out in a temporary directory, the tapes under --path
+are rendered from its root, and the files they write are copied to --artifacts.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if repoOptions.URL == "" {
+			return errors.New("--from-repo is required")
+		}
+		if !skipDependencyCheck() {
+			if err := ensureDependencies(); err != nil {
+				return err
+			}
+		}
+		artifacts, err := RenderRepo(cmd.Context(), repoOptions, os.Stdout)
+		fmt.Printf("%d artifact(s) in %s\n", len(artifacts), repoOptions.Artifacts)
+		return err
+	},
+}
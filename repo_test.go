@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestRepo returns a git repository with the given files committed.
+func newTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=vhs", "-c", "user.email=vhs@example.com", "commit", "-q", "-m", "tapes"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if b, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, b)
+		}
+	}
+	return dir
+}
+
+// fakeRenderTapes replaces the rendering of the tapes with one writing a GIF
+// next to each tape.
+func fakeRenderTapes(t *testing.T) *[]string {
+	t.Helper()
+	var rendered []string
+	render := renderTapes
+	t.Cleanup(func() { renderTapes = render })
+	renderTapes = func(_ context.Context, dir string, files []string, _ io.Writer) error {
+		rendered = append(rendered, files...)
+		for _, file := range files {
+			gif := filepath.Join(dir, file[:len(file)-len(extension)]+".gif")
+			if err := os.WriteFile(gif, []byte("GIF"), 0o600); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return &rendered
+}
+
+func TestRenderRepo(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"README.md":           "# Demo",
+		"demo.tape":           "Output demo.gif",
+		"docs/tapes/foo.tape": "Output foo.gif",
+		"docs/tapes/old.gif":  "GIF",
+	})
+	rendered := fakeRenderTapes(t)
+	artifacts := t.TempDir()
+
+	got, err := RenderRepo(context.Background(), RepoOptions{URL: repo, Path: "docs", Artifacts: artifacts}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foo := filepath.Join("docs", "tapes", "foo.tape")
+	if !reflect.DeepEqual(*rendered, []string{foo}) {
+		t.Errorf("expected only the tapes under docs to be rendered, got %v", *rendered)
+	}
+	gif := filepath.Join("docs", "tapes", "foo.gif")
+	if !reflect.DeepEqual(got, []string{gif}) {
+		t.Errorf("expected the written files to be the artifacts, got %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(artifacts, gif)); err != nil {
+		t.Errorf("expected the artifact to be copied: %v", err)
+	}
+}
+
+func TestRenderRepoErrors(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{"README.md": "# Demo"})
+	fakeRenderTapes(t)
+
+	for name, opts := range map[string]RepoOptions{
+		"no tapes":     {URL: repo},
+		"outside path": {URL: repo, Path: "../.."},
+		"missing ref":  {URL: repo, Ref: "missing"},
+		"dash":         {URL: "--upload-pack=sh"},
+	} {
+		opts.Artifacts = t.TempDir()
+		if _, err := RenderRepo(context.Background(), opts, io.Discard); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
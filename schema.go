@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// TapeDocument is the JSON representation of a parsed tape file, as printed
+// by `vhs validate --json`.
+type TapeDocument struct {
+	File     string        `json:"file"`
+	Valid    bool          `json:"valid"`
+	Commands []TapeCommand `json:"commands"`
+	Errors   []Diagnostic  `json:"errors"`
+	Warnings []Diagnostic  `json:"warnings"`
+}
+
+// TapeCommand is the JSON representation of a parsed command, along with the
+// position of its first token.
+type TapeCommand struct {
+	Command
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Diagnostic is the JSON representation of a parser error or warning.
+type Diagnostic struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// NewTapeDocument returns the JSON representation of a parsed tape file. The
+// tokens are those of the commands, as returned by the parser.
+func NewTapeDocument(file string, cmds []Command, tokens []Token, errs, warns []ParserError) TapeDocument {
+	diagnostics := func(errs []ParserError) []Diagnostic {
+		d := make([]Diagnostic, 0, len(errs))
+		for _, err := range errs {
+			d = append(d, Diagnostic{Line: err.Token.Line, Column: err.Token.Column, Message: err.Msg})
+		}
+		return d
+	}
+	commands := make([]TapeCommand, 0, len(cmds))
+	for i, cmd := range cmds {
+		c := TapeCommand{Command: cmd}
+		if i < len(tokens) {
+			c.Line, c.Column = tokens[i].Line, tokens[i].Column
+		}
+		commands = append(commands, c)
+	}
+	return TapeDocument{
+		File:     file,
+		Valid:    len(errs) == 0,
+		Commands: commands,
+		Errors:   diagnostics(errs),
+		Warnings: diagnostics(warns),
+	}
+}
+
+// Schema returns the JSON Schema of the output of `vhs parse` and
+// `vhs validate --json`. The
+// command types and settings are taken from CommandTypes and Settings, which
+// are used to evaluate the tapes, so that the schema is always up to date.
+func Schema() ([]byte, error) {
+	types := make([]string, 0, len(CommandTypes))
+	for _, t := range CommandTypes {
+		types = append(types, string(t))
+	}
+
+	settings := make([]string, 0, len(Settings))
+	for name := range Settings {
+		settings = append(settings, name)
+	}
+	sort.Strings(settings)
+
+	type object = map[string]interface{}
+	str := object{"type": "string"}
+	integer := object{"type": "integer"}
+	boolean := object{"type": "boolean"}
+
+	schema := object{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "VHS tapes",
+		"description": "Tape files parsed by vhs parse and vhs validate --json.",
+		"type":        "array",
+		"items":       object{"$ref": "#/$defs/document"},
+		"$defs": object{
+			"document": object{
+				"type":                 "object",
+				"required":             []string{"file", "valid", "commands", "errors", "warnings"},
+				"additionalProperties": false,
+				"properties": object{
+					"file":     str,
+					"valid":    boolean,
+					"commands": object{"type": "array", "items": object{"$ref": "#/$defs/command"}},
+					"errors":   object{"type": "array", "items": object{"$ref": "#/$defs/diagnostic"}},
+					"warnings": object{"type": "array", "items": object{"$ref": "#/$defs/diagnostic"}},
+				},
+			},
+			"diagnostic": object{
+				"type":                 "object",
+				"required":             []string{"line", "column", "message"},
+				"additionalProperties": false,
+				"properties": object{
+					"line":    integer,
+					"column":  integer,
+					"message": str,
+				},
+			},
+			"command": object{
+				"type":                 "object",
+				"required":             []string{"type", "line", "column"},
+				"additionalProperties": false,
+				"properties": object{
+					"type":    object{"enum": types},
+					"options": str,
+					"args":    str,
+					"line":    integer,
+					"column":  integer,
+				},
+				"if": object{
+					"properties": object{"type": object{"const": string(SET)}},
+				},
+				"then": object{
+					"required":   []string{"options"},
+					"properties": object{"options": object{"enum": settings}},
+				},
+			},
+		},
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	b, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Defs struct {
+			Command struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+				Then struct {
+					Properties struct {
+						Options struct {
+							Enum []string `json:"enum"`
+						} `json:"options"`
+					} `json:"properties"`
+				} `json:"then"`
+			} `json:"command"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[string]bool{}
+	for _, t := range schema.Defs.Command.Properties.Type.Enum {
+		types[t] = true
+	}
+	settings := map[string]bool{}
+	for _, s := range schema.Defs.Command.Then.Properties.Options.Enum {
+		settings[s] = true
+	}
+	if len(settings) != len(Settings) {
+		t.Errorf("expected %d settings in the schema, got %d", len(Settings), len(settings))
+	}
+
+	l := NewLexer("Output demo.gif\nSet FontSize 32\nType \"hello\"\nEnter 2\nSleep 1s")
+	p := NewParser(l)
+	doc := NewTapeDocument("demo.tape", p.Parse(), p.Tokens(), p.Errors(), p.Warnings())
+
+	b, err = json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed TapeDocument
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range parsed.Commands {
+		if !types[string(cmd.Type)] {
+			t.Errorf("command type %s is not in the schema", cmd.Type)
+		}
+		if cmd.Type == SET && !settings[cmd.Options] {
+			t.Errorf("setting %s is not in the schema", cmd.Options)
+		}
+	}
+}
+
+func TestNewTapeDocument(t *testing.T) {
+	l := NewLexer("Sleep 1s\nFoo")
+	p := NewParser(l)
+	doc := NewTapeDocument("demo.tape", p.Parse(), p.Tokens(), p.Errors(), p.Warnings())
+
+	if doc.Valid {
+		t.Error("expected the document to be invalid")
+	}
+	if len(doc.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(doc.Errors), doc.Errors)
+	}
+	if doc.Errors[0].Line != 2 {
+		t.Errorf("expected the error on line 2, got %d", doc.Errors[0].Line)
+	}
+	if len(doc.Commands) == 0 || doc.Commands[0].Line != 1 || doc.Commands[0].Column != 1 {
+		t.Errorf("expected the Sleep command at 1:1, got %+v", doc.Commands)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecuteScreenshot is a CommandFunc that saves the current frame of the
+// terminal as a PNG image. The screenshots of a tape rendered by the server
+// aren't saved.
+func ExecuteScreenshot(c Command, v *VHS) {
+	if v.sandboxed {
+		return
+	}
+	now := time.Now()
+	// In the deterministic mode, the time of a screenshot is its time on
+	// the virtual clock, from the Unix epoch.
+	if v.Options.Deterministic {
+		now = time.Unix(0, 0).UTC().Add(v.elapsed())
+	}
+	path := v.screenshotPath(v.screenshotName(c.Args, now))
+	if err := v.Screenshot(path); err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("failed to take screenshot %s: %w", path, err))
+		return
+	}
+	v.shots = append(v.shots, path)
+}
+
+// The placeholders of the screenshot names.
+const (
+	// screenshotCounter is replaced with the number of the screenshot in the
+	// tape, padded with zeros to the width of `Set ScreenshotDigits`.
+	screenshotCounter = "{n}"
+	// screenshotTime is replaced with the time of the screenshot.
+	screenshotTime = "{time}"
+)
+
+const (
+	defaultScreenshotDigits = 3
+	// screenshotTimeFormat is RFC 3339 without the colons, which can't be
+	// part of a file name on Windows.
+	screenshotTimeFormat = "2006-01-02T15-04-05"
+)
+
+// screenshotName replaces the placeholders of the name of a screenshot taken
+// at the given time. Every screenshot of the tape is counted, so that {n} is
+// the number of the screenshot whether the previous ones use it or not. The
+// time is in the timezone of `Set Timezone`, if any.
+func (v *VHS) screenshotName(name string, now time.Time) string {
+	v.screenshots++
+	name = strings.ReplaceAll(name, screenshotCounter, fmt.Sprintf("%0*d", v.Options.ScreenshotDigits, v.screenshots))
+	if strings.Contains(name, screenshotTime) {
+		if loc, err := time.LoadLocation(v.Options.Timezone); err == nil && v.Options.Timezone != "" {
+			now = now.In(loc)
+		}
+		name = strings.ReplaceAll(name, screenshotTime, now.Format(screenshotTimeFormat))
+	}
+	return name
+}
+
+// screenshotPath resolves the path of a screenshot relative to the screenshot
+// directory, if one is set.
+func (v *VHS) screenshotPath(name string) string {
+	if v.Options.ScreenshotDir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(v.Options.ScreenshotDir, name)
+}
+
+// Screenshot captures the text and cursor layers of the terminal and saves
+// them, along with the padding, as a PNG image to the given path.
+func (v *VHS) Screenshot(path string) error {
+	// With a steady cursor, redraw the row of the cursor first so that the
+	// cursor layer is up to date and the cursor is always in the screenshot.
+	if !v.Options.CursorBlink {
+		if _, err := v.Page.Eval(steadyCursorRefresh); err != nil {
+			return err
+		}
+	}
+
+	cursor, err := v.CursorCanvas.CanvasToImage("image/png", quality)
+	if err != nil {
+		return err
+	}
+	text, err := v.TextCanvas.CanvasToImage("image/png", quality)
+	if err != nil {
+		return err
+	}
+
+	img, err := composeScreenshot(text, cursor, v.Options.Video.Padding, v.Options.Video.BackgroundColor)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, img, 0o644) //nolint:gosec,gomnd
+}
+
+// steadyCursorRefresh redraws the row of the cursor of the terminal.
+const steadyCursorRefresh = "() => { const y = term.buffer.active.cursorY; term.refresh(y, y) }"
+
+// MakeLastFrame saves the last recorded frame to the PNG output, if any. The
+// frames hidden with Hide are not recorded, so a tape which ends with a Hide
+// block saves the last frame which was visible.
+func MakeLastFrame(opts VideoOptions, frames int) error {
+	if opts.Output.PNG == "" {
+		return nil
+	}
+
+	fmt.Println("Creating PNG...")
+
+	if frames == 0 {
+		return fmt.Errorf("no frames were recorded for %s", opts.Output.PNG)
+	}
+	last := opts.StartingFrame + frames - 1
+	text, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(textFrameFormat, last)))
+	if err != nil {
+		return err
+	}
+	cursor, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(cursorFrameFormat, last)))
+	if err != nil {
+		return err
+	}
+
+	img, err := composeScreenshot(text, cursor, opts.Padding, opts.BackgroundColor)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Output.PNG), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(opts.Output.PNG, img, 0o644) //nolint:gosec,gomnd
+}
+
+// composeScreenshot overlays the cursor layer on top of the text layer and
+// surrounds them with the padding in the background color.
+func composeScreenshot(text, cursor []byte, padding int, background string) ([]byte, error) {
+	textImg, err := png.Decode(bytes.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+	cursorImg, err := png.Decode(bytes.NewReader(cursor))
+	if err != nil {
+		return nil, err
+	}
+
+	bg, err := parseHexColor(background)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := textImg.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, bounds.Dx()+padding*2, bounds.Dy()+padding*2))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	inner := image.Rect(padding, padding, padding+bounds.Dx(), padding+bounds.Dy())
+	draw.Draw(img, inner, textImg, bounds.Min, draw.Over)
+	draw.Draw(img, inner, cursorImg, cursorImg.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseHexColor parses a color in the #rrggbb or #rgb format.
+func parseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 { //nolint:gomnd
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 { //nolint:gomnd
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n), A: 0xff}, nil //nolint:gomnd
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestComposeScreenshot(t *testing.T) {
+	text := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	text.Set(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	cursor := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	cursor.Set(3, 1, color.NRGBA{G: 0xff, A: 0xff})
+
+	out, err := composeScreenshot(encodePNG(t, text), encodePNG(t, cursor), 2, "#123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != 8 || img.Bounds().Dy() != 6 {
+		t.Fatalf("expected 8x6 image, got %v", img.Bounds())
+	}
+
+	tests := []struct {
+		x, y     int
+		expected color.NRGBA
+	}{
+		{0, 0, color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}},
+		{2, 2, color.NRGBA{R: 0xff, A: 0xff}},
+		{5, 3, color.NRGBA{G: 0xff, A: 0xff}},
+		{3, 3, color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}},
+	}
+	for _, tc := range tests {
+		got := color.NRGBAModel.Convert(img.At(tc.x, tc.y)).(color.NRGBA)
+		if got != tc.expected {
+			t.Errorf("expected %v at (%d, %d), got %v", tc.expected, tc.x, tc.y, got)
+		}
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, err := parseHexColor("#fa0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (color.NRGBA{R: 0xff, G: 0xaa, B: 0x00, A: 0xff}) {
+		t.Errorf("unexpected color %v", c)
+	}
+
+	if _, err := parseHexColor("red"); err == nil {
+		t.Error("expected an error for an invalid color")
+	}
+}
+
+func TestScreenshotPath(t *testing.T) {
+	v := VHS{Options: &Options{}}
+	if got := v.screenshotPath("shot.png"); got != "shot.png" {
+		t.Errorf("expected shot.png, got %s", got)
+	}
+
+	v.Options.ScreenshotDir = "shots/"
+	if got := v.screenshotPath("shot.png"); got != "shots/shot.png" {
+		t.Errorf("expected shots/shot.png, got %s", got)
+	}
+}
+
+func TestMakeLastFrame(t *testing.T) {
+	dir := t.TempDir()
+	for n, c := range []color.NRGBA{{R: 0xff, A: 0xff}, {B: 0xff, A: 0xff}} {
+		text := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		text.Set(0, 0, c)
+		cursor := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(textFrameFormat, n+1)), encodePNG(t, text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(cursorFrameFormat, n+1)), encodePNG(t, cursor), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := VideoOptions{
+		Input:           dir,
+		StartingFrame:   1,
+		BackgroundColor: "#000000",
+		Output:          VideoOutputs{PNG: filepath.Join(dir, "out", "last.png")},
+	}
+	if err := MakeLastFrame(opts, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(opts.Output.PNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); got != (color.NRGBA{B: 0xff, A: 0xff}) {
+		t.Errorf("expected the last frame, got %v", got)
+	}
+}
+
+func TestScreenshotName(t *testing.T) {
+	opts := DefaultVHSOptions()
+	v := VHS{Options: &opts}
+	now := time.Date(2024, 3, 9, 14, 5, 30, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"shot.png", "shot.png"},
+		{"shot-{n}.png", "shot-002.png"},
+		{"shot-{time}.png", "shot-2024-03-09T14-05-30.png"},
+		{"{n}-{n}.png", "004-004.png"},
+	}
+	for _, tc := range tests {
+		if got := v.screenshotName(tc.name, now); got != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, got)
+		}
+	}
+
+	v.Options.ScreenshotDigits = 1
+	if got := v.screenshotName("shot-{n}.png", now); got != "shot-5.png" {
+		t.Errorf("expected shot-5.png, got %s", got)
+	}
+
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skip("no tz database")
+	}
+	v.Options.Timezone = "Asia/Tokyo"
+	if got := v.screenshotName("{time}.png", now); got != "2024-03-09T23-05-30.png" {
+		t.Errorf("expected the time in the timezone, got %s", got)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// prepareWorkingDirectory creates the working directory of the shell, so that
+// the Setup scripts can fill it.
+func (vhs *VHS) prepareWorkingDirectory() error {
+	if vhs.Options.WorkingDirectory == "" {
+		return nil
+	}
+	return os.MkdirAll(vhs.Options.WorkingDirectory, os.ModePerm)
+}
+
+// runSetup runs the Setup scripts in order, before the shell starts. It stops
+// at the first one which fails.
+func (vhs *VHS) runSetup(ctx context.Context) error {
+	for _, script := range vhs.Options.Setup {
+		if err := vhs.runScript(ctx, SETUP, script); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTeardown runs the Teardown scripts in order, once the recording is done.
+// They all run, even if one of them fails.
+func (vhs *VHS) runTeardown() {
+	for _, script := range vhs.Options.Teardown {
+		if err := vhs.runScript(context.Background(), TEARDOWN, script); err != nil {
+			log.Print(err.Error())
+		}
+	}
+}
+
+// runScript runs a Setup or Teardown script in the working directory, with
+// the environment of the shell. What it prints is only shown if it fails, so
+// that it stays out of the recording and of the log.
+func (vhs *VHS) runScript(ctx context.Context, t CommandType, script string) error {
+	args := append(scriptShellWithArgs(), script)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec
+	cmd.Dir = vhs.Options.WorkingDirectory
+	cmd.Env = append(os.Environ(), vhs.environment()...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(out)); out != "" {
+			return fmt.Errorf("%s failed: %w\n%s", t, err, out)
+		}
+		return fmt.Errorf("%s failed: %w", t, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunScripts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the scripts are run by cmd.exe on Windows")
+	}
+
+	dir := filepath.Join(t.TempDir(), "demo")
+	v := New()
+	ExecuteSetWorkingDirectory(Command{Type: SET, Options: "WorkingDirectory", Args: dir}, &v)
+	ExecuteEnv(Command{Type: ENV, Options: "GREETING", Args: "hello"}, &v)
+	ExecuteSetup(Command{Type: SETUP, Args: `echo "$GREETING" > greeting.txt`}, &v)
+	ExecuteTeardown(Command{Type: TEARDOWN, Args: "rm greeting.txt"}, &v)
+
+	if err := v.prepareWorkingDirectory(); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.runSetup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "greeting.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(b)) != "hello" {
+		t.Errorf("expected the environment of the shell, got %q", b)
+	}
+
+	v.runTeardown()
+	if _, err := os.Stat(filepath.Join(dir, "greeting.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the teardown to remove the file, got %v", err)
+	}
+
+	ExecuteSetup(Command{Type: SETUP, Args: "echo oops >&2; exit 3"}, &v)
+	err = v.runSetup(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("expected the failed setup with its output, got %v", err)
+	}
+}
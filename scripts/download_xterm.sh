@@ -0,0 +1,10 @@
+#!/bin/sh
+
+# Downloads the xterm.js files embedded in the builtin backend into $1.
+set -e
+
+mkdir -p "$1"
+curl -fsSL https://cdn.jsdelivr.net/npm/xterm@5.1.0/lib/xterm.js -o "$1/xterm.js"
+curl -fsSL https://cdn.jsdelivr.net/npm/xterm@5.1.0/css/xterm.css -o "$1/xterm.css"
+curl -fsSL https://cdn.jsdelivr.net/npm/xterm-addon-fit@0.7.0/lib/xterm-addon-fit.js -o "$1/xterm-addon-fit.js"
+curl -fsSL https://cdn.jsdelivr.net/npm/xterm-addon-canvas@0.3.0/lib/xterm-addon-canvas.js -o "$1/xterm-addon-canvas.js"
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// secretMask is the character which replaces each character of a secret.
+const secretMask = "•"
+
+// secretMasker returns the script which wraps term.write so that the secrets
+// are masked before they reach the terminal. The characters which may start a
+// secret are written as dots right away, since the shell echoes a typed
+// secret one character at a time. If they turn out not to be a secret, the
+// cursor moves back over the dots to write them as they are.
+func secretMasker(secrets []string) string {
+	b, _ := json.Marshal(secrets)
+	return fmt.Sprintf(`() => {
+	const secrets = %s;
+	const decoder = new TextDecoder();
+	const write = term.write.bind(term);
+	let pending = '';
+	const mask = (data) => {
+		let out = '';
+		const queue = [...data];
+		while (queue.length > 0) {
+			const c = queue.shift();
+			const next = pending + c;
+			if (secrets.some((s) => s.startsWith(next))) {
+				out += '%s';
+				pending = secrets.includes(next) ? '' : next;
+				continue;
+			}
+			if (pending === '') {
+				out += c;
+				continue;
+			}
+			const chars = [...pending];
+			out += '\b'.repeat(chars.length) + chars[0];
+			pending = '';
+			queue.unshift(...chars.slice(1), c);
+		}
+		return out;
+	};
+	term.write = (data, callback) => {
+		return write(mask(typeof data === 'string' ? data : decoder.decode(data, { stream: true })), callback);
+	};
+}`, b, secretMask)
+}
+
+// maskSecrets replaces each character of the secrets in s with a dot, the
+// same as in the terminal.
+func maskSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, strings.Repeat(secretMask, utf8.RuneCountInString(secret)))
+	}
+	return s
+}
+
+// highlight returns the highlighted command with the secrets masked, along
+// with the value of Set Secret itself.
+func (vhs *VHS) highlight(c Command, faint bool) string {
+	if c.Type == SET && c.Options == "Secret" {
+		c.Args = maskSecrets(c.Args, []string{c.Args})
+	}
+	return maskSecrets(highlight(c, faint), vhs.Options.Secrets)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExecuteSetSecret(t *testing.T) {
+	v := New()
+	ExecuteSetSecret(Command{Type: SET, Options: "Secret", Args: "hunter2"}, &v)
+	ExecuteSetSecret(Command{Type: SET, Options: "Secret", Args: "s3cr3t"}, &v)
+	if len(v.Options.Secrets) != 2 {
+		t.Fatalf("expected 2 secrets, got %v", v.Options.Secrets)
+	}
+
+	ExecuteSetSecret(Command{Type: SET, Options: "Secret", Args: ""}, &v)
+	if len(v.Errors) != 1 {
+		t.Errorf("expected an error for an empty secret, got %v", v.Errors)
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	tests := []struct {
+		s       string
+		secrets []string
+		want    string
+	}{
+		{"echo hunter2", []string{"hunter2"}, "echo •••••••"},
+		{"hunter2 hunter2", []string{"hunter2"}, "••••••• •••••••"},
+		{"pässwort", []string{"pässwort"}, "••••••••"},
+		{"echo hello", []string{"hunter2", ""}, "echo hello"},
+		{"a=one b=two", []string{"one", "two"}, "a=••• b=•••"},
+	}
+	for _, tt := range tests {
+		if got := maskSecrets(tt.s, tt.secrets); got != tt.want {
+			t.Errorf("maskSecrets(%q, %q) = %q, want %q", tt.s, tt.secrets, got, tt.want)
+		}
+	}
+}
+
+func TestDryRunSecret(t *testing.T) {
+	tape := `Set Secret "hunter2"
+Type "login hunter2"`
+
+	var out bytes.Buffer
+	if errs := DryRun(tape, nil, &out); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("expected the secret to be masked, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Type login •••••••") {
+		t.Errorf("expected the typed secret to be masked, got:\n%s", out.String())
+	}
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -32,11 +34,23 @@ type config struct {
 	UID                int    `env:"UID" envDefault:"0"`
 	KeyPath            string `env:"KEY_PATH" envDefault:""`
 	AuthorizedKeysPath string `env:"AUTHORIZED_KEYS_PATH"`
+
+	// The render API is served over HTTP if HTTPPort isn't zero.
+	HTTPPort  int           `env:"HTTP_PORT" envDefault:"0"`
+	HTTPToken string        `env:"HTTP_TOKEN"`
+	Workers   int           `env:"WORKERS" envDefault:"2"`
+	QueueSize int           `env:"QUEUE_SIZE" envDefault:"16"`
+	JobTTL    time.Duration `env:"JOB_TTL" envDefault:"1h"`
+
+	// The webhook is served by the render API if WebhookSecret isn't empty.
+	WebhookSecret string `env:"WEBHOOK_SECRET"`
+	WebhookPath   string `env:"WEBHOOK_PATH" envDefault:"."`
+	ArtifactsDir  string `env:"ARTIFACTS_DIR"`
 }
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
-	Short: "Start the VHS SSH server",
+	Short: "Start the VHS SSH server, and the render API over HTTP",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var cfg config
 		if err := env.Parse(&cfg, env.Options{
@@ -49,6 +63,11 @@ var serveCmd = &cobra.Command{
 			key = filepath.Join(".ssh", "vhs_ed25519")
 		}
 		addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+
+		// The renders of both servers share the same queue, so that there are
+		// never more than VHS_WORKERS at once.
+		queue := newRenderQueue(cmd.Context(), os.TempDir(), cfg.Workers, cfg.QueueSize, cfg.JobTTL)
+
 		s, err := wish.NewServer(
 			wish.WithAddress(addr),
 			wish.WithHostKeyPath(key),
@@ -88,15 +107,20 @@ var serveCmd = &cobra.Command{
 							return
 						}
 
+						if err := queue.acquire(s.Context()); err != nil {
+							wish.Errorln(s, err)
+							_ = s.Exit(1)
+							return
+						}
+						defer queue.release()
+
 						//nolint:gosec
 						rand := rand.Int63n(maxNumber)
 						tempFile := filepath.Join(os.TempDir(), fmt.Sprintf("vhs-%d.gif", rand))
 						defer func() { _ = os.Remove(tempFile) }()
 						errs := Evaluate(s.Context(), b.String(), s.Stderr(), func(v *VHS) {
+							sandboxOutputs(v)
 							v.Options.Video.Output.GIF = tempFile
-							// Disable generating MP4 & WebM.
-							v.Options.Video.Output.MP4 = ""
-							v.Options.Video.Output.WebM = ""
 						})
 
 						if len(errs) > 0 {
@@ -123,6 +147,24 @@ var serveCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to listen on %s: %w", addr, err)
 		}
+		var hls net.Listener
+		if cfg.HTTPPort != 0 {
+			// The render API always requires a token, even on the loopback
+			// interface, where any program or web page of the machine could
+			// type in the shell otherwise.
+			if cfg.HTTPToken == "" {
+				if cfg.HTTPToken, err = newToken(); err != nil {
+					return err
+				}
+				log.Printf("Render API token: %s", cfg.HTTPToken)
+			}
+			httpAddr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.HTTPPort))
+			log.Printf("Starting render API on %s", httpAddr)
+			hls, err = net.Listen("tcp", httpAddr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", httpAddr, err)
+			}
+		}
 
 		// drop privileges
 		gid, uid := cfg.GID, cfg.UID
@@ -133,6 +175,29 @@ var serveCmd = &cobra.Command{
 			}
 		}
 
+		var api *http.Server
+		if hls != nil {
+			// Make the directory of the outputs once the privileges are
+			// dropped, so that the renders can write to it.
+			queue.dir, err = os.MkdirTemp("", "vhs-serve")
+			if err != nil {
+				return err
+			}
+			defer func() { _ = os.RemoveAll(queue.dir) }()
+
+			var webhook http.Handler
+			if cfg.WebhookSecret != "" {
+				log.Printf("Serving the webhook on /webhook")
+				webhook = &webhookHandler{queue: queue, secret: cfg.WebhookSecret, path: cfg.WebhookPath, artifacts: cfg.ArtifactsDir}
+			}
+			api = &http.Server{Handler: renderAPI(queue, cfg.HTTPToken, webhook), ReadHeaderTimeout: timeout}
+			go func() {
+				if err := api.Serve(hls); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Printf("Render API error: %v", err)
+				}
+			}()
+		}
+
 		sch := make(chan error)
 		go func() {
 			defer close(sch)
@@ -143,6 +208,11 @@ var serveCmd = &cobra.Command{
 		log.Println("Stopping SSH server")
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
+		if api != nil {
+			if err := api.Shutdown(ctx); err != nil {
+				return err
+			}
+		}
 		if err := s.Shutdown(ctx); err != nil {
 			return err
 		}
@@ -151,3 +221,21 @@ var serveCmd = &cobra.Command{
 		return <-sch
 	},
 }
+
+// sandboxOutputs clears every file which the tape would write, for the tapes
+// rendered by the server on behalf of its clients: the outputs, the frames,
+// the key log and the screenshots. The Output, Set KeyLog, Set ScreenshotDir
+// and Screenshot commands which come later are ignored, so that the server
+// only writes the outputs it sets itself.
+func sandboxOutputs(v *VHS) {
+	if v.Options.Video.Output.Frames != "" {
+		v.Options.Video.Input = randomDir()
+		v.Options.Video.CleanupFrames = true
+	}
+	v.Options.Video.Output = VideoOutputs{}
+	v.Options.Test = TestOptions{}
+	v.Options.HTML = HTMLOptions{}
+	v.Options.KeyLog = ""
+	v.Options.ScreenshotDir = ""
+	v.sandboxed = true
+}
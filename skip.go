@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/vhs/parser"
+)
+
+// The conditions of the Skip block.
+const (
+	skipUnless = parser.SkipUnless
+	skipOn     = parser.SkipOn
+	// skipEnd is the argument of the Skip command which ends a block.
+	skipEnd = parser.SkipEnd
+)
+
+// skipReason returns why the block of a Skip command is skipped on this
+// machine, or an empty string if its commands are run. The condition is
+// either `unless <program>`, to skip the block when the program isn't
+// installed, or `on <os>`, to skip it on an operating system.
+func skipReason(condition string) string {
+	kind, arg, _ := strings.Cut(condition, " ")
+	switch kind {
+	case skipUnless:
+		if _, err := exec.LookPath(arg); err != nil {
+			return arg + " is not installed"
+		}
+	case skipOn:
+		if skipOS(arg) == runtime.GOOS {
+			return "on " + arg
+		}
+	}
+	return ""
+}
+
+// skipOS returns the operating system of Skip on, as in GOOS.
+func skipOS(name string) string {
+	name = strings.ToLower(name)
+	if name == "macos" {
+		return "darwin"
+	}
+	return name
+}
+
+// ExecuteSkip starts or ends a Skip block. The blocks can be nested, and the
+// commands are skipped while any of the blocks around them is.
+func ExecuteSkip(c Command, v *VHS) {
+	if c.Args == skipEnd {
+		if len(v.skips) > 0 {
+			v.skips = v.skips[:len(v.skips)-1]
+		}
+		return
+	}
+	v.skips = append(v.skips, skipReason(c.Args))
+}
+
+// skipped returns why the commands are skipped, or an empty string if they
+// are run.
+func (v *VHS) skipped() string {
+	for _, reason := range v.skips {
+		if reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// skip runs the command if it is a Skip command, and returns whether the
+// command is left out of the recording. The start of a skipped block is
+// reported to out.
+func (v *VHS) skip(c Command, out io.Writer) bool {
+	if c.Type != SKIP {
+		return v.skipped() != ""
+	}
+	wasSkipped := v.skipped() != ""
+	ExecuteSkip(c, v)
+	if reason := v.skipped(); reason != "" && !wasSkipped {
+		fmt.Fprintln(out, FaintStyle.Render(fmt.Sprintf("Skip %s: %s", c.Args, reason)))
+	}
+	return true
+}
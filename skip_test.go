@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSkipReason(t *testing.T) {
+	if reason := skipReason("unless vhs-missing-program"); reason != "vhs-missing-program is not installed" {
+		t.Errorf("expected the missing program to skip the block, got %q", reason)
+	}
+	if reason := skipReason("unless go"); reason != "" {
+		t.Errorf("expected go to be installed, got %q", reason)
+	}
+	if reason := skipReason("on " + runtime.GOOS); reason == "" {
+		t.Errorf("expected the block to be skipped on %s", runtime.GOOS)
+	}
+	if reason := skipReason("on plan10"); reason != "" {
+		t.Errorf("expected the block not to be skipped on another OS, got %q", reason)
+	}
+}
+
+func TestSkipNested(t *testing.T) {
+	v := New()
+	var out bytes.Buffer
+	cmds := []Command{
+		{Type: SKIP, Args: "unless go"},
+		{Type: TYPE, Args: "run"},
+		{Type: SKIP, Args: "unless vhs-missing-program"},
+		{Type: TYPE, Args: "skipped"},
+		{Type: SKIP, Args: skipEnd},
+		{Type: TYPE, Args: "run"},
+		{Type: SKIP, Args: skipEnd},
+		{Type: TYPE, Args: "run"},
+	}
+	var run []string
+	for _, cmd := range cmds {
+		if !v.skip(cmd, &out) {
+			run = append(run, cmd.Args)
+		}
+	}
+	if got := strings.Join(run, " "); got != "run run run" {
+		t.Errorf("expected only the commands of the skipped block to be left out, got %q", got)
+	}
+	if !strings.Contains(out.String(), "vhs-missing-program is not installed") {
+		t.Errorf("expected the skipped block to be reported, got %q", out.String())
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/vhs/parser"
+)
+
+// speedEnd is the argument of the Speed command which ends a block.
+const speedEnd = parser.SpeedEnd
+
+// ExecuteSpeed starts or ends a Speed block, which speeds up the frames
+// captured during its commands by the factor. A factor of 0, for a Cut block,
+// drops the frames altogether. Blocks can be nested, and the innermost one
+// applies.
+func ExecuteSpeed(c Command, v *VHS) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if c.Args == speedEnd {
+		if len(v.speeds) > 0 {
+			v.speeds = v.speeds[:len(v.speeds)-1]
+		}
+		v.speedCarry = 0
+		return
+	}
+	speed, err := strconv.ParseFloat(c.Args, 64)
+	if err != nil || speed < 0 {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid `Speed %s`: expected a positive factor", c.Args))
+		speed = 1
+	}
+	v.speeds = append(v.speeds, speed)
+	v.speedCarry = 0
+}
+
+// speed returns the factor of the innermost Speed block, 1 outside of them.
+func (v *VHS) speed() float64 {
+	if len(v.speeds) == 0 {
+		return 1
+	}
+	return v.speeds[len(v.speeds)-1]
+}
+
+// spedUp returns how long the duration lasts in the outputs once sped up by
+// the factor.
+func spedUp(d time.Duration, speed float64) time.Duration {
+	if speed == 0 {
+		return 0
+	}
+	return time.Duration(float64(d) / speed)
+}
+
+// speedFrames returns how many frames the frame being captured makes in the
+// outputs: inside a Speed block, only one out of every factor frames is kept,
+// or every frame is repeated when slowed down, and none inside a Cut block.
+func (v *VHS) speedFrames() int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	speed := v.speed()
+	if speed == 1 {
+		return 1
+	}
+	if speed == 0 {
+		return 0
+	}
+	// Round up the rounding errors of the factors such as 1.5, so that
+	// every frame which is due is kept.
+	v.speedCarry += 1 / speed
+	n := int(v.speedCarry + 1e-9) //nolint:gomnd
+	v.speedCarry -= float64(n)
+	return n
+}
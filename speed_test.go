@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSpeedFrames(t *testing.T) {
+	tests := []struct {
+		speed string
+		ticks int
+		want  int
+	}{
+		{"4", 12, 3},
+		{"1.5", 9, 6},
+		{"0.5", 3, 6},
+		{"0", 10, 0},
+	}
+	for _, tc := range tests {
+		v := New()
+		ExecuteSpeed(Command{Type: SPEED, Args: tc.speed}, &v)
+		var frames int
+		for i := 0; i < tc.ticks; i++ {
+			frames += v.speedFrames()
+		}
+		if frames != tc.want {
+			t.Errorf("Speed %s: expected %d frames for %d ticks, got %d", tc.speed, tc.want, tc.ticks, frames)
+		}
+		ExecuteSpeed(Command{Type: SPEED, Args: speedEnd}, &v)
+		if n := v.speedFrames(); n != 1 {
+			t.Errorf("Speed %s: expected every frame to be kept after the block, got %d", tc.speed, n)
+		}
+	}
+}
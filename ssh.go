@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// sshTarget matches the hosts of Set SSH, as [user@]host. The host can be an
+// alias of the SSH configuration.
+var sshTarget = regexp.MustCompile(`^([A-Za-z0-9._-]+@)?[A-Za-z0-9._:\[\]-]+$`)
+
+// validSSHTarget returns an error if the target isn't [user@]host. It can't
+// start with a dash, which ssh would read as an option.
+func validSSHTarget(target string) error {
+	if !sshTarget.MatchString(target) || strings.HasPrefix(target, "-") {
+		return errors.New("expected [user@]host")
+	}
+	return nil
+}
+
+// sshShellWithArgs returns the command which runs bash on the host over SSH,
+// with a terminal and the environment variables, since SSH doesn't forward
+// them. The remote command is a string read by the shell of the user on the
+// host, so the variables are quoted.
+func sshShellWithArgs(target string, env []string) ([]string, error) {
+	if err := validSSHTarget(target); err != nil {
+		return nil, fmt.Errorf("invalid SSH host %s: %w", target, err)
+	}
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, errors.New("ssh is not installed")
+	}
+
+	remote := "bash --login"
+	if len(env) > 0 {
+		quoted := make([]string, len(env))
+		for i, e := range env {
+			quoted[i] = shellQuote(e)
+		}
+		remote = "env " + strings.Join(quoted, " ") + " " + remote
+	}
+	return []string{"ssh", "-t", "--", target, remote}, nil
+}
+
+// shellQuote quotes a string for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestValidSSHTarget(t *testing.T) {
+	for _, target := range []string{"example.com", "me@example.com", "build-box", "me@10.0.0.2", "me@[::1]"} {
+		if err := validSSHTarget(target); err != nil {
+			t.Errorf("expected %q to be valid, got %v", target, err)
+		}
+	}
+	for _, target := range []string{"", "-oProxyCommand=sh", "me@", "me@host; rm -rf /", "me@host cmd"} {
+		if err := validSSHTarget(target); err == nil {
+			t.Errorf("expected %q to be invalid", target)
+		}
+	}
+}
+
+func TestExecuteSetSSH(t *testing.T) {
+	v := New()
+	ExecuteSetSSH(Command{Type: SET, Options: "SSH", Args: "me@example.com"}, &v)
+	if v.Options.SSH != "me@example.com" {
+		t.Errorf("expected the SSH host to be set, got %q", v.Options.SSH)
+	}
+	ExecuteSetSSH(Command{Type: SET, Options: "SSH", Args: "-oProxyCommand=sh"}, &v)
+	if len(v.Errors) != 1 || v.Options.SSH != "me@example.com" {
+		t.Errorf("expected an error for an option, got %v", v.Errors)
+	}
+}
+
+func TestSSHShellWithArgs(t *testing.T) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		t.Skip("ssh is not installed")
+	}
+
+	args, err := sshShellWithArgs("me@example.com", []string{"TZ=UTC", "GREETING=it's me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ssh", "-t", "--", "me@example.com", `env 'TZ=UTC' 'GREETING=it'\''s me' bash --login`}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("want %q, got %q", want, args)
+	}
+
+	if _, err := sshShellWithArgs("-oProxyCommand=sh", nil); err == nil {
+		t.Error("expected an error for an option")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not installed")
+	}
+	for _, s := range []string{"plain", "it's", `a "b" $c`, "'"} {
+		out, err := exec.Command(sh, "-c", "printf %s "+shellQuote(s)).Output() //nolint:gosec
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != s {
+			t.Errorf("expected %q, got %q", s, out)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WithFrameStream returns an EvaluatorOption which writes the frames of the
+// recording to w instead of the outputs of the tape.
+func WithFrameStream(w io.Writer) EvaluatorOption {
+	return func(v *VHS) {
+		v.Options.Video.Output = VideoOutputs{}
+		v.Options.Test.Output = ""
+		v.Options.Test.Screen = ""
+		v.Options.HTML.Output = ""
+		v.Options.FrameStream = w
+	}
+}
+
+// StreamFrames writes the recorded frames to w, in the order in which they
+// are rendered, as complete PNG images with the padding. The images are
+// concatenated without any separator, since a PNG image ends with its IEND
+// chunk, which is the framing expected by `ffmpeg -f image2pipe`.
+func StreamFrames(opts VideoOptions, frames int, w io.Writer) error {
+	for n := opts.StartingFrame; n < opts.StartingFrame+frames; n++ {
+		text, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(textFrameFormat, n)))
+		if err != nil {
+			return err
+		}
+		cursor, err := os.ReadFile(filepath.Join(opts.Input, fmt.Sprintf(cursorFrameFormat, n)))
+		if err != nil {
+			return err
+		}
+
+		img, err := composeScreenshot(text, cursor, opts.Padding, opts.BackgroundColor)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(img); err != nil {
+			return fmt.Errorf("error streaming frame %d: %w", n, err)
+		}
+	}
+	return nil
+}
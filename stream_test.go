@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamFrames(t *testing.T) {
+	dir := t.TempDir()
+	colors := []color.NRGBA{{R: 0xff, A: 0xff}, {G: 0xff, A: 0xff}, {B: 0xff, A: 0xff}}
+	for n, c := range colors {
+		text := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		text.Set(0, 0, c)
+		cursor := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(textFrameFormat, n+1)), encodePNG(t, text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(cursorFrameFormat, n+1)), encodePNG(t, cursor), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Start from the second frame, as after a loop offset.
+	opts := VideoOptions{Input: dir, StartingFrame: 2, BackgroundColor: "#000000"}
+	var buf bytes.Buffer
+	if err := StreamFrames(opts, 2, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	for _, want := range colors[1:] {
+		img, err := png.Decode(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); got != want {
+			t.Errorf("expected a frame of %v, got %v", want, got)
+		}
+	}
+	if r.Len() != 0 {
+		t.Errorf("expected 2 frames, got %d more bytes", r.Len())
+	}
+
+	if err := StreamFrames(opts, 3, &buf); err == nil {
+		t.Errorf("expected an error for a missing frame")
+	}
+}
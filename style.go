@@ -1,6 +1,9 @@
 package main
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
 
 // Theme colors.
 const (
@@ -38,6 +41,7 @@ var (
 	TimeStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
 	LineNumberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
 	ErrorStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	WarningStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
 	FileStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
 	ErrorFileStyle  = lipgloss.NewStyle().
 			Border(lipgloss.NormalBorder()).
@@ -46,3 +50,33 @@ var (
 			Padding(0, 1).
 			Width(defaultColumns)
 )
+
+// colorEnabled returns whether the output is styled. --no-color, and then
+// --color, take precedence over the NO_COLOR environment variable. Otherwise
+// the output is only styled on a terminal.
+func colorEnabled(noColor, color bool, noColorEnv string, tty bool) bool {
+	switch {
+	case noColor:
+		return false
+	case color:
+		return true
+	case noColorEnv != "":
+		return false
+	default:
+		return tty
+	}
+}
+
+// setColor styles the output or not. When it is disabled, the styles render
+// plain strings, without the colors and the border of the errors. When it is
+// enabled but the output isn't a terminal, the colors are forced.
+func setColor(enabled, tty bool) {
+	if enabled {
+		if !tty {
+			lipgloss.SetColorProfile(termenv.ANSI256)
+		}
+		return
+	}
+	lipgloss.SetColorProfile(termenv.Ascii)
+	ErrorFileStyle = lipgloss.NewStyle()
+}
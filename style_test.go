@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		noColor    bool
+		color      bool
+		noColorEnv string
+		tty        bool
+		expected   bool
+	}{
+		{"terminal", false, false, "", true, true},
+		{"not a terminal", false, false, "", false, false},
+		{"NO_COLOR", false, false, "1", true, false},
+		{"--no-color", true, false, "", true, false},
+		{"--color", false, true, "", false, true},
+		{"--color over NO_COLOR", false, true, "1", false, true},
+		{"--no-color over --color", true, true, "", true, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := colorEnabled(tc.noColor, tc.color, tc.noColorEnv, tc.tty); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// subtitleHold is how long the typed text stays in the subtitles once it is
+// typed, unless more text is typed.
+const subtitleHold = 2 * time.Second
+
+// subtitle is a cue of the subtitles, from Start to End of the recording.
+type subtitle struct {
+	Text       string
+	Start, End time.Duration
+	// typed is whether the text was typed, rather than a caption.
+	typed bool
+}
+
+// addSubtitle adds a cue for the text typed or shown as a caption. A typed
+// text replaces the previous one.
+func (v *VHS) addSubtitle(text string, start, end time.Duration, typed bool) {
+	text = strings.TrimSpace(maskSecrets(text, v.Options.Secrets))
+	if text == "" {
+		return
+	}
+	if typed {
+		for i := len(v.subtitles) - 1; i >= 0; i-- {
+			if s := &v.subtitles[i]; s.typed {
+				if s.End > start {
+					s.End = start
+				}
+				break
+			}
+		}
+	}
+	v.subtitles = append(v.subtitles, subtitle{Text: text, Start: start, End: end, typed: typed})
+}
+
+// MakeSubtitles writes the SRT and WebVTT outputs of the recording, if any,
+// with the typed text and the captions.
+func MakeSubtitles(vhs *VHS) error {
+	video := vhs.Options.Video
+	if video.Output.SRT == "" && video.Output.VTT == "" {
+		return nil
+	}
+
+	fmt.Println("Creating Subtitles...")
+
+	cues := make([]subtitle, 0, len(vhs.subtitles))
+	for _, s := range vhs.subtitles {
+		if s.End <= s.Start {
+			continue
+		}
+		cues = append(cues, subtitle{Text: s.Text, Start: outputTime(video, s.Start), End: outputTime(video, s.End)})
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+
+	for _, output := range []struct {
+		path   string
+		encode func([]subtitle) string
+	}{
+		{video.Output.SRT, encodeSRT},
+		{video.Output.VTT, encodeVTT},
+	} {
+		if output.path == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(output.path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(output.path, []byte(output.encode(cues)), 0o644); err != nil { //nolint:gosec,gomnd
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeSRT encodes the cues as SubRip subtitles.
+//
+//	1
+//	00:00:01,250 --> 00:00:03,500
+//	ls -l
+func encodeSRT(cues []subtitle) string {
+	var b strings.Builder
+	for i, c := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, cueTime(c.Start, ","), cueTime(c.End, ","), c.Text)
+	}
+	return b.String()
+}
+
+// encodeVTT encodes the cues as WebVTT subtitles, whose text is escaped as
+// HTML.
+//
+//	WEBVTT
+//
+//	00:00:01.250 --> 00:00:03.500
+//	ls -l
+func encodeVTT(cues []subtitle) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	for _, c := range cues {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", cueTime(c.Start, "."), cueTime(c.End, "."), escape.Replace(c.Text))
+	}
+	return b.String()
+}
+
+// cueTime formats the time of a cue as hours, minutes, seconds and
+// milliseconds, with the separator before the milliseconds.
+func cueTime(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000) //nolint:gomnd
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMakeSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	v := New()
+	_ = os.RemoveAll(v.Options.Video.Input)
+	v.Options.Video.Output.SRT = filepath.Join(dir, "demo.srt")
+	v.Options.Video.Output.VTT = filepath.Join(dir, "subs", "demo.vtt")
+	v.Options.Video.PlaybackSpeed = 2
+	v.Options.Secrets = []string{"hunter2"}
+
+	v.addSubtitle("echo <hello>", time.Second, 3*time.Second, true)
+	v.addSubtitle("Install the CLI", 2*time.Second, 6*time.Second, false)
+	v.addSubtitle("login hunter2", 2500*time.Millisecond, 5*time.Second, true)
+	v.addSubtitle(" ", 5*time.Second, 7*time.Second, true)
+
+	if err := MakeSubtitles(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	srt, err := os.ReadFile(v.Options.Video.Output.SRT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `1
+00:00:00,500 --> 00:00:01,250
+echo <hello>
+
+2
+00:00:01,000 --> 00:00:03,000
+Install the CLI
+
+3
+00:00:01,250 --> 00:00:02,500
+login •••••••
+
+`
+	if string(srt) != want {
+		t.Errorf("expected the SRT:\n%s\ngot:\n%s", want, srt)
+	}
+
+	vtt, err := os.ReadFile(v.Options.Video.Output.VTT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `WEBVTT
+
+00:00:00.500 --> 00:00:01.250
+echo &lt;hello&gt;
+
+00:00:01.000 --> 00:00:03.000
+Install the CLI
+
+00:00:01.250 --> 00:00:02.500
+login •••••••
+
+`
+	if string(vtt) != want {
+		t.Errorf("expected the WebVTT:\n%s\ngot:\n%s", want, vtt)
+	}
+}
+
+func TestCueTime(t *testing.T) {
+	d := time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond
+	if got := cueTime(d, ","); got != "01:02:03,045" {
+		t.Errorf("expected 01:02:03,045, got %s", got)
+	}
+}
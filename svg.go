@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// svgCellWidth is the width of a cell relative to the font size, which is the
+// usual aspect ratio of monospace fonts.
+const svgCellWidth = 0.6
+
+// Keyframe is a snapshot of the terminal screen at a point of the recording.
+type Keyframe struct {
+	Time time.Duration
+	Rows [][]Cell
+}
+
+// captureKeyframe records the current screen as a keyframe at the given time
+// of the recording, unless the screen has not changed since the last one.
+func (vhs *VHS) captureKeyframe(at time.Duration) error {
+	rows, err := vhs.screenCells()
+	if err != nil {
+		return err
+	}
+
+	if n := len(vhs.keyframes); n > 0 && reflect.DeepEqual(vhs.keyframes[n-1].Rows, rows) {
+		return nil
+	}
+	vhs.keyframes = append(vhs.keyframes, Keyframe{Time: at, Rows: rows})
+	return nil
+}
+
+// RenderSVG renders the keyframes to an animated SVG. Every keyframe is drawn
+// once as selectable text, stacked vertically, and a CSS animation moves the
+// stack so that only the current keyframe is visible.
+func RenderSVG(frames []Keyframe, duration time.Duration, theme Theme, fontFamily string, fontSize int, padding int) string {
+	var columns, rows int
+	for _, frame := range frames {
+		if len(frame.Rows) > rows {
+			rows = len(frame.Rows)
+		}
+		for _, row := range frame.Rows {
+			if len(row) > columns {
+				columns = len(row)
+			}
+		}
+	}
+
+	cellWidth := float64(fontSize) * svgCellWidth
+	lineHeight := float64(fontSize) * defaultLineHeight * 1.2 //nolint:gomnd
+	screenWidth := float64(columns) * cellWidth
+	screenHeight := float64(rows) * lineHeight
+	width := screenWidth + float64(padding*2)
+	height := screenHeight + float64(padding*2)
+
+	if duration <= 0 {
+		duration = time.Millisecond
+	}
+
+	var s strings.Builder
+	fmt.Fprintf(&s, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n", width, height, width, height)
+	fmt.Fprintf(&s, `<style>text{font-family:%s;font-size:%dpx;white-space:pre}`, html.EscapeString(fontFamily), fontSize)
+	fmt.Fprintf(&s, `#screen{animation:play %dms step-end infinite}`, duration.Milliseconds())
+	s.WriteString(`@keyframes play{`)
+	for i, frame := range frames {
+		fmt.Fprintf(&s, `%.3f%%{transform:translateY(%.2fpx)}`, float64(frame.Time)/float64(duration)*100, float64(-i)*screenHeight) //nolint:gomnd
+	}
+	s.WriteString("}</style>\n")
+	fmt.Fprintf(&s, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", theme.Background)
+	fmt.Fprintf(&s, `<svg x="%d" y="%d" width="%.2f" height="%.2f">`+"\n", padding, padding, screenWidth, screenHeight)
+	fmt.Fprintf(&s, `<g id="screen" fill="%s">`+"\n", theme.Foreground)
+
+	for i, frame := range frames {
+		top := float64(i) * screenHeight
+		for y, row := range frame.Rows {
+			baseline := top + float64(y)*lineHeight + float64(fontSize)
+			writeSVGRow(&s, row, theme, cellWidth, top+float64(y)*lineHeight, lineHeight, baseline)
+		}
+	}
+
+	s.WriteString("</g>\n</svg>\n</svg>\n")
+	return s.String()
+}
+
+// writeSVGRow writes the backgrounds and text of a row of cells. Consecutive
+// cells with the same style are grouped together.
+func writeSVGRow(s *strings.Builder, row []Cell, theme Theme, cellWidth, top, lineHeight, baseline float64) {
+	type run struct {
+		start int
+		text  strings.Builder
+		cell  Cell
+	}
+
+	var runs []*run
+	for x, cell := range row {
+		if n := len(runs); n > 0 {
+			last := runs[n-1].cell
+			if last.Attrs == cell.Attrs && last.FgMode == cell.FgMode && last.Fg == cell.Fg && last.BgMode == cell.BgMode && last.Bg == cell.Bg {
+				runs[n-1].text.WriteString(cell.Char)
+				continue
+			}
+		}
+		r := &run{start: x, cell: cell}
+		r.text.WriteString(cell.Char)
+		runs = append(runs, r)
+	}
+
+	for _, r := range runs {
+		_, bg := r.cell.colors(theme)
+		if bg == "" {
+			continue
+		}
+		fmt.Fprintf(s, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`+"\n",
+			float64(r.start)*cellWidth, top, float64(len([]rune(r.text.String())))*cellWidth, lineHeight, bg)
+	}
+
+	for _, r := range runs {
+		text := strings.TrimRight(r.text.String(), " ")
+		if text == "" {
+			continue
+		}
+		fg, _ := r.cell.colors(theme)
+		var attrs strings.Builder
+		if fg != "" {
+			fmt.Fprintf(&attrs, ` fill="%s"`, fg)
+		}
+		if r.cell.Attrs&attrBold != 0 {
+			attrs.WriteString(` font-weight="bold"`)
+		}
+		if r.cell.Attrs&attrItalic != 0 {
+			attrs.WriteString(` font-style="italic"`)
+		}
+		if r.cell.Attrs&attrUnderline != 0 {
+			attrs.WriteString(` text-decoration="underline"`)
+		}
+		if r.cell.Attrs&attrDim != 0 {
+			attrs.WriteString(` opacity="0.5"`)
+		}
+		fmt.Fprintf(s, `<text x="%.2f" y="%.2f"%s>%s</text>`+"\n",
+			float64(r.start)*cellWidth, baseline, attrs.String(), html.EscapeString(text))
+	}
+}
+
+// MakeSVG writes the keyframes captured during the recording to the SVG
+// output as an animation.
+func MakeSVG(vhs *VHS) error {
+	if vhs.Options.Video.Output.SVG == "" {
+		return nil
+	}
+
+	fmt.Println("Creating SVG...")
+
+	if len(vhs.keyframes) == 0 {
+		return fmt.Errorf("no frames were captured for %s", vhs.Options.Video.Output.SVG)
+	}
+
+	speed := vhs.Options.Video.PlaybackSpeed
+	if speed <= 0 {
+		speed = defaultPlaybackSpeed
+	}
+	frames := make([]Keyframe, len(vhs.keyframes))
+	for i, frame := range vhs.keyframes {
+		frames[i] = Keyframe{Time: time.Duration(float64(frame.Time) / speed), Rows: frame.Rows}
+	}
+	interval := time.Second / time.Duration(vhs.Options.Video.Framerate)
+	duration := time.Duration(float64(time.Duration(vhs.totalFrames)*interval) / speed)
+
+	out := RenderSVG(frames, duration, vhs.Options.Theme, vhs.Options.FontFamily, vhs.Options.FontSize, vhs.Options.Video.Padding)
+
+	if err := os.MkdirAll(filepath.Dir(vhs.Options.Video.Output.SVG), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(vhs.Options.Video.Output.SVG, []byte(out), 0o644) //nolint:gosec,gomnd
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSVG(t *testing.T) {
+	row := func(s string, fg int) []Cell {
+		var cells []Cell
+		for _, r := range s {
+			cells = append(cells, Cell{Char: string(r), FgMode: colorPalette, Fg: fg})
+		}
+		return cells
+	}
+
+	frames := []Keyframe{
+		{Time: 0, Rows: [][]Cell{row("> ", 4)}},
+		{Time: 500 * time.Millisecond, Rows: [][]Cell{row("> a<b", 4)}},
+	}
+
+	svg := RenderSVG(frames, time.Second, DefaultTheme, "monospace", 20, 10)
+
+	if err := xml.Unmarshal([]byte(svg), new(interface{})); err != nil {
+		t.Fatalf("expected valid XML, got %v:\n%s", err, svg)
+	}
+
+	for _, want := range []string{
+		`#screen{animation:play 1000ms step-end infinite}`,
+		`0.000%{transform:translateY(0.00px)}`,
+		`50.000%{transform:translateY(-24.00px)}`,
+		`fill="` + DefaultTheme.Blue + `">&gt; a&lt;b</text>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected SVG to contain %q:\n%s", want, svg)
+		}
+	}
+}
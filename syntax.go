@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
-// Highlight syntax highlights a command for prettier printing.
+// highlight syntax highlights a command for prettier printing.
 // It takes an argument whether or not to print the command in a faint style to
 // represent hidden commands.
-func (c Command) Highlight(faint bool) string {
+func highlight(c Command, faint bool) string {
 	var (
 		optionsStyle = TimeStyle
 		argsStyle    = NumberStyle
@@ -35,13 +37,25 @@ func (c Command) Highlight(faint bool) string {
 	case OUTPUT:
 		optionsStyle = NoneStyle
 		argsStyle = StringStyle
-	case CTRL:
+	case CTRL, ALT, SHIFT:
 		argsStyle = CommandStyle
 	case SLEEP:
 		argsStyle = TimeStyle
-	case TYPE:
+	case WAIT, EXPECT, EXPECT_NOT:
+		argsStyle = StringStyle
+	case TYPE, COPY, PASTE:
+		optionsStyle = TimeStyle
+		argsStyle = StringStyle
+	case CAPTION, CHAPTER, AUDIO:
 		optionsStyle = TimeStyle
 		argsStyle = StringStyle
+	case HIGHLIGHT:
+		optionsStyle = TimeStyle
+		argsStyle = NumberStyle
+	case SPLIT:
+		argsStyle = StringStyle
+	case FOCUS:
+		argsStyle = NumberStyle
 	case HIDE, SHOW:
 		return FaintStyle.Render(c.Type.String())
 	}
@@ -55,6 +69,93 @@ func (c Command) Highlight(faint bool) string {
 	return s.String()
 }
 
+// HighlightTape syntax highlights a whole tape file. Unlike Highlight, the
+// tape is not parsed into commands: every token is styled in place so that
+// the comments, spacing and layout of the file are preserved.
+func HighlightTape(tape string) string {
+	// Offsets of the start of each line, to locate the tokens in the tape.
+	lines := []int{0}
+	for i := 0; i < len(tape); i++ {
+		if tape[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	offset := func(tok Token) int {
+		if tok.Line < 1 || tok.Line > len(lines) {
+			return len(tape)
+		}
+		o := lines[tok.Line-1] + tok.Column - 1
+		if o > len(tape) {
+			return len(tape)
+		}
+		return o
+	}
+
+	var s strings.Builder
+	l := NewLexer(tape)
+	tok := l.NextToken()
+	start := 0
+	for tok.Type != EOF {
+		next := l.NextToken()
+		end := offset(next)
+		if next.Type == EOF {
+			end = len(tape)
+		}
+		if from := offset(tok); from > start {
+			s.WriteString(tape[start:from])
+			start = from
+		}
+		if end < start {
+			end = start
+		}
+
+		text := tape[start:end]
+		trimmed := strings.TrimRight(text, " \t\r\n")
+		// Render each line on its own, as lipgloss pads multi-line text,
+		// which would alter the layout of multi-line JSON themes.
+		style := tokenStyle(tok)
+		for i, line := range strings.Split(trimmed, "\n") {
+			if i > 0 {
+				s.WriteString("\n")
+			}
+			s.WriteString(style.Render(line))
+		}
+		s.WriteString(text[len(trimmed):])
+
+		start = end
+		tok = next
+	}
+	s.WriteString(tape[start:])
+	return s.String()
+}
+
+// tokenStyle returns the style of a token in a highlighted tape.
+func tokenStyle(tok Token) lipgloss.Style {
+	switch tok.Type {
+	case COMMENT:
+		return FaintStyle
+	case STRING, JSON, REGEX:
+		return StringStyle
+	case NUMBER:
+		return NumberStyle
+	case MILLISECONDS, SECONDS, MINUTES:
+		return TimeStyle
+	case ILLEGAL:
+		return ErrorStyle
+	case SOURCE, REPEAT, CUT:
+		return CommandStyle
+	}
+	if IsSetting(tok.Type) {
+		return KeywordStyle
+	}
+	for _, t := range CommandTypes {
+		if CommandType(tok.Type) == t {
+			return CommandStyle
+		}
+	}
+	return NoneStyle
+}
+
 var numberRegex = regexp.MustCompile("^[0-9]+$")
 
 func isNumber(s string) bool {
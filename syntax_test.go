@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHighlightTapePreservesLayout(t *testing.T) {
+	// Without a terminal, no colors are added so the highlighted tape must be
+	// identical to the original.
+	tapes, err := filepath.Glob("examples/*/*.tape")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tapes = append(tapes, "")
+
+	for _, path := range tapes {
+		tape := "# Comment\n\nSet Theme { \"name\": \"x\",\n  \"background\": \"#000\" }\nType@50ms  \"echo\"   # trailing\r\nSleep 1.5s\n\tCtrl+C\n~"
+		if path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tape = string(b)
+		}
+
+		if got := HighlightTape(tape); got != tape {
+			t.Errorf("%s: expected the layout to be preserved, got:\n%s", path, got)
+		}
+	}
+}
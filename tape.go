@@ -0,0 +1,171 @@
+package main
+
+import "github.com/charmbracelet/vhs/parser"
+
+// The tapes are read by the parser package, which can be imported by other
+// programs. These aliases keep its names short in VHS.
+
+type (
+	Token        = parser.Token
+	TokenType    = parser.TokenType
+	Lexer        = parser.Lexer
+	Parser       = parser.Parser
+	ParserOption = parser.ParserOption
+	ParserError  = parser.ParserError
+	Command      = parser.Command
+	CommandType  = parser.CommandType
+)
+
+var (
+	NewLexer         = parser.NewLexer
+	NewError         = parser.NewError
+	WithParserVars   = parser.WithParserVars
+	IsSetting        = parser.IsSetting
+	IsCommand        = parser.IsCommand
+	IsModifier       = parser.IsModifier
+	LookupIdentifier = parser.LookupIdentifier
+	isConfiguration  = parser.IsConfiguration
+	isRuntimeSetting = parser.IsRuntimeSetting
+	keywords         = parser.Keywords
+	varName          = parser.VarName
+)
+
+// NewParser returns a parser of the tape of the lexer, which also checks the
+// keys of the chords and the inline themes, as VHS records them.
+func NewParser(l *Lexer, opts ...ParserOption) *Parser {
+	checks := []ParserOption{
+		parser.WithParserKeys(func(key string) bool {
+			_, ok := chordKey(key)
+			return ok
+		}),
+		parser.WithParserThemes(func(theme string) error {
+			_, err := parseJSONTheme(theme)
+			return err
+		}),
+	}
+	return parser.NewParser(l, append(checks, opts...)...)
+}
+
+// The tokens of the tapes.
+const (
+	AT              = parser.AT
+	EQUAL           = parser.EQUAL
+	PLUS            = parser.PLUS
+	PERCENT         = parser.PERCENT
+	COMMA           = parser.COMMA
+	SLASH           = parser.SLASH
+	DOT             = parser.DOT
+	DASH            = parser.DASH
+	PX              = parser.PX
+	EM              = parser.EM
+	EOF             = parser.EOF
+	ILLEGAL         = parser.ILLEGAL
+	SPACE           = parser.SPACE
+	BACKSPACE       = parser.BACKSPACE
+	CTRL            = parser.CTRL
+	ALT             = parser.ALT
+	SHIFT           = parser.SHIFT
+	ENTER           = parser.ENTER
+	NUMBER          = parser.NUMBER
+	SET             = parser.SET
+	SLEEP           = parser.SLEEP
+	WAIT            = parser.WAIT
+	COPY            = parser.COPY
+	PASTE           = parser.PASTE
+	EXPECT          = parser.EXPECT
+	EXPECT_NOT      = parser.EXPECT_NOT
+	STRING          = parser.STRING
+	JSON            = parser.JSON
+	REGEX           = parser.REGEX
+	TYPE            = parser.TYPE
+	DOWN            = parser.DOWN
+	LEFT            = parser.LEFT
+	RIGHT           = parser.RIGHT
+	UP              = parser.UP
+	TAB             = parser.TAB
+	ESCAPE          = parser.ESCAPE
+	DELETE          = parser.DELETE
+	HOME            = parser.HOME
+	INSERT          = parser.INSERT
+	END             = parser.END
+	HIDE            = parser.HIDE
+	REQUIRE         = parser.REQUIRE
+	SHOW            = parser.SHOW
+	QUIET           = parser.QUIET
+	REPEAT          = parser.REPEAT
+	SKIP            = parser.SKIP
+	SPEED           = parser.SPEED
+	CUT             = parser.CUT
+	SCREENSHOT      = parser.SCREENSHOT
+	BREAKPOINT      = parser.BREAKPOINT
+	ABORT           = parser.ABORT
+	ENV             = parser.ENV
+	SETUP           = parser.SETUP
+	TEARDOWN        = parser.TEARDOWN
+	VAR             = parser.VAR
+	SOURCE          = parser.SOURCE
+	OUTPUT          = parser.OUTPUT
+	MILLISECONDS    = parser.MILLISECONDS
+	SECONDS         = parser.SECONDS
+	MINUTES         = parser.MINUTES
+	COMMENT         = parser.COMMENT
+	SHELL           = parser.SHELL
+	FONT_FAMILY     = parser.FONT_FAMILY
+	FONT_FILE       = parser.FONT_FILE
+	FONT_SIZE       = parser.FONT_SIZE
+	FRAMERATE       = parser.FRAMERATE
+	PLAYBACK_SPEED  = parser.PLAYBACK_SPEED
+	LOOPS           = parser.LOOPS
+	HEIGHT          = parser.HEIGHT
+	WIDTH           = parser.WIDTH
+	LETTER_SPACING  = parser.LETTER_SPACING
+	LINE_HEIGHT     = parser.LINE_HEIGHT
+	TYPING_SPEED    = parser.TYPING_SPEED
+	TYPING_VARIANCE = parser.TYPING_VARIANCE
+	TYPING_SEED     = parser.TYPING_SEED
+	PADDING         = parser.PADDING
+	THEME           = parser.THEME
+	LOOP_OFFSET     = parser.LOOP_OFFSET
+	SLEEP_SCALE     = parser.SLEEP_SCALE
+	KEY_LOG         = parser.KEY_LOG
+	FLASH           = parser.FLASH
+	CAPTION         = parser.CAPTION
+	CHAPTER         = parser.CHAPTER
+	SPLIT           = parser.SPLIT
+	FOCUS           = parser.FOCUS
+	AUDIO           = parser.AUDIO
+	HIGHLIGHT       = parser.HIGHLIGHT
+	FLASH_COLOR     = parser.FLASH_COLOR
+	SHOW_GRID       = parser.SHOW_GRID
+	SHOW_KEYS       = parser.SHOW_KEYS
+	PROGRESS_BAR    = parser.PROGRESS_BAR
+	TIMEZONE        = parser.TIMEZONE
+	HTML_FULL       = parser.HTML_FULL
+	CRT             = parser.CRT
+	CRT_INTENSITY   = parser.CRT_INTENSITY
+	WEBP_QUALITY    = parser.WEBP_QUALITY
+	WEBP_LOSSLESS   = parser.WEBP_LOSSLESS
+	SECRET          = parser.SECRET
+	SSH             = parser.SSH
+	CONTAINER       = parser.CONTAINER
+
+	FRAMERATE_FROM_TYPING = parser.FRAMERATE_FROM_TYPING
+	TITLE                 = parser.TITLE
+	SCREENSHOT_DIR        = parser.SCREENSHOT_DIR
+	SCREENSHOT_DIGITS     = parser.SCREENSHOT_DIGITS
+	KEY_DELAY             = parser.KEY_DELAY
+	CURSOR_COLOR          = parser.CURSOR_COLOR
+	CURSOR_BLINK          = parser.CURSOR_BLINK
+	CURSOR_STYLE          = parser.CURSOR_STYLE
+	DEFAULT_SLEEP         = parser.DEFAULT_SLEEP
+	WINDOW_BAR            = parser.WINDOW_BAR
+	WINDOW_BAR_SIZE       = parser.WINDOW_BAR_SIZE
+	WINDOW_TITLE          = parser.WINDOW_TITLE
+	WINDOW_BAR_TITLE      = parser.WINDOW_BAR_TITLE
+	BORDER_RADIUS         = parser.BORDER_RADIUS
+	MARGIN                = parser.MARGIN
+	MARGIN_FILL           = parser.MARGIN_FILL
+	SHADOW                = parser.SHADOW
+	MAX_FILE_SIZE         = parser.MAX_FILE_SIZE
+	WORKING_DIRECTORY     = parser.WORKING_DIRECTORY
+)
@@ -3,6 +3,8 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -10,6 +12,7 @@ import (
 type TestOptions struct {
 	Output string
 	Golden string
+	Screen string
 }
 
 // DefaultTestOptions returns the default set of options for the testing functionality.
@@ -52,3 +55,48 @@ func (v *VHS) SaveOutput() {
 
 	_, _ = file.WriteString(separator + "\n")
 }
+
+// currentScreen returns the lines of text currently visible in the terminal,
+// without any styling.
+func (v *VHS) currentScreen() ([]string, error) {
+	buf, err := v.Page.Eval("() => Array(term.rows).fill(0).map((e, i) => term.buffer.active.getLine(term.buffer.active.viewportY + i).translateToString().trimEnd())")
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range buf.Value.Arr() {
+		lines = append(lines, line.Str())
+	}
+	return lines, nil
+}
+
+// ansiSequence matches the escape sequences of colors and styles.
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// screenText returns the lines of a screen as plain text, without any escape
+// sequences, trailing spaces or trailing blank lines.
+func screenText(lines []string) string {
+	var text []string
+	for _, line := range lines {
+		text = append(text, strings.TrimRight(ansiSequence.ReplaceAllString(line, ""), " "))
+	}
+	for len(text) > 0 && text[len(text)-1] == "" {
+		text = text[:len(text)-1]
+	}
+	return strings.Join(text, "\n") + "\n"
+}
+
+// SaveScreen saves the final screen of the terminal as plain text to the
+// screen output file.
+func (v *VHS) SaveScreen() error {
+	lines, err := v.currentScreen()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.Options.Test.Screen), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(v.Options.Test.Screen, []byte(screenText(lines)), 0o644) //nolint:gosec,gomnd
+}
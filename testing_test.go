@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestScreenText(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"plain", []string{"$ echo hi", "hi"}, "$ echo hi\nhi\n"},
+		{"ansi", []string{"\x1b[1;32m$\x1b[0m ls", "\x1b[34mdir\x1b[m  \x1b[?25lfile"}, "$ ls\ndir  file\n"},
+		{"trailing blank lines", []string{"$ true", "", "  ", "\x1b[0m", ""}, "$ true\n"},
+		{"inner blank lines", []string{"a", "", "b   ", ""}, "a\n\nb\n"},
+		{"empty", []string{"", ""}, "\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := screenText(tc.lines); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importName      string
+	themesImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import the color schemes of iTerm2, Windows Terminal or Alacritty as themes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			themes, err := ImportThemes(args[0])
+			if err != nil {
+				return err
+			}
+			if importName != "" {
+				if len(themes) > 1 {
+					return fmt.Errorf("--name can't be used with the %d color schemes of %s", len(themes), args[0])
+				}
+				themes[0].Name = importName
+			}
+			for _, theme := range themes {
+				if err := saveUserTheme(theme); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Imported theme %q, use it with Set Theme %q\n", theme.Name, theme.Name)
+			}
+			return nil
+		},
+	}
+)
+
+// ImportThemes converts the color schemes of a configuration file of another
+// terminal to themes, depending on its extension: .itermcolors for iTerm2,
+// .json for Windows Terminal and .toml, .yml or .yaml for Alacritty. The
+// colors missing from a scheme are those of the default theme.
+func ImportThemes(path string) ([]Theme, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var themes []Theme
+	switch filepath.Ext(path) {
+	case ".itermcolors":
+		theme, err := importITerm2(bts)
+		if err != nil {
+			return nil, err
+		}
+		theme.Name = name
+		themes = []Theme{theme}
+	case ".json":
+		themes, err = importWindowsTerminal(bts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range themes {
+			if themes[i].Name == "" {
+				themes[i].Name = name
+			}
+		}
+	case ".toml", ".yml", ".yaml":
+		theme := importAlacritty(bts)
+		theme.Name = name
+		themes = []Theme{theme}
+	default:
+		return nil, fmt.Errorf("unknown color scheme %s: expected .itermcolors, .json, .toml, .yml or .yaml", path)
+	}
+	if len(themes) == 0 {
+		return nil, fmt.Errorf("no color scheme in %s", path)
+	}
+	return themes, nil
+}
+
+// iTerm2Colors maps the colors of the iTerm2 color schemes to the keys of the
+// themes.
+var iTerm2Colors = map[string]string{
+	"Background Color":  "background",
+	"Foreground Color":  "foreground",
+	"Cursor Color":      "cursor",
+	"Cursor Text Color": "cursorAccent",
+	"Selection Color":   "selection",
+	"Ansi 0 Color":      "black",
+	"Ansi 1 Color":      "red",
+	"Ansi 2 Color":      "green",
+	"Ansi 3 Color":      "yellow",
+	"Ansi 4 Color":      "blue",
+	"Ansi 5 Color":      "magenta",
+	"Ansi 6 Color":      "cyan",
+	"Ansi 7 Color":      "white",
+	"Ansi 8 Color":      "brightBlack",
+	"Ansi 9 Color":      "brightRed",
+	"Ansi 10 Color":     "brightGreen",
+	"Ansi 11 Color":     "brightYellow",
+	"Ansi 12 Color":     "brightBlue",
+	"Ansi 13 Color":     "brightMagenta",
+	"Ansi 14 Color":     "brightCyan",
+	"Ansi 15 Color":     "brightWhite",
+}
+
+// plistNode is an element of a property list.
+type plistNode struct {
+	XMLName xml.Name
+	Text    string      `xml:",chardata"`
+	Nodes   []plistNode `xml:",any"`
+}
+
+// dict returns the keys of a dict element along with their values.
+func (n plistNode) dict() map[string]plistNode {
+	m := map[string]plistNode{}
+	for i := 0; i+1 < len(n.Nodes); i += 2 {
+		if n.Nodes[i].XMLName.Local == "key" {
+			m[strings.TrimSpace(n.Nodes[i].Text)] = n.Nodes[i+1]
+		}
+	}
+	return m
+}
+
+// importITerm2 converts an .itermcolors property list, whose colors are dicts
+// of their red, green and blue components from 0 to 1.
+func importITerm2(bts []byte) (Theme, error) {
+	var plist plistNode
+	if err := xml.Unmarshal(bts, &plist); err != nil {
+		return DefaultTheme, fmt.Errorf("invalid iTerm2 color scheme: %w", err)
+	}
+	if len(plist.Nodes) == 0 || plist.Nodes[0].XMLName.Local != "dict" {
+		return DefaultTheme, fmt.Errorf("invalid iTerm2 color scheme: expected a dict")
+	}
+
+	colors := map[string]string{}
+	for key, node := range plist.Nodes[0].dict() {
+		field, ok := iTerm2Colors[key]
+		if !ok {
+			continue
+		}
+		components := node.dict()
+		var rgb [3]uint8
+		for i, c := range []string{"Red Component", "Green Component", "Blue Component"} {
+			v, err := strconv.ParseFloat(strings.TrimSpace(components[c].Text), 64)
+			if err != nil {
+				return DefaultTheme, fmt.Errorf("invalid %s of %s: %w", c, key, err)
+			}
+			rgb[i] = uint8(math.Round(math.Max(0, math.Min(1, v)) * 255)) //nolint:gomnd
+		}
+		colors[field] = fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+	}
+	return themeFromColors(colors)
+}
+
+// windowsTerminalColors maps the keys of the Windows Terminal color schemes
+// which differ from the ones of the themes.
+var windowsTerminalColors = map[string]string{
+	"cursorColor":         "cursor",
+	"selectionBackground": "selection",
+	"purple":              "magenta",
+	"brightPurple":        "brightMagenta",
+}
+
+// importWindowsTerminal converts the color schemes of Windows Terminal: a
+// single scheme, or the schemes of a settings file or a fragment.
+func importWindowsTerminal(bts []byte) ([]Theme, error) {
+	var settings struct {
+		Schemes []map[string]string `json:"schemes"`
+	}
+	if err := json.Unmarshal(bts, &settings); err != nil {
+		return nil, fmt.Errorf("invalid Windows Terminal color scheme: %w", err)
+	}
+	schemes := settings.Schemes
+	if len(schemes) == 0 {
+		var scheme map[string]string
+		if err := json.Unmarshal(bts, &scheme); err != nil {
+			return nil, fmt.Errorf("invalid Windows Terminal color scheme: %w", err)
+		}
+		schemes = []map[string]string{scheme}
+	}
+
+	themes := make([]Theme, 0, len(schemes))
+	for _, scheme := range schemes {
+		colors := map[string]string{}
+		for key, value := range scheme {
+			if k, ok := windowsTerminalColors[key]; ok {
+				key = k
+			}
+			colors[key] = value
+		}
+		theme, err := themeFromColors(colors)
+		if err != nil {
+			return nil, err
+		}
+		themes = append(themes, theme)
+	}
+	return themes, nil
+}
+
+// alacrittyColors maps the colors of the Alacritty configuration to the keys
+// of the themes.
+var alacrittyColors = func() map[string]string {
+	colors := map[string]string{
+		"colors.primary.background":   "background",
+		"colors.primary.foreground":   "foreground",
+		"colors.cursor.cursor":        "cursor",
+		"colors.cursor.text":          "cursorAccent",
+		"colors.selection.background": "selection",
+	}
+	for _, c := range []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"} {
+		colors["colors.normal."+c] = c
+		colors["colors.bright."+c] = "bright" + strings.ToUpper(c[:1]) + c[1:]
+	}
+	return colors
+}()
+
+// importAlacritty converts the colors section of an Alacritty configuration,
+// in TOML or YAML. Only the tables or mappings of colors are read, which is
+// all that is needed for them, instead of the whole formats.
+func importAlacritty(bts []byte) Theme {
+	colors := map[string]string{}
+	var section string
+	// The keys of the YAML mappings, by indentation.
+	var path []string
+	var indents []int
+	s := bufio.NewScanner(bytes.NewReader(bts))
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			section = strings.Trim(trimmed, "[] ")
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, "=")
+		if ok {
+			key = section + "." + strings.TrimSpace(key)
+		} else if key, value, ok = strings.Cut(trimmed, ":"); ok {
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			for len(indents) > 0 && indents[len(indents)-1] >= indent {
+				path, indents = path[:len(path)-1], indents[:len(indents)-1]
+			}
+			key = strings.TrimSpace(key)
+			path, indents = append(path, key), append(indents, indent)
+			key = strings.Join(path, ".")
+		} else {
+			continue
+		}
+
+		if field, ok := alacrittyColors[strings.TrimPrefix(key, ".")]; ok {
+			if c := alacrittyColor(value); c != "" {
+				colors[field] = c
+			}
+		}
+	}
+
+	theme, err := themeFromColors(colors)
+	if err != nil {
+		return DefaultTheme
+	}
+	return theme
+}
+
+// alacrittyColor returns a color of Alacritty, quoted or not and in the
+// #rrggbb or 0xrrggbb format, as #rrggbb.
+func alacrittyColor(value string) string {
+	value = strings.TrimSpace(value)
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			value = value[1 : end+1]
+		}
+	} else if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	value = strings.ToLower(value)
+	if strings.HasPrefix(value, "0x") {
+		value = "#" + value[2:]
+	}
+	if _, err := parseHexColor(value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// themeFromColors returns the theme with the colors, by their keys in the
+// JSON themes. The other keys are ignored.
+func themeFromColors(colors map[string]string) (Theme, error) {
+	for key, c := range colors {
+		if key != "name" {
+			colors[key] = strings.ToLower(c)
+		}
+	}
+	bts, err := json.Marshal(colors)
+	if err != nil {
+		return DefaultTheme, err
+	}
+	return parseJSONTheme(string(bts))
+}
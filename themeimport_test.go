@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportThemes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"iterm.itermcolors": `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Background Color</key>
+	<dict>
+		<key>Blue Component</key><real>0.0</real>
+		<key>Green Component</key><real>0.0</real>
+		<key>Red Component</key><real>1</real>
+	</dict>
+	<key>Ansi 1 Color</key>
+	<dict>
+		<key>Blue Component</key><real>1</real>
+		<key>Green Component</key><real>0.5</real>
+		<key>Red Component</key><real>0</real>
+	</dict>
+</dict>
+</plist>`,
+		"wt.json": `{"schemes": [
+	{"name": "Brand", "background": "#FF0000", "purple": "#0080ff"},
+	{"name": "Brand Light", "background": "#ffffff"}
+]}`,
+		"alacritty.toml": `[colors.primary]
+background = "#ff0000" # red
+
+[colors.normal]
+magenta = '0x0080FF'
+`,
+		"alacritty.yml": `font:
+  size: 12
+colors:
+  primary:
+    background: '#ff0000'
+  normal:
+    magenta: "0x0080ff"
+  bright:
+    black: '#101010'
+`,
+		"alacritty.ini": "[colors]",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		file    string
+		name    string
+		themes  int
+		color   func(Theme) string
+		expects string
+	}{
+		{"iterm.itermcolors", "iterm", 1, func(t Theme) string { return t.Red }, "#0080ff"},
+		{"wt.json", "Brand", 2, func(t Theme) string { return t.Magenta }, "#0080ff"},
+		{"alacritty.toml", "alacritty", 1, func(t Theme) string { return t.Magenta }, "#0080ff"},
+		{"alacritty.yml", "alacritty", 1, func(t Theme) string { return t.BrightBlack }, "#101010"},
+	}
+	for _, tc := range tests {
+		themes, err := ImportThemes(filepath.Join(dir, tc.file))
+		if err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		if len(themes) != tc.themes {
+			t.Errorf("%s: expected %d themes, got %d", tc.file, tc.themes, len(themes))
+			continue
+		}
+		theme := themes[0]
+		if theme.Name != tc.name {
+			t.Errorf("%s: expected the theme %q, got %q", tc.file, tc.name, theme.Name)
+		}
+		if theme.Background != "#ff0000" {
+			t.Errorf("%s: expected a red background, got %s", tc.file, theme.Background)
+		}
+		if c := tc.color(theme); c != tc.expects {
+			t.Errorf("%s: expected %s, got %s", tc.file, tc.expects, c)
+		}
+		if theme.Foreground != DefaultTheme.Foreground {
+			t.Errorf("%s: expected the default foreground, got %s", tc.file, theme.Foreground)
+		}
+	}
+
+	if _, err := ImportThemes(filepath.Join(dir, "alacritty.ini")); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
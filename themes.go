@@ -6,6 +6,7 @@
 //
 // Set Theme {"background": "#171717"}
 // Set Theme "Catppuccin Mocha"
+// Set Theme "./brand.json"
 //
 //go:generate make all
 package main
@@ -15,7 +16,13 @@ import (
 
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -60,6 +67,63 @@ func (t Theme) String() string {
 	return string(ts)
 }
 
+// minCursorContrast is the minimum contrast ratio between the cursor and the
+// background, as recommended by WCAG for non-text elements.
+const minCursorContrast = 3.0
+
+// WithCursorContrast returns the theme with a cursor color which stands out
+// from the background. The cursor of the theme is kept if it has enough
+// contrast, otherwise the foreground is used, or black or white, whichever
+// has the most contrast.
+func (t Theme) WithCursorContrast() Theme {
+	if contrastRatio(t.Cursor, t.Background) >= minCursorContrast {
+		return t
+	}
+
+	t.Cursor = t.Foreground
+	if contrastRatio(t.Foreground, t.Background) < minCursorContrast {
+		t.Cursor = "#ffffff"
+		if contrastRatio("#000000", t.Background) > contrastRatio("#ffffff", t.Background) {
+			t.Cursor = "#000000"
+		}
+	}
+	if t.CursorAccent == "" {
+		t.CursorAccent = t.Background
+	}
+	return t
+}
+
+// contrastRatio returns the WCAG contrast ratio between two colors, from 1
+// (no contrast) to 21. Invalid colors have no contrast.
+func contrastRatio(a, b string) float64 {
+	ca, err := parseHexColor(a)
+	if err != nil {
+		return 1
+	}
+	cb, err := parseHexColor(b)
+	if err != nil {
+		return 1
+	}
+	la, lb := relativeLuminance(ca), relativeLuminance(cb)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05) //nolint:gomnd
+}
+
+// relativeLuminance returns the relative luminance of a color, as defined by
+// WCAG.
+func relativeLuminance(c color.NRGBA) float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255 //nolint:gomnd
+		if s <= 0.03928 {     //nolint:gomnd
+			return s / 12.92 //nolint:gomnd
+		}
+		return math.Pow((s+0.055)/1.055, 2.4) //nolint:gomnd
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B) //nolint:gomnd
+}
+
 // DefaultTheme is the default theme to use for recording demos and
 // screenshots.
 //
@@ -143,18 +207,32 @@ func (e ThemeNotFoundError) Error() string {
 	)
 }
 
-// sortedThemeNames returns the names of the themes, sorted.
-func sortedThemeNames() ([]string, error) {
-	var keys []string
+// allThemes returns the built-in themes, followed by the themes of the user.
+func allThemes() ([]Theme, error) {
+	var all []Theme
 	for _, bts := range [][]byte{themesBts, customThemesBts} {
 		themes, err := parseThemes(bts)
 		if err != nil {
 			return nil, err
 		}
+		all = append(all, themes...)
+	}
+	themes, err := userThemes()
+	if err != nil {
+		return nil, err
+	}
+	return append(all, themes...), nil
+}
 
-		for _, theme := range themes {
-			keys = append(keys, theme.Name)
-		}
+// sortedThemeNames returns the names of the themes, sorted.
+func sortedThemeNames() ([]string, error) {
+	themes, err := allThemes()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(themes))
+	for _, theme := range themes {
+		keys = append(keys, theme.Name)
 	}
 	sort.Slice(keys, func(i, j int) bool {
 		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
@@ -166,16 +244,13 @@ const distance = 2
 
 // findTheme return the given theme, if it exists.
 func findTheme(name string) (Theme, error) {
-	for _, bts := range [][]byte{themesBts, customThemesBts} {
-		themes, err := parseThemes(bts)
-		if err != nil {
-			return DefaultTheme, err
-		}
-
-		for _, theme := range themes {
-			if theme.Name == name {
-				return theme, nil
-			}
+	themes, err := allThemes()
+	if err != nil {
+		return DefaultTheme, err
+	}
+	for _, theme := range themes {
+		if theme.Name == name {
+			return theme, nil
 		}
 	}
 
@@ -199,6 +274,163 @@ func findTheme(name string) (Theme, error) {
 	return DefaultTheme, ThemeNotFoundError{name, suggestions}
 }
 
+// ExportTheme returns the colors of the given theme as indented JSON, in the
+// format of Set Theme {...}, so that it can be customized.
+func ExportTheme(name string) ([]byte, error) {
+	theme, err := findTheme(name)
+	var notFound ThemeNotFoundError
+	if errors.As(err, &notFound) {
+		if len(notFound.Suggestions) == 0 {
+			return nil, fmt.Errorf("unknown theme %q, see `vhs themes` for the list of themes", name)
+		}
+		return nil, fmt.Errorf("unknown theme %q, did you mean %q", name, strings.Join(notFound.Suggestions, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	bts, err := json.MarshalIndent(theme, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(bts, '\n'), nil
+}
+
+// parseJSONTheme parses an inline theme, in the format of the themes.json
+// file. The colors which are missing or empty are those of the default theme,
+// so that a theme can only change a few colors. Unknown keys are ignored, as
+// they are by xterm.js.
+func parseJSONTheme(s string) (Theme, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &fields); err != nil {
+		return DefaultTheme, err
+	}
+
+	theme := DefaultTheme
+	value := reflect.ValueOf(&theme).Elem()
+	for key, raw := range fields {
+		field, ok := themeFields[key]
+		if !ok {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return DefaultTheme, fmt.Errorf("%s must be a string", key)
+		}
+		if s == "" {
+			continue
+		}
+		if key != "name" {
+			if _, err := parseHexColor(s); err != nil {
+				return DefaultTheme, fmt.Errorf("invalid %s color %q: expected #rrggbb", key, s)
+			}
+		}
+		value.Field(field).SetString(s)
+	}
+	return theme, nil
+}
+
+// themeFields maps the JSON keys of a theme to the index of their field.
+var themeFields = func() map[string]int {
+	t := reflect.TypeOf(Theme{})
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		fields[t.Field(i).Tag.Get("json")] = i
+	}
+	return fields
+}()
+
+// userThemesDir returns the directory of the themes added with
+// `vhs themes add`, in the configuration directory of the user.
+func userThemesDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "vhs", "themes"), nil
+}
+
+// userThemes returns the themes added with `vhs themes add`, one per JSON file
+// of the themes directory. There are none if the directory doesn't exist.
+func userThemes() ([]Theme, error) {
+	dir, err := userThemesDir()
+	if err != nil {
+		return nil, nil //nolint:nilerr
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	themes := make([]Theme, 0, len(files))
+	for _, file := range files {
+		theme, err := readThemeFile(file)
+		if err != nil {
+			return nil, err
+		}
+		themes = append(themes, theme)
+	}
+	return themes, nil
+}
+
+// readThemeFile reads a theme in the format of Set Theme {...}. Its name is
+// the name of the file, unless the theme has one.
+func readThemeFile(path string) (Theme, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return DefaultTheme, err
+	}
+	theme, err := parseJSONTheme(string(bts))
+	if err != nil {
+		return DefaultTheme, fmt.Errorf("invalid theme %s: %w", path, err)
+	}
+	if theme.Name == DefaultTheme.Name {
+		theme.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return theme, nil
+}
+
+// AddTheme copies the theme file to the themes of the user, so that it can be
+// used by name with Set Theme. It returns the name of the theme.
+func AddTheme(path string) (string, error) {
+	theme, err := readThemeFile(path)
+	if err != nil {
+		return "", err
+	}
+	return theme.Name, saveUserTheme(theme)
+}
+
+// saveUserTheme saves the theme in the themes directory of the user. It can't
+// have the name of a built-in theme.
+func saveUserTheme(theme Theme) error {
+	for _, bts := range [][]byte{themesBts, customThemesBts} {
+		themes, err := parseThemes(bts)
+		if err != nil {
+			return err
+		}
+		for _, t := range themes {
+			if t.Name == theme.Name {
+				return fmt.Errorf("theme %q already exists", theme.Name)
+			}
+		}
+	}
+
+	dir, err := userThemesDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	bts, err := json.MarshalIndent(theme, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := themeSlug(theme.Name)
+	if name == "" {
+		return fmt.Errorf("invalid theme name %q", theme.Name)
+	}
+	return os.WriteFile(filepath.Join(dir, name+".json"), append(bts, '\n'), 0o644) //nolint:gosec
+}
+
 func parseThemes(bts []byte) ([]Theme, error) {
 	var themes []Theme
 	if err := json.Unmarshal(bts, &themes); err != nil {
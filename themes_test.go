@@ -1,10 +1,25 @@
 package main
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
+// isolateUserThemes points the configuration directory to an empty directory,
+// so that the themes of the user don't change the tests.
+func isolateUserThemes(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	return dir
+}
+
 func TestFindAllThemes(t *testing.T) {
+	isolateUserThemes(t)
 	themes, err := sortedThemeNames()
 	if err != nil {
 		t.Fatal(err)
@@ -33,3 +48,145 @@ func TestFindTheme(t *testing.T) {
 		t.Fatal("wrong suggestion:", te.Suggestions[0])
 	}
 }
+
+func TestExportTheme(t *testing.T) {
+	bts, err := ExportTheme("Dracula")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var theme Theme
+	if err := json.Unmarshal(bts, &theme); err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := findTheme("Dracula")
+	if theme != expected {
+		t.Errorf("expected the exported theme to be %v, got %v", expected, theme)
+	}
+
+	if _, err := ExportTheme("Draculaa"); err == nil || err.Error() != `unknown theme "Draculaa", did you mean "Dracula, Dracula+"` {
+		t.Errorf("unexpected error for an unknown theme: %v", err)
+	}
+}
+
+func TestParseJSONTheme(t *testing.T) {
+	theme, err := parseJSONTheme(`{"name": "Mine", "background": "#29283b", "selection": "", "purple": "#aa7ff0"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DefaultTheme
+	expected.Name = "Mine"
+	expected.Background = "#29283b"
+	if theme != expected {
+		t.Errorf("expected the missing colors to be those of the default theme, got %v", theme)
+	}
+
+	for input, msg := range map[string]string{
+		`{"red": "tomato"}`: `invalid red color "tomato": expected #rrggbb`,
+		`{"red": 1}`:        "red must be a string",
+	} {
+		if _, err := parseJSONTheme(input); err == nil || err.Error() != msg {
+			t.Errorf("expected the error %q for %s, got %v", msg, input, err)
+		}
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	if r := contrastRatio("#000000", "#ffffff"); r < 20.9 || r > 21.1 {
+		t.Errorf("expected a contrast of 21 between black and white, got %f", r)
+	}
+	if r := contrastRatio("#171717", "#171717"); r != 1 {
+		t.Errorf("expected no contrast between identical colors, got %f", r)
+	}
+}
+
+func TestWithCursorContrast(t *testing.T) {
+	theme := DefaultTheme
+	theme.Cursor = theme.Background
+	theme = theme.WithCursorContrast()
+	if theme.Cursor != theme.Foreground {
+		t.Errorf("expected the cursor to fall back to the foreground, got %s", theme.Cursor)
+	}
+
+	if c := DefaultTheme.WithCursorContrast().Cursor; c != DefaultTheme.Cursor {
+		t.Errorf("expected the cursor of the theme to be kept, got %s", c)
+	}
+
+	for _, bts := range [][]byte{themesBts, customThemesBts} {
+		themes, err := parseThemes(bts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, theme := range themes {
+			theme = theme.WithCursorContrast()
+			if r := contrastRatio(theme.Cursor, theme.Background); r < minCursorContrast {
+				t.Errorf("%s: cursor %s has a contrast of %f with background %s", theme.Name, theme.Cursor, r, theme.Background)
+			}
+		}
+	}
+}
+
+func TestThemeFiles(t *testing.T) {
+	isolateUserThemes(t)
+	file := filepath.Join(t.TempDir(), "brand.json")
+	if err := os.WriteFile(file, []byte(`{"background": "#101010", "red": "#ff0000"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := getTheme(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Background != "#101010" || theme.Red != "#ff0000" || theme.Foreground != DefaultTheme.Foreground {
+		t.Errorf("expected the colors of the file over the default theme, got %+v", theme)
+	}
+	if _, err := getTheme(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing theme file")
+	}
+
+	name, err := AddTheme(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "brand" {
+		t.Errorf("expected the theme to be named after the file, got %q", name)
+	}
+	theme, err = findTheme("brand")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Background != "#101010" {
+		t.Errorf("expected the added theme, got %+v", theme)
+	}
+
+	dracula := filepath.Join(t.TempDir(), "dracula.json")
+	if err := os.WriteFile(dracula, []byte(`{"name": "Dracula"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddTheme(dracula); err == nil {
+		t.Error("expected an error for the name of a built-in theme")
+	}
+}
+
+func TestPreviewThemes(t *testing.T) {
+	if slug := themeSlug("Catppuccin Mocha (Dark)"); slug != "catppuccin-mocha-dark" {
+		t.Errorf("expected catppuccin-mocha-dark, got %s", slug)
+	}
+
+	p := NewParser(NewLexer(previewTape("Builtin Dark", "themes/builtin-dark.png")))
+	cmds := p.Parse()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("expected a valid tape, got %v", p.Errors())
+	}
+	if cmds[0].Type != OUTPUT || cmds[0].Args != "themes/builtin-dark.png" {
+		t.Errorf("expected the preview to be saved as a PNG, got %v", cmds[0])
+	}
+	if cmds[1].Type != SET || cmds[1].Args != "Builtin Dark" {
+		t.Errorf("expected the theme to be set, got %v", cmds[1])
+	}
+
+	expected := "# Themes\n\n## Builtin Dark\n\n![Builtin Dark](./builtin-dark.png)\n"
+	if md := previewMarkdown([]string{"Builtin Dark"}); md != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, md)
+	}
+}
@@ -12,6 +12,7 @@ package main
 import (
 	"fmt"
 	"net"
+	"os"
 	"os/exec"
 )
 
@@ -22,20 +23,50 @@ func randomPort() int {
 	return addr.Addr().(*net.TCPAddr).Port
 }
 
-// StartTTY starts the ttyd process on the given port.
-func StartTTY(port int) *exec.Cmd {
+// StartTTY starts the ttyd process on the given port, with the shell and in
+// the directory of the options. Their environment variables are added to the
+// ones of the current process and inherited by the shell. Unless the cursor
+// blinks, it is steady.
+func StartTTY(port int, opts TerminalOptions) *exec.Cmd {
 	args := []string{
 		fmt.Sprintf("--port=%d", port),
 		"-t", "rendererType=canvas",
 		"-t", "disableResizeOverlay=true",
-		"-t", "cursorBlink=true",
+		"-t", fmt.Sprintf("cursorBlink=%t", opts.CursorBlink),
 		"-t", "enableSixel=true",
 		"-t", "customGlyphs=true",
 	}
 
-	args = append(args, defaultShellWithArgs()...)
+	args = append(args, opts.shellWithArgs()...)
 
 	//nolint:gosec
 	cmd := exec.Command("ttyd", args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = append(os.Environ(), opts.Env...)
 	return cmd
 }
+
+// ttydTerminal is the terminal of the ttyd backend, which serves xterm.js
+// along with the shell.
+type ttydTerminal struct {
+	cmd *exec.Cmd
+}
+
+// Start starts ttyd on a random port.
+func (t *ttydTerminal) Start(opts TerminalOptions) (string, error) {
+	port := randomPort()
+	t.cmd = StartTTY(port, opts)
+	if err := t.cmd.Start(); err != nil {
+		return "", fmt.Errorf("could not start ttyd: %w", err)
+	}
+	go t.cmd.Wait() //nolint:errcheck
+	return fmt.Sprintf("http://localhost:%d", port), nil
+}
+
+// Stop kills ttyd.
+func (t *ttydTerminal) Stop() error {
+	if t.cmd == nil || t.cmd.Process == nil {
+		return nil
+	}
+	return t.cmd.Process.Kill()
+}
@@ -5,6 +5,12 @@ package main
 
 const defaultShell = bash
 
+// scriptShellWithArgs returns the command which runs the Setup and Teardown
+// scripts, followed by the script.
+func scriptShellWithArgs() []string {
+	return []string{"bash", "-c"}
+}
+
 func defaultShellWithArgs() []string {
 	return []string{
 		"bash", "--login",
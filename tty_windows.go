@@ -11,6 +11,12 @@ import (
 
 var defaultShell = cmdexe
 
+// scriptShellWithArgs returns the command which runs the Setup and Teardown
+// scripts, followed by the script.
+func scriptShellWithArgs() []string {
+	return []string{"cmd", "/C"}
+}
+
 func defaultShellWithArgs() []string {
 	major, _, _ := windows.RtlGetNtVersionNumbers()
 	if major >= 10 {
@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"math"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/input"
@@ -26,23 +31,95 @@ type VHS struct {
 	CursorCanvas *rod.Element
 	mutex        *sync.Mutex
 	recording    bool
-	tty          *exec.Cmd
+	terminal     Terminal
+	container    *container
 	totalFrames  int
+	recordStart  time.Time
+	frames       int
+	clock        time.Duration
+	missed       int
+	capture      func() error
+	progress     ProgressFunc
+	panes        *paneSet
+	skips        []string
+	sandboxed    bool
+	speeds       []float64
+	speedCarry   float64
+	started      time.Time
+	defaults     []Command
+	clipboard    Clipboard
+	copied       string
+	hasCopied    bool
+	screenshots  int
+	shots        []string
+	keyLog       *os.File
+	keyframes    []Keyframe
+	castEvents   []castEvent
+	castWidth    int
+	castHeight   int
+	rand         *rand.Rand
+	subtitles    []subtitle
+	quiet        bool
+	quietRow     int
+	quietPaused  bool
 	close        func() error
 }
 
 // Options is the set of options for the setup.
 type Options struct {
-	Shell         Shell
-	FontFamily    string
-	FontSize      int
-	LetterSpacing float64
-	LineHeight    float64
-	TypingSpeed   time.Duration
-	Theme         Theme
-	Test          TestOptions
-	Video         VideoOptions
-	LoopOffset    float64
+	Shell          Shell
+	FontFamily     string
+	FontFiles      []string
+	Split          string
+	FontSize       int
+	LetterSpacing  float64
+	LineHeight     float64
+	TypingSpeed    time.Duration
+	TypingVariance float64
+	TypingSeed     int64
+	Theme          Theme
+	Test           TestOptions
+	HTML           HTMLOptions
+	Video          VideoOptions
+	LoopOffset     float64
+	SleepScale     float64
+	KeyLog         string
+	ShowKeys       bool
+	FlashColor     string
+	Timezone       string
+	Title          string
+	ScreenshotDir  string
+	KeyDelay       time.Duration
+	CursorColor    string
+	CursorBlink    bool
+	CursorStyle    string
+	DefaultSleep   time.Duration
+	Env            []string
+	Secrets        []string
+	Setup          []string
+	Teardown       []string
+	Vars           map[string]string
+	Deterministic  bool
+	FrameStream    io.Writer
+
+	// SSH is the host, as [user@]host, on which the shell runs, or empty for
+	// a local shell.
+	SSH string
+
+	// Container is the image of the container in which the shell runs, or
+	// empty for a local shell.
+	Container string
+
+	// Backend is the name of the terminal backend, the default one if empty.
+	Backend string
+
+	// WorkingDirectory is the directory of the shell and of the Setup and
+	// Teardown scripts, the current one if empty.
+	WorkingDirectory string
+
+	// ScreenshotDigits is the width of the {n} counter of the screenshot
+	// names.
+	ScreenshotDigits int
 }
 
 const (
@@ -50,6 +127,8 @@ const (
 	defaultTypingSpeed   = 50 * time.Millisecond
 	defaultLineHeight    = 1.0
 	defaultLetterSpacing = 0
+	defaultSleepScale    = 1.0
+	defaultCursorStyle   = "block"
 	fontsSeparator       = ","
 )
 
@@ -74,35 +153,94 @@ func DefaultVHSOptions() Options {
 		LetterSpacing: defaultLetterSpacing,
 		LineHeight:    defaultLineHeight,
 		TypingSpeed:   defaultTypingSpeed,
+		SleepScale:    defaultSleepScale,
+		FlashColor:    Foreground,
+		CursorBlink:   true,
+		CursorStyle:   defaultCursorStyle,
 		Shell:         Shells[defaultShell],
 		Theme:         DefaultTheme,
 		Video:         DefaultVideoOptions(),
+
+		ScreenshotDigits: defaultScreenshotDigits,
 	}
 }
 
-// New sets up ttyd and go-rod for recording frames.
+// New returns a VHS instance with the default options.
+//
+// The ttyd and go-rod processes are not started until Start is called, so
+// that the options affecting them can be set beforehand.
 func New() VHS {
-	port := randomPort()
-	tty := StartTTY(port)
-	go tty.Run() //nolint:errcheck
-
 	opts := DefaultVHSOptions()
-	path, _ := launcher.LookPath()
-	u := launcher.New().Leakless(false).Bin(path).MustLaunch()
-	browser := rod.New().ControlURL(u).MustConnect()
-	page := browser.MustPage(fmt.Sprintf("http://localhost:%d", port))
-
 	mu := &sync.Mutex{}
 
 	return VHS{
 		Options:   &opts,
-		Page:      page,
-		browser:   browser,
-		tty:       tty,
 		recording: true,
 		mutex:     mu,
-		close:     browser.Close,
+		quietRow:  -1,
+		clipboard: systemClipboard{},
+		close:     func() error { return nil },
+	}
+}
+
+// Start sets up the terminal of the backend and go-rod for recording frames.
+func (vhs *VHS) Start() error {
+	backend, err := findBackend(vhs.Options.Backend)
+	if err != nil {
+		return err
+	}
+	vhs.terminal = backend.New()
+	opts := TerminalOptions{
+		Dir:         vhs.Options.WorkingDirectory,
+		Env:         vhs.environment(),
+		CursorBlink: vhs.Options.CursorBlink,
+	}
+	switch {
+	case vhs.Options.SSH != "" && vhs.Options.Container != "":
+		return errors.New("the shell can't run both over SSH and in a container")
+	case vhs.Options.SSH != "":
+		if opts.Shell, err = sshShellWithArgs(vhs.Options.SSH, opts.Env); err != nil {
+			return err
+		}
+	case vhs.Options.Container != "":
+		if opts.Shell, vhs.container, err = containerShellWithArgs(vhs.Options.Container, opts.Env); err != nil {
+			return err
+		}
+	}
+	url, err := vhs.terminal.Start(opts)
+	if err != nil {
+		return err
+	}
+
+	path, _ := launcher.LookPath()
+	u := launcher.New().Leakless(false).Bin(path).MustLaunch()
+	vhs.browser = rod.New().ControlURL(u).MustConnect()
+	vhs.Page = vhs.browser.MustPage(url)
+	vhs.close = vhs.browser.Close
+	return nil
+}
+
+// environment returns the environment variables, in addition to the ones of
+// the current process, of the shell running in ttyd.
+func (vhs *VHS) environment() []string {
+	var env []string
+	if vhs.Options.Timezone != "" {
+		env = append(env, "TZ="+vhs.Options.Timezone)
 	}
+	return append(env, vhs.Options.Env...)
+}
+
+// titleSequence returns the OSC 0 and OSC 2 sequences which set the icon name
+// and window title of the terminal. Control characters are removed from the
+// title since they would end the sequence early.
+func titleSequence(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, title)
+	return "\x1b]0;" + title + "\x07\x1b]2;" + title + "\x07"
 }
 
 // Setup sets up the VHS instance and performs the necessary actions to reflect
@@ -112,7 +250,7 @@ func (vhs *VHS) Setup() {
 	// added during the render.
 	padding := vhs.Options.Video.Padding
 	width := vhs.Options.Video.Width - padding - padding
-	height := vhs.Options.Video.Height - padding - padding
+	height := vhs.Options.Video.Height - padding - padding - windowBarHeight(vhs.Options.Video)
 	vhs.Page = vhs.Page.MustSetViewport(width, height, 0, false)
 
 	// Let's wait until we can access the window.term variable.
@@ -122,6 +260,18 @@ func (vhs *VHS) Setup() {
 	vhs.TextCanvas, _ = vhs.Page.Element("canvas.xterm-text-layer")
 	vhs.CursorCanvas, _ = vhs.Page.Element("canvas.xterm-cursor-layer")
 
+	// Keep the output of the terminal from now on for the asciicast, so that
+	// it starts with the prompt being set up and the screen cleared.
+	if vhs.Options.Video.Output.Cast != "" {
+		vhs.Page.MustEval(castRecorder)
+	}
+
+	// Mask the secrets before anything is written to the terminal, including
+	// the asciicast.
+	if len(vhs.Options.Secrets) > 0 {
+		vhs.Page.MustEval(secretMasker(vhs.Options.Secrets))
+	}
+
 	// Set up the Prompt
 	shellCommand := fmt.Sprintf(vhs.Options.Shell.Command, vhs.Options.Shell.Prompt)
 	if vhs.Options.Shell.Prompt == "" {
@@ -131,23 +281,69 @@ func (vhs *VHS) Setup() {
 		MustInput(shellCommand).
 		MustType(input.Enter)
 
+	// Make sure the cursor is visible on the background, unless its color was
+	// set explicitly.
+	if vhs.Options.CursorColor != "" {
+		vhs.Options.Theme.Cursor = vhs.Options.CursorColor
+	} else {
+		vhs.Options.Theme = vhs.Options.Theme.WithCursorContrast()
+	}
+
+	// Load the font files before the terminal uses them.
+	if len(vhs.Options.FontFiles) > 0 {
+		loader, err := fontLoader(vhs.Options.FontFiles)
+		if err == nil {
+			_, err = vhs.Page.Eval(loader)
+		}
+		if err != nil {
+			vhs.Errors = append(vhs.Errors, fmt.Errorf("could not load the font files: %w", err))
+		}
+	}
+
 	// Apply options to the terminal
 	// By this point the setting commands have been executed, so the `opts` struct is up to date.
-	vhs.Page.MustEval(fmt.Sprintf("() => { term.options = { fontSize: %d, fontFamily: '%s', letterSpacing: %f, lineHeight: %f, theme: %s } }",
-		vhs.Options.FontSize, vhs.Options.FontFamily, vhs.Options.LetterSpacing,
-		vhs.Options.LineHeight, vhs.Options.Theme.String()))
+	vhs.Page.MustEval(fmt.Sprintf("() => { term.options = { fontSize: %d, fontFamily: '%s', letterSpacing: %f, lineHeight: %f, cursorBlink: %t, cursorStyle: '%s', theme: %s } }",
+		vhs.Options.FontSize, terminalFontFamily(vhs.Options), vhs.Options.LetterSpacing,
+		vhs.Options.LineHeight, vhs.Options.CursorBlink, vhs.Options.CursorStyle, vhs.Options.Theme.String()))
 
 	// Fit the terminal into the window
 	vhs.Page.MustEval("term.fit")
 
+	if vhs.Options.Video.Output.Cast != "" {
+		dims := vhs.Page.MustEval("() => [term.cols, term.rows]").Arr()
+		vhs.castWidth, vhs.castHeight = dims[0].Int(), dims[1].Int()
+	}
+
+	// Set the title of the terminal, as if a program had written the OSC
+	// sequences, so that it is reported to the programs asking for it.
+	if vhs.Options.Title != "" {
+		vhs.Page.MustEval("(seq) => term.write(seq)", titleSequence(vhs.Options.Title))
+	}
+
+	if vhs.Options.Video.ShowGrid {
+		vhs.measureGrid()
+	}
+
 	_ = os.RemoveAll(vhs.Options.Video.Input)
 	_ = os.MkdirAll(vhs.Options.Video.Input, os.ModePerm)
 }
 
+// measureGrid measures the cells of the terminal, so that the grid overlay
+// and the highlights match them.
+func (vhs *VHS) measureGrid() {
+	dims := vhs.Page.MustEval("() => { const c = document.querySelector('canvas.xterm-text-layer'); return [term.cols, term.rows, c.width, c.height] }").Arr()
+	vhs.Options.Video.Grid = GridOptions{
+		Columns: dims[0].Int(),
+		Rows:    dims[1].Int(),
+		Width:   dims[2].Int(),
+		Height:  dims[3].Int(),
+	}
+}
+
 const cleanupWaitTime = 100 * time.Millisecond
 
-// Terminate cleans up a VHS instance and terminates the go-rod browser and ttyd
-// processes.
+// Terminate cleans up a VHS instance and terminates the go-rod browser and the
+// terminal.
 func (vhs *VHS) terminate() error {
 	// Give some time for any commands executed (such as `rm`) to finish.
 	//
@@ -157,7 +353,11 @@ func (vhs *VHS) terminate() error {
 
 	// Tear down the processes we started.
 	vhs.browser.MustClose()
-	return vhs.tty.Process.Kill()
+	err := vhs.terminal.Stop()
+	if cerr := vhs.container.remove(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 // Cleanup individual frames.
@@ -171,28 +371,106 @@ func (vhs *VHS) Cleanup() error {
 
 // Render starts rendering the individual frames into a video.
 func (vhs *VHS) Render() error {
+	// Save the last frame before the loop offset moves it.
+	if err := MakeLastFrame(vhs.Options.Video, vhs.totalFrames); err != nil {
+		return err
+	}
+
 	// Apply Loop Offset by modifying frame sequence
 	if err := vhs.ApplyLoopOffset(); err != nil {
 		return err
 	}
 
+	if err := MakeFrames(vhs.Options.Video, vhs.totalFrames); err != nil {
+		return err
+	}
+
+	if vhs.Options.FrameStream != nil {
+		if err := StreamFrames(vhs.Options.Video, vhs.totalFrames, vhs.Options.FrameStream); err != nil {
+			return err
+		}
+	}
+
+	if vhs.Options.Video.Adaptive {
+		if err := MakeAdaptiveFrames(vhs.Options.Video, vhs.totalFrames); err != nil {
+			return err
+		}
+	}
+
+	if vhs.Options.Video.ShowGrid {
+		if err := MakeGrid(vhs.Options.Video); err != nil {
+			return err
+		}
+	}
+
+	if vhs.Options.Video.WindowBar != "" {
+		if err := MakeWindowBar(vhs.Options.Video); err != nil {
+			return err
+		}
+	}
+
+	if vhs.Options.Video.BorderRadius > 0 {
+		if err := MakeWindowFrame(vhs.Options.Video); err != nil {
+			return err
+		}
+	}
+
+	if vhs.Options.Video.Margin > 0 {
+		if err := MakeMargin(vhs.Options.Video); err != nil {
+			return err
+		}
+	}
+
+	vhs.Options.Video.Duration = time.Duration(vhs.totalFrames) * time.Second / time.Duration(vhs.Options.Video.Framerate)
+	vhs.Options.Video.ProgressColor = vhs.Options.Theme.Blue
+	if err := MakeChapters(vhs.Options.Video); err != nil {
+		return err
+	}
+
+	for _, w := range loopWarnings(vhs.Options.Video) {
+		fmt.Println(WarningStyle.Render(w))
+	}
+
 	// Generate the video(s) with the frames.
-	var cmds []*exec.Cmd
-	cmds = append(cmds, MakeGIF(vhs.Options.Video))
-	cmds = append(cmds, MakeMP4(vhs.Options.Video))
-	cmds = append(cmds, MakeWebM(vhs.Options.Video))
+	video := vhs.Options.Video
+	encoders := []struct {
+		output string
+		cmd    *exec.Cmd
+	}{
+		{video.Output.GIF, MakeGIF(video)},
+		{video.Output.MP4, MakeMP4(video)},
+		{video.Output.WebM, MakeWebM(video)},
+		{video.Output.WebP, MakeWebP(video)},
+		{video.Output.APNG, MakeAPNG(video)},
+	}
 
-	for _, cmd := range cmds {
-		if cmd == nil {
+	for _, e := range encoders {
+		if e.cmd == nil {
 			continue
 		}
-		out, err := cmd.CombinedOutput()
+		var report func(float64)
+		if vhs.progress != nil {
+			output := e.output
+			report = func(percent float64) {
+				vhs.reportProgress(Progress{Output: output, Percent: percent})
+			}
+		}
+		out, err := runEncoder(e.cmd, outputTime(video, video.Duration), report)
 		if err != nil {
 			fmt.Println(string(out))
 		}
 	}
+	if err := FitGIF(video); err != nil {
+		return err
+	}
 
-	return nil
+	if err := MakeCast(vhs); err != nil {
+		return err
+	}
+	if err := MakeSubtitles(vhs); err != nil {
+		return err
+	}
+	return MakeSVG(vhs)
 }
 
 // Apply Loop Offset by modifying frame sequence
@@ -269,8 +547,9 @@ func (vhs *VHS) Record(ctx context.Context) <-chan error {
 	ch := make(chan error)
 	interval := time.Second / time.Duration(vhs.Options.Video.Framerate)
 
+	vhs.recordStart = time.Now()
+
 	go func() {
-		counter := 0
 		start := time.Now()
 		for {
 			select {
@@ -278,7 +557,7 @@ func (vhs *VHS) Record(ctx context.Context) <-chan error {
 				_ = vhs.terminate()
 
 				// Save total # of frames for offset calculation
-				vhs.totalFrames = counter
+				vhs.totalFrames = vhs.frames
 
 				// Signal caller that we're done recording.
 				close(ch)
@@ -288,36 +567,20 @@ func (vhs *VHS) Record(ctx context.Context) <-chan error {
 				// record last attempt
 				start = time.Now()
 
-				if !vhs.recording {
+				// In the deterministic mode, the frames are captured as the
+				// tape sleeps, see sleep.
+				if vhs.Options.Deterministic {
 					continue
 				}
-				if vhs.Page == nil {
+				if !vhs.recording {
 					continue
 				}
-
-				cursor, cursorErr := vhs.CursorCanvas.CanvasToImage("image/png", quality)
-				text, textErr := vhs.TextCanvas.CanvasToImage("image/png", quality)
-				if textErr != nil || cursorErr != nil {
-					ch <- fmt.Errorf("error: %v, %v", textErr, cursorErr)
+				if vhs.Page == nil {
 					continue
 				}
 
-				counter++
-				if err := os.WriteFile(
-					filepath.Join(vhs.Options.Video.Input, fmt.Sprintf(cursorFrameFormat, counter)),
-					cursor,
-					os.ModePerm,
-				); err != nil {
-					ch <- fmt.Errorf("error writing cursor frame: %w", err)
-					continue
-				}
-				if err := os.WriteFile(
-					filepath.Join(vhs.Options.Video.Input, fmt.Sprintf(textFrameFormat, counter)),
-					text,
-					os.ModePerm,
-				); err != nil {
-					ch <- fmt.Errorf("error writing text frame: %w", err)
-					continue
+				if err := vhs.captureFrame(); err != nil {
+					ch <- err
 				}
 			}
 		}
@@ -326,6 +589,142 @@ func (vhs *VHS) Record(ctx context.Context) <-chan error {
 	return ch
 }
 
+// captureFrame captures the next frame from the xterm.js canvases.
+func (vhs *VHS) captureFrame() error {
+	interval := time.Second / time.Duration(vhs.Options.Video.Framerate)
+
+	// Inside a Speed or a Cut block, the frame is dropped or repeated.
+	copies := vhs.speedFrames()
+	if copies == 0 {
+		return nil
+	}
+
+	cursor, cursorErr := vhs.CursorCanvas.CanvasToImage("image/png", quality)
+	text, textErr := vhs.TextCanvas.CanvasToImage("image/png", quality)
+	if textErr != nil || cursorErr != nil {
+		return fmt.Errorf("error: %v, %v", textErr, cursorErr)
+	}
+
+	first := vhs.frames
+	for i := 0; i < copies; i++ {
+		vhs.frames++
+		if err := os.WriteFile(
+			filepath.Join(vhs.Options.Video.Input, fmt.Sprintf(cursorFrameFormat, vhs.frames)),
+			cursor,
+			os.ModePerm,
+		); err != nil {
+			return fmt.Errorf("error writing cursor frame: %w", err)
+		}
+		if err := os.WriteFile(
+			filepath.Join(vhs.Options.Video.Input, fmt.Sprintf(textFrameFormat, vhs.frames)),
+			text,
+			os.ModePerm,
+		); err != nil {
+			return fmt.Errorf("error writing text frame: %w", err)
+		}
+	}
+
+	// Capture the screen as text for the animated SVG.
+	if vhs.Options.Video.Output.SVG != "" {
+		if err := vhs.captureKeyframe(time.Duration(first) * interval); err != nil {
+			return fmt.Errorf("error capturing screen: %w", err)
+		}
+	}
+
+	// Capture the output of the terminal for the asciicast.
+	if vhs.Options.Video.Output.Cast != "" {
+		if err := vhs.captureCastEvent(time.Duration(first) * interval); err != nil {
+			return fmt.Errorf("error capturing terminal output: %w", err)
+		}
+	}
+	return nil
+}
+
+// sleep pauses the tape for the given duration.
+//
+// In the deterministic mode, the recording follows a virtual clock which only
+// advances as the tape sleeps. One frame is captured for every frame interval
+// of the duration, at about the time it is due, so that the number of frames
+// and their timestamps only depend on the tape and not on the load of the
+// host.
+func (vhs *VHS) sleep(d time.Duration) {
+	if !vhs.Options.Deterministic || !vhs.recording || vhs.recordStart.IsZero() || d <= 0 {
+		time.Sleep(d)
+		return
+	}
+
+	capture := vhs.captureFrame
+	if vhs.capture != nil {
+		capture = vhs.capture
+	}
+
+	interval := time.Second / time.Duration(vhs.Options.Video.Framerate)
+	start, end := time.Now(), vhs.clock+d
+	for due := time.Duration(vhs.frames) * interval; due < end; due = time.Duration(vhs.frames) * interval {
+		time.Sleep(due - vhs.clock - time.Since(start))
+		n := vhs.frames
+		if err := capture(); err != nil {
+			log.Print(err.Error())
+		}
+		if err := vhs.fillFrames(n); err != nil {
+			log.Print(err.Error())
+		}
+	}
+	time.Sleep(d - time.Since(start))
+	vhs.clock = end
+}
+
+// fillFrames fills the frame after n if it couldn't be captured, so that the
+// timing of the next ones stays the same and the sequence has no gap, at
+// which ffmpeg would stop. The missing frame is the previous one, or the first
+// one captured for the frames missing at the start.
+func (vhs *VHS) fillFrames(n int) error {
+	if vhs.frames > n {
+		// The frame was captured, and is the first one of the frames
+		// missing before it.
+		for ; vhs.missed > 0; vhs.missed-- {
+			if err := vhs.repeatFrame(n+1, vhs.missed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	vhs.frames++
+	if n == 0 || vhs.missed > 0 {
+		vhs.missed++
+		return nil
+	}
+	return vhs.repeatFrame(n, n+1)
+}
+
+// repeatFrame writes the frame from as the frame to, hard-linked if possible.
+func (vhs *VHS) repeatFrame(from, to int) error {
+	for _, format := range []string{textFrameFormat, cursorFrameFormat} {
+		src := filepath.Join(vhs.Options.Video.Input, fmt.Sprintf(format, from))
+		dst := filepath.Join(vhs.Options.Video.Input, fmt.Sprintf(format, to))
+		if err := os.Link(src, dst); err == nil {
+			continue
+		}
+		b, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("error repeating frame: %w", err)
+		}
+		if err := os.WriteFile(dst, b, os.ModePerm); err != nil {
+			return fmt.Errorf("error repeating frame: %w", err)
+		}
+	}
+	return nil
+}
+
+// elapsed returns the time elapsed since the recording started, on the
+// virtual clock in the deterministic mode.
+func (vhs *VHS) elapsed() time.Duration {
+	if vhs.Options.Deterministic {
+		return vhs.clock
+	}
+	return time.Since(vhs.recordStart)
+}
+
 // ResumeRecording indicates to VHS that the recording should be resumed.
 func (vhs *VHS) ResumeRecording() {
 	vhs.mutex.Lock()
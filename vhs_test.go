@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeterministicSleepFillsFrames(t *testing.T) {
+	dir := t.TempDir()
+	v := &VHS{
+		Options:     &Options{Deterministic: true, Video: VideoOptions{Framerate: 50, Input: dir}},
+		mutex:       &sync.Mutex{},
+		recording:   true,
+		recordStart: time.Now(),
+	}
+
+	// The first two frames and the fourth one can't be captured.
+	calls := 0
+	v.capture = func() error {
+		calls++
+		if calls == 1 || calls == 2 || calls == 4 {
+			return errors.New("the canvas is gone")
+		}
+		v.frames++
+		for _, format := range []string{textFrameFormat, cursorFrameFormat} {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(format, v.frames)), []byte(fmt.Sprint(calls)), 0o600); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	v.sleep(100 * time.Millisecond)
+
+	if v.frames != 5 {
+		t.Fatalf("expected 5 frames, got %d", v.frames)
+	}
+	expected := []string{"3", "3", "3", "3", "5"}
+	for i, want := range expected {
+		for _, format := range []string{textFrameFormat, cursorFrameFormat} {
+			b, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf(format, i+1)))
+			if err != nil {
+				t.Fatalf("expected frame %d to be filled: %v", i+1, err)
+			}
+			if string(b) != want {
+				t.Errorf("expected frame %d to be the one of call %s, got %s", i+1, want, b)
+			}
+		}
+	}
+}
@@ -1,6 +1,6 @@
 // Package vhs video.go spawns the ffmpeg process to convert the frames,
 // collected by go-rod's  screenshots into the input folder, to a GIF, WebM,
-// MP4.
+// MP4, WebP or APNG.
 //
 // MakeGIF takes several options to modify the behaviour of the ffmpeg process,
 // which can be configured through the Set command.
@@ -11,9 +11,12 @@ package main
 import (
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 const textFrameFormat = "frame-text-%05d.png"
@@ -35,6 +38,16 @@ type VideoOutputs struct {
 	GIF  string
 	WebM string
 	MP4  string
+	WebP string
+	APNG string
+	SVG  string
+	PNG  string
+	Cast string
+	SRT  string
+	VTT  string
+	// Frames is the directory of the frames, exported along with a timing
+	// manifest.
+	Frames string
 }
 
 // Options is the set of options for converting frames to a GIF.
@@ -42,6 +55,7 @@ type VideoOptions struct {
 	CleanupFrames   bool
 	Framerate       int
 	PlaybackSpeed   float64
+	Loops           int
 	Input           string
 	MaxColors       int
 	Output          VideoOutputs
@@ -50,6 +64,32 @@ type VideoOptions struct {
 	Padding         int
 	BackgroundColor string
 	StartingFrame   int
+	ShowGrid        bool
+	CRT             bool
+	CRTIntensity    float64
+	WebPQuality     int
+	WebPLossless    bool
+	Adaptive        bool
+	Grid            GridOptions
+	Overlays        []Overlay
+	Chapters        []Chapter
+	Audio           []AudioClip
+	ProgressBar     bool
+	ProgressColor   string
+	WindowBar       string
+	WindowBarSize   int
+	WindowTitle     string
+	BorderRadius    int
+	Margin          int
+	MarginFill      string
+	Shadow          bool
+	Deterministic   bool
+	MaxFileSize     int64
+	GIFFramerate    int
+	GIFScale        float64
+	// Duration is the duration of the recording, before the playback speed
+	// is applied. It is known once the recording is done.
+	Duration time.Duration
 }
 
 const defaultFramerate = 50
@@ -59,6 +99,8 @@ const defaultPadding = 72
 const defaultPlaybackSpeed = 1.0
 const defaultWidth = 1200
 const defaultStartingFrame = 1
+const defaultCRTIntensity = 0.5
+const defaultWebPQuality = 75
 
 // DefaultVideoOptions is the set of default options for converting frames
 // to a GIF, which are used if they are not overridden.
@@ -75,9 +117,134 @@ func DefaultVideoOptions() VideoOptions {
 		PlaybackSpeed:   defaultPlaybackSpeed,
 		BackgroundColor: DefaultTheme.Background,
 		StartingFrame:   defaultStartingFrame,
+		CRTIntensity:    defaultCRTIntensity,
+		WebPQuality:     defaultWebPQuality,
+		WindowBarSize:   defaultWindowBarSize,
 	}
 }
 
+// frameInputs returns the ffmpeg arguments to read the text and cursor frame
+// sequences and, if enabled, the grid overlay, the window bar, the rounded
+// corners of the window and the backdrop of the margin.
+func frameInputs(opts VideoOptions) []string {
+	args := []string{
+		"-r", fmt.Sprint(opts.Framerate),
+		"-start_number", fmt.Sprint(opts.StartingFrame),
+		"-i", filepath.Join(opts.Input, textFrameFormat),
+		"-r", fmt.Sprint(opts.Framerate),
+		"-start_number", fmt.Sprint(opts.StartingFrame),
+		"-i", filepath.Join(opts.Input, cursorFrameFormat),
+	}
+	if opts.Adaptive {
+		args = []string{
+			"-f", "concat", "-safe", "0", "-i", filepath.Join(opts.Input, textConcatFile),
+			"-f", "concat", "-safe", "0", "-i", filepath.Join(opts.Input, cursorConcatFile),
+		}
+	}
+	if opts.ShowGrid {
+		args = append(args, "-i", filepath.Join(opts.Input, gridFrame))
+	}
+	if opts.WindowBar != "" {
+		args = append(args, "-i", filepath.Join(opts.Input, windowBarFrame))
+	}
+	if opts.BorderRadius > 0 {
+		args = append(args, "-i", filepath.Join(opts.Input, windowFrame))
+	}
+	if opts.Margin > 0 {
+		args = append(args, "-i", filepath.Join(opts.Input, marginFrame))
+	}
+	return args
+}
+
+// inputCount returns the number of inputs of ffmpeg for the frames, the grid
+// and the window decorations, which come before the other inputs.
+func inputCount(opts VideoOptions) int {
+	n := 2
+	if opts.ShowGrid {
+		n++
+	}
+	if opts.WindowBar != "" {
+		n++
+	}
+	if opts.BorderRadius > 0 {
+		n++
+	}
+	if opts.Margin > 0 {
+		n++
+	}
+	return n
+}
+
+// mergeFrames returns the filter which overlays the cursor frames (and the
+// grid) on top of the text frames. The resulting stream is left unlabeled so
+// that the caller can continue or label the filter chain.
+func mergeFrames(opts VideoOptions) string {
+	if opts.ShowGrid {
+		return "[0][1]overlay[frames];[frames][2]overlay"
+	}
+	return "[0][1]overlay"
+}
+
+// fpsFilter returns the filter which resamples the frames to a constant
+// framerate. With the adaptive framerate, the variable frame durations are
+// kept instead.
+func fpsFilter(opts VideoOptions) string {
+	if opts.Adaptive {
+		return ""
+	}
+	return fmt.Sprintf("fps=%d,", opts.Framerate)
+}
+
+// frameRateMode returns the ffmpeg output arguments which keep the variable
+// frame durations of the adaptive framerate.
+func frameRateMode(opts VideoOptions) []string {
+	if opts.Adaptive {
+		return []string{"-vsync", "vfr"}
+	}
+	return nil
+}
+
+// bitexactArgs returns the ffmpeg arguments which make the outputs of the
+// deterministic mode byte-identical from one recording to the next: the
+// version of ffmpeg and the time of the encoding are left out, and the frames
+// are filtered and encoded on a single thread.
+func bitexactArgs(opts VideoOptions) []string {
+	if !opts.Deterministic {
+		return nil
+	}
+	return []string{"-fflags", "+bitexact", "-flags", "+bitexact", "-filter_threads", "1", "-threads", "1"}
+}
+
+// crtFilter returns the filters which give the frames a retro CRT look:
+// a slight barrel distortion, scanlines, and a vignette. The strength of the
+// effect is controlled by the CRT intensity.
+func crtFilter(opts VideoOptions) string {
+	if !opts.CRT {
+		return ""
+	}
+	i := math.Max(0, math.Min(1, opts.CRTIntensity))
+	return fmt.Sprintf(",lenscorrection=k1=%f:k2=%f,drawgrid=w=iw:h=3:t=1:c=black@%f,vignette=angle=%f",
+		0.1*i, 0.05*i, 0.6*i, math.Pi/(6-3*i)) //nolint:gomnd
+}
+
+// filterGraph returns the -filter_complex graph of ffmpeg shared by all the
+// videos, which merges the layers of the frames, scales them and draws the
+// padding, the window bar, the overlays, the progress bar and the margin,
+// followed by the filters of tail specific to the format.
+func filterGraph(opts VideoOptions, tail string) string {
+	return mergeFrames(opts) + fmt.Sprintf(`,scale=%d:%d:force_original_aspect_ratio=1%s,%ssetpts=PTS/%f,pad=%d:%d:(ow-iw)/2:%s:%s,fillborders=left=%d:right=%d:top=%d:bottom=%d:mode=fixed:color=%s%s`,
+		opts.Width-(opts.Padding+opts.Padding),
+		opts.Height-(opts.Padding+opts.Padding)-windowBarHeight(opts),
+		crtFilter(opts),
+		fpsFilter(opts), opts.PlaybackSpeed,
+		opts.Width, opts.Height, padY(opts),
+		opts.BackgroundColor,
+		opts.Padding, opts.Padding, opts.Padding, opts.Padding,
+		opts.BackgroundColor,
+		windowBarFilter(opts)+overlayFilter(opts)+progressFilter(opts)+marginFilter(opts),
+	) + tail
+}
+
 // MakeGIF takes a list of images (as frames) and converts them to a GIF.
 func MakeGIF(opts VideoOptions) *exec.Cmd {
 	if opts.Output.GIF == "" {
@@ -86,28 +253,52 @@ func MakeGIF(opts VideoOptions) *exec.Cmd {
 
 	fmt.Println("Creating GIF...")
 
-	//nolint:gosec
-	return exec.Command(
-		"ffmpeg", "-y",
-		"-r", fmt.Sprint(opts.Framerate),
-		"-start_number", fmt.Sprint(opts.StartingFrame),
-		"-i", filepath.Join(opts.Input, textFrameFormat),
-		"-r", fmt.Sprint(opts.Framerate),
-		"-start_number", fmt.Sprint(opts.StartingFrame),
-		"-i", filepath.Join(opts.Input, cursorFrameFormat),
+	return gifCommand(opts)
+}
+
+// gifCommand returns the ffmpeg command which encodes the GIF.
+func gifCommand(opts VideoOptions) *exec.Cmd {
+	args := append([]string{"-y"}, frameInputs(opts)...)
+	args = append(args,
 		"-filter_complex",
-		fmt.Sprintf(`[0][1]overlay[merged];[merged]scale=%d:%d:force_original_aspect_ratio=1[scaled];[scaled]fps=%d,setpts=PTS/%f[speed];[speed]pad=%d:%d:(ow-iw)/2:(oh-ih)/2:%s[padded];[padded]fillborders=left=%d:right=%d:top=%d:bottom=%d:mode=fixed:color=%s[bordered];[bordered]split[a][b];[a]palettegen=max_colors=256[p];[b][p]paletteuse[out]`,
-			opts.Width-(opts.Padding+opts.Padding),
-			opts.Height-(opts.Padding+opts.Padding),
-			opts.Framerate, opts.PlaybackSpeed,
-			opts.Width, opts.Height,
-			opts.BackgroundColor,
-			opts.Padding, opts.Padding, opts.Padding, opts.Padding,
-			opts.BackgroundColor,
-		),
+		filterGraph(opts, gifReductionFilter(opts)+fmt.Sprintf("[bordered];[bordered]split[a][b];[a]palettegen=max_colors=%d[p];[b][p]paletteuse[out]", gifColors(opts))),
 		"-map", "[out]",
-		opts.Output.GIF,
+		"-loop", gifLoop(opts.Loops),
 	)
+	args = append(args, frameRateMode(opts)...)
+	args = append(args, bitexactArgs(opts)...)
+	args = append(args, opts.Output.GIF)
+
+	//nolint:gosec
+	return exec.Command("ffmpeg", args...)
+}
+
+// gifLoop returns the -loop option of the GIF muxer of ffmpeg, which is the
+// number of times a GIF is played again after the first time, or -1 to play
+// it only once.
+func gifLoop(loops int) string {
+	if loops == 1 {
+		return "-1"
+	}
+	if loops > 1 {
+		loops--
+	}
+	return strconv.Itoa(loops)
+}
+
+// loopWarnings returns a warning for every output which is played once
+// regardless of `Set Loops`.
+func loopWarnings(opts VideoOptions) []string {
+	if opts.Loops == 0 {
+		return nil
+	}
+	var warnings []string
+	for _, output := range []string{opts.Output.MP4, opts.Output.WebM} {
+		if output != "" {
+			warnings = append(warnings, fmt.Sprintf("Set Loops is ignored for %s, which can't loop", output))
+		}
+	}
+	return warnings
 }
 
 // MakeWebM takes a list of images (as frames) and converts them to a WebM.
@@ -118,31 +309,26 @@ func MakeWebM(opts VideoOptions) *exec.Cmd {
 
 	fmt.Println("Creating WebM...")
 
-	//nolint:gosec
-	return exec.Command(
-		"ffmpeg", "-y",
-		"-r", fmt.Sprint(opts.Framerate),
-		"-start_number", fmt.Sprint(opts.StartingFrame),
-		"-i", filepath.Join(opts.Input, textFrameFormat),
-		"-r", fmt.Sprint(opts.Framerate),
-		"-start_number", fmt.Sprint(opts.StartingFrame),
-		"-i", filepath.Join(opts.Input, cursorFrameFormat),
+	chapters, mapChapters := chapterInputs(opts)
+	audio, mixAudio := audioInputs(opts)
+	args := append([]string{"-y"}, frameInputs(opts)...)
+	args = append(args, chapters...)
+	args = append(args, audio...)
+	args = append(args,
 		"-filter_complex",
-		fmt.Sprintf(`[0][1]overlay,scale=%d:%d:force_original_aspect_ratio=1,fps=%d,setpts=PTS/%f,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:%s,fillborders=left=%d:right=%d:top=%d:bottom=%d:mode=fixed:color=%s`,
-			opts.Width-(opts.Padding+opts.Padding),
-			opts.Height-(opts.Padding+opts.Padding),
-			opts.Framerate, opts.PlaybackSpeed,
-			opts.Width, opts.Height,
-			opts.BackgroundColor,
-			opts.Padding, opts.Padding, opts.Padding, opts.Padding,
-			opts.BackgroundColor,
-		),
+		filterGraph(opts, mixAudio),
 		"-pix_fmt", "yuv420p",
-		"-an",
 		"-crf", "30",
 		"-b:v", "0",
-		opts.Output.WebM,
 	)
+	args = append(args, audioOutput(opts, "libopus")...)
+	args = append(args, mapChapters...)
+	args = append(args, frameRateMode(opts)...)
+	args = append(args, bitexactArgs(opts)...)
+	args = append(args, opts.Output.WebM)
+
+	//nolint:gosec
+	return exec.Command("ffmpeg", args...)
 }
 
 // MakeMP4 takes a list of images (as frames) and converts them to an MP4.
@@ -153,29 +339,84 @@ func MakeMP4(opts VideoOptions) *exec.Cmd {
 
 	fmt.Println("Creating MP4...")
 
-	//nolint:gosec
-	return exec.Command(
-		"ffmpeg", "-y",
-		"-r", fmt.Sprint(opts.Framerate),
-		"-start_number", fmt.Sprint(opts.StartingFrame),
-		"-i", filepath.Join(opts.Input, textFrameFormat),
-		"-r", fmt.Sprint(opts.Framerate),
-		"-start_number", fmt.Sprint(opts.StartingFrame),
-		"-i", filepath.Join(opts.Input, cursorFrameFormat),
+	chapters, mapChapters := chapterInputs(opts)
+	audio, mixAudio := audioInputs(opts)
+	args := append([]string{"-y"}, frameInputs(opts)...)
+	args = append(args, chapters...)
+	args = append(args, audio...)
+	args = append(args,
 		"-filter_complex",
-		fmt.Sprintf(`[0][1]overlay,scale=%d:%d:force_original_aspect_ratio=1,fps=%d,setpts=PTS/%f,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:%s,fillborders=left=%d:right=%d:top=%d:bottom=%d:mode=fixed:color=%s`,
-			opts.Width-(opts.Padding+opts.Padding),
-			opts.Height-(opts.Padding+opts.Padding),
-			opts.Framerate, opts.PlaybackSpeed,
-			opts.Width, opts.Height,
-			opts.BackgroundColor,
-			opts.Padding, opts.Padding, opts.Padding, opts.Padding,
-			opts.BackgroundColor,
-		),
+		filterGraph(opts, mixAudio),
 		"-vcodec", "libx264",
 		"-pix_fmt", "yuv420p",
-		"-an",
 		"-crf", "20",
-		opts.Output.MP4,
 	)
+	args = append(args, audioOutput(opts, "aac")...)
+	args = append(args, mapChapters...)
+	args = append(args, frameRateMode(opts)...)
+	args = append(args, bitexactArgs(opts)...)
+	args = append(args, opts.Output.MP4)
+
+	//nolint:gosec
+	return exec.Command("ffmpeg", args...)
+}
+
+// MakeWebP takes a list of images (as frames) and converts them to an
+// animated WebP, with a lossy quality.
+func MakeWebP(opts VideoOptions) *exec.Cmd {
+	if opts.Output.WebP == "" {
+		return nil
+	}
+
+	fmt.Println("Creating WebP...")
+
+	args := append([]string{"-y"}, frameInputs(opts)...)
+	args = append(args,
+		"-filter_complex",
+		filterGraph(opts, ""),
+		"-vcodec", "libwebp",
+		"-lossless", webPLossless(opts),
+		"-quality", strconv.Itoa(opts.WebPQuality),
+		"-loop", strconv.Itoa(opts.Loops),
+		"-an",
+	)
+	args = append(args, frameRateMode(opts)...)
+	args = append(args, bitexactArgs(opts)...)
+	args = append(args, opts.Output.WebP)
+
+	//nolint:gosec
+	return exec.Command("ffmpeg", args...)
+}
+
+// webPLossless returns the -lossless option of the WebP encoder of ffmpeg.
+func webPLossless(opts VideoOptions) string {
+	if opts.WebPLossless {
+		return "1"
+	}
+	return "0"
+}
+
+// MakeAPNG takes a list of images (as frames) and converts them to an
+// animated PNG.
+func MakeAPNG(opts VideoOptions) *exec.Cmd {
+	if opts.Output.APNG == "" {
+		return nil
+	}
+
+	fmt.Println("Creating APNG...")
+
+	args := append([]string{"-y"}, frameInputs(opts)...)
+	args = append(args,
+		"-filter_complex",
+		filterGraph(opts, ""),
+		"-f", "apng",
+		"-plays", strconv.Itoa(opts.Loops),
+		"-an",
+	)
+	args = append(args, frameRateMode(opts)...)
+	args = append(args, bitexactArgs(opts)...)
+	args = append(args, opts.Output.APNG)
+
+	//nolint:gosec
+	return exec.Command("ffmpeg", args...)
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestMakeWebPAndAPNG(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = "frames"
+
+	if MakeWebP(opts) != nil || MakeAPNG(opts) != nil {
+		t.Fatal("expected no command without an output")
+	}
+
+	opts.Output.WebP = "out.webp"
+	opts.Output.APNG = "out.apng"
+
+	webp := strings.Join(MakeWebP(opts).Args, " ")
+	if !strings.Contains(webp, "-vcodec libwebp -lossless 0 -quality 75 -loop 0") || !strings.HasSuffix(webp, "out.webp") {
+		t.Errorf("unexpected WebP command: %s", webp)
+	}
+
+	apng := strings.Join(MakeAPNG(opts).Args, " ")
+	if !strings.Contains(apng, "-f apng -plays 0") || !strings.HasSuffix(apng, "out.apng") {
+		t.Errorf("unexpected APNG command: %s", apng)
+	}
+
+	opts.WebPQuality = 90
+	opts.WebPLossless = true
+	if webp := strings.Join(MakeWebP(opts).Args, " "); !strings.Contains(webp, "-lossless 1 -quality 90") {
+		t.Errorf("expected a lossless WebP: %s", webp)
+	}
+
+	opts.Loops = 3
+	if webp := strings.Join(MakeWebP(opts).Args, " "); !strings.Contains(webp, "-loop 3") {
+		t.Errorf("expected the WebP to play 3 times: %s", webp)
+	}
+	if apng := strings.Join(MakeAPNG(opts).Args, " "); !strings.Contains(apng, "-plays 3") {
+		t.Errorf("expected the APNG to play 3 times: %s", apng)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	for loops, expected := range map[int]string{0: "0", 1: "-1", 2: "1", 5: "4"} {
+		if got := gifLoop(loops); got != expected {
+			t.Errorf("expected -loop %s for %d plays, got %s", expected, loops, got)
+		}
+	}
+
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Output.MP4 = "out.mp4"
+	if warnings := loopWarnings(opts); len(warnings) != 0 {
+		t.Errorf("expected no warnings when looping forever, got %v", warnings)
+	}
+	opts.Loops = 1
+	if warnings := loopWarnings(opts); len(warnings) != 1 || !strings.Contains(warnings[0], "out.mp4") {
+		t.Errorf("expected a warning for the MP4, got %v", warnings)
+	}
+}
+
+func TestBitexact(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Output.GIF = "out.gif"
+	opts.Output.MP4 = "out.mp4"
+	opts.Output.WebM = "out.webm"
+
+	for _, cmd := range []*exec.Cmd{MakeGIF(opts), MakeMP4(opts), MakeWebM(opts)} {
+		if args := strings.Join(cmd.Args, " "); strings.Contains(args, "bitexact") {
+			t.Errorf("expected no bitexact flags by default: %s", args)
+		}
+	}
+
+	opts.Deterministic = true
+	for _, cmd := range []*exec.Cmd{MakeGIF(opts), MakeMP4(opts), MakeWebM(opts)} {
+		if args := strings.Join(cmd.Args, " "); !strings.Contains(args, "-fflags +bitexact -flags +bitexact -filter_threads 1 -threads 1 ") {
+			t.Errorf("expected the bitexact flags before the output: %s", args)
+		}
+	}
+}
+
+func TestFilterGraph(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Output = VideoOutputs{GIF: "out.gif", WebM: "out.webm", MP4: "out.mp4", WebP: "out.webp", APNG: "out.apng"}
+
+	graph := filterGraph(opts, "")
+	for _, cmd := range []*exec.Cmd{MakeGIF(opts), MakeWebM(opts), MakeMP4(opts), MakeWebP(opts), MakeAPNG(opts)} {
+		var filter string
+		for i, arg := range cmd.Args {
+			if arg == "-filter_complex" && i+1 < len(cmd.Args) {
+				filter = cmd.Args[i+1]
+			}
+		}
+		if !strings.HasPrefix(filter, graph) {
+			t.Errorf("expected the filter graph to start with %q, got %q", graph, filter)
+		}
+	}
+}
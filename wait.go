@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/vhs/parser"
+)
+
+// The scopes of the Wait command.
+const (
+	waitLine   = parser.WaitLine
+	waitScreen = parser.WaitScreen
+)
+
+const (
+	// defaultWaitTimeout is how long Wait waits for a match when no timeout
+	// is given.
+	defaultWaitTimeout = 5 * time.Second
+	// waitPollInterval is how often the terminal is read while waiting.
+	waitPollInterval = 50 * time.Millisecond
+	// promptPattern matches the prompt of the shells, which Wait waits for
+	// when no regular expression is given: the command is done once the
+	// prompt is back on the last line.
+	promptPattern = parser.PromptPattern
+)
+
+// ExecuteWait is a CommandFunc that waits until the regular expression
+// matches the last line or the whole screen of the terminal, depending on the
+// scope of the command. It fails if there is no match before the timeout.
+func ExecuteWait(c Command, v *VHS) {
+	scope, pattern, _ := strings.Cut(c.Args, " ")
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/")
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		v.Errors = append(v.Errors, fmt.Errorf("invalid regular expression /%s/: %w", pattern, err))
+		return
+	}
+
+	timeout := defaultWaitTimeout
+	if c.Options != "" {
+		timeout, err = time.ParseDuration(c.Options)
+		if err != nil || timeout <= 0 {
+			v.Errors = append(v.Errors, fmt.Errorf("invalid timeout %s of Wait /%s/: expected a duration", c.Options, pattern))
+			return
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		screen, err := v.currentScreen()
+		if err != nil {
+			v.Errors = append(v.Errors, fmt.Errorf("failed to read the screen while waiting for /%s/: %w", pattern, err))
+			return
+		}
+		if re.MatchString(waitTarget(screen, scope)) {
+			return
+		}
+		if time.Now().After(deadline) {
+			v.Errors = append(v.Errors, fmt.Errorf("timed out after %s waiting for /%s/ to match the %s", timeout, pattern, strings.ToLower(scope)))
+			return
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// waitTarget returns the text of the screen which Wait matches against: the
+// last line which isn't blank for the Line scope, or all the lines otherwise.
+func waitTarget(screen []string, scope string) string {
+	if scope != waitLine {
+		return strings.Join(screen, "\n")
+	}
+	for i := len(screen) - 1; i >= 0; i-- {
+		if strings.TrimSpace(screen[i]) != "" {
+			return screen[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestWaitTarget(t *testing.T) {
+	screen := []string{"$ make", "ok", "> ", "", ""}
+
+	if got := waitTarget(screen, waitLine); got != "> " {
+		t.Errorf("expected the last line which isn't blank, got %q", got)
+	}
+	if got := waitTarget(screen, waitScreen); got != "$ make\nok\n> \n\n" {
+		t.Errorf("expected the whole screen, got %q", got)
+	}
+	if got := waitTarget([]string{"", ""}, waitLine); got != "" {
+		t.Errorf("expected an empty line for a blank screen, got %q", got)
+	}
+}
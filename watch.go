@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+// watchDebounce is how long to wait for the writes to a file to settle before
+// recording again, since editors often write a file several times on save.
+const watchDebounce = 200 * time.Millisecond
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <file>",
+	Short: "Record a tape file again every time it changes",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vars, err := parseVars(varFlags)
+		if err != nil {
+			return err
+		}
+		if err := checkEnv(envFlags); err != nil {
+			return err
+		}
+		if err := checkRemote(sshFlag, containerFlag); err != nil {
+			return err
+		}
+		return runWatch(cmd, args, vars)
+	},
+}
+
+// runWatch watches the tape file of vhs watch and vhs --watch.
+func runWatch(cmd *cobra.Command, args []string, vars map[string]string) error {
+	if len(args) != 1 || args[0] == "-" {
+		return errors.New("--watch expects a single tape file")
+	}
+	if !skipDependencyCheck() {
+		if err := ensureDependencies(); err != nil {
+			return err
+		}
+	}
+	opts, err := evaluatorOptions(vars)
+	if err != nil {
+		return err
+	}
+	return Watch(cmd.Context(), args[0], os.Stdout, opts...)
+}
+
+// Watch records the tape file, then records it again whenever it changes,
+// until the context is canceled. Errors are printed and the file keeps being
+// watched, so that they can be fixed in the tape.
+func Watch(ctx context.Context, file string, out io.Writer, opts ...EvaluatorOption) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = watcher.Close() }()
+
+	// Editors often save by replacing the file, which ends the watch of the
+	// file itself, so the directories are watched instead.
+	watched := map[string]bool{}
+	watch := func(files []string) error {
+		for _, f := range files {
+			path, err := filepath.Abs(f)
+			if err != nil {
+				return err
+			}
+			if watched[path] {
+				continue
+			}
+			watched[path] = true
+			if err := watcher.Add(filepath.Dir(path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := watch([]string{file}); err != nil {
+		return err
+	}
+
+	vars := evaluatorVars(opts)
+	record := func() {
+		fmt.Fprintf(out, "%s Recording %s\n", time.Now().Format("15:04:05"), file)
+		tape, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, ErrorStyle.Render(err.Error()))
+			return
+		}
+		// The sourced tapes are watched as well, as soon as they are added
+		// to the tape.
+		if err := watch(watchedFiles(string(tape), vars)); err != nil {
+			fmt.Fprintln(os.Stderr, ErrorStyle.Render(err.Error()))
+		}
+		if errs := Evaluate(ctx, string(tape), out, opts...); len(errs) > 0 {
+			printErrors(os.Stderr, string(tape), errs)
+			return
+		}
+		fmt.Fprintf(out, "%s Watching %s for changes...\n", time.Now().Format("15:04:05"), file)
+	}
+	record()
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			path, _ := filepath.Abs(event.Name)
+			if !watched[path] || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			debounce.Reset(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintln(os.Stderr, ErrorStyle.Render(err.Error()))
+		case <-debounce.C:
+			record()
+		}
+	}
+}
+
+// watchedFiles returns the files, other than the tape itself, which affect its
+// recording: the tapes it sources.
+func watchedFiles(tape string, vars map[string]string) []string {
+	p := NewParser(NewLexer(tape), WithParserVars(vars))
+	p.Parse()
+	return p.Sourced()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a buffer which can be written to and read from concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "demo.tape")
+	// Invalid tapes are not recorded, so the test doesn't need ttyd.
+	if err := os.WriteFile(file, []byte("Foo"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var out syncBuffer
+	done := make(chan error)
+	go func() { done <- Watch(ctx, file, &out) }()
+
+	waitFor := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Count(out.String(), "Recording") < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d recordings, got:\n%s", n, out.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor(1)
+	// Two writes in a row only record the tape once.
+	for _, tape := range []string{"Bar", "Baz"} {
+		if err := os.WriteFile(file, []byte(tape), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(2)
+	time.Sleep(2 * watchDebounce)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "Recording"); n != 2 {
+		t.Errorf("expected 2 recordings, got %d:\n%s", n, out.String())
+	}
+}
+
+func TestWatchedFiles(t *testing.T) {
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common.tape")
+	setup := filepath.Join(dir, "setup.tape")
+	if err := os.WriteFile(common, []byte("Set FontSize 20"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(setup, []byte(`Source "`+common+`"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tape := `Source "${SETUP}"
+Repeat 2 { Type "hello" }`
+	files := watchedFiles(tape, map[string]string{"SETUP": setup})
+	if strings.Join(files, ",") != setup+","+common {
+		t.Errorf("expected the sourced tapes to be watched, got %v", files)
+	}
+}
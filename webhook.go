@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxWebhookSize is the maximum size of the payload of a webhook.
+const maxWebhookSize = 25 << 20
+
+var (
+	repoNameRegex = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+	commitRegex   = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// pushEvent is the part of the payload of a GitHub push which is needed to
+// render the tapes of the commit.
+type pushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		CloneURL      string `json:"clone_url"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+}
+
+// webhookHandler receives the pushes of GitHub repositories, and renders the
+// tapes of the pushed commit when it is on the default branch.
+type webhookHandler struct {
+	queue  *renderQueue
+	secret string
+	// path is the directory of the repositories with the tapes.
+	path string
+	// artifacts is the directory where the artifacts are kept, under the name
+	// of the repository and the commit. If empty, they are removed along with
+	// their job.
+	artifacts string
+}
+
+// verifySignature checks the signature of the payload, which GitHub sends as
+// the HMAC of the secret, in hex.
+func verifySignature(secret string, payload []byte, signature string) error {
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return errors.New("missing or invalid signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("the signature doesn't match")
+	}
+	return nil
+}
+
+// validRepoName reports whether the name is an owner and a repository, which
+// can be used as a path.
+func validRepoName(name string) bool {
+	if !repoNameRegex.MatchString(name) {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP queues the rendering of the pushed commit. Other events, and
+// pushes to other branches, are acknowledged and ignored.
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := verifySignature(h.secret, payload, r.Header.Get("X-Hub-Signature-256")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("X-GitHub-Event") != "push" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var event pushEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	repo := event.Repository
+	if event.Deleted || event.Ref != "refs/heads/"+repo.DefaultBranch {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !validRepoName(repo.FullName) || !commitRegex.MatchString(event.After) {
+		http.Error(w, "invalid repository or commit", http.StatusBadRequest)
+		return
+	}
+
+	opts := RepoOptions{URL: repo.CloneURL, Ref: event.After, Path: h.path}
+	if h.artifacts != "" {
+		opts.Artifacts = filepath.Join(h.artifacts, filepath.FromSlash(repo.FullName), event.After)
+	}
+	j, err := h.queue.submitRepo(opts)
+	if errors.Is(err, errQueueFull) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s, _ := j.snapshot()
+	writeJSON(w, http.StatusAccepted, s)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	_, _ = mac.Write([]byte("payload"))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if err := verifySignature("secret", []byte("payload"), signature); err != nil {
+		t.Error(err)
+	}
+	for _, signature := range []string{"", "sha256=zz", signature[len("sha256="):], "sha256=" + strings.Repeat("0", 64)} {
+		if err := verifySignature("secret", []byte("payload"), signature); err == nil {
+			t.Errorf("expected %q to be rejected", signature)
+		}
+	}
+}
+
+func TestValidRepoName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"charmbracelet/vhs":     true,
+		"charmbracelet/.github": true,
+		"charmbracelet":         false,
+		"../vhs":                false,
+		"charmbracelet/..":      false,
+		"a/b/c":                 false,
+	} {
+		if got := validRepoName(name); got != want {
+			t.Errorf("validRepoName(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{"demo.tape": "Output demo.gif"})
+	fakeRenderTapes(t)
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = repo
+	sha, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := newRenderQueue(context.Background(), t.TempDir(), 1, 1, time.Minute)
+	artifacts := t.TempDir()
+	srv := httptest.NewServer(renderAPI(q, "token", &webhookHandler{queue: q, secret: "secret", path: ".", artifacts: artifacts}))
+	defer srv.Close()
+
+	send := func(event, ref string) *http.Response {
+		payload, _ := json.Marshal(map[string]any{
+			"ref":   ref,
+			"after": strings.TrimSpace(string(sha)),
+			"repository": map[string]string{
+				"full_name":      "charmbracelet/vhs",
+				"clone_url":      repo,
+				"default_branch": "main",
+			},
+		})
+		mac := hmac.New(sha256.New, []byte("secret"))
+		_, _ = mac.Write(payload)
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/webhook", strings.NewReader(string(payload)))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, ignored := range [][2]string{{"ping", "refs/heads/main"}, {"push", "refs/heads/feature"}} {
+		resp := send(ignored[0], ignored[1])
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("expected %s to be ignored, got %d", ignored, resp.StatusCode)
+		}
+	}
+
+	resp := send("push", "refs/heads/main")
+	var job renderJobStatus
+	_ = json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected the push to be rendered, got %d", resp.StatusCode)
+	}
+
+	j, _ := q.job(job.ID)
+	for {
+		s, changed := j.snapshot()
+		if s.finished() {
+			job = s
+			break
+		}
+		<-changed
+	}
+	if job.Status != jobDone || len(job.Artifacts) != 1 {
+		t.Fatalf("expected the job to have an artifact, got %+v", job)
+	}
+	gif := filepath.Join(artifacts, "charmbracelet", "vhs", strings.TrimSpace(string(sha)), "demo.gif")
+	if _, err := os.Stat(gif); err != nil {
+		t.Errorf("expected the artifact to be kept: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+job.Artifacts[0], nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the artifact to be served, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// windowBarFrame is the file name of the window bar in the frames directory.
+const windowBarFrame = "window-bar.png"
+
+// windowFrame is the file name of the rounded corners of the window in the
+// frames directory.
+const windowFrame = "window-frame.png"
+
+const defaultWindowBarSize = 30
+
+// windowBarStyles are the styles of Set WindowBar, and whether their buttons
+// are on the right.
+var windowBarStyles = map[string]bool{
+	"Colorful":      false,
+	"ColorfulRight": true,
+	"Rings":         false,
+	"RingsRight":    true,
+}
+
+var (
+	windowBarButtons = []color.NRGBA{
+		{R: 0xff, G: 0x5f, B: 0x58, A: 0xff},
+		{R: 0xff, G: 0xbd, B: 0x2e, A: 0xff},
+		{R: 0x18, G: 0xc1, B: 0x32, A: 0xff},
+	}
+	windowTitleColor  = color.NRGBA{R: 0xa0, G: 0xa0, B: 0xa0, A: 0xff}
+	windowBorderColor = color.NRGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xff}
+)
+
+// windowBarHeight returns the height of the window bar, or 0 if there is none.
+func windowBarHeight(opts VideoOptions) int {
+	if opts.WindowBar == "" {
+		return 0
+	}
+	return opts.WindowBarSize
+}
+
+// MakeWindowBar draws the window bar, as wide as the terminal, with its three
+// buttons and the title centered. It is overlaid on top of every frame, right
+// above the terminal.
+func MakeWindowBar(opts VideoOptions) error {
+	width := opts.Width - opts.Padding - opts.Padding
+	height := opts.WindowBarSize
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid window bar dimensions: %dx%d", width, height)
+	}
+
+	bg, err := parseHexColor(opts.BackgroundColor)
+	if err != nil {
+		return err
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	radius := height / 5  //nolint:gomnd
+	spacing := radius * 3 //nolint:gomnd
+	x := height / 2       //nolint:gomnd
+	if windowBarStyles[opts.WindowBar] {
+		x = width - height/2 - spacing*(len(windowBarButtons)-1)
+	}
+	ring := opts.WindowBar == "Rings" || opts.WindowBar == "RingsRight"
+	for i, c := range windowBarButtons {
+		drawCircle(img, x+i*spacing, height/2, radius, c, ring)
+	}
+
+	if opts.WindowTitle != "" {
+		face := basicfont.Face7x13
+		d := font.Drawer{Dst: img, Src: image.NewUniform(windowTitleColor), Face: face}
+		w := d.MeasureString(opts.WindowTitle).Round()
+		d.Dot = fixed.P((width-w)/2, (height+face.Ascent-face.Descent)/2) //nolint:gomnd
+		d.DrawString(opts.WindowTitle)
+	}
+
+	f, err := os.Create(filepath.Join(opts.Input, windowBarFrame))
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	return png.Encode(f, img)
+}
+
+// drawCircle draws a filled circle, or only its outline for a ring.
+func drawCircle(img *image.NRGBA, cx, cy, r int, c color.NRGBA, ring bool) {
+	inner := (r - 2) * (r - 2) //nolint:gomnd
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			d := x*x + y*y
+			if d > r*r || (ring && d < inner) {
+				continue
+			}
+			img.SetNRGBA(cx+x, cy+y, c)
+		}
+	}
+}
+
+// MakeWindowFrame draws the rounded corners of the window, which is the
+// terminal and its window bar, along with its outline. The corners are filled
+// with the background, and the rest is transparent. It is overlaid on top of
+// every frame.
+func MakeWindowFrame(opts VideoOptions) error {
+	bg, err := parseHexColor(opts.BackgroundColor)
+	if err != nil {
+		return err
+	}
+	window := image.Rect(opts.Padding, opts.Padding, opts.Width-opts.Padding, opts.Height-opts.Padding)
+	r := opts.BorderRadius
+	if max := window.Dx() / 2; r > max { //nolint:gomnd
+		r = max
+	}
+	if max := window.Dy() / 2; r > max { //nolint:gomnd
+		r = max
+	}
+	if r <= 0 {
+		return fmt.Errorf("invalid window dimensions: %dx%d", window.Dx(), window.Dy())
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	for y := window.Min.Y; y < window.Max.Y; y++ {
+		for x := window.Min.X; x < window.Max.X; x++ {
+			// The distance to the center of the corner, if in a corner.
+			dx, dy := 0.0, 0.0
+			if cx := window.Min.X + r; x < cx {
+				dx = float64(cx-x) - 0.5 //nolint:gomnd
+			} else if cx := window.Max.X - r; x >= cx {
+				dx = float64(x-cx) + 0.5 //nolint:gomnd
+			}
+			if cy := window.Min.Y + r; y < cy {
+				dy = float64(cy-y) - 0.5 //nolint:gomnd
+			} else if cy := window.Max.Y - r; y >= cy {
+				dy = float64(y-cy) + 0.5 //nolint:gomnd
+			}
+			edge := x == window.Min.X || x == window.Max.X-1 || y == window.Min.Y || y == window.Max.Y-1
+			switch d := math.Hypot(dx, dy); {
+			case dx > 0 && dy > 0 && d > float64(r):
+				img.SetNRGBA(x, y, bg)
+			case dx > 0 && dy > 0 && d > float64(r-1):
+				img.SetNRGBA(x, y, windowBorderColor)
+			case edge && (dx == 0 || dy == 0):
+				img.SetNRGBA(x, y, windowBorderColor)
+			}
+		}
+	}
+
+	f, err := os.Create(filepath.Join(opts.Input, windowFrame))
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+
+	return png.Encode(f, img)
+}
+
+// windowBarFilter returns the filter which overlays the window bar above the
+// terminal and the rounded corners of the window, once the frames are padded.
+// The stream is left unlabeled, as with mergeFrames.
+func windowBarFilter(opts VideoOptions) string {
+	input := 2
+	if opts.ShowGrid {
+		input++
+	}
+	var filter string
+	if opts.WindowBar != "" {
+		filter = fmt.Sprintf("[framed];[framed][%d]overlay=%d:%d", input, opts.Padding, opts.Padding)
+		input++
+	}
+	if opts.BorderRadius > 0 {
+		filter += fmt.Sprintf("[windowed];[windowed][%d]overlay=0:0", input)
+	}
+	return filter
+}
+
+// padY returns the vertical position of the terminal in the padded frames,
+// which makes room for the window bar above it.
+func padY(opts VideoOptions) string {
+	if bar := windowBarHeight(opts); bar > 0 {
+		return fmt.Sprintf("(oh-ih+%d)/2", bar)
+	}
+	return "(oh-ih)/2"
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMakeWindowBar(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = t.TempDir()
+	opts.WindowBar = "ColorfulRight"
+	opts.WindowTitle = "demo"
+	if err := MakeWindowBar(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filepath.Join(opts.Input, windowBarFrame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	width := opts.Width - 2*opts.Padding
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != opts.WindowBarSize {
+		t.Fatalf("expected a window bar of %dx%d, got %dx%d", width, opts.WindowBarSize, b.Dx(), b.Dy())
+	}
+	// The last button, on the right, is green.
+	if c := color.NRGBAModel.Convert(img.At(width-15, 15)); c != windowBarButtons[2] {
+		t.Errorf("expected the green button on the right, got %v", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(15, 15)); c == windowBarButtons[0] {
+		t.Errorf("expected no button on the left")
+	}
+}
+
+func TestWindowBarFilter(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = "frames"
+	opts.Output.MP4 = "out.mp4"
+
+	plain := strings.Join(MakeMP4(opts).Args, " ")
+	if strings.Contains(plain, windowBarFrame) || !strings.Contains(plain, "scale=1056:456:") || !strings.Contains(plain, ":(ow-iw)/2:(oh-ih)/2:") {
+		t.Errorf("expected no window bar by default: %s", plain)
+	}
+
+	opts.WindowBar = "Rings"
+	bar := strings.Join(MakeMP4(opts).Args, " ")
+	for _, want := range []string{
+		"-i frames/" + windowBarFrame,
+		"scale=1056:426:",
+		":(ow-iw)/2:(oh-ih+30)/2:",
+		"[framed];[framed][2]overlay=72:72",
+	} {
+		if !strings.Contains(bar, want) {
+			t.Errorf("expected %q in the command: %s", want, bar)
+		}
+	}
+}
+
+func TestMakeWindowFrame(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = t.TempDir()
+	opts.BorderRadius = 10
+	if err := MakeWindowFrame(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filepath.Join(opts.Input, windowFrame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bg, _ := parseHexColor(opts.BackgroundColor)
+	p := opts.Padding
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{p, p, bg},
+		{opts.Width - p - 1, opts.Height - p - 1, bg},
+		{p + 10, p, windowBorderColor},
+		{p, opts.Height / 2, windowBorderColor},
+		{p + 10, p + 10, color.NRGBA{}},
+		{p - 1, p - 1, color.NRGBA{}},
+	} {
+		if c := color.NRGBAModel.Convert(img.At(tc.x, tc.y)); c != tc.want {
+			t.Errorf("expected %v at %d,%d, got %v", tc.want, tc.x, tc.y, c)
+		}
+	}
+}
+
+func TestWindowFrameFilter(t *testing.T) {
+	opts := DefaultVideoOptions()
+	_ = os.RemoveAll(opts.Input)
+	opts.Input = "frames"
+	opts.Output.MP4 = "out.mp4"
+	opts.WindowBar = "Colorful"
+	opts.BorderRadius = 8
+
+	cmd := strings.Join(MakeMP4(opts).Args, " ")
+	for _, want := range []string{
+		"-i frames/" + windowBarFrame + " -i frames/" + windowFrame,
+		"[framed];[framed][2]overlay=72:72[windowed];[windowed][3]overlay=0:0",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in the command: %s", want, cmd)
+		}
+	}
+
+	v := New()
+	_ = os.RemoveAll(v.Options.Video.Input)
+	ExecuteSetWindowBar(Command{Type: SET, Options: "WindowBar", Args: "Rings"}, &v)
+	ExecuteSetWindowBar(Command{Type: SET, Options: "WindowBar", Args: "None"}, &v)
+	if v.Options.Video.WindowBar != "" || len(v.Errors) != 0 {
+		t.Errorf("expected no window bar with None, got %q (%v)", v.Options.Video.WindowBar, v.Errors)
+	}
+}